@@ -0,0 +1,302 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/updater"
+)
+
+// doctorCheck is one line of the `ccdash doctor` checklist
+type doctorCheck struct {
+	name     string
+	ok       bool
+	optional bool     // A failed optional check doesn't fail the command
+	detail   string   // Shown after the name
+	extra    []string // Further indented lines, e.g. one per file
+	hint     string   // Remediation, shown when the check fails
+}
+
+// runDoctor implements `ccdash doctor`, which checks the environment for the
+// usual causes of an empty dashboard. Returns the process exit code.
+func runDoctor(args []string) int {
+	fs := flag.NewFlagSet("doctor", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash doctor")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Checks the terminal, tmux, Claude Code data, the token cache and hooks.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	projectsDir := metrics.ClaudeProjectsDir()
+
+	checks := []doctorCheck{
+		checkTrueColor(),
+		checkTmux(),
+		checkProjectsDir(projectsDir),
+		checkCwdProject(projectsDir),
+		checkTokenCache(),
+		checkDataDirs(),
+		checkHooks(),
+		checkBinaries(),
+	}
+
+	failed := 0
+	for _, c := range checks {
+		mark := "✓"
+		if !c.ok {
+			mark = "✗"
+			if !c.optional {
+				failed++
+			}
+		}
+		line := mark + " " + c.name
+		if c.detail != "" {
+			line += ": " + c.detail
+		}
+		fmt.Println(line)
+		for _, e := range c.extra {
+			fmt.Println("    " + e)
+		}
+		if !c.ok && c.hint != "" {
+			fmt.Println("    → " + c.hint)
+		}
+	}
+
+	fmt.Println()
+	if failed > 0 {
+		fmt.Printf("%d problem(s) found.\n", failed)
+		return 1
+	}
+	fmt.Println("No problems found.")
+	return 0
+}
+
+// checkTrueColor reports whether the terminal advertises 24-bit color
+func checkTrueColor() doctorCheck {
+	c := doctorCheck{name: "True-color terminal", optional: true}
+	colorterm := os.Getenv("COLORTERM")
+	switch strings.ToLower(colorterm) {
+	case "truecolor", "24bit":
+		c.ok = true
+		c.detail = "COLORTERM=" + colorterm
+	default:
+		c.detail = "COLORTERM is not truecolor; colors fall back to the 256-color palette"
+		c.hint = "Export COLORTERM=truecolor if your terminal supports it"
+		if os.Getenv("TMUX") != "" {
+			c.hint += `; in tmux also add: set -ga terminal-overrides ",*:Tc"`
+		}
+	}
+	return c
+}
+
+// checkTmux reports whether tmux is installed and its version
+func checkTmux() doctorCheck {
+	c := doctorCheck{name: "tmux", optional: true}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, "tmux", "-V").Output()
+	if err != nil {
+		c.detail = "not found in PATH"
+		c.hint = "Install tmux to see sessions from pane inspection, or use hooks (ccdash --install-hooks)"
+		return c
+	}
+	c.ok = true
+	c.detail = strings.TrimSpace(string(out))
+	return c
+}
+
+// checkProjectsDir reports whether Claude Code's log directory exists and has projects
+func checkProjectsDir(projectsDir string) doctorCheck {
+	c := doctorCheck{name: "Claude Code projects"}
+	entries, err := os.ReadDir(projectsDir)
+	if err != nil {
+		c.detail = projectsDir + " not found"
+		c.hint = "Run Claude Code at least once to create it, or point ccdash at other roots with --extra-dirs"
+		return c
+	}
+
+	projects := 0
+	for _, e := range entries {
+		if e.IsDir() {
+			projects++
+		}
+	}
+	c.ok = projects > 0
+	c.detail = fmt.Sprintf("%s (%d projects)", projectsDir, projects)
+	if !c.ok {
+		c.hint = "No token data yet; it appears after your first Claude Code conversation"
+	}
+	return c
+}
+
+// checkCwdProject reports whether the working directory has a Claude Code project
+func checkCwdProject(projectsDir string) doctorCheck {
+	c := doctorCheck{name: "Current directory", optional: true}
+	cwd, err := os.Getwd()
+	if err != nil {
+		c.detail = err.Error()
+		return c
+	}
+
+	projectPath := filepath.Join(projectsDir, metrics.ProjectDirName(cwd))
+	if _, err := os.Stat(projectPath); err != nil {
+		c.detail = "no Claude Code project for " + cwd
+		c.hint = "Only informational: token totals cover every project"
+		return c
+	}
+	c.ok = true
+	c.detail = "matches " + projectPath
+	return c
+}
+
+// checkTokenCache opens the token cache and checks it is writable and in WAL mode
+func checkTokenCache() doctorCheck {
+	c := doctorCheck{name: "Token cache"}
+	cache := metrics.NewTokenCache()
+	defer cache.Close()
+
+	path := cache.GetDBPath()
+	if cache.GetDB() == nil {
+		c.detail = "could not open " + path
+		c.hint = "Check that the directory is writable"
+		return c
+	}
+	if err := cache.CheckWritable(); err != nil {
+		c.detail = fmt.Sprintf("%s is not writable: %v", path, err)
+		c.hint = "Fix the file's permissions, or press X twice in the dashboard to rebuild it"
+		return c
+	}
+
+	mode, err := cache.JournalMode()
+	if err != nil {
+		c.detail = fmt.Sprintf("%s: could not read journal mode: %v", path, err)
+		return c
+	}
+	c.detail = fmt.Sprintf("%s (journal mode %s)", path, mode)
+	if cache.RebuiltAfterCorruption() {
+		c.extra = append(c.extra, "was corrupt; rebuilt empty, old file moved to "+cache.CorruptBackupPath())
+	}
+	c.ok = strings.EqualFold(mode, "wal")
+	if !c.ok {
+		c.hint = "WAL mode is needed for several ccdash instances to share the cache; network filesystems often don't support it"
+	}
+	return c
+}
+
+// checkDataDirs reports ~/.ccdash locations still used after XDG_DATA_HOME or
+// XDG_CONFIG_HOME was set, since ccdash keeps using them until they're moved
+func checkDataDirs() doctorCheck {
+	c := doctorCheck{name: "Data directories", optional: true}
+	dataDir, _ := metrics.DataDir()
+	c.detail = dataDir
+
+	stale := staleDirs()
+	if len(stale) == 0 {
+		c.ok = true
+		return c
+	}
+	for _, s := range stale {
+		c.extra = append(c.extra, fmt.Sprintf("%s is used in place of %s", s.path, s.xdgPath))
+	}
+	c.hint = "Move them to follow XDG_DATA_HOME/XDG_CONFIG_HOME, then run ccdash --install-hooks so the hooks use the new directory"
+	return c
+}
+
+// checkHooks reports hook installation in each Claude Code settings file
+func checkHooks() doctorCheck {
+	c := doctorCheck{name: "Claude Code hooks", optional: true}
+	collector, err := metrics.NewHookSessionCollector()
+	if err != nil {
+		c.detail = err.Error()
+		return c
+	}
+
+	status := collector.GetSettingsFilesStatus()
+	files := make([]string, 0, len(status))
+	for f := range status {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	for _, f := range files {
+		mark := "✗"
+		if status[f] {
+			mark = "✓"
+		}
+		c.extra = append(c.extra, fmt.Sprintf("%s %s (%s)", mark, f, metrics.SettingsFileKind(f)))
+	}
+
+	c.ok = collector.AreHooksInstalled()
+	if c.ok {
+		c.detail = "installed"
+	} else {
+		c.detail = "not installed; session status comes from tmux pane inspection only"
+		c.hint = "Run 'ccdash --install-hooks' for accurate WORKING/ASKING detection"
+	}
+	return c
+}
+
+// checkBinaries lists every ccdash binary a self-update would replace with
+// the version each reports, flagging any that differ from this one, e.g. after
+// a self-update that failed for some locations
+func checkBinaries() doctorCheck {
+	locations := updater.FindAllBinaryLocations()
+	sort.Strings(locations)
+	c := doctorCheck{name: "ccdash binaries", ok: true}
+
+	width := 0
+	for _, path := range locations {
+		width = max(width, len(path))
+	}
+	mismatched := 0
+	for _, path := range locations {
+		v, err := binaryVersion(path)
+		mark := " "
+		if err != nil {
+			v = fmt.Sprintf("unknown (%v)", err)
+		}
+		if v != version {
+			mark = "≠"
+			mismatched++
+		}
+		c.extra = append(c.extra, fmt.Sprintf("%s %-*s  %s", mark, width, path, v))
+	}
+
+	c.detail = fmt.Sprintf("%d found (version %s)", len(locations), version)
+	if mismatched > 0 {
+		c.ok = false
+		c.detail = fmt.Sprintf("%d found, %d not at version %s", len(locations), mismatched, version)
+		c.hint = "A self-update may have failed for the marked locations; press u in ccdash or reinstall them"
+	}
+	return c
+}
+
+// binaryVersion runs the ccdash binary at path with --version and returns the
+// version it reports, without build details
+func binaryVersion(path string) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	out, err := exec.CommandContext(ctx, path, "--version").Output()
+	if err != nil {
+		return "", err
+	}
+	// "ccdash version v1.2.3 (commit abc, built ...)"
+	line, _, _ := strings.Cut(string(out), "\n")
+	fields := strings.Fields(strings.TrimPrefix(line, "ccdash version "))
+	if len(fields) == 0 || !strings.HasPrefix(line, "ccdash version ") {
+		return "", fmt.Errorf("unexpected --version output %q", line)
+	}
+	return fields[0], nil
+}
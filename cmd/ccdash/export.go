@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/config"
+	"github.com/jedarden/ccdash/internal/export"
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// runExport implements `ccdash export`, a one-shot snapshot writer for cron jobs.
+// Returns the process exit code.
+func runExport(args []string, cfg *config.Config) int {
+	fs := flag.NewFlagSet("export", flag.ContinueOnError)
+	textfile := fs.String("prometheus-textfile", "", "Write the snapshot in Prometheus text format to this file (atomically)")
+	format := fs.String("format", "prometheus", "Output format: prometheus (a metrics snapshot) or jsonl (every token event, one per line)")
+	output := fs.String("output", "", "With --format=jsonl, write to this file instead of stdout")
+	extraDirs := fs.String("extra-dirs", strings.Join(cfg.ExtraDirs, ","), "Additional Claude project root directories to scan (comma-separated)")
+	diskPaths := fs.String("disk-path", strings.Join(cfg.DiskPaths, ","), "Filesystem paths to report disk capacity for (comma-separated)")
+	redact := fs.Bool("redact", false, "With --format=jsonl, replace project paths in source_file with stable pseudonyms such as project-3f9a1c")
+	since := fs.String("since", "", "Only include tokens from this time: a duration back from now (24h, 7d) or a date (2025-11-01, 2025-11-01T09:00)")
+	until := fs.String("until", "", "Only include tokens before this time, in the same forms as --since")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash export --prometheus-textfile=<path> [--since=<time>] [--until=<time>] [--extra-dirs=<dirs>] [--disk-path=<paths>]")
+		fmt.Fprintln(os.Stderr, "       ccdash export --format=jsonl [--output=<path>] [--since=<time>] [--until=<time>] [--extra-dirs=<dirs>] [--redact]")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	from, to, err := metrics.ParseTimeRange(*since, *until, time.Now())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 2
+	}
+
+	switch *format {
+	case "prometheus":
+	case "jsonl":
+		return exportJSONL(*output, splitList(*extraDirs), from, to, *redact)
+	default:
+		fmt.Fprintf(os.Stderr, "Error: unknown export format %q (want prometheus or jsonl)\n", *format)
+		return 2
+	}
+
+	if *textfile == "" {
+		fmt.Fprintln(os.Stderr, "Error: ccdash export needs an output, e.g. --prometheus-textfile=/var/lib/node_exporter/textfile/ccdash.prom")
+		return 2
+	}
+
+	loadPricing(cfg.PricingURL)
+	c := newSnapshotCollectors(splitList(*extraDirs), splitList(*diskPaths), cfg.CaptureLines)
+	defer c.close()
+	if *since != "" || *until != "" {
+		// Without --since, tokens count from Monday as usual
+		if *since == "" {
+			from = metrics.GetMondayNineAM()
+		}
+		c.tokens.SetLookbackRange(from, to)
+	}
+	snap := c.collect(time.Now().Add(time.Second))
+
+	if err := export.WritePrometheusTextfile(*textfile, snap); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", *textfile, err)
+		return 1
+	}
+	return 0
+}
+
+// collectSnapshot gathers system, token and session metrics once, for
+// `ccdash export` and --json.
+// Token files are ingested synchronously so the snapshot reflects the logs on disk.
+func collectSnapshot(extraDirs, diskPaths []string, captureLines int) *export.Snapshot {
+	c := newSnapshotCollectors(extraDirs, diskPaths, captureLines)
+	defer c.close()
+
+	// CPU usage is measured between the collector's creation and Collect, so
+	// give it a second of samples
+	return c.collect(time.Now().Add(time.Second))
+}
+
+// snapshotCollectors holds the collectors behind a snapshot. --stream-json
+// keeps them between snapshots, so CPU usage covers the whole interval and
+// pane content changes are seen as activity.
+type snapshotCollectors struct {
+	system *metrics.SystemCollector
+	tokens *metrics.TokenCollector
+	tmux   *metrics.TmuxCollector
+}
+
+// newSnapshotCollectors creates the collectors for snapshots of the default
+// projects directories plus extraDirs, with disk bars for diskPaths and
+// captureLines of scrollback read from each tmux pane
+func newSnapshotCollectors(extraDirs, diskPaths []string, captureLines int) *snapshotCollectors {
+	c := &snapshotCollectors{
+		system: metrics.NewSystemCollector(),
+		tokens: metrics.NewOneShotTokenCollector(metrics.GetMondayNineAM()),
+		tmux:   metrics.NewTmuxCollector(),
+	}
+	c.system.SetDiskPaths(diskPaths)
+	if err := c.tmux.SetCaptureLines(captureLines); err != nil {
+		// The config already rejects out-of-range values
+		fmt.Fprintf(os.Stderr, "Note: %v; capturing %d\n", err, metrics.DefaultCaptureLines)
+	}
+	for _, dir := range metrics.ExpandGlobPatterns(extraDirs) {
+		c.tokens.AddProjectsDir(dir)
+	}
+	return c
+}
+
+// collect takes one snapshot. System metrics are read no earlier than
+// systemAt; the wait overlaps with ingestion.
+func (c *snapshotCollectors) collect(systemAt time.Time) *export.Snapshot {
+	systemChan := make(chan metrics.SystemMetrics, 1)
+	go func() {
+		time.Sleep(time.Until(systemAt))
+		systemChan <- c.system.Collect()
+	}()
+
+	c.tokens.Ingest()
+	tokens, _ := c.tokens.Collect()
+	tmux := c.tmux.Collect()
+	c.tokens.AttachContextUsage(tmux.Sessions)
+	c.tokens.AttachSessionUsage(tmux.Sessions)
+
+	return &export.Snapshot{
+		System:    <-systemChan,
+		Tokens:    tokens,
+		Tmux:      tmux,
+		Timestamp: time.Now(),
+	}
+}
+
+// close releases the token cache
+func (c *snapshotCollectors) close() {
+	c.tokens.GetCache().Close()
+}
+
+// streamSnapshots implements --stream-json: one JSON snapshot per line on
+// stdout every interval until SIGINT or SIGTERM, redacted with --redact.
+// Returns the process exit code.
+func streamSnapshots(cfg *config.Config, redact bool) int {
+	if cfg.Interval < time.Second {
+		fmt.Fprintf(os.Stderr, "Error: refresh interval must be at least 1s, got %s\n", cfg.Interval)
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	c := newSnapshotCollectors(cfg.ExtraDirs, cfg.DiskPaths, cfg.CaptureLines)
+	defer c.close()
+
+	w := bufio.NewWriter(os.Stdout)
+	enc := json.NewEncoder(w)
+	ticker := time.NewTicker(cfg.Interval)
+	defer ticker.Stop()
+
+	systemAt := time.Now().Add(time.Second)
+	for {
+		// The default lookback starts on Monday, so move it when a new week begins
+		c.tokens.SetLookback(metrics.GetMondayNineAM())
+		snap := c.collect(systemAt)
+		if redact {
+			export.RedactSnapshot(snap)
+		}
+		if err := enc.Encode(snap); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if err := w.Flush(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		if !systemAt.IsZero() {
+			// Count intervals from the first snapshot, which waited for a CPU sample
+			ticker.Reset(cfg.Interval)
+			systemAt = time.Time{}
+		}
+
+		select {
+		case <-ctx.Done():
+			return 0
+		case <-ticker.C:
+		}
+	}
+}
+
+// exportJSONL brings the token cache up to date with the logs on disk, with
+// compacted files expanded back into events, then writes the token events from
+// since up to until (zero for either end to leave it open) as JSONL to path,
+// or to stdout when path is empty. With redact, source paths are replaced by
+// project pseudonyms; the cache keeps the real ones.
+func exportJSONL(path string, extraDirs []string, since, until time.Time, redact bool) int {
+	tokenCollector := metrics.NewOneShotTokenCollector(time.Time{})
+	for _, dir := range metrics.ExpandGlobPatterns(extraDirs) {
+		tokenCollector.AddProjectsDir(dir)
+	}
+	cache := tokenCollector.GetCache()
+	defer cache.Close()
+	tokenCollector.IngestEvents()
+
+	var rewrite func(*metrics.TokenEvent)
+	if redact {
+		rewrite = export.RedactEvent
+	}
+
+	if path == "" {
+		if err := cache.ExportJSONLWith(os.Stdout, since, until, rewrite); err != nil {
+			fmt.Fprintf(os.Stderr, "Error exporting token events: %v\n", err)
+			return 1
+		}
+		return 0
+	}
+
+	f, err := os.Create(path)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if err := cache.ExportJSONLWith(f, since, until, rewrite); err != nil {
+		f.Close()
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		return 1
+	}
+	if err := f.Close(); err != nil {
+		fmt.Fprintf(os.Stderr, "Error writing %s: %v\n", path, err)
+		return 1
+	}
+	return 0
+}
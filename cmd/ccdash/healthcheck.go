@@ -0,0 +1,78 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// runHealthcheck implements `ccdash healthcheck`, a liveness probe for
+// supervisors such as systemd or a Kubernetes sidecar. It opens the token
+// cache and queries it, and fails if either goes wrong or takes longer than
+// --timeout. Returns the process exit code: 0 when healthy, 1 when not.
+func runHealthcheck(args []string) int {
+	fs := flag.NewFlagSet("healthcheck", flag.ContinueOnError)
+	timeout := fs.Duration("timeout", 5*time.Second, "Fail if the cache hasn't answered within this long")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash healthcheck [--timeout=<d>]")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Checks that the token cache opens and answers a query. Exits 0 when healthy, 1 otherwise.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *timeout <= 0 {
+		fmt.Fprintf(os.Stderr, "Error: --timeout must be positive, got %s\n", *timeout)
+		return 2
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	// Opening the cache can wait on another process's lock without a context,
+	// so the check runs aside and is abandoned on timeout
+	type result struct {
+		status string
+		err    error
+	}
+	done := make(chan result, 1)
+	go func() {
+		cache := metrics.NewTokenCache()
+		defer cache.Close()
+
+		path := cache.GetDBPath()
+		if cache.GetDB() == nil {
+			done <- result{err: fmt.Errorf("can't open %s", path)}
+			return
+		}
+		if err := cache.Ping(ctx); err != nil {
+			done <- result{err: fmt.Errorf("%s doesn't answer queries: %w", path, err)}
+			return
+		}
+		events, files, _ := cache.GetStatsContext(ctx)
+		status := fmt.Sprintf("%s (%d events, %d files)", path, events, files)
+		if cache.RebuiltAfterCorruption() {
+			status += "; was corrupt, rebuilt empty"
+		}
+		done <- result{status: status}
+	}()
+
+	select {
+	case r := <-done:
+		if r.err != nil {
+			fmt.Fprintf(os.Stderr, "unhealthy: %v\n", r.err)
+			return 1
+		}
+		fmt.Printf("healthy: %s\n", r.status)
+		return 0
+	case <-ctx.Done():
+		fmt.Fprintf(os.Stderr, "unhealthy: token cache didn't answer within %s\n", *timeout)
+		return 1
+	}
+}
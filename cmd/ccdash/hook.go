@@ -0,0 +1,47 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// runHook implements `ccdash hook [--hooks-dir=<dir>] <event>`, which the
+// scripts written by --install-hooks run with Claude Code's hook JSON on
+// stdin. It's left out of --help. Failures are reported on stderr, which
+// Claude Code shows in verbose mode, but the exit code is always 0 so a
+// problem with ccdash never interrupts a Claude Code session.
+func runHook(args []string) int {
+	fs := flag.NewFlagSet("hook", flag.ContinueOnError)
+	hooksDir := fs.String("hooks-dir", "", "Data directory the session files are written to")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash hook [--hooks-dir=<dir>] <event>")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Records a Claude Code hook event read from stdin. Installed by ccdash --install-hooks.")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintf(os.Stderr, "Events: %v\n", metrics.HookEvents)
+	}
+	if err := fs.Parse(args); err != nil {
+		return 0
+	}
+	if fs.NArg() != 1 || !slices.Contains(metrics.HookEvents, fs.Arg(0)) {
+		fs.Usage()
+		return 0
+	}
+
+	dir := *hooksDir
+	if dir == "" {
+		dir, _ = metrics.DataDir()
+	}
+	collector, err := metrics.NewHookSessionCollectorWithDir(dir)
+	if err == nil {
+		err = collector.HandleHookEvent(fs.Arg(0), os.Stdin)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "ccdash hook %s: %v\n", fs.Arg(0), err)
+	}
+	return 0
+}
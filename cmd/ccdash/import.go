@@ -0,0 +1,64 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// runImport implements `ccdash import`, which loads token events written by
+// `ccdash export --format=jsonl` into the token cache. Returns the process exit code.
+func runImport(args []string) int {
+	fs := flag.NewFlagSet("import", flag.ContinueOnError)
+	format := fs.String("format", "jsonl", "Input format: jsonl (as written by 'ccdash export --format=jsonl')")
+	input := fs.String("input", "", "File to read token events from ('-' for stdin)")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash import --format=jsonl --input=<path>")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	if *format != "jsonl" {
+		fmt.Fprintf(os.Stderr, "Error: unknown import format %q (want jsonl)\n", *format)
+		return 2
+	}
+	if *input == "" {
+		fmt.Fprintln(os.Stderr, "Error: ccdash import needs an input, e.g. --input=tokens.jsonl")
+		return 2
+	}
+
+	var r io.Reader = os.Stdin
+	if *input != "-" {
+		f, err := os.Open(*input)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		defer f.Close()
+		r = f
+	}
+
+	cache := metrics.NewTokenCache()
+	defer cache.Close()
+	if cache.GetDB() == nil {
+		fmt.Fprintf(os.Stderr, "Error: token cache %s could not be opened\n", cache.GetDBPath())
+		return 1
+	}
+
+	imported, skipped, err := cache.ImportJSONL(r)
+	fmt.Printf("Imported %d token events into %s (duplicates ignored)\n", imported, cache.GetDBPath())
+	if skipped > 0 {
+		fmt.Printf("Skipped %d malformed lines\n", skipped)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error importing %s: %v\n", *input, err)
+		return 1
+	}
+	return 0
+}
@@ -1,15 +1,24 @@
 package main
 
 import (
+	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"io"
+	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
+	"sort"
 	"strings"
 	"syscall"
 
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/jedarden/ccdash/internal/config"
+	"github.com/jedarden/ccdash/internal/export"
 	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/remote"
 	"github.com/jedarden/ccdash/internal/ui"
 	"golang.org/x/term"
 )
@@ -18,21 +27,136 @@ import (
 // If not set, defaults to "dev" for local development builds
 var version = "dev"
 
+// commit and buildDate are also set via -ldflags ("-X main.commit=1a2b3c4
+// -X main.buildDate=2025-01-02T03:04:05Z") and stay empty otherwise
+var (
+	commit    string
+	buildDate string
+)
+
+// versionInfo returns the version followed by the commit and build date, when known
+func versionInfo() string {
+	var details []string
+	if commit != "" {
+		details = append(details, "commit "+commit)
+	}
+	if buildDate != "" {
+		details = append(details, "built "+buildDate)
+	}
+	if len(details) == 0 {
+		return version
+	}
+	return fmt.Sprintf("%s (%s)", version, strings.Join(details, ", "))
+}
+
 func main() {
+	// Hooks run inside Claude Code and must always exit 0, so they don't
+	// depend on the config file, which might not parse
+	if len(os.Args) > 1 && os.Args[1] == "hook" {
+		os.Exit(runHook(os.Args[2:]))
+	}
+
+	// Defaults, then the config file, then CCDASH_* variables; flags are applied after parsing
+	cfg, err := config.Load(config.Path())
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: config: %v\n", err)
+		os.Exit(1)
+	}
+	applyConfig(cfg)
+
+	// Subcommands parse their own flags
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "export":
+			os.Exit(runExport(os.Args[2:], cfg))
+		case "import":
+			os.Exit(runImport(os.Args[2:]))
+		case "doctor":
+			os.Exit(runDoctor(os.Args[2:]))
+		case "healthcheck":
+			os.Exit(runHealthcheck(os.Args[2:]))
+		case "query":
+			os.Exit(runQuery(os.Args[2:]))
+		case "update":
+			os.Exit(runUpdate(os.Args[2:]))
+		case "update-history":
+			os.Exit(runUpdateHistory(os.Args[2:]))
+		}
+	}
+
 	// Parse command-line flags
 	var (
 		showVersion  = flag.Bool("version", false, "Show version information")
 		showHelp     = flag.Bool("help", false, "Show help information")
 		installHooks = flag.Bool("install-hooks", false, "Install Claude Code hooks for session tracking")
 		checkHooks   = flag.Bool("check-hooks", false, "Check if Claude Code hooks are installed")
-		extraDirs    = flag.String("extra-dirs", "", "Additional Claude project root directories to scan (comma-separated). Also set via CCDASH_EXTRA_DIRS env var (colon-separated)")
+		notifyReady  = flag.Bool("notify", false, "Show a desktop notification when a session becomes READY")
+		bellOnError  = flag.Bool("bell-on-error", false, "Ring the terminal bell and flash the sessions panel when a session enters ERROR")
+		flashCross   = flag.Bool("flash-thresholds", false, "Flash CPU, memory, cost and the attention count for a refresh when they cross a threshold (toggle with f)")
+		onReady      = flag.String("on-ready", "", "Shell command to run when a session becomes READY (session name in CCDASH_SESSION)")
+		compact      = flag.Bool("compact", false, "Dense three-line view without panels, for small tmux panes")
+		noStatusBar  = flag.Bool("no-status-bar", false, "Hide the status bar")
+		manual       = flag.Bool("manual", false, "Refresh only when r is pressed; no background collection after startup")
+		noEmoji      = flag.Bool("no-emoji", false, "Show text status labels like [WRK] instead of emoji")
+		noAttached   = flag.Bool("ignore-attached", false, "Don't treat attached sessions as ACTIVE; classify them by pane content and idle time")
+		fullModels   = flag.Bool("full-model-names", false, "Show raw model IDs (e.g. claude-opus-4-5-20251101) in the token panel instead of short names")
+		exactTokens  = flag.Bool("exact-tokens", false, "Show token counts as 1,234,567 instead of 1.2M (toggle with k)")
+		groupPrefix  = flag.Bool("group-by-prefix", false, "Collapse 3+ sessions sharing a name prefix into one row (toggle with g, expand with G)")
+		userTokens   = flag.Bool("include-user-tokens", false, "Also count token usage reported on user messages (separate 'User' line, billed as input)")
+		jsonOutput   = flag.Bool("json", false, "Print a single JSON snapshot to stdout and exit")
+		streamJSON   = flag.Bool("stream-json", false, "Print a JSON snapshot line to stdout every --interval until interrupted")
+		redact       = flag.Bool("redact", false, "With --json, --stream-json or --snapshot-file, replace session names and project paths with stable pseudonyms")
+		snapshotFile = flag.String("snapshot-file", "", "Write the --json snapshot to this file after every refresh, replacing it atomically")
+		remoteHosts  = flag.String("remote", "", "Also show these hosts (comma-separated), collected via 'ssh <host> ccdash --json'")
+		remoteCmd    = flag.String("remote-command", remote.DefaultCommand, "Command run on each --remote host")
+		dumpConfig   = flag.Bool("dump-config", false, "Print the effective configuration (defaults, config file, env, flags) and exit")
+		reingest     = flag.Bool("force-reingest", false, "Re-read every JSONL log from the start, keeping cached events (when new usage isn't showing up)")
 	)
 
+	// Flags that override config keys; defaults show the value from the config file or env
+	flag.Duration("interval", cfg.Interval, "Time between refreshes")
+	flag.Duration("collect-timeout", cfg.CollectTimeout, "Longest a refresh waits on tmux and system calls before showing what it has")
+	flag.Duration("session-cleanup-interval", cfg.SessionCleanupInterval, "Time between removals of hook sessions that ended without the session-end hook (0 = never)")
+	flag.Duration("alert-cooldown", cfg.AlertCooldown, "Shortest gap between --notify, --on-ready or --bell-on-error alerts for the same session (0 = none)")
+	flag.String("lookback", cfg.Lookback, "Initial token lookback: monday, today, yesterday, 5h, 24h, 7d, 30d, month, all, another duration (12h, 3d) or a date (2025-11-01)")
+	flag.Float64("warn-threshold", cfg.WarnThreshold, "Usage percent at which bars turn orange")
+	flag.Float64("crit-threshold", cfg.CritThreshold, "Usage percent at which bars turn red")
+	flag.String("projects-dir", cfg.ProjectsDir, "Claude Code projects directory")
+	flag.String("cache-dir", cfg.CacheDir, "Token cache directory (relative paths resolve against the working directory)")
+	flag.String("hooks-dir", cfg.HooksDir, "Directory for hook scripts, session files and the log (default: ~/.ccdash or $XDG_DATA_HOME/ccdash)")
+	flag.String("settings-file", cfg.SettingsFile, "Install hooks only in this Claude Code settings file (a name in ~/.claude such as settings.local.json, or a path)")
+	flag.String("extra-dirs", strings.Join(cfg.ExtraDirs, ","), "Additional Claude project root directories to scan (comma-separated). Also set via CCDASH_EXTRA_DIRS env var (colon-separated)")
+	flag.String("disk-path", strings.Join(cfg.DiskPaths, ","), "Filesystem paths to show disk capacity for (comma-separated)")
+	flag.String("pin", strings.Join(cfg.Pin, ","), "Sessions to list first in the sessions panel (comma-separated names)")
+	flag.Int("capture-lines", cfg.CaptureLines, "Lines of scrollback above each tmux pane read for status detection")
+	flag.String("group-delimiter", cfg.GroupDelimiter, "Separator ending the name prefix --group-by-prefix groups by, e.g. agent- in agent-12")
+	flag.Var(&listFlag{items: cfg.ExcludeModel}, "exclude-model", "Leave models starting with this prefix out of token and cost totals (repeatable or comma-separated)")
+	flag.String("token-source", cfg.TokenSource, "Token data source: jsonl or ccusage")
+	flag.String("model-sort", cfg.ModelSort, "Order of the per-model breakdown: cost, tokens or name")
+	flag.Int("cpu-core-lines", cfg.CPUCoreLines, "Lines of per-core CPU bars to show before '+N more cores'")
+	flag.Int("cpu-cores-per-line", cfg.CPUCoresPerLine, "Per-core CPU bars on each line (0 = as many as fit)")
+	flag.Float64("cpu-smoothing", cfg.CPUSmoothing, "Smooth CPU bars across refreshes, from 0 (off) to below 1 (calmest)")
+	flag.Bool("mem-by-available", cfg.MemByAvailable, "Fill the memory bar with memory that isn't available, so page cache doesn't count as used")
+	flag.Float64("rate-smoothing", cfg.RateSmoothing, "Show a Trend line averaging the token rate across refreshes, from 0 (off) to below 1 (calmest)")
+	flag.Int("max-width", cfg.MaxWidth, "Widest the dashboard is drawn, centered in wider terminals (0 = full width)")
+	flag.Int("system-panel-width", cfg.SystemPanelWidth, "Width of the system panel in the three-column layout (0 = automatic)")
+	flag.Int("token-panel-width", cfg.TokenPanelWidth, "Width of the token panel in the three-column layout (0 = automatic)")
+	flag.String("secondary-currency", cfg.SecondaryCurrency, "Also show costs in this currency (ISO code, e.g. GBP); needs --fx-rate")
+	flag.Float64("fx-rate", cfg.FXRate, "Units of --secondary-currency per US dollar")
+	flag.String("status-format", cfg.StatusFormat, "Status bar template, e.g. '{time} {cost} | {update} | {sessions} sessions {keys}'")
+	flag.String("pricing-url", cfg.PricingURL, "Fetch model pricing JSON from this URL at startup, cached for a day (built-in rates if unreachable)")
+
 	flag.Parse()
 
+	if err := cfg.ApplyFlags(flag.CommandLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(2)
+	}
+	applyConfig(cfg)
+
 	// Handle --version
 	if *showVersion {
-		fmt.Printf("ccdash version %s\n", version)
+		fmt.Printf("ccdash version %s\n", versionInfo())
 		fmt.Println("Claude Code Dashboard - A terminal UI for monitoring system resources, token usage, and tmux sessions")
 		os.Exit(0)
 	}
@@ -43,6 +167,27 @@ func main() {
 		os.Exit(0)
 	}
 
+	// Handle --dump-config
+	if *dumpConfig {
+		if err := cfg.Dump(os.Stdout); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// settings_file (--settings-file) limits hook installation to one file;
+	// empty for every ~/.claude/settings*.json
+	hookSettings := ""
+	if cfg.SettingsFile != "" {
+		path, err := metrics.SettingsFilePath(cfg.SettingsFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: settings_file: %v\n", err)
+			os.Exit(2)
+		}
+		hookSettings = path
+	}
+
 	// Handle --install-hooks
 	if *installHooks {
 		collector, err := metrics.NewHookSessionCollector()
@@ -53,14 +198,16 @@ func main() {
 
 		// Always run InstallHooks - it's idempotent and will add any missing hooks
 		fmt.Println("Installing Claude Code hooks for session tracking...")
-		if err := collector.InstallHooks(); err != nil {
+		results, err := collector.InstallHooksIn(hookSettings)
+		printSettingsResults(results)
+		if err != nil {
 			fmt.Fprintf(os.Stderr, "Error installing hooks: %v\n", err)
 			os.Exit(1)
 		}
 
 		fmt.Println("✓ Hooks installed successfully!")
 		fmt.Println()
-		fmt.Println("The following hooks have been added to ~/.claude/settings.json:")
+		fmt.Println("These hooks are registered in each file:")
 		fmt.Println("  • SessionStart       - Registers new Claude Code sessions")
 		fmt.Println("  • UserPromptSubmit   - Marks session as working")
 		fmt.Println("  • PreToolUse         - Refreshes activity during long-running tasks")
@@ -94,8 +241,10 @@ func main() {
 			if err == nil {
 				fmt.Printf("  Active sessions: %d\n", len(sessions))
 			}
+			printSettingsStatus(collector)
 		} else {
 			fmt.Println("✗ Claude Code hooks are NOT installed")
+			printSettingsStatus(collector)
 			fmt.Println()
 			fmt.Println("Run 'ccdash --install-hooks' to install them.")
 			os.Exit(1)
@@ -103,68 +252,294 @@ func main() {
 		os.Exit(0)
 	}
 
+	loadPricing(cfg.PricingURL)
+
+	// Handle --json: one-shot snapshot, usable without a terminal (e.g. over SSH)
+	if *jsonOutput {
+		snap := collectSnapshot(cfg.ExtraDirs, cfg.DiskPaths, cfg.CaptureLines)
+		if *redact {
+			export.RedactSnapshot(snap)
+		}
+		if err := json.NewEncoder(os.Stdout).Encode(snap); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		os.Exit(0)
+	}
+
+	// Handle --stream-json: a snapshot per interval, for pipes and log shippers
+	if *streamJSON {
+		os.Exit(streamSnapshots(cfg, *redact))
+	}
+
 	// Check if running in a terminal
 	if !term.IsTerminal(int(os.Stdout.Fd())) {
 		fmt.Fprintln(os.Stderr, "Error: ccdash must be run in a terminal")
 		os.Exit(1)
 	}
 
-	// Set up hook management with cleanup on exit
-	hookCollector := setupHooks()
-	if hookCollector != nil {
-		defer hookCollector.Cleanup()
-
-		// Set up signal handler for graceful shutdown
-		sigChan := make(chan os.Signal, 1)
-		signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
-		go func() {
-			<-sigChan
-			hookCollector.Cleanup()
-			os.Exit(0)
-		}()
-	}
+	// The TUI owns the terminal, so background failures go to a log file
+	setupLogging()
 
 	// Create and run the dashboard
 	dashboard := ui.NewDashboard(version)
+	dashboard.SetBuildInfo(commit, buildDate)
+	dashboard.SetNotify(*notifyReady)
+	dashboard.SetOnReadyCommand(*onReady)
+	dashboard.SetBellOnError(*bellOnError)
+	dashboard.SetFlashThresholds(*flashCross)
+	dashboard.SetMinimalMode(*compact)
+	dashboard.SetStatusBar(!*noStatusBar)
+	dashboard.SetManualRefresh(*manual)
+	dashboard.SetNoEmoji(*noEmoji)
+	dashboard.SetDiskPaths(cfg.DiskPaths)
+	dashboard.SetPinnedSessions(cfg.Pin)
+	dashboard.SetExcludedModels(cfg.ExcludeModel)
+	dashboard.SetMemoryByAvailable(cfg.MemByAvailable)
+	dashboard.SetIgnoreAttached(*noAttached)
+	dashboard.SetFullModelNames(*fullModels)
+	dashboard.SetExactTokens(*exactTokens)
+	dashboard.SetIncludeUserTokens(*userTokens)
+	if err := dashboard.SetThresholds(cfg.WarnThreshold, cfg.CritThreshold); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetRefreshInterval(cfg.Interval); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetCollectTimeout(cfg.CollectTimeout); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetSessionCleanupInterval(cfg.SessionCleanupInterval); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetAlertCooldown(cfg.AlertCooldown); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetLookback(cfg.Lookback); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetCPUCoreLayout(cfg.CPUCoreLines, cfg.CPUCoresPerLine); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetCaptureLines(cfg.CaptureLines); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetCPUSmoothing(cfg.CPUSmoothing); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetSnapshotFile(*snapshotFile, *redact); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetRateSmoothing(cfg.RateSmoothing); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetMaxWidth(cfg.MaxWidth); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetPanelWidths(cfg.SystemPanelWidth, cfg.TokenPanelWidth); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetSecondaryCurrency(cfg.SecondaryCurrency, cfg.FXRate); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetStatusFormat(cfg.StatusFormat); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetModelSort(cfg.ModelSort); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if err := dashboard.SetSessionGrouping(*groupPrefix, cfg.GroupDelimiter); err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		os.Exit(1)
+	}
+	if hosts := splitList(*remoteHosts); len(hosts) > 0 {
+		dashboard.SetRemoteHosts(hosts, *remoteCmd)
+	}
+	if err := dashboard.SetTokenSource(cfg.TokenSource); err != nil {
+		fmt.Fprintf(os.Stderr, "Note: --token-source=%s unavailable (%v), using JSONL logs\n", cfg.TokenSource, err)
+	}
 
 	// Add any extra project directories specified via --extra-dirs flag
-	if *extraDirs != "" {
-		var dirs []string
-		for _, d := range strings.Split(*extraDirs, ",") {
-			if d = strings.TrimSpace(d); d != "" {
-				dirs = append(dirs, d)
-			}
+	if len(cfg.ExtraDirs) > 0 {
+		expandedDirs := metrics.ExpandGlobPatterns(cfg.ExtraDirs)
+		dashboard.AddProjectsDirs(expandedDirs)
+	}
+
+	// Clear the cache last, once every setting has been accepted
+	if *reingest {
+		if err := dashboard.ForceReingest(); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: --force-reingest: %v\n", err)
+			os.Exit(1)
 		}
-		if len(dirs) > 0 {
-			expandedDirs := metrics.ExpandGlobPatterns(dirs)
-			dashboard.AddProjectsDirs(expandedDirs)
+	}
+
+	// Set up hook management only after every setting has been validated, so
+	// a bad flag never touches the Claude settings files. Every exit from here
+	// on goes through exit, so the instance is unregistered (and the hooks
+	// removed with the last instance) however the dashboard ends; deferred
+	// calls would be skipped by os.Exit.
+	hookCollector := setupHooks(hookSettings)
+	exit := func(code int) {
+		if hookCollector != nil {
+			hookCollector.Cleanup()
 		}
+		os.Exit(code)
 	}
 
 	p := tea.NewProgram(
 		dashboard,
 		tea.WithAltScreen(),       // Use alternate screen buffer
 		tea.WithMouseCellMotion(), // Enable mouse support
+		tea.WithReportFocus(),     // Pause collection while the window is unfocused
 	)
 
+	// Bubble Tea turns SIGINT and SIGTERM into a normal shutdown that restores
+	// the terminal; a closed terminal (SIGHUP) is handled the same way
+	sigChan := make(chan os.Signal, 1)
+	signal.Notify(sigChan, syscall.SIGHUP)
+	go func() {
+		<-sigChan
+		p.Quit()
+	}()
+
 	if _, err := p.Run(); err != nil {
+		if errors.Is(err, tea.ErrInterrupted) {
+			exit(130)
+		}
 		fmt.Fprintf(os.Stderr, "Error running dashboard: %v\n", err)
-		os.Exit(1)
+		exit(1)
+	}
+	exit(0)
+}
+
+// applyConfig applies settings that must be in place before any collector is created
+func applyConfig(cfg *config.Config) {
+	metrics.SetProjectsDir(cfg.ProjectsDir)
+	metrics.SetCacheDir(cfg.CacheDir)
+	metrics.SetDataDir(cfg.HooksDir)
+}
+
+// loadPricing replaces the built-in model rates with those at url, if set.
+// Failures are only a note: costs then use the cached or built-in rates.
+func loadPricing(url string) {
+	if url == "" {
+		return
+	}
+	dir, _ := metrics.DataDir()
+	if err := metrics.LoadPricing(url, dir); err != nil {
+		fmt.Fprintf(os.Stderr, "Note: --pricing-url: %v\n", err)
+	}
+}
+
+// splitList splits a comma-separated flag value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// listFlag is a comma-separated list flag that can also be repeated, e.g.
+// --exclude-model=claude-haiku --exclude-model=claude-3-5-haiku
+type listFlag struct {
+	items []string
+	set   bool
+}
+
+func (l *listFlag) String() string {
+	return strings.Join(l.items, ",")
+}
+
+func (l *listFlag) Set(value string) error {
+	// The first use replaces the list from the config file
+	if !l.set {
+		l.items, l.set = nil, true
+	}
+	l.items = append(l.items, splitList(value)...)
+	return nil
+}
+
+// setupLogging sends the standard logger to ccdash.log in the data directory
+// (~/.ccdash unless XDG_DATA_HOME is set). Anything written to stderr while the
+// dashboard runs would corrupt the display.
+func setupLogging() {
+	log.SetOutput(io.Discard)
+
+	logDir, _ := metrics.DataDir()
+	if err := os.MkdirAll(logDir, 0755); err != nil {
+		return
+	}
+
+	f, err := os.OpenFile(filepath.Join(logDir, "ccdash.log"), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return
+	}
+	log.SetOutput(f)
+	logStaleDirs()
+}
+
+// logStaleDirs notes directories still read from ~/.ccdash (or ./.ccdash)
+// because they were created before XDG_DATA_HOME or XDG_CONFIG_HOME was set
+func logStaleDirs() {
+	for _, s := range staleDirs() {
+		log.Printf("%s is in use because %s doesn't exist yet; move it there to follow %s", s.path, s.xdgPath, s.env)
+	}
+}
+
+// staleDir is a pre-XDG location in use in place of its XDG counterpart
+type staleDir struct {
+	path    string
+	xdgPath string
+	env     string
+}
+
+// staleDirs lists the data, cache and config locations that fell back to
+// their pre-XDG paths
+func staleDirs() []staleDir {
+	var dirs []staleDir
+	if dir, stale := metrics.DataDir(); stale {
+		dirs = append(dirs, staleDir{dir, filepath.Join(os.Getenv("XDG_DATA_HOME"), "ccdash"), "XDG_DATA_HOME"})
+	}
+	if dir, stale := metrics.CacheDir(); stale {
+		dirs = append(dirs, staleDir{dir, filepath.Join(os.Getenv("XDG_DATA_HOME"), "ccdash"), "XDG_DATA_HOME"})
+	}
+	if path, stale := config.Location(); stale {
+		dirs = append(dirs, staleDir{path, filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "ccdash", config.FileName), "XDG_CONFIG_HOME"})
 	}
+	return dirs
 }
 
-// setupHooks installs hooks, registers this instance, and returns the collector for cleanup
-func setupHooks() *metrics.HookSessionCollector {
+// setupHooks installs hooks into settingsFile, or every settings file when
+// it's empty, registers this instance, and returns the collector for cleanup
+func setupHooks(settingsFile string) *metrics.HookSessionCollector {
 	collector, err := metrics.NewHookSessionCollector()
 	if err != nil {
 		// Silently continue - hooks are optional
 		return nil
 	}
 
-	wasInstalled := collector.AreHooksInstalled()
-
 	// Always run InstallHooks - it's idempotent and will add any missing hooks
-	if err := collector.InstallHooks(); err != nil {
+	results, err := collector.InstallHooksIn(settingsFile)
+	if err != nil {
 		// Installation failed - continue without hooks (tmux fallback will be used)
 		fmt.Fprintf(os.Stderr, "Note: Could not install Claude Code hooks: %v\n", err)
 		fmt.Fprintf(os.Stderr, "      Session tracking will use tmux fallback.\n")
@@ -183,41 +558,206 @@ func setupHooks() *metrics.HookSessionCollector {
 		return collector
 	}
 
-	// Only notify on fresh install (not on updates)
-	if !wasInstalled {
-		fmt.Println("✓ Installed Claude Code hooks for session tracking")
+	// Only notify about files that didn't have the hooks yet
+	var added []string
+	for _, r := range results {
+		if r.Added {
+			added = append(added, fmt.Sprintf("%s (%s)", r.Path, metrics.SettingsFileKind(r.Path)))
+		}
+	}
+	if len(added) > 0 {
+		fmt.Println("✓ Installed Claude Code hooks for session tracking in:")
+		for _, f := range added {
+			fmt.Println("    " + f)
+		}
 		fmt.Println("  Restart Claude Code sessions for hooks to take effect.")
+		fmt.Println("  Use --settings-file to install into one file only.")
 		fmt.Println()
 	}
 
 	return collector
 }
 
+// printSettingsResults lists what installing the hooks did to each settings file
+func printSettingsResults(results []metrics.SettingsFileResult) {
+	for _, r := range results {
+		kind := metrics.SettingsFileKind(r.Path)
+		switch {
+		case r.Err != nil:
+			fmt.Printf("  ✗ %s (%s): %v\n", r.Path, kind, r.Err)
+		case r.Added:
+			fmt.Printf("  ✓ %s (%s): hooks added\n", r.Path, kind)
+		default:
+			fmt.Printf("  ✓ %s (%s): already installed\n", r.Path, kind)
+		}
+	}
+	fmt.Println()
+}
+
+// printSettingsStatus lists whether each ~/.claude/settings*.json has the hooks
+func printSettingsStatus(collector *metrics.HookSessionCollector) {
+	status := collector.GetSettingsFilesStatus()
+	files := make([]string, 0, len(status))
+	for f := range status {
+		files = append(files, f)
+	}
+	sort.Strings(files)
+	if len(files) > 0 {
+		fmt.Println("  Settings files:")
+	}
+	for _, f := range files {
+		mark := "✗"
+		if status[f] {
+			mark = "✓"
+		}
+		fmt.Printf("    %s %s (%s)\n", mark, f, metrics.SettingsFileKind(f))
+	}
+}
+
 func printHelp() {
 	fmt.Println("ccdash - Claude Code Dashboard")
 	fmt.Println()
-	fmt.Printf("Version: %s\n", version)
+	fmt.Printf("Version: %s\n", versionInfo())
 	fmt.Println()
 	fmt.Println("USAGE:")
 	fmt.Println("  ccdash [OPTIONS]")
+	fmt.Println("  ccdash export --prometheus-textfile=<path> [--since=<time>] [--until=<time>] [--extra-dirs=<dirs>] [--disk-path=<paths>]")
+	fmt.Println("  ccdash export --format=jsonl [--output=<path>] [--since=<time>] [--until=<time>] [--extra-dirs=<dirs>] [--redact]")
+	fmt.Println("  ccdash import --format=jsonl --input=<path>")
+	fmt.Println("  ccdash query [--format=table|csv] \"SELECT ...\"")
+	fmt.Println("                        Run a read-only SQL query against the token cache")
+	fmt.Println("  ccdash query --schema Print the token cache's tables and indexes")
+	fmt.Println("  ccdash doctor         Check the terminal, tmux, Claude Code data, cache and hooks")
+	fmt.Println("  ccdash healthcheck [--timeout=<d>]")
+	fmt.Println("                        Exit 0 if the token cache opens and answers a query, 1 if not")
+	fmt.Println("  ccdash update --dry-run")
+	fmt.Println("                        Show the release a self-update would install and the binaries it would replace")
+	fmt.Println("  ccdash update-history Show when ccdash updated itself and what changed")
 	fmt.Println()
 	fmt.Println("OPTIONS:")
 	fmt.Println("  --version             Show version information")
 	fmt.Println("  --help                Show this help message")
 	fmt.Println("  --install-hooks       Install Claude Code hooks for session tracking")
 	fmt.Println("  --check-hooks         Check if Claude Code hooks are installed")
+	fmt.Println("  --settings-file=<file>")
+	fmt.Println("                        Install hooks only in this settings file, e.g. settings.local.json")
+	fmt.Println("                        (default: settings.json and every other ~/.claude/settings*.json)")
 	fmt.Println("  --extra-dirs=<dirs>   Additional Claude project root directories to scan")
 	fmt.Println("                        Comma-separated list of paths")
 	fmt.Println("                        Also configurable via CCDASH_EXTRA_DIRS env var (colon-separated)")
+	fmt.Println("  --compact             Dense three-line view (system, tokens, sessions) without panels")
+	fmt.Println("  --no-status-bar       Hide the status bar (pairs well with --compact)")
+	fmt.Println("  --no-emoji            Text status labels ([WRK] [RDY] [ACT] [ERR]) instead of emoji")
+	fmt.Println("  --ignore-attached     Don't mark sessions ACTIVE because a tmux client is attached")
+	fmt.Println("                        For a client that stays attached; pane content and idle time decide")
+	fmt.Println("  --capture-lines=<n>   Lines of scrollback above each tmux pane read for status detection")
+	fmt.Println("                        (default: 15, at most 1000); raise it if statuses are misdetected")
+	fmt.Println("  --full-model-names    Show raw model IDs in the token panel (toggle with M)")
+	fmt.Println("  --exact-tokens        Show token counts as 1,234,567 instead of 1.2M (toggle with k)")
+	fmt.Println("  --mem-by-available    Fill the memory bar by unavailable memory, not used memory")
+	fmt.Println("                        (on Linux, page cache then doesn't count as used)")
+	fmt.Println("  --disk-path=<paths>   Filesystems to show disk capacity for (default: /)")
+	fmt.Println("                        Comma-separated list, one bar per path")
+	fmt.Println("  --pin=<names>         Sessions listed first in the sessions panel, marked 📌")
+	fmt.Println("                        Comma-separated; never cut off behind '+N more'")
+	fmt.Println("  --group-by-prefix     Collapse 3+ sessions sharing a name prefix, e.g. agent-1 to agent-20,")
+	fmt.Println("                        into one row with their status counts (toggle with g, expand with G)")
+	fmt.Println("  --group-delimiter=<s> Separator that ends the prefix (default: -)")
+	fmt.Println("  --exclude-model=<prefix>")
+	fmt.Println("                        Leave matching models out of token and cost totals; they stay")
+	fmt.Println("                        in the breakdown, struck through. Repeatable or comma-separated")
+	fmt.Println("  --token-source=<src>  Token data source: jsonl (default) or ccusage")
+	fmt.Println("                        ccusage must be on PATH; falls back to jsonl otherwise")
+	fmt.Println("  --model-sort=<order>  Order of the per-model breakdown: cost (default), tokens or name")
+	fmt.Println("  --warn-threshold=<n>  Usage percent at which bars turn orange (default: 80)")
+	fmt.Println("  --crit-threshold=<n>  Usage percent at which bars turn red (default: 95)")
+	fmt.Println("                        Bars are yellow from 3/4 of the warn threshold")
+	fmt.Println("  --interval=<d>        Time between refreshes (default: 2s, minimum 1s)")
+	fmt.Println("  --manual              Refresh only when r is pressed, with no background work after startup")
+	fmt.Println("  --collect-timeout=<d> Longest a refresh waits on tmux and system calls (default: 3s)")
+	fmt.Println("  --session-cleanup-interval=<d>")
+	fmt.Println("                        Time between removals of hook sessions whose process or tmux")
+	fmt.Println("                        session is gone, or idle for a day (default: 1m, 0 = never)")
+	fmt.Println("                        Slower calls, e.g. a wedged pane, are abandoned until the next refresh")
+	fmt.Println("  --lookback=<key>      Initial token lookback: monday (default), today, yesterday, 5h, 24h, 7d, 30d, month, all,")
+	fmt.Println("                        another duration (12h, 3d, 2w) or a date or time (2025-11-01, 2025-11-01T09:00)")
+	fmt.Println("  --projects-dir=<dir>  Claude Code projects directory (default: ~/.claude/projects)")
+	fmt.Println("  --cache-dir=<dir>     Token cache directory (default: .ccdash in the working directory,")
+	fmt.Println("                        or $XDG_DATA_HOME/ccdash when XDG_DATA_HOME is set)")
+	fmt.Println("  --hooks-dir=<dir>     Directory for hook scripts, session files and the log")
+	fmt.Println("                        (default: ~/.ccdash, or $XDG_DATA_HOME/ccdash when XDG_DATA_HOME is set)")
+	fmt.Println("  --cpu-core-lines=<n>  Lines of per-core CPU bars before '+N more cores' (default: 6)")
+	fmt.Println("  --cpu-cores-per-line=<n>")
+	fmt.Println("                        Per-core CPU bars on each line (default: 0, as many as fit)")
+	fmt.Println("  --cpu-smoothing=<f>   Smooth CPU bars across refreshes, from 0 (off, default) to below 1;")
+	fmt.Println("                        0.5 averages each reading with the previous one")
+	fmt.Println("  --rate-smoothing=<f>  Add a Trend line to the token panel: the 60s token rate averaged")
+	fmt.Println("                        across refreshes, from 0 (off, default) to below 1 (calmest)")
+	fmt.Println("  --max-width=<n>       Widest the dashboard is drawn; wider terminals center it")
+	fmt.Println("                        (default: 0, the full terminal width; otherwise at least 80)")
+	fmt.Println("  --system-panel-width=<n>, --token-panel-width=<n>")
+	fmt.Println("                        Fixed panel widths in the three-column layout, at least 30;")
+	fmt.Println("                        the sessions panel gets the rest (default: 0, automatic)")
+	fmt.Println("  --secondary-currency=<code>")
+	fmt.Println("                        Also show costs in this currency, e.g. GBP: $12.30 (£9.80)")
+	fmt.Println("  --fx-rate=<n>         Units of the secondary currency per US dollar, e.g. 0.79")
+	fmt.Println("  --status-format=<template>")
+	fmt.Println("                        Status bar layout, e.g. '{time} {cost} | {update} | {keys}'")
+	fmt.Println("                        Tokens: {time} {version} {cost} {tokens} {today} {sessions}")
+	fmt.Println("                        {attention} {update} {size} {keys}; | splits left, center, right")
+	fmt.Println("  --pricing-url=<url>   Fetch model rates (JSON) at startup instead of using the built-in ones")
+	fmt.Println("                        Cached in pricing-cache.json for a day; used offline if unreachable")
+	fmt.Println("  --dump-config         Print the effective configuration and where each value came from")
+	fmt.Println("                        Settings also load from ~/.ccdash/config.toml (or")
+	fmt.Println("                        $XDG_CONFIG_HOME/ccdash/config.toml) and CCDASH_* env vars")
+	fmt.Println("  --include-user-tokens Also count usage reported on user messages")
+	fmt.Println("                        Shown as a 'User' line, added to totals and billed as input")
+	fmt.Println("  --json                Print one JSON snapshot (system, tokens, sessions) and exit")
+	fmt.Println("  --stream-json         Print a JSON snapshot line every --interval until Ctrl+C or SIGTERM")
+	fmt.Println("  --snapshot-file=<p>   While the dashboard runs, write the --json snapshot to <p> after")
+	fmt.Println("                        every refresh (atomically), e.g. for status bars and scripts")
+	fmt.Println("  --redact              With --json, --stream-json or --snapshot-file, replace session")
+	fmt.Println("                        names and project paths with stable pseudonyms like")
+	fmt.Println("                        project-3f9a1c, for sharing")
+	fmt.Println("  --remote=<hosts>      Also show remote machines (comma-separated SSH hosts)")
+	fmt.Println("                        Runs 'ccdash --json' on each host; needs key-based SSH")
+	fmt.Println("  --remote-command=<c>  Command run on remote hosts (default: ccdash --json)")
+	fmt.Println("  --notify              Desktop notification when a session becomes READY")
+	fmt.Println("                        Uses notify-send (Linux), osascript (macOS) or a toast (Windows)")
+	fmt.Println("  --on-ready=<cmd>      Run a shell command when a session becomes READY")
+	fmt.Println("                        The session name is passed in $CCDASH_SESSION")
+	fmt.Println("                        Failures are logged to ~/.ccdash/ccdash.log")
+	fmt.Println("  --bell-on-error       Ring the terminal bell when a session enters ERROR")
+	fmt.Println("                        The sessions panel border also flashes red")
+	fmt.Println("  --alert-cooldown=<d>  Shortest gap between alerts of one kind for the same session, so")
+	fmt.Println("                        a session flapping between WORKING and READY alerts once")
+	fmt.Println("                        (default: 30s, 0 = alert on every transition)")
+	fmt.Println("  --flash-thresholds    Flash a metric's label for one refresh when it crosses a threshold:")
+	fmt.Println("                        CPU or memory into warn or critical, cost past $1, $2, $5, $10, …,")
+	fmt.Println("                        or more sessions needing you (toggle with f)")
+	fmt.Println("  --force-reingest      Re-read every JSONL log from the start, keeping cached events")
+	fmt.Println("                        Use when new token usage isn't showing up (same as R)")
 	fmt.Println()
 	fmt.Println("KEYBOARD SHORTCUTS:")
 	fmt.Println("  q, Ctrl+C    Quit the dashboard")
 	fmt.Println("  r            Refresh metrics immediately")
 	fmt.Println("  h            Cycle through help panels")
 	fmt.Println("  l            Open token usage lookback picker")
+	fmt.Println("  i            Open session inspector (context-window usage per session)")
+	fmt.Println("  t            Show token usage by hour of day")
+	fmt.Println("  p            Compare token usage and cost per project")
+	fmt.Println("  $            Toggle token panel emphasis between total and cost")
+	fmt.Println("  A            Show or hide session age in the sessions panel")
+	fmt.Println("  M            Toggle full model IDs in the token panel")
+	fmt.Println("  a            Toggle token totals between the current project and all projects")
+	fmt.Println("  m            Copy system, token and session stats to the clipboard as markdown")
+	fmt.Println("  X            Clear the token cache and re-ingest (press twice to confirm)")
+	fmt.Println("  R            Re-read all logs from the start, keeping cached events")
+	fmt.Println("  ?            Show the keybinding cheat sheet")
 	fmt.Println("  1            Focus on System Resources panel")
 	fmt.Println("  2            Focus on Token Usage panel")
 	fmt.Println("  3            Focus on Sessions panel")
+	fmt.Println("  0, Esc       Return to all panels")
 	fmt.Println()
 	fmt.Println("PANELS:")
 	fmt.Println("  System Resources  - CPU, memory, swap, disk I/O, and load averages")
@@ -247,6 +787,11 @@ func printHelp() {
 	fmt.Println("  💤 IDLE      - No activity for >5 minutes")
 	fmt.Println("  ❌ STALLED  - Error or stale session detected")
 	fmt.Println()
+	fmt.Println("FOCUS PAUSE:")
+	fmt.Println("  Collection pauses while the terminal window is unfocused and resumes")
+	fmt.Println("  on focus. Requires a terminal that supports focus reporting; in tmux")
+	fmt.Println("  enable it with 'set -g focus-events on'.")
+	fmt.Println()
 	fmt.Println("REQUIREMENTS:")
 	fmt.Println("  - Terminal size: minimum 80x24 characters")
 	fmt.Println("  - True color support recommended")
@@ -262,6 +807,17 @@ func printHelp() {
 	fmt.Println("  ccdash --extra-dirs=/alt/path             Scan additional project directory")
 	fmt.Println("  ccdash --extra-dirs=/path1,/path2         Scan multiple extra directories")
 	fmt.Println("  CCDASH_EXTRA_DIRS=/path1:/path2 ccdash    Use env var for extra directories")
+	fmt.Println("  ccdash --disk-path=/,/home,/var/lib/docker Show capacity of several filesystems")
+	fmt.Println("  ccdash --compact --no-status-bar          Minimal view for a small tmux pane")
+	fmt.Println("  ccdash --notify                           Notify when a session needs input")
+	fmt.Println("  ccdash --on-ready='paplay done.oga'       Play a sound when a session needs input")
+	fmt.Println("  ccdash --remote=gpu1,gpu2,build            Show sessions from remote servers too")
+	fmt.Println("  ccdash export --prometheus-textfile=/var/lib/node_exporter/textfile/ccdash.prom")
+	fmt.Println("                                            Write a snapshot for node_exporter (e.g. from cron)")
+	fmt.Println("  ccdash export --format=jsonl --output=tokens.jsonl")
+	fmt.Println("                                            Back up every token event as JSON lines")
+	fmt.Println("  ccdash export --format=jsonl --since=7d   Export the last week's token events")
+	fmt.Println("  ccdash import --input=laptop.jsonl        Add another machine's token history to this cache")
 	fmt.Println()
 	fmt.Println("For more information, visit: https://github.com/jedarden/ccdash")
 }
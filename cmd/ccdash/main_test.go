@@ -0,0 +1,26 @@
+package main
+
+import "testing"
+
+func TestVersionInfo(t *testing.T) {
+	savedVersion, savedCommit, savedBuildDate := version, commit, buildDate
+	t.Cleanup(func() { version, commit, buildDate = savedVersion, savedCommit, savedBuildDate })
+
+	tests := []struct {
+		commit, buildDate string
+		want              string
+	}{
+		{"", "", "v1.0.3"},
+		{"1a2b3c4", "", "v1.0.3 (commit 1a2b3c4)"},
+		{"", "2025-01-02T03:04:05Z", "v1.0.3 (built 2025-01-02T03:04:05Z)"},
+		{"1a2b3c4", "2025-01-02T03:04:05Z", "v1.0.3 (commit 1a2b3c4, built 2025-01-02T03:04:05Z)"},
+	}
+
+	version = "v1.0.3"
+	for _, tt := range tests {
+		commit, buildDate = tt.commit, tt.buildDate
+		if got := versionInfo(); got != tt.want {
+			t.Errorf("versionInfo() with commit %q, build date %q = %q, want %q", tt.commit, tt.buildDate, got, tt.want)
+		}
+	}
+}
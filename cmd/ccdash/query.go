@@ -0,0 +1,155 @@
+package main
+
+import (
+	"context"
+	"encoding/csv"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// runQuery implements `ccdash query`, which runs a read-only SQL query against
+// the token cache and prints the result as a table or CSV, or prints the
+// cache's schema. Returns the process exit code.
+func runQuery(args []string) int {
+	fs := flag.NewFlagSet("query", flag.ContinueOnError)
+	format := fs.String("format", "table", "Output format: table or csv")
+	schema := fs.Bool("schema", false, "Print the SQL that creates the cache's tables and indexes")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, `Usage: ccdash query [--format=table|csv] "SELECT ..."`)
+		fmt.Fprintln(os.Stderr, "       ccdash query --schema")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Runs a read-only query (SELECT, WITH, EXPLAIN or VALUES) against the token cache.")
+		fmt.Fprintln(os.Stderr)
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if *format != "table" && *format != "csv" {
+		fmt.Fprintf(os.Stderr, "Error: unknown query format %q (want table or csv)\n", *format)
+		return 2
+	}
+	query := strings.TrimSpace(strings.Join(fs.Args(), " "))
+	if *schema == (query != "") {
+		fs.Usage()
+		return 2
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+
+	cache := metrics.NewTokenCache()
+	defer cache.Close()
+	if cache.GetDB() == nil {
+		fmt.Fprintf(os.Stderr, "Error: token cache %s could not be opened\n", cache.GetDBPath())
+		return 1
+	}
+
+	if *schema {
+		ddl, err := cache.Schema(ctx)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			return 1
+		}
+		fmt.Print(ddl)
+		return 0
+	}
+
+	result, err := cache.Query(ctx, query)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	if *format == "csv" {
+		err = writeQueryCSV(os.Stdout, result)
+	} else {
+		err = writeQueryTable(os.Stdout, result)
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	return 0
+}
+
+// writeQueryCSV writes a header row and then the rows; NULL is an empty field
+func writeQueryCSV(w io.Writer, result *metrics.QueryResult) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(result.Columns); err != nil {
+		return err
+	}
+	record := make([]string, len(result.Columns))
+	for _, row := range result.Rows {
+		for i, v := range row {
+			record[i] = v.Text
+		}
+		if err := cw.Write(record); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// writeQueryTable writes the result as aligned columns under a header, with
+// NULL spelled out and line breaks in values shown as ↵, then the row count
+func writeQueryTable(w io.Writer, result *metrics.QueryResult) error {
+	cells := make([][]string, 0, len(result.Rows)+1)
+	cells = append(cells, result.Columns)
+	for _, row := range result.Rows {
+		line := make([]string, len(row))
+		for i, v := range row {
+			if v.Null {
+				line[i] = "NULL"
+			} else {
+				line[i] = strings.NewReplacer("\r\n", "↵", "\n", "↵").Replace(v.Text)
+			}
+		}
+		cells = append(cells, line)
+	}
+
+	widths := make([]int, len(result.Columns))
+	for _, line := range cells {
+		for i, cell := range line {
+			widths[i] = max(widths[i], utf8.RuneCountInString(cell))
+		}
+	}
+
+	var b strings.Builder
+	writeLine := func(line []string) {
+		for i, cell := range line {
+			if i > 0 {
+				b.WriteString("  ")
+			}
+			b.WriteString(cell)
+			if i < len(line)-1 {
+				b.WriteString(strings.Repeat(" ", widths[i]-utf8.RuneCountInString(cell)))
+			}
+		}
+		b.WriteString("\n")
+	}
+	writeLine(cells[0])
+	rule := make([]string, len(widths))
+	for i, width := range widths {
+		rule[i] = strings.Repeat("-", width)
+	}
+	writeLine(rule)
+	for _, line := range cells[1:] {
+		writeLine(line)
+	}
+	rows := "rows"
+	if len(result.Rows) == 1 {
+		rows = "row"
+	}
+	fmt.Fprintf(&b, "(%d %s)\n", len(result.Rows), rows)
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,94 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"runtime"
+	"strings"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/updater"
+)
+
+// runUpdate implements `ccdash update --dry-run`, which shows what a
+// self-update would do: the release it would install and every binary it
+// would replace, marking those that need sudo. Nothing is downloaded or
+// changed. Returns the process exit code.
+func runUpdate(args []string) int {
+	fs := flag.NewFlagSet("update", flag.ContinueOnError)
+	dryRun := fs.Bool("dry-run", false, "Show the update plan without downloading or replacing anything")
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash update --dry-run")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Shows the release a self-update would install and the binaries it would replace.")
+		fmt.Fprintln(os.Stderr, "To update, press u in the dashboard.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if !*dryRun || fs.NArg() > 0 {
+		fs.Usage()
+		return 2
+	}
+
+	u := updater.NewUpdater(version)
+	u.SetBuildInfo(commit, buildDate)
+	if dataDir, _ := metrics.DataDir(); dataDir != "" {
+		u.SetCacheDir(dataDir)
+	}
+	info := u.CheckForUpdate()
+	if info.Error != "" {
+		fmt.Fprintf(os.Stderr, "Error: %s\n", info.Error)
+		return 1
+	}
+
+	fmt.Printf("Current version: %s\n", info.CurrentVersion)
+	fmt.Printf("Latest version:  %s\n", info.LatestVersion)
+	switch {
+	case !info.UpdateAvailable:
+		fmt.Println("Already up to date; an update to a newer release would replace:")
+	case info.DownloadURL == "":
+		fmt.Printf("No %s/%s binary in the release yet, so an update would fail. Once there is one, it would replace:\n",
+			runtime.GOOS, runtime.GOARCH)
+	default:
+		fmt.Printf("Download:        %s\n", info.DownloadURL)
+		fmt.Println("Would replace:")
+	}
+
+	writeUpdatePlan(os.Stdout, u.PlanUpdate())
+	fmt.Println("Dry run: nothing was downloaded or changed.")
+	return 0
+}
+
+// writeUpdatePlan lists the binaries an update would replace, one per line,
+// noting the running one and those that need sudo
+func writeUpdatePlan(w io.Writer, targets []updater.UpdateTarget) {
+	width := 0
+	for _, t := range targets {
+		width = max(width, len(t.Path))
+	}
+	sudo := 0
+	for _, t := range targets {
+		var notes []string
+		if t.Running {
+			notes = append(notes, "running, restarted afterwards")
+		}
+		if t.NeedsSudo {
+			notes = append(notes, "needs sudo")
+			sudo++
+		}
+		if len(notes) == 0 {
+			fmt.Fprintf(w, "  %s\n", t.Path)
+			continue
+		}
+		fmt.Fprintf(w, "  %-*s  %s\n", width, t.Path, strings.Join(notes, ", "))
+	}
+	if len(targets) == 0 {
+		fmt.Fprintln(w, "  (no ccdash binaries found)")
+	}
+	if sudo > 0 {
+		fmt.Fprintf(w, "%d of %d would be replaced with sudo -n, or pkexec if sudo needs a password.\n", sudo, len(targets))
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedarden/ccdash/internal/updater"
+)
+
+func TestWriteUpdatePlan(t *testing.T) {
+	var sb strings.Builder
+	writeUpdatePlan(&sb, []updater.UpdateTarget{
+		{Path: "/home/me/.local/bin/ccdash", Running: true},
+		{Path: "/usr/local/bin/ccdash", NeedsSudo: true},
+		{Path: "/opt/ccdash"},
+	})
+
+	want := "  /home/me/.local/bin/ccdash  running, restarted afterwards\n" +
+		"  /usr/local/bin/ccdash       needs sudo\n" +
+		"  /opt/ccdash\n" +
+		"1 of 3 would be replaced with sudo -n, or pkexec if sudo needs a password.\n"
+	if sb.String() != want {
+		t.Errorf("Unexpected plan:\n%s\nwant:\n%s", sb.String(), want)
+	}
+
+	sb.Reset()
+	writeUpdatePlan(&sb, nil)
+	if sb.String() != "  (no ccdash binaries found)\n" {
+		t.Errorf("Expected the empty plan to say so, got %q", sb.String())
+	}
+}
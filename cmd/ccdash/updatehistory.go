@@ -0,0 +1,40 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/updater"
+)
+
+// runUpdateHistory implements `ccdash update-history`, which prints the log
+// of self-updates, oldest first. Returns the process exit code.
+func runUpdateHistory(args []string) int {
+	fs := flag.NewFlagSet("update-history", flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(os.Stderr, "Usage: ccdash update-history")
+		fmt.Fprintln(os.Stderr)
+		fmt.Fprintln(os.Stderr, "Prints when ccdash updated itself, the versions, the binaries replaced and any failures.")
+	}
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+
+	dataDir, _ := metrics.DataDir()
+	path := filepath.Join(dataDir, updater.HistoryFileName)
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		fmt.Printf("No self-updates recorded yet (%s)\n", path)
+		return 0
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		return 1
+	}
+	os.Stdout.Write(data)
+	return 0
+}
@@ -0,0 +1,79 @@
+package clipboard
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+// copyCommandTimeout bounds a clipboard helper. They return almost
+// immediately, but one waiting on an unreachable display must not hang.
+const copyCommandTimeout = 5 * time.Second
+
+// ErrNoClipboard is returned when none of the clipboard helpers is available
+var ErrNoClipboard = errors.New("no clipboard tool found (pbcopy, wl-copy, xclip, xsel or tmux)")
+
+// Copy puts text on the clipboard and returns the name of the tool it used.
+// It tries pbcopy on macOS, wl-copy under Wayland, xclip or xsel under X11,
+// and finally tmux's paste buffer, which tmux 3.2+ also passes on to the
+// terminal's clipboard when set-clipboard is on, so it works over SSH.
+func Copy(text string) (string, error) {
+	candidates := copyCommands(runtime.GOOS, os.Getenv)
+	if len(candidates) == 0 {
+		return "", ErrNoClipboard
+	}
+
+	var errs []string
+	for _, args := range candidates {
+		if _, err := exec.LookPath(args[0]); err != nil {
+			continue
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), copyCommandTimeout)
+		cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		// No output is captured: xclip stays in the background to serve the
+		// selection and would hold a pipe open until the timeout
+		err := cmd.Run()
+		cancel()
+		if err == nil {
+			return args[0], nil
+		}
+		errs = append(errs, fmt.Sprintf("%s: %v", args[0], err))
+	}
+
+	if len(errs) == 0 {
+		return "", ErrNoClipboard
+	}
+	return "", fmt.Errorf("copy to clipboard failed: %s", strings.Join(errs, "; "))
+}
+
+// copyCommands lists the commands to try, in order, for the platform and
+// environment
+func copyCommands(goos string, getenv func(string) string) [][]string {
+	var cmds [][]string
+	if goos == "darwin" {
+		cmds = append(cmds, []string{"pbcopy"})
+	}
+	if getenv("WAYLAND_DISPLAY") != "" {
+		cmds = append(cmds, []string{"wl-copy"})
+	}
+	if getenv("DISPLAY") != "" {
+		cmds = append(cmds,
+			[]string{"xclip", "-selection", "clipboard"},
+			[]string{"xsel", "--clipboard", "--input"},
+		)
+	}
+	if getenv("TMUX") != "" {
+		cmds = append(cmds,
+			[]string{"tmux", "load-buffer", "-w", "-"},
+			[]string{"tmux", "load-buffer", "-"}, // Before tmux 3.2, no -w
+		)
+	}
+	return cmds
+}
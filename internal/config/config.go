@@ -0,0 +1,710 @@
+// Package config loads ccdash settings from defaults, the config file (see
+// Path), CCDASH_* environment variables and command-line flags, in that order.
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// FileName is the config file name inside the ccdash config directory
+const FileName = "config.toml"
+
+// Sources a value can come from, as reported by --dump-config
+const (
+	SourceDefault = "default"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Config is the effective ccdash configuration
+type Config struct {
+	Interval      time.Duration // Time between collection ticks
+	Lookback      string        // Lookback preset key, e.g. "monday" or "7d"
+	WarnThreshold float64       // Usage percent at which bars turn orange
+	CritThreshold float64       // Usage percent at which bars turn red
+	ProjectsDir   string        // Claude Code's projects directory
+	CacheDir      string        // Token cache directory; relative paths resolve against the working directory
+	HooksDir      string        // Data directory for hook scripts, session files and the log; empty for the default
+	SettingsFile  string        // Claude Code settings file hooks are installed into; empty for every settings*.json
+	ExtraDirs     []string      // Additional project roots
+	DiskPaths     []string      // Filesystems shown as disk capacity bars
+	Pin           []string      // Sessions listed first in the sessions panel
+	ExcludeModel  []string      // Model name prefixes left out of token and cost totals
+	TokenSource   string        // "jsonl" or "ccusage"
+	ModelSort     string        // Order of the per-model breakdown: "cost", "tokens" or "name"
+
+	GroupDelimiter string // Ends the session name prefix --group-by-prefix groups by, e.g. "-"
+	CaptureLines   int    // Lines of scrollback above each tmux pane read for status detection
+
+	CPUCoreLines    int // Lines of per-core CPU bars in the system panel
+	CPUCoresPerLine int // Cores per line of CPU bars; 0 fits as many as the panel width allows
+
+	CPUSmoothing float64 // Weight of the previous CPU reading in the bars' moving average; 0 for raw readings
+
+	MemByAvailable bool // Fill the memory bar by memory that isn't available rather than by used memory
+
+	RateSmoothing float64 // Weight of the previous value in the token panel's Trend, a moving average of the 60s rate; 0 hides it
+
+	MaxWidth int // Widest the dashboard is drawn, centered in wider terminals; 0 for no limit
+
+	SystemPanelWidth int // Width of the system panel in the three-column layout; 0 for automatic
+	TokenPanelWidth  int // Width of the token panel in the three-column layout; 0 for automatic
+
+	CollectTimeout time.Duration // Longest a collection may run before slow tmux and system calls are abandoned
+
+	SessionCleanupInterval time.Duration // Time between removals of dead and abandoned hook session files; 0 to never remove them
+
+	AlertCooldown time.Duration // Shortest gap between alerts (notification, --on-ready, bell) for the same session; 0 for none
+
+	SecondaryCurrency string  // ISO 4217 code shown next to USD costs, e.g. "GBP"; empty for USD only
+	FXRate            float64 // Units of SecondaryCurrency per US dollar
+
+	StatusFormat string // Status bar template, e.g. "{time} | {cost}"; empty for the default layout
+
+	PricingURL string // Pricing JSON fetched at startup to replace the built-in rates; empty to use the built-ins
+
+	// Sources records where each key's value came from: SourceDefault, the
+	// config file path, SourceEnv or SourceFlag
+	Sources map[string]string
+}
+
+// field maps one config key to its environment variable and Config member
+type field struct {
+	key  string // Config file key; the flag name is the same with dashes
+	env  string // Environment variable, empty if none
+	list bool   // Accepts a TOML array; env and flag values are comma-separated
+	set  func(c *Config, value string) error
+	get  func(c *Config) string // TOML-formatted value
+}
+
+var fields = []field{
+	{
+		key: "interval", env: "CCDASH_INTERVAL",
+		set: func(c *Config, v string) error {
+			d, err := parseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.Interval = d
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.Interval.String()) },
+	},
+	{
+		key: "collect_timeout", env: "CCDASH_COLLECT_TIMEOUT",
+		set: func(c *Config, v string) error {
+			d, err := parseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.CollectTimeout = d
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.CollectTimeout.String()) },
+	},
+	{
+		key: "session_cleanup_interval", env: "CCDASH_SESSION_CLEANUP_INTERVAL",
+		set: func(c *Config, v string) error {
+			d, err := parseDuration(v)
+			if err != nil {
+				return err
+			}
+			c.SessionCleanupInterval = d
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.SessionCleanupInterval.String()) },
+	},
+	{
+		key: "alert_cooldown", env: "CCDASH_ALERT_COOLDOWN",
+		set: func(c *Config, v string) error {
+			d, err := parseDuration(v)
+			if err != nil {
+				return err
+			}
+			if d < 0 {
+				return fmt.Errorf("alert_cooldown can't be negative, got %s", d)
+			}
+			c.AlertCooldown = d
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.AlertCooldown.String()) },
+	},
+	{
+		key: "lookback", env: "CCDASH_LOOKBACK",
+		set: func(c *Config, v string) error { c.Lookback = v; return nil },
+		get: func(c *Config) string { return strconv.Quote(c.Lookback) },
+	},
+	{
+		key: "warn_threshold", env: "CCDASH_WARN_THRESHOLD",
+		set: func(c *Config, v string) error { return parseFloat(v, &c.WarnThreshold) },
+		get: func(c *Config) string { return strconv.FormatFloat(c.WarnThreshold, 'f', -1, 64) },
+	},
+	{
+		key: "crit_threshold", env: "CCDASH_CRIT_THRESHOLD",
+		set: func(c *Config, v string) error { return parseFloat(v, &c.CritThreshold) },
+		get: func(c *Config) string { return strconv.FormatFloat(c.CritThreshold, 'f', -1, 64) },
+	},
+	{
+		key: "projects_dir", env: "CCDASH_PROJECTS_DIR",
+		set: func(c *Config, v string) error { c.ProjectsDir = expandHome(v); return nil },
+		get: func(c *Config) string { return strconv.Quote(c.ProjectsDir) },
+	},
+	{
+		key: "cache_dir", env: "CCDASH_CACHE_DIR",
+		set: func(c *Config, v string) error { c.CacheDir = expandHome(v); return nil },
+		get: func(c *Config) string { return strconv.Quote(c.CacheDir) },
+	},
+	{
+		key: "hooks_dir", env: "CCDASH_HOOKS_DIR",
+		set: func(c *Config, v string) error { c.HooksDir = expandHome(v); return nil },
+		get: func(c *Config) string { return strconv.Quote(c.HooksDir) },
+	},
+	{
+		key: "settings_file", env: "CCDASH_SETTINGS_FILE",
+		set: func(c *Config, v string) error { c.SettingsFile = expandHome(v); return nil },
+		get: func(c *Config) string { return strconv.Quote(c.SettingsFile) },
+	},
+	{
+		// CCDASH_EXTRA_DIRS predates the config file and is read by the token
+		// collector itself (colon-separated), so it isn't mapped here; Dump
+		// still lists its directories, see extraDirsWithEnv
+		key: "extra_dirs", list: true,
+		set: func(c *Config, v string) error { c.ExtraDirs = splitList(v); return nil },
+		get: func(c *Config) string { return formatList(c.ExtraDirs) },
+	},
+	{
+		key: "disk_path", env: "CCDASH_DISK_PATH", list: true,
+		set: func(c *Config, v string) error { c.DiskPaths = splitList(v); return nil },
+		get: func(c *Config) string { return formatList(c.DiskPaths) },
+	},
+	{
+		key: "pin", env: "CCDASH_PIN", list: true,
+		set: func(c *Config, v string) error { c.Pin = splitList(v); return nil },
+		get: func(c *Config) string { return formatList(c.Pin) },
+	},
+	{
+		key: "group_delimiter", env: "CCDASH_GROUP_DELIMITER",
+		set: func(c *Config, v string) error {
+			if v == "" {
+				return fmt.Errorf("group delimiter can't be empty")
+			}
+			c.GroupDelimiter = v
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.GroupDelimiter) },
+	},
+	{
+		key: "capture_lines", env: "CCDASH_CAPTURE_LINES",
+		set: func(c *Config, v string) error {
+			if err := parseInt(v, 1, &c.CaptureLines); err != nil {
+				return err
+			}
+			if c.CaptureLines > metrics.MaxCaptureLines {
+				return fmt.Errorf("capture_lines must be at most %d, got %d", metrics.MaxCaptureLines, c.CaptureLines)
+			}
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Itoa(c.CaptureLines) },
+	},
+	{
+		key: "exclude_model", env: "CCDASH_EXCLUDE_MODEL", list: true,
+		set: func(c *Config, v string) error { c.ExcludeModel = splitList(v); return nil },
+		get: func(c *Config) string { return formatList(c.ExcludeModel) },
+	},
+	{
+		key: "token_source", env: "CCDASH_TOKEN_SOURCE",
+		set: func(c *Config, v string) error { c.TokenSource = v; return nil },
+		get: func(c *Config) string { return strconv.Quote(c.TokenSource) },
+	},
+	{
+		key: "model_sort", env: "CCDASH_MODEL_SORT",
+		set: func(c *Config, v string) error {
+			if !slices.Contains(metrics.ModelSorts, v) {
+				return fmt.Errorf("unknown model sort %q (available: %s)", v, strings.Join(metrics.ModelSorts, ", "))
+			}
+			c.ModelSort = v
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.ModelSort) },
+	},
+	{
+		key: "cpu_core_lines", env: "CCDASH_CPU_CORE_LINES",
+		set: func(c *Config, v string) error { return parseInt(v, 1, &c.CPUCoreLines) },
+		get: func(c *Config) string { return strconv.Itoa(c.CPUCoreLines) },
+	},
+	{
+		key: "cpu_cores_per_line", env: "CCDASH_CPU_CORES_PER_LINE",
+		set: func(c *Config, v string) error { return parseInt(v, 0, &c.CPUCoresPerLine) },
+		get: func(c *Config) string { return strconv.Itoa(c.CPUCoresPerLine) },
+	},
+	{
+		key: "cpu_smoothing", env: "CCDASH_CPU_SMOOTHING",
+		set: func(c *Config, v string) error {
+			if err := parseFloat(v, &c.CPUSmoothing); err != nil {
+				return err
+			}
+			if c.CPUSmoothing < 0 || c.CPUSmoothing >= 1 {
+				return fmt.Errorf("cpu_smoothing must be at least 0 and below 1, got %v", c.CPUSmoothing)
+			}
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatFloat(c.CPUSmoothing, 'f', -1, 64) },
+	},
+	{
+		key: "mem_by_available", env: "CCDASH_MEM_BY_AVAILABLE",
+		set: func(c *Config, v string) error { return parseBool(v, &c.MemByAvailable) },
+		get: func(c *Config) string { return strconv.FormatBool(c.MemByAvailable) },
+	},
+	{
+		key: "rate_smoothing", env: "CCDASH_RATE_SMOOTHING",
+		set: func(c *Config, v string) error {
+			if err := parseFloat(v, &c.RateSmoothing); err != nil {
+				return err
+			}
+			if c.RateSmoothing < 0 || c.RateSmoothing >= 1 {
+				return fmt.Errorf("rate_smoothing must be at least 0 and below 1, got %v", c.RateSmoothing)
+			}
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatFloat(c.RateSmoothing, 'f', -1, 64) },
+	},
+	{
+		key: "max_width", env: "CCDASH_MAX_WIDTH",
+		set: func(c *Config, v string) error { return parseInt(v, 0, &c.MaxWidth) },
+		get: func(c *Config) string { return strconv.Itoa(c.MaxWidth) },
+	},
+	{
+		key: "system_panel_width", env: "CCDASH_SYSTEM_PANEL_WIDTH",
+		set: func(c *Config, v string) error { return parseInt(v, 0, &c.SystemPanelWidth) },
+		get: func(c *Config) string { return strconv.Itoa(c.SystemPanelWidth) },
+	},
+	{
+		key: "token_panel_width", env: "CCDASH_TOKEN_PANEL_WIDTH",
+		set: func(c *Config, v string) error { return parseInt(v, 0, &c.TokenPanelWidth) },
+		get: func(c *Config) string { return strconv.Itoa(c.TokenPanelWidth) },
+	},
+	{
+		key: "secondary_currency", env: "CCDASH_SECONDARY_CURRENCY",
+		set: func(c *Config, v string) error {
+			code := strings.ToUpper(strings.TrimSpace(v))
+			if code != "" && !isCurrencyCode(code) {
+				return fmt.Errorf("invalid currency %q, expected a 3-letter code like GBP", v)
+			}
+			c.SecondaryCurrency = code
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.SecondaryCurrency) },
+	},
+	{
+		key: "fx_rate", env: "CCDASH_FX_RATE",
+		set: func(c *Config, v string) error {
+			if err := parseFloat(v, &c.FXRate); err != nil {
+				return err
+			}
+			if c.FXRate < 0 {
+				return fmt.Errorf("fx_rate can't be negative, got %v", c.FXRate)
+			}
+			return nil
+		},
+		get: func(c *Config) string { return strconv.FormatFloat(c.FXRate, 'f', -1, 64) },
+	},
+	{
+		key: "status_format", env: "CCDASH_STATUS_FORMAT",
+		set: func(c *Config, v string) error { c.StatusFormat = v; return nil },
+		get: func(c *Config) string { return strconv.Quote(c.StatusFormat) },
+	},
+	{
+		key: "pricing_url", env: "CCDASH_PRICING_URL",
+		set: func(c *Config, v string) error {
+			if v != "" && !strings.HasPrefix(v, "https://") && !strings.HasPrefix(v, "http://") {
+				return fmt.Errorf("pricing_url must be an http:// or https:// URL, got %q", v)
+			}
+			c.PricingURL = v
+			return nil
+		},
+		get: func(c *Config) string { return strconv.Quote(c.PricingURL) },
+	},
+}
+
+// Default returns the built-in configuration
+func Default() *Config {
+	home, _ := os.UserHomeDir()
+	c := &Config{
+		Interval:      2 * time.Second,
+		Lookback:      "monday",
+		WarnThreshold: metrics.DefaultWarnThreshold,
+		CritThreshold: metrics.DefaultCritThreshold,
+		ProjectsDir:   filepath.Join(home, ".claude", "projects"),
+		CacheDir:      ".ccdash",
+		DiskPaths:     []string{"/"},
+		TokenSource:   metrics.TokenSourceJSONL,
+		ModelSort:     metrics.ModelSortCost,
+		CPUCoreLines:  6,
+		Sources:       make(map[string]string),
+
+		CollectTimeout:         3 * time.Second,
+		SessionCleanupInterval: metrics.DefaultSessionCleanupInterval,
+		AlertCooldown:          30 * time.Second,
+		GroupDelimiter:         "-",
+		CaptureLines:           metrics.DefaultCaptureLines,
+	}
+	for _, f := range fields {
+		c.Sources[f.key] = SourceDefault
+	}
+	return c
+}
+
+// Path returns the config file location: $CCDASH_CONFIG if set, otherwise
+// $XDG_CONFIG_HOME/ccdash/config.toml, falling back to ~/.ccdash/config.toml
+func Path() string {
+	path, _ := Location()
+	return path
+}
+
+// Location is Path, also reporting when ~/.ccdash/config.toml is used only
+// because it predates XDG_CONFIG_HOME being set
+func Location() (path string, stale bool) {
+	if p := os.Getenv("CCDASH_CONFIG"); p != "" {
+		return p, false
+	}
+	home, _ := os.UserHomeDir()
+	dir, stale := metrics.XDGDir("XDG_CONFIG_HOME", filepath.Join(home, metrics.HooksDir), FileName)
+	return filepath.Join(dir, FileName), stale
+}
+
+// Load returns the defaults overridden by the config file at path (if it
+// exists) and then by environment variables. Flags are applied separately
+// with ApplyFlags once they are parsed.
+func Load(path string) (*Config, error) {
+	c := Default()
+
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return nil, err
+	}
+	if err == nil {
+		if err := c.applyFile(path, data); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, f := range fields {
+		if f.env == "" {
+			continue
+		}
+		if v, ok := os.LookupEnv(f.env); ok {
+			if err := f.set(c, v); err != nil {
+				return nil, fmt.Errorf("%s: %w", f.env, err)
+			}
+			c.Sources[f.key] = SourceEnv
+		}
+	}
+	return c, nil
+}
+
+// ApplyFlags overrides the config with every flag that was set explicitly on
+// the command line and whose name matches a config key (dashes for underscores)
+func (c *Config) ApplyFlags(fs *flag.FlagSet) error {
+	var err error
+	fs.Visit(func(fl *flag.Flag) {
+		f, ok := lookupField(strings.ReplaceAll(fl.Name, "-", "_"))
+		if !ok || err != nil {
+			return
+		}
+		if setErr := f.set(c, fl.Value.String()); setErr != nil {
+			err = fmt.Errorf("--%s: %w", fl.Name, setErr)
+			return
+		}
+		c.Sources[f.key] = SourceFlag
+	})
+	return err
+}
+
+// Dump writes the config in config file syntax, noting where each value came from
+func (c *Config) Dump(w io.Writer) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintln(bw, "# Effective ccdash configuration")
+	for _, f := range fields {
+		value, source := f.get(c), c.Sources[f.key]
+		if f.key == "extra_dirs" {
+			value, source = c.extraDirsWithEnv()
+		}
+		fmt.Fprintf(bw, "%s = %s  # %s\n", f.key, value, source)
+	}
+	return bw.Flush()
+}
+
+// extraDirsWithEnv returns extra_dirs plus the CCDASH_EXTRA_DIRS directories
+// the token collector adds on top of them, and the source of the result
+func (c *Config) extraDirsWithEnv() (string, string) {
+	dirs, source := c.ExtraDirs, c.Sources["extra_dirs"]
+	var fromEnv []string
+	for _, d := range strings.Split(os.Getenv("CCDASH_EXTRA_DIRS"), ":") {
+		if d = strings.TrimSpace(d); d != "" && !slices.Contains(dirs, d) && !slices.Contains(fromEnv, d) {
+			fromEnv = append(fromEnv, d)
+		}
+	}
+	if len(fromEnv) == 0 {
+		return formatList(dirs), source
+	}
+	if len(dirs) == 0 {
+		source = SourceEnv
+	} else {
+		source += " + " + SourceEnv
+	}
+	return formatList(append(slices.Clone(dirs), fromEnv...)), source
+}
+
+// Keys returns the recognized config keys, sorted
+func Keys() []string {
+	keys := make([]string, 0, len(fields))
+	for _, f := range fields {
+		keys = append(keys, f.key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// applyFile parses a flat TOML document: key = value lines, where values are
+// strings, numbers, booleans or single-line arrays of strings
+func (c *Config) applyFile(path string, data []byte) error {
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(stripComment(scanner.Text()))
+		if line == "" {
+			continue
+		}
+		if strings.HasPrefix(line, "[") {
+			return fmt.Errorf("%s:%d: tables are not supported; put every key at the top level", path, lineNumber)
+		}
+
+		key, raw, ok := strings.Cut(line, "=")
+		if !ok {
+			return fmt.Errorf("%s:%d: expected key = value", path, lineNumber)
+		}
+		key = strings.ReplaceAll(strings.TrimSpace(key), "-", "_")
+		f, ok := lookupField(key)
+		if !ok {
+			return fmt.Errorf("%s:%d: unknown key %q (known keys: %s)", path, lineNumber, key, strings.Join(Keys(), ", "))
+		}
+
+		value, err := parseValue(strings.TrimSpace(raw), f.list)
+		if err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, lineNumber, key, err)
+		}
+		if err := f.set(c, value); err != nil {
+			return fmt.Errorf("%s:%d: %s: %w", path, lineNumber, key, err)
+		}
+		c.Sources[f.key] = path
+	}
+	return scanner.Err()
+}
+
+// parseValue decodes a TOML scalar or string array into the string form the
+// field setters take; arrays are joined with commas
+func parseValue(raw string, list bool) (string, error) {
+	if strings.HasPrefix(raw, "[") {
+		if !list {
+			return "", fmt.Errorf("expected a single value, not an array")
+		}
+		if !strings.HasSuffix(raw, "]") {
+			return "", fmt.Errorf("arrays must be on one line")
+		}
+		var items []string
+		for _, item := range splitArray(strings.TrimSpace(raw[1 : len(raw)-1])) {
+			s, err := parseString(item)
+			if err != nil {
+				return "", err
+			}
+			if strings.Contains(s, ",") {
+				return "", fmt.Errorf("array items can't contain commas: %q", s)
+			}
+			items = append(items, s)
+		}
+		return strings.Join(items, ","), nil
+	}
+	if strings.HasPrefix(raw, `"`) || strings.HasPrefix(raw, "'") {
+		return parseString(raw)
+	}
+	if raw == "" {
+		return "", fmt.Errorf("missing value")
+	}
+	// Numbers and booleans are passed through for the field to parse
+	return raw, nil
+}
+
+// parseString decodes a TOML basic ("...") or literal ('...') string
+func parseString(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if len(raw) >= 2 && raw[0] == '\'' && raw[len(raw)-1] == '\'' {
+		return raw[1 : len(raw)-1], nil
+	}
+	if len(raw) >= 2 && raw[0] == '"' {
+		s, err := strconv.Unquote(raw)
+		if err != nil {
+			return "", fmt.Errorf("invalid string %s", raw)
+		}
+		return s, nil
+	}
+	return "", fmt.Errorf("expected a quoted string, got %s", raw)
+}
+
+// splitArray splits the inside of a one-line array on commas outside quotes
+func splitArray(s string) []string {
+	var items []string
+	var quote byte
+	start := 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quote != 0:
+			if s[i] == '\\' && quote == '"' {
+				i++
+			} else if s[i] == quote {
+				quote = 0
+			}
+		case s[i] == '"' || s[i] == '\'':
+			quote = s[i]
+		case s[i] == ',':
+			items = append(items, s[start:i])
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		items = append(items, last) // A trailing comma is allowed
+	}
+	return items
+}
+
+// stripComment removes a # comment that isn't inside a string
+func stripComment(line string) string {
+	var quote byte
+	for i := 0; i < len(line); i++ {
+		switch {
+		case quote != 0:
+			if line[i] == '\\' && quote == '"' {
+				i++
+			} else if line[i] == quote {
+				quote = 0
+			}
+		case line[i] == '"' || line[i] == '\'':
+			quote = line[i]
+		case line[i] == '#':
+			return line[:i]
+		}
+	}
+	return line
+}
+
+// lookupField finds a field by config key
+func lookupField(key string) (field, bool) {
+	for _, f := range fields {
+		if f.key == key {
+			return f, true
+		}
+	}
+	return field{}, false
+}
+
+// parseDuration accepts Go durations ("2s", "500ms") or a bare number of seconds
+func parseDuration(v string) (time.Duration, error) {
+	if secs, err := strconv.ParseFloat(v, 64); err == nil {
+		return time.Duration(secs * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q", v)
+	}
+	return d, nil
+}
+
+// parseFloat parses v into dst
+func parseFloat(v string, dst *float64) error {
+	f, err := strconv.ParseFloat(v, 64)
+	if err != nil {
+		return fmt.Errorf("invalid number %q", v)
+	}
+	*dst = f
+	return nil
+}
+
+// parseBool parses true or false
+func parseBool(v string, dst *bool) error {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fmt.Errorf("expected true or false, got %q", v)
+	}
+	*dst = b
+	return nil
+}
+
+// parseInt parses a whole number no smaller than minimum
+func parseInt(v string, minimum int, dst *int) error {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("invalid whole number %q", v)
+	}
+	if n < minimum {
+		return fmt.Errorf("must be at least %d, got %d", minimum, n)
+	}
+	*dst = n
+	return nil
+}
+
+// splitList splits a comma-separated value, dropping empty entries
+func splitList(value string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// formatList formats a list as a TOML array of strings
+func formatList(items []string) string {
+	quoted := make([]string, len(items))
+	for i, item := range items {
+		quoted[i] = strconv.Quote(item)
+	}
+	return "[" + strings.Join(quoted, ", ") + "]"
+}
+
+// isCurrencyCode reports whether code looks like an ISO 4217 code (three letters)
+func isCurrencyCode(code string) bool {
+	if len(code) != 3 {
+		return false
+	}
+	for _, r := range code {
+		if r < 'A' || r > 'Z' {
+			return false
+		}
+	}
+	return true
+}
+
+// expandHome replaces a leading ~/ with the home directory
+func expandHome(path string) string {
+	if path == "~" || strings.HasPrefix(path, "~/") {
+		if home, err := os.UserHomeDir(); err == nil {
+			return filepath.Join(home, path[1:])
+		}
+	}
+	return path
+}
@@ -0,0 +1,225 @@
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, content string) string {
+	t.Helper()
+
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tmpDir) })
+
+	path := filepath.Join(tmpDir, FileName)
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write config: %v", err)
+	}
+	return path
+}
+
+func TestLoadPrecedence(t *testing.T) {
+	path := writeConfig(t, `
+# Comments and blank lines are ignored
+interval = "5s"
+lookback = "7d"       # trailing comment
+warn_threshold = 70
+crit_threshold = 90
+disk_path = ["/", "/home", ]
+cache_dir = '/var/cache/ccdash'
+`)
+	t.Setenv("CCDASH_CRIT_THRESHOLD", "92")
+	t.Setenv("CCDASH_SETTINGS_FILE", "settings.local.json")
+
+	cfg, err := Load(path)
+	if err != nil {
+		t.Fatalf("Load failed: %v", err)
+	}
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.String("lookback", cfg.Lookback, "")
+	fs.Bool("notify", false, "") // Not a config key, must be ignored
+	if err := fs.Parse([]string{"--lookback=today", "--notify"}); err != nil {
+		t.Fatalf("Parse failed: %v", err)
+	}
+	if err := cfg.ApplyFlags(fs); err != nil {
+		t.Fatalf("ApplyFlags failed: %v", err)
+	}
+
+	if cfg.Interval != 5*time.Second || cfg.Sources["interval"] != path {
+		t.Errorf("Expected interval 5s from the file, got %v from %s", cfg.Interval, cfg.Sources["interval"])
+	}
+	if cfg.WarnThreshold != 70 {
+		t.Errorf("Expected warn_threshold 70 from the file, got %v", cfg.WarnThreshold)
+	}
+	if cfg.CritThreshold != 92 || cfg.Sources["crit_threshold"] != SourceEnv {
+		t.Errorf("Expected env to override crit_threshold with 92, got %v from %s", cfg.CritThreshold, cfg.Sources["crit_threshold"])
+	}
+	if cfg.Lookback != "today" || cfg.Sources["lookback"] != SourceFlag {
+		t.Errorf("Expected flag to override lookback with today, got %q from %s", cfg.Lookback, cfg.Sources["lookback"])
+	}
+	if strings.Join(cfg.DiskPaths, ",") != "/,/home" {
+		t.Errorf("Expected disk paths [/ /home], got %v", cfg.DiskPaths)
+	}
+	if cfg.CacheDir != "/var/cache/ccdash" {
+		t.Errorf("Expected literal string cache_dir, got %q", cfg.CacheDir)
+	}
+	if cfg.SettingsFile != "settings.local.json" || cfg.Sources["settings_file"] != SourceEnv {
+		t.Errorf("Expected settings_file from env, got %q from %s", cfg.SettingsFile, cfg.Sources["settings_file"])
+	}
+	if cfg.TokenSource != "jsonl" || cfg.Sources["token_source"] != SourceDefault {
+		t.Errorf("Expected default token_source, got %q from %s", cfg.TokenSource, cfg.Sources["token_source"])
+	}
+}
+
+func TestLoadMissingFileUsesDefaults(t *testing.T) {
+	cfg, err := Load(filepath.Join(os.TempDir(), "ccdash-does-not-exist", FileName))
+	if err != nil {
+		t.Fatalf("Expected a missing config file to be ignored, got %v", err)
+	}
+	if cfg.Interval != 2*time.Second || cfg.Lookback != "monday" {
+		t.Errorf("Expected defaults, got interval %v lookback %q", cfg.Interval, cfg.Lookback)
+	}
+}
+
+func TestLoadRejectsInvalidFiles(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr string
+	}{
+		{"unknown key", "intervall = \"5s\"\n", `unknown key "intervall"`},
+		{"table", "[ui]\ninterval = \"5s\"\n", "tables are not supported"},
+		{"bad duration", "interval = \"soon\"\n", "invalid duration"},
+		{"negative alert cooldown", "alert_cooldown = \"-5s\"\n", "can't be negative"},
+		{"bad number", "warn_threshold = \"high\"\n", "invalid number"},
+		{"array for scalar", "lookback = [\"7d\"]\n", "not an array"},
+		{"unknown model sort", "model_sort = \"price\"\n", "unknown model sort"},
+		{"empty group delimiter", "group_delimiter = \"\"\n", "can't be empty"},
+		{"zero capture lines", "capture_lines = 0\n", "at least 1"},
+		{"too many capture lines", "capture_lines = 5000\n", "at most 1000"},
+		{"bad currency", "secondary_currency = \"pounds\"\n", "invalid currency"},
+		{"negative fx rate", "fx_rate = -1\n", "can't be negative"},
+		{"pricing path", "pricing_url = \"prices.json\"\n", "http:// or https://"},
+		{"fractional core lines", "cpu_core_lines = 2.5\n", "invalid whole number"},
+		{"zero core lines", "cpu_core_lines = 0\n", "at least 1"},
+		{"smoothing of 1", "cpu_smoothing = 1\n", "below 1"},
+		{"not a boolean", "mem_by_available = yes\n", "expected true or false"},
+		{"negative rate smoothing", "rate_smoothing = -0.5\n", "at least 0"},
+		{"no equals", "interval\n", "expected key = value"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Load(writeConfig(t, tt.content))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+			if err != nil && !strings.Contains(err.Error(), ":1:") && tt.name != "table" {
+				t.Errorf("Expected error to point at line 1, got %v", err)
+			}
+		})
+	}
+}
+
+func TestDumpRoundTrips(t *testing.T) {
+	cfg := Default()
+	cfg.Interval = 3 * time.Second
+	cfg.DiskPaths = []string{"/", "/data # not a comment"}
+
+	var sb strings.Builder
+	if err := cfg.Dump(&sb); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+
+	loaded, err := Load(writeConfig(t, sb.String()))
+	if err != nil {
+		t.Fatalf("Dump output doesn't load: %v\n%s", err, sb.String())
+	}
+	if loaded.Interval != cfg.Interval || strings.Join(loaded.DiskPaths, "|") != strings.Join(cfg.DiskPaths, "|") {
+		t.Errorf("Round trip mismatch: interval %v, disk paths %v", loaded.Interval, loaded.DiskPaths)
+	}
+}
+
+func TestDumpIncludesExtraDirsEnv(t *testing.T) {
+	cfg := Default()
+	cfg.ExtraDirs = []string{"/work"}
+	cfg.Sources["extra_dirs"] = SourceFlag
+	t.Setenv("CCDASH_EXTRA_DIRS", "/work:/shared: :/shared")
+
+	var sb strings.Builder
+	if err := cfg.Dump(&sb); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	want := `extra_dirs = ["/work", "/shared"]  # flag + env`
+	if !strings.Contains(sb.String(), want+"\n") {
+		t.Errorf("Expected %q in dump, got:\n%s", want, sb.String())
+	}
+
+	cfg.ExtraDirs = nil
+	sb.Reset()
+	if err := cfg.Dump(&sb); err != nil {
+		t.Fatalf("Dump failed: %v", err)
+	}
+	want = `extra_dirs = ["/work", "/shared"]  # env`
+	if !strings.Contains(sb.String(), want+"\n") {
+		t.Errorf("Expected %q in dump, got:\n%s", want, sb.String())
+	}
+}
+
+func TestPathFollowsXDGConfigHome(t *testing.T) {
+	home, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(home) })
+	xdg := filepath.Join(home, "xdg")
+	legacy := filepath.Join(home, ".ccdash", FileName)
+
+	t.Setenv("HOME", home)
+	t.Setenv("CCDASH_CONFIG", "")
+	t.Setenv("XDG_CONFIG_HOME", "")
+	if path, stale := Location(); path != legacy || stale {
+		t.Errorf("Expected %s without XDG_CONFIG_HOME, got %s (stale=%v)", legacy, path, stale)
+	}
+
+	t.Setenv("XDG_CONFIG_HOME", xdg)
+	want := filepath.Join(xdg, "ccdash", FileName)
+	if path, stale := Location(); path != want || stale {
+		t.Errorf("Expected %s with XDG_CONFIG_HOME set, got %s (stale=%v)", want, path, stale)
+	}
+
+	// An existing ~/.ccdash/config.toml keeps being read until it's moved
+	if err := os.MkdirAll(filepath.Dir(legacy), 0755); err != nil {
+		t.Fatalf("Failed to create legacy dir: %v", err)
+	}
+	if err := os.WriteFile(legacy, []byte("interval = \"5s\"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write legacy config: %v", err)
+	}
+	if path, stale := Location(); path != legacy || !stale {
+		t.Errorf("Expected the legacy %s to be used and reported, got %s (stale=%v)", legacy, path, stale)
+	}
+
+	if err := os.MkdirAll(filepath.Dir(want), 0755); err != nil {
+		t.Fatalf("Failed to create XDG dir: %v", err)
+	}
+	if err := os.WriteFile(want, nil, 0644); err != nil {
+		t.Fatalf("Failed to write XDG config: %v", err)
+	}
+	if path, stale := Location(); path != want || stale {
+		t.Errorf("Expected %s once it exists, got %s (stale=%v)", want, path, stale)
+	}
+
+	// Relative values are invalid per the XDG spec and ignored
+	t.Setenv("XDG_CONFIG_HOME", "relative/xdg")
+	if path, _ := Location(); path != legacy {
+		t.Errorf("Expected a relative XDG_CONFIG_HOME to be ignored, got %s", path)
+	}
+}
@@ -0,0 +1,283 @@
+package export
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"math"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// Snapshot is a combined point-in-time view of all three collectors
+type Snapshot struct {
+	System    metrics.SystemMetrics `json:"system"`
+	Tokens    *metrics.TokenMetrics `json:"tokens"`
+	Tmux      *metrics.TmuxMetrics  `json:"tmux"`
+	Timestamp time.Time             `json:"timestamp"`
+}
+
+// metricDef describes one metric family in the Prometheus exposition format
+type metricDef struct {
+	name string
+	help string
+	typ  string // "gauge" or "counter"
+}
+
+// Metric families written by WritePrometheus. Token and cost values cover the
+// lookback window, which resets (weekly by default), so they are gauges.
+var (
+	metricCPUPercent    = metricDef{"ccdash_cpu_usage_percent", "Total CPU utilization in percent.", "gauge"}
+	metricLoadAverage   = metricDef{"ccdash_load_average", "System load average by period.", "gauge"}
+	metricMemoryUsed    = metricDef{"ccdash_memory_used_bytes", "Used physical memory in bytes.", "gauge"}
+	metricMemoryTotal   = metricDef{"ccdash_memory_total_bytes", "Total physical memory in bytes.", "gauge"}
+	metricSwapUsed      = metricDef{"ccdash_swap_used_bytes", "Used swap in bytes.", "gauge"}
+	metricDiskUsed      = metricDef{"ccdash_disk_used_bytes", "Used disk space in bytes by monitored path.", "gauge"}
+	metricDiskTotal     = metricDef{"ccdash_disk_total_bytes", "Total disk space in bytes by monitored path.", "gauge"}
+	metricTokens        = metricDef{"ccdash_tokens", "Claude Code tokens used since the lookback start, by token type.", "gauge"}
+	metricModelTokens   = metricDef{"ccdash_model_tokens", "Claude Code tokens used since the lookback start, by model and token type.", "gauge"}
+	metricCost          = metricDef{"ccdash_cost_dollars", "Estimated API cost in USD since the lookback start.", "gauge"}
+	metricCostPer1K     = metricDef{"ccdash_cost_per_1k_tokens_dollars", "Estimated API cost in USD per thousand tokens since the lookback start.", "gauge"}
+	metricCacheCost     = metricDef{"ccdash_cache_cost_dollars", "Estimated API cost in USD of prompt cache writes and reads since the lookback start, by type.", "gauge"}
+	metricModelCost     = metricDef{"ccdash_model_cost_dollars", "Estimated API cost in USD since the lookback start, by model.", "gauge"}
+	metricTokenRate     = metricDef{"ccdash_token_rate_per_minute", "Tokens per minute over the last 60 seconds.", "gauge"}
+	metricLookbackStart = metricDef{"ccdash_lookback_start_timestamp_seconds", "Unix time the token lookback window starts at.", "gauge"}
+	metricSessions      = metricDef{"ccdash_sessions", "Claude Code sessions by status.", "gauge"}
+	metricSnapshotTime  = metricDef{"ccdash_snapshot_timestamp_seconds", "Unix time the snapshot was taken.", "gauge"}
+)
+
+// sample is a single labelled value of a metric family
+type sample struct {
+	labels [][2]string
+	value  float64
+}
+
+// promWriter accumulates exposition output and remembers the first write error
+type promWriter struct {
+	w   *bufio.Writer
+	err error
+}
+
+// family writes the HELP/TYPE header and all samples of one metric family
+func (pw *promWriter) family(def metricDef, samples ...sample) {
+	if pw.err != nil || len(samples) == 0 {
+		return
+	}
+	fmt.Fprintf(pw.w, "# HELP %s %s\n", def.name, escapeHelp(def.help))
+	fmt.Fprintf(pw.w, "# TYPE %s %s\n", def.name, def.typ)
+	for _, s := range samples {
+		pw.w.WriteString(def.name)
+		if len(s.labels) > 0 {
+			pw.w.WriteByte('{')
+			for i, l := range s.labels {
+				if i > 0 {
+					pw.w.WriteByte(',')
+				}
+				fmt.Fprintf(pw.w, `%s="%s"`, l[0], escapeLabelValue(l[1]))
+			}
+			pw.w.WriteByte('}')
+		}
+		pw.w.WriteByte(' ')
+		pw.w.WriteString(formatValue(s.value))
+		if _, err := pw.w.WriteString("\n"); err != nil {
+			pw.err = err
+			return
+		}
+	}
+}
+
+// WritePrometheus writes the snapshot in the Prometheus text exposition format
+func WritePrometheus(w io.Writer, snap *Snapshot) error {
+	pw := &promWriter{w: bufio.NewWriter(w)}
+
+	sys := snap.System
+	if sys.CPU.Error == nil {
+		pw.family(metricCPUPercent, sample{value: sys.CPU.TotalPercent})
+	}
+	if sys.Load.Error == nil {
+		pw.family(metricLoadAverage,
+			sample{labels: [][2]string{{"period", "1m"}}, value: sys.Load.Load1},
+			sample{labels: [][2]string{{"period", "5m"}}, value: sys.Load.Load5},
+			sample{labels: [][2]string{{"period", "15m"}}, value: sys.Load.Load15},
+		)
+	}
+	if sys.Memory.Error == nil {
+		pw.family(metricMemoryUsed, sample{value: float64(sys.Memory.Used)})
+		pw.family(metricMemoryTotal, sample{value: float64(sys.Memory.Total)})
+	}
+	if sys.Swap.Error == nil && sys.Swap.Total > 0 {
+		pw.family(metricSwapUsed, sample{value: float64(sys.Swap.Used)})
+	}
+
+	var diskUsed, diskTotal []sample
+	for _, du := range sys.DiskUsages {
+		if du.Error != nil {
+			continue
+		}
+		labels := [][2]string{{"path", du.Path}}
+		diskUsed = append(diskUsed, sample{labels: labels, value: float64(du.Used)})
+		diskTotal = append(diskTotal, sample{labels: labels, value: float64(du.Total)})
+	}
+	pw.family(metricDiskUsed, diskUsed...)
+	pw.family(metricDiskTotal, diskTotal...)
+
+	if t := snap.Tokens; t != nil && t.Available {
+		pw.family(metricTokens,
+			sample{labels: [][2]string{{"type", "input"}}, value: float64(t.InputTokens)},
+			sample{labels: [][2]string{{"type", "output"}}, value: float64(t.OutputTokens)},
+			sample{labels: [][2]string{{"type", "cache_read"}}, value: float64(t.CacheReadTokens)},
+			sample{labels: [][2]string{{"type", "cache_creation"}}, value: float64(t.CacheCreationTokens)},
+		)
+
+		var modelTokens, modelCost []sample
+		for _, mu := range t.ModelUsages {
+			for _, tt := range []struct {
+				typ   string
+				count int64
+			}{
+				{"input", mu.InputTokens},
+				{"output", mu.OutputTokens},
+				{"cache_read", mu.CacheReadTokens},
+				{"cache_creation", mu.CacheCreationTokens},
+			} {
+				modelTokens = append(modelTokens, sample{
+					labels: [][2]string{{"model", mu.Model}, {"type", tt.typ}},
+					value:  float64(tt.count),
+				})
+			}
+			modelCost = append(modelCost, sample{labels: [][2]string{{"model", mu.Model}}, value: mu.Cost})
+		}
+		pw.family(metricModelTokens, modelTokens...)
+		pw.family(metricCost, sample{value: t.TotalCost})
+		pw.family(metricCostPer1K, sample{value: t.CostPer1K})
+		pw.family(metricCacheCost,
+			sample{labels: [][2]string{{"type", "write"}}, value: t.CacheWriteCost},
+			sample{labels: [][2]string{{"type", "read"}}, value: t.CacheReadCost})
+		pw.family(metricModelCost, modelCost...)
+		pw.family(metricTokenRate, sample{value: t.Rate})
+		if !t.LookbackFrom.IsZero() {
+			pw.family(metricLookbackStart, sample{value: float64(t.LookbackFrom.Unix())})
+		}
+	}
+
+	if snap.Tmux != nil {
+		counts := make(map[metrics.SessionStatus]int)
+		for _, s := range snap.Tmux.Sessions {
+			counts[s.Status]++
+		}
+		// Always emit every status so series don't disappear when a count drops to zero
+		statuses := []metrics.SessionStatus{metrics.StatusWorking, metrics.StatusReady, metrics.StatusActive, metrics.StatusError}
+		for status := range counts {
+			if !containsStatus(statuses, status) {
+				statuses = append(statuses, status)
+			}
+		}
+		sort.Slice(statuses, func(i, j int) bool { return statuses[i] < statuses[j] })
+
+		var sessions []sample
+		for _, status := range statuses {
+			sessions = append(sessions, sample{
+				labels: [][2]string{{"status", strings.ToLower(string(status))}},
+				value:  float64(counts[status]),
+			})
+		}
+		pw.family(metricSessions, sessions...)
+	}
+
+	pw.family(metricSnapshotTime, sample{value: float64(snap.Timestamp.Unix())})
+
+	if pw.err != nil {
+		return pw.err
+	}
+	return pw.w.Flush()
+}
+
+// WritePrometheusTextfile atomically writes the snapshot to path for
+// node_exporter's textfile collector. The data goes to a temp file in the same
+// directory first and is renamed into place, so the collector never reads a
+// partial file.
+func WritePrometheusTextfile(path string, snap *Snapshot) error {
+	var sb strings.Builder
+	if err := WritePrometheus(&sb, snap); err != nil {
+		return err
+	}
+	return WriteFileAtomic(path, []byte(sb.String()))
+}
+
+// WriteFileAtomic writes data to a temp file next to path and renames it into place
+func WriteFileAtomic(path string, data []byte) error {
+	dir := filepath.Dir(path)
+	// The temp name doesn't end in .prom, so node_exporter ignores it meanwhile
+	tmp, err := os.CreateTemp(dir, "."+filepath.Base(path)+".tmp-*")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file: %w", err)
+	}
+	tmpName := tmp.Name()
+	defer os.Remove(tmpName) // No-op after a successful rename
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write temp file: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to sync temp file: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to close temp file: %w", err)
+	}
+	// CreateTemp uses 0600; the collector usually runs as another user
+	if err := os.Chmod(tmpName, 0644); err != nil {
+		return fmt.Errorf("failed to set permissions: %w", err)
+	}
+	if err := os.Rename(tmpName, path); err != nil {
+		return fmt.Errorf("failed to rename temp file: %w", err)
+	}
+	return nil
+}
+
+// escapeLabelValue escapes a label value per the exposition format:
+// backslash, double quote and newline
+func escapeLabelValue(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// escapeHelp escapes HELP text, which allows quotes but not raw backslashes or newlines
+func escapeHelp(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	return strings.ReplaceAll(s, "\n", `\n`)
+}
+
+// formatValue formats a sample value, using the exposition format's spelling of special values
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	case v == math.Trunc(v) && math.Abs(v) < 1e15:
+		// Byte and token counts read better without an exponent
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+	return strconv.FormatFloat(v, 'g', -1, 64)
+}
+
+// containsStatus reports whether status is in the list
+func containsStatus(list []metrics.SessionStatus, status metrics.SessionStatus) bool {
+	for _, s := range list {
+		if s == status {
+			return true
+		}
+	}
+	return false
+}
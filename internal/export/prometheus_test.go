@@ -0,0 +1,104 @@
+package export
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+func TestEscapeLabelValue(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"claude-sonnet-4", "claude-sonnet-4"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\models`, `C:\\models`},
+		{"line1\nline2", `line1\nline2`},
+	}
+	for _, tt := range tests {
+		if got := escapeLabelValue(tt.in); got != tt.want {
+			t.Errorf("escapeLabelValue(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestWritePrometheus(t *testing.T) {
+	snap := &Snapshot{
+		System: metrics.SystemMetrics{
+			CPU:        metrics.CPUMetrics{TotalPercent: 12.5},
+			DiskUsages: []metrics.DiskUsageMetrics{{Path: "/", Used: 100, Total: 400}},
+		},
+		Tokens: &metrics.TokenMetrics{
+			Available:   true,
+			InputTokens: 1500,
+			TotalCost:   1.25,
+			ModelUsages: []metrics.ModelUsage{{Model: `odd"model`, InputTokens: 1500, Cost: 1.25}},
+
+			CacheWriteCost: 0.5,
+		},
+		Tmux: &metrics.TmuxMetrics{
+			Sessions: []metrics.TmuxSession{{Name: "a", Status: metrics.StatusWorking}},
+		},
+		Timestamp: time.Unix(1700000000, 0),
+	}
+
+	var sb strings.Builder
+	if err := WritePrometheus(&sb, snap); err != nil {
+		t.Fatalf("WritePrometheus failed: %v", err)
+	}
+	out := sb.String()
+
+	for _, want := range []string{
+		"# HELP ccdash_cpu_usage_percent ",
+		"# TYPE ccdash_cpu_usage_percent gauge\nccdash_cpu_usage_percent 12.5\n",
+		`ccdash_disk_total_bytes{path="/"} 400`,
+		`ccdash_tokens{type="input"} 1500`,
+		`ccdash_model_tokens{model="odd\"model",type="input"} 1500`,
+		`ccdash_model_cost_dollars{model="odd\"model"} 1.25`,
+		`ccdash_cache_cost_dollars{type="write"} 0.5`,
+		`ccdash_cache_cost_dollars{type="read"} 0`,
+		`ccdash_sessions{status="working"} 1`,
+		`ccdash_sessions{status="ready"} 0`,
+		"ccdash_snapshot_timestamp_seconds 1700000000\n",
+	} {
+		if !strings.Contains(out, want) {
+			t.Errorf("Expected output to contain %q, got:\n%s", want, out)
+		}
+	}
+}
+
+func TestWritePrometheusTextfileIsAtomic(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	path := filepath.Join(tmpDir, "ccdash.prom")
+	if err := os.WriteFile(path, []byte("old contents\n"), 0644); err != nil {
+		t.Fatalf("Failed to write existing file: %v", err)
+	}
+
+	if err := WritePrometheusTextfile(path, &Snapshot{Timestamp: time.Now()}); err != nil {
+		t.Fatalf("WritePrometheusTextfile failed: %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read textfile: %v", err)
+	}
+	if !strings.HasPrefix(string(data), "# HELP ") {
+		t.Errorf("Expected textfile to be replaced, got %q", data)
+	}
+
+	// The temp file must be renamed away, not left behind
+	entries, _ := os.ReadDir(tmpDir)
+	if len(entries) != 1 {
+		t.Errorf("Expected only the textfile in the directory, found %d entries", len(entries))
+	}
+}
@@ -0,0 +1,73 @@
+package export
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"path"
+	"path/filepath"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+// Pseudonym returns a stable stand-in for a project directory name (as
+// under ~/.claude/projects), e.g. "project-3f9a1c". The same project always
+// gets the same pseudonym, across rows and runs, so redacted data still
+// groups by project. It's a hash, not encryption: someone who can guess a
+// project's path can check the guess.
+func Pseudonym(project string) string {
+	return "project-" + shortHash(project)
+}
+
+// sessionPseudonym is Pseudonym for tmux session names
+func sessionPseudonym(name string) string {
+	return "session-" + shortHash(name)
+}
+
+// shortHash returns the first six hex digits of the SHA-256 of s
+func shortHash(s string) string {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:3])
+}
+
+// RedactSourceFile replaces the part of a JSONL log path up to its project
+// directory with the project's pseudonym:
+// "/home/me/.claude/projects/-home-me-app/abc.jsonl" becomes
+// "project-3f9a1c/abc.jsonl". Session and subagent file names are kept.
+func RedactSourceFile(sourceFile string) string {
+	project := metrics.ProjectFromSourceFile(sourceFile)
+	for dir := filepath.Dir(sourceFile); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		if filepath.Base(dir) != project {
+			continue
+		}
+		if rel, err := filepath.Rel(dir, sourceFile); err == nil {
+			return path.Join(Pseudonym(project), filepath.ToSlash(rel))
+		}
+	}
+	return path.Join(Pseudonym(project), filepath.Base(sourceFile))
+}
+
+// RedactEvent redacts an event's source file for sharing
+func RedactEvent(e *metrics.TokenEvent) {
+	e.SourceFile = RedactSourceFile(e.SourceFile)
+}
+
+// RedactSnapshot replaces what identifies projects in snap, in place: the
+// token scope's directory, each session's name and working directory, and
+// the pane lines captured from it, which are dropped. Working directories
+// get the same pseudonym as their logs in a redacted JSONL export.
+func RedactSnapshot(snap *Snapshot) {
+	if snap.Tokens != nil && snap.Tokens.Project != "" {
+		snap.Tokens.Project = Pseudonym(metrics.ProjectDirName(snap.Tokens.Project))
+	}
+	if snap.Tmux == nil {
+		return
+	}
+	for i := range snap.Tmux.Sessions {
+		s := &snap.Tmux.Sessions[i]
+		s.Name = sessionPseudonym(s.Name)
+		if s.ProjectDir != "" {
+			s.ProjectDir = Pseudonym(metrics.ProjectDirName(s.ProjectDir))
+		}
+		s.LastLines = nil
+	}
+}
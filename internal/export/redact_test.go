@@ -0,0 +1,55 @@
+package export
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jedarden/ccdash/internal/metrics"
+)
+
+func TestRedactSourceFile(t *testing.T) {
+	app := Pseudonym("-home-me-app")
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"/home/me/.claude/projects/-home-me-app/abc.jsonl", app + "/abc.jsonl"},
+		{"/home/me/.claude/projects/-home-me-app/abc/subagents/agent-1.jsonl", app + "/abc/subagents/agent-1.jsonl"},
+		{"/srv/agents/projects/-home-me-app/def.jsonl", app + "/def.jsonl"},
+	}
+	for _, tt := range tests {
+		if got := RedactSourceFile(tt.in); got != tt.want {
+			t.Errorf("RedactSourceFile(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+
+	if !strings.HasPrefix(app, "project-") || len(app) != len("project-")+6 {
+		t.Errorf("Expected a project-xxxxxx pseudonym, got %q", app)
+	}
+	if other := Pseudonym("-home-me-web"); other == app {
+		t.Errorf("Expected different projects to get different pseudonyms, both got %q", app)
+	}
+}
+
+func TestRedactSnapshot(t *testing.T) {
+	snap := &Snapshot{
+		Tokens: &metrics.TokenMetrics{Project: "/home/me/app", TotalTokens: 1000},
+		Tmux: &metrics.TmuxMetrics{Sessions: []metrics.TmuxSession{
+			{Name: "secret-api", ProjectDir: "/home/me/app", LastLines: []string{"$ cat secrets"}},
+		}},
+	}
+	RedactSnapshot(snap)
+
+	app := Pseudonym(metrics.ProjectDirName("/home/me/app"))
+	if snap.Tokens.Project != app || snap.Tokens.TotalTokens != 1000 {
+		t.Errorf("Expected project %q with totals kept, got %+v", app, snap.Tokens)
+	}
+	s := snap.Tmux.Sessions[0]
+	if s.ProjectDir != app || !strings.HasPrefix(s.Name, "session-") || strings.Contains(s.Name, "secret") || s.LastLines != nil {
+		t.Errorf("Expected the session name, directory and pane lines redacted, got %+v", s)
+	}
+	// The working directory and its logs get the same pseudonym
+	if got := RedactSourceFile("/home/me/.claude/projects/-home-me-app/abc.jsonl"); !strings.HasPrefix(got, app+"/") {
+		t.Errorf("Expected the log under %q, got %q", app, got)
+	}
+}
@@ -1,13 +1,20 @@
 package metrics
 
 import (
+	"bufio"
+	"bytes"
 	"context"
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -38,12 +45,19 @@ type TokenCache struct {
 	ingestMu sync.RWMutex // Protects slow ingest operations (file scan, batch inserts)
 	metaMu   sync.RWMutex // Protects fast cache/lease operations (never blocked by ingestion)
 	cacheDir string
+
+	// Set when a corrupt database was moved aside and a fresh one created
+	rebuiltAfterCorruption bool
+
+	// Set when a migration needs every log read again, compacted ones included
+	rereadLogs atomic.Bool
 }
 
 const (
 	cacheDirName  = ".ccdash"
 	cacheDBName   = "tokens.db"
-	schemaVersion = 3
+	corruptSuffix = ".corrupt" // Appended to the database path when a corrupt file is moved aside
+	schemaVersion = 5
 
 	// Threshold for marking a file as complete (no longer being written to)
 	fileCompleteThreshold = 30 * time.Minute
@@ -120,6 +134,23 @@ func isLockError(errStr string) bool {
 	return false
 }
 
+// isCorruptError checks if the error means the database file is damaged or
+// isn't SQLite at all, so retrying can't help
+func isCorruptError(errStr string) bool {
+	corruptPhrases := []string{
+		"database disk image is malformed",
+		"file is not a database",
+		"SQLITE_CORRUPT",
+		"SQLITE_NOTADB",
+	}
+	for _, phrase := range corruptPhrases {
+		if contains(errStr, phrase) {
+			return true
+		}
+	}
+	return false
+}
+
 // contains checks if s contains substr (case-insensitive)
 func contains(s, substr string) bool {
 	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsImpl(s, substr))
@@ -134,15 +165,24 @@ func containsImpl(s, substr string) bool {
 	return false
 }
 
-// NewTokenCache creates a new SQLite-based token cache in the .ccdash directory
+// cacheDirOverride replaces the default .ccdash cache directory (config cache_dir)
+var cacheDirOverride string
+
+// SetCacheDir sets the directory NewTokenCache uses. Relative paths resolve
+// against the working directory. Call before creating any collectors.
+func SetCacheDir(dir string) {
+	cacheDirOverride = dir
+}
+
+// NewTokenCache creates a new SQLite-based token cache in the directory
+// returned by CacheDir
 func NewTokenCache() *TokenCache {
-	// Get directory where binary is invoked (current working directory)
-	cwd, err := os.Getwd()
-	if err != nil {
-		cwd = "."
-	}
+	dir, _ := CacheDir()
+	return NewTokenCacheWithDir(dir)
+}
 
-	cacheDir := filepath.Join(cwd, cacheDirName)
+// NewTokenCacheWithDir creates a token cache stored in the given directory
+func NewTokenCacheWithDir(cacheDir string) *TokenCache {
 	dbPath := filepath.Join(cacheDir, cacheDBName)
 
 	tc := &TokenCache{
@@ -157,12 +197,59 @@ func NewTokenCache() *TokenCache {
 
 	// Initialize database
 	if err := tc.initDB(); err != nil {
+		if isCorruptError(err.Error()) {
+			tc.rebuildCorruptDB()
+		}
 		return tc
 	}
 
 	return tc
 }
 
+// rebuildCorruptDB moves a corrupt database to tokens.db.corrupt and creates
+// a fresh one. Token data is re-ingested from the JSONL logs. Events added with
+// ccdash import, and totals for logs that have since been deleted, have no log
+// to come back from and are not kept.
+func (tc *TokenCache) rebuildCorruptDB() {
+	if tc.db != nil {
+		tc.db.Close()
+		tc.db = nil
+	}
+
+	if err := os.Rename(tc.dbPath, tc.dbPath+corruptSuffix); err != nil {
+		return
+	}
+	// The WAL and shared-memory files belong to the corrupt database
+	os.Remove(tc.dbPath + "-wal")
+	os.Remove(tc.dbPath + "-shm")
+
+	if err := tc.initDB(); err != nil {
+		if tc.db != nil {
+			tc.db.Close()
+			tc.db = nil
+		}
+		return
+	}
+	tc.rebuiltAfterCorruption = true
+}
+
+// RebuiltAfterCorruption reports whether the database was found corrupt on
+// open and replaced with an empty one, which is now being re-ingested
+func (tc *TokenCache) RebuiltAfterCorruption() bool {
+	return tc.rebuiltAfterCorruption
+}
+
+// takeRereadLogs reports whether a migration asked for every log to be read
+// again, and clears the request
+func (tc *TokenCache) takeRereadLogs() bool {
+	return tc.rereadLogs.Swap(false)
+}
+
+// CorruptBackupPath returns where a corrupt database is moved before rebuilding
+func (tc *TokenCache) CorruptBackupPath() string {
+	return tc.dbPath + corruptSuffix
+}
+
 // initDB initializes the SQLite database with the required schema
 func (tc *TokenCache) initDB() error {
 	tc.ingestMu.Lock()
@@ -209,7 +296,9 @@ func (tc *TokenCache) initDB() error {
 		}
 	}
 
-	// Create schema
+	// Create the base (version 1) schema. Everything added after version 1
+	// lives in the migrations list so that existing databases are upgraded
+	// in place instead of being left with a stale table layout.
 	schema := `
 	CREATE TABLE IF NOT EXISTS schema_version (
 		version INTEGER PRIMARY KEY
@@ -237,40 +326,6 @@ func (tc *TokenCache) initDB() error {
 		last_line INTEGER DEFAULT 0,
 		last_modified INTEGER DEFAULT 0
 	);
-
-	-- Metrics cache for leader election pattern
-	-- Only one instance collects, others read from cache
-	CREATE TABLE IF NOT EXISTS metrics_cache (
-		metric_type TEXT PRIMARY KEY,
-		data BLOB NOT NULL,
-		updated_at INTEGER NOT NULL
-	);
-
-	-- Collector lease for leader election
-	-- Instance with valid lease is the collector
-	CREATE TABLE IF NOT EXISTS collector_lease (
-		id INTEGER PRIMARY KEY CHECK (id = 1),
-		instance_id TEXT NOT NULL,
-		expires_at INTEGER NOT NULL
-	);
-
-	-- Pre-aggregated totals for complete files (not being written to anymore)
-	-- Allows skipping file I/O and individual event queries for old sessions
-	CREATE TABLE IF NOT EXISTS file_aggregates (
-		source_file TEXT PRIMARY KEY,
-		is_complete BOOLEAN DEFAULT 0,
-		completed_at INTEGER DEFAULT 0,
-		total_input_tokens INTEGER DEFAULT 0,
-		total_output_tokens INTEGER DEFAULT 0,
-		total_cache_read_tokens INTEGER DEFAULT 0,
-		total_cache_creation_tokens INTEGER DEFAULT 0,
-		event_count INTEGER DEFAULT 0,
-		earliest_timestamp INTEGER DEFAULT 0,
-		latest_timestamp INTEGER DEFAULT 0,
-		model_breakdown TEXT DEFAULT '{}'
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_file_aggregates_complete ON file_aggregates(is_complete);
 	`
 
 	_, err = tc.db.Exec(schema)
@@ -282,12 +337,195 @@ func (tc *TokenCache) initDB() error {
 	var version int
 	err = tc.db.QueryRow("SELECT version FROM schema_version LIMIT 1").Scan(&version)
 	if err == sql.ErrNoRows {
-		_, err = tc.db.Exec("INSERT INTO schema_version (version) VALUES (?)", schemaVersion)
+		version = 1
+		_, err = tc.db.Exec("INSERT INTO schema_version (version) VALUES (?)", version)
+	}
+	if err != nil {
+		return err
+	}
+
+	return tc.migrate(version)
+}
+
+// migration upgrades the database schema from version-1 to version
+type migration struct {
+	version     int
+	description string
+	statements  []string
+	// reread makes the next ingestion read every log from the start,
+	// including compacted ones, to correct what earlier versions stored
+	reread bool
+}
+
+// migrations lists every schema change after the base schema, in order.
+// Each migration runs in its own transaction together with the version bump,
+// so a failure leaves the database at the last fully applied version.
+// New columns must be added here (and schemaVersion bumped), never to the
+// base schema in initDB.
+var migrations = []migration{
+	{
+		version:     2,
+		description: "metrics cache and collector lease for leader election",
+		statements: []string{
+			// Metrics cache for leader election pattern
+			// Only one instance collects, others read from cache
+			`CREATE TABLE IF NOT EXISTS metrics_cache (
+				metric_type TEXT PRIMARY KEY,
+				data BLOB NOT NULL,
+				updated_at INTEGER NOT NULL
+			)`,
+			// Collector lease for leader election
+			// Instance with valid lease is the collector
+			`CREATE TABLE IF NOT EXISTS collector_lease (
+				id INTEGER PRIMARY KEY CHECK (id = 1),
+				instance_id TEXT NOT NULL,
+				expires_at INTEGER NOT NULL
+			)`,
+		},
+	},
+	{
+		version:     3,
+		description: "pre-aggregated totals for complete files",
+		statements: []string{
+			// Pre-aggregated totals for complete files (not being written to anymore)
+			// Allows skipping file I/O and individual event queries for old sessions
+			`CREATE TABLE IF NOT EXISTS file_aggregates (
+				source_file TEXT PRIMARY KEY,
+				is_complete BOOLEAN DEFAULT 0,
+				completed_at INTEGER DEFAULT 0,
+				total_input_tokens INTEGER DEFAULT 0,
+				total_output_tokens INTEGER DEFAULT 0,
+				total_cache_read_tokens INTEGER DEFAULT 0,
+				total_cache_creation_tokens INTEGER DEFAULT 0,
+				event_count INTEGER DEFAULT 0,
+				earliest_timestamp INTEGER DEFAULT 0,
+				latest_timestamp INTEGER DEFAULT 0,
+				model_breakdown TEXT DEFAULT '{}'
+			)`,
+			`CREATE INDEX IF NOT EXISTS idx_file_aggregates_complete ON file_aggregates(is_complete)`,
+		},
+	},
+	{
+		version:     4,
+		description: "token usage reported on user messages",
+		statements: []string{
+			// Kept apart from token_events so the default totals, file
+			// aggregates and request counts are unaffected when disabled
+			`CREATE TABLE IF NOT EXISTS user_token_events (
+				id INTEGER PRIMARY KEY AUTOINCREMENT,
+				timestamp_unix INTEGER NOT NULL,
+				model TEXT NOT NULL DEFAULT '',
+				input_tokens INTEGER DEFAULT 0,
+				cache_read_tokens INTEGER DEFAULT 0,
+				cache_creation_tokens INTEGER DEFAULT 0,
+				source_file TEXT NOT NULL,
+				line_number INTEGER NOT NULL
+			)`,
+			`CREATE UNIQUE INDEX IF NOT EXISTS idx_user_source_line ON user_token_events(source_file, line_number)`,
+			`CREATE INDEX IF NOT EXISTS idx_user_timestamp_unix ON user_token_events(timestamp_unix)`,
+		},
+	},
+	{
+		version:     5,
+		description: "re-read logs for the larger cache write count",
+		statements: []string{
+			// Cache writes were undercounted when a line's TTL breakdown was
+			// larger than its total. Re-reading every log from the start
+			// raises the stored counts; events and aggregates from logs that
+			// no longer exist are kept as they are.
+			`DELETE FROM file_state`,
+		},
+		reread: true,
+	},
+}
+
+// migrate applies all migrations newer than the given version, in order
+func (tc *TokenCache) migrate(current int) error {
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+		if err := tc.applyMigration(m); err != nil {
+			return fmt.Errorf("schema migration to v%d (%s) failed: %w", m.version, m.description, err)
+		}
+		if m.reread {
+			tc.rereadLogs.Store(true)
+		}
+		current = m.version
+	}
+	return nil
+}
+
+// applyMigration runs a single migration and records its version atomically
+func (tc *TokenCache) applyMigration(m migration) error {
+	tx, err := tc.db.Begin()
+	if err != nil {
+		return err
 	}
+	defer tx.Rollback()
 
+	for _, stmt := range m.statements {
+		if _, err := tx.Exec(stmt); err != nil {
+			return err
+		}
+	}
+
+	if _, err := tx.Exec("UPDATE schema_version SET version = ?", m.version); err != nil {
+		return err
+	}
+
+	return tx.Commit()
+}
+
+// SchemaVersion returns the schema version recorded in the database
+func (tc *TokenCache) SchemaVersion() (int, error) {
+	if tc.db == nil {
+		return 0, fmt.Errorf("database not initialized")
+	}
+
+	var version int
+	err := tc.db.QueryRow("SELECT version FROM schema_version LIMIT 1").Scan(&version)
+	return version, err
+}
+
+// JournalMode returns the database's journal mode ("wal" when concurrent access is set up correctly)
+func (tc *TokenCache) JournalMode() (string, error) {
+	if tc.db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	var mode string
+	err := tc.db.QueryRow("PRAGMA journal_mode").Scan(&mode)
+	return mode, err
+}
+
+// CheckWritable verifies the database accepts writes by running a no-op update
+// in a transaction that is rolled back
+func (tc *TokenCache) CheckWritable() error {
+	if tc.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	tx, err := tc.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec("UPDATE schema_version SET version = version")
 	return err
 }
 
+// Ping checks that the database answers a read query in time, for health checks
+func (tc *TokenCache) Ping(ctx context.Context) error {
+	if tc.db == nil {
+		return fmt.Errorf("database not initialized")
+	}
+
+	var files int64
+	return tc.db.QueryRowContext(ctx, "SELECT COUNT(*) FROM file_state").Scan(&files)
+}
+
 // Close closes the database connection
 func (tc *TokenCache) Close() error {
 	tc.ingestMu.Lock()
@@ -328,15 +566,19 @@ func (tc *TokenCache) InsertTokenEventContext(ctx context.Context, timestamp tim
 
 	return withRetryNoResult(ctx, func() error {
 		_, err := tc.db.ExecContext(ctx, `
-			INSERT OR IGNORE INTO token_events
+			INSERT INTO token_events
 			(timestamp, timestamp_unix, model, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, source_file, line_number)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_file, line_number) DO UPDATE SET
+				cache_creation_tokens = MAX(cache_creation_tokens, excluded.cache_creation_tokens)
 		`, timestamp.Format(time.RFC3339Nano), timestamp.Unix(), model, inputTokens, outputTokens, cacheReadTokens, cacheCreationTokens, sourceFile, lineNumber)
 		return err
 	})
 }
 
-// InsertTokenEventBatch inserts multiple token events in a single transaction
+// InsertTokenEventBatch inserts multiple token events in a single transaction.
+// A line that is already stored is kept, except that its cache write count is
+// raised if the new reading is larger (see cacheCreationTokens).
 func (tc *TokenCache) InsertTokenEventBatch(events []TokenEvent) error {
 	return tc.InsertTokenEventBatchContext(context.Background(), events)
 }
@@ -361,9 +603,11 @@ func (tc *TokenCache) InsertTokenEventBatchContext(ctx context.Context, events [
 		defer tx.Rollback()
 
 		stmt, err := tx.PrepareContext(ctx, `
-			INSERT OR IGNORE INTO token_events
+			INSERT INTO token_events
 			(timestamp, timestamp_unix, model, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, source_file, line_number)
 			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_file, line_number) DO UPDATE SET
+				cache_creation_tokens = MAX(cache_creation_tokens, excluded.cache_creation_tokens)
 		`)
 		if err != nil {
 			return err
@@ -381,16 +625,144 @@ func (tc *TokenCache) InsertTokenEventBatchContext(ctx context.Context, events [
 	})
 }
 
+// InsertUserTokenEventBatch inserts token usage from user messages in a single
+// transaction. OutputTokens is ignored: user turns only contribute input.
+func (tc *TokenCache) InsertUserTokenEventBatch(events []TokenEvent) error {
+	return tc.InsertUserTokenEventBatchContext(context.Background(), events)
+}
+
+// InsertUserTokenEventBatchContext inserts user message token usage with context support
+func (tc *TokenCache) InsertUserTokenEventBatchContext(ctx context.Context, events []TokenEvent) error {
+	tc.ingestMu.Lock()
+	defer tc.ingestMu.Unlock()
+
+	if tc.db == nil || len(events) == 0 {
+		return nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetryNoResult(ctx, func() error {
+		tx, err := tc.db.BeginTx(ctx, nil)
+		if err != nil {
+			return err
+		}
+		defer tx.Rollback()
+
+		stmt, err := tx.PrepareContext(ctx, `
+			INSERT INTO user_token_events
+			(timestamp_unix, model, input_tokens, cache_read_tokens, cache_creation_tokens, source_file, line_number)
+			VALUES (?, ?, ?, ?, ?, ?, ?)
+			ON CONFLICT(source_file, line_number) DO UPDATE SET
+				cache_creation_tokens = MAX(cache_creation_tokens, excluded.cache_creation_tokens)
+		`)
+		if err != nil {
+			return err
+		}
+		defer stmt.Close()
+
+		for _, e := range events {
+			_, err = stmt.ExecContext(ctx, e.Timestamp.Unix(), e.Model, e.InputTokens, e.CacheReadTokens, e.CacheCreationTokens, e.SourceFile, e.LineNumber)
+			if err != nil {
+				return err
+			}
+		}
+
+		return tx.Commit()
+	})
+}
+
+// QueryUserTokensSince returns user message token usage since a given timestamp,
+// broken down by the model the turn was sent to
+func (tc *TokenCache) QueryUserTokensSince(since time.Time) (*AggregatedTokens, error) {
+	return tc.QueryUserTokensSinceContext(context.Background(), since)
+}
+
+// QueryUserTokensSinceContext returns user message token usage with context support
+func (tc *TokenCache) QueryUserTokensSinceContext(ctx context.Context, since time.Time) (*AggregatedTokens, error) {
+	return tc.QueryUserTokensBetweenContext(ctx, since, time.Time{})
+}
+
+// QueryUserTokensBetween returns user message token usage from since up to,
+// but not including, until; a zero until means no upper bound
+func (tc *TokenCache) QueryUserTokensBetween(since, until time.Time, sourcePrefixes ...string) (*AggregatedTokens, error) {
+	return tc.QueryUserTokensBetweenContext(context.Background(), since, until, sourcePrefixes...)
+}
+
+// QueryUserTokensBetweenContext returns user message token usage for a time range with context support
+func (tc *TokenCache) QueryUserTokensBetweenContext(ctx context.Context, since, until time.Time, sourcePrefixes ...string) (*AggregatedTokens, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return &AggregatedTokens{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (*AggregatedTokens, error) {
+		result := &AggregatedTokens{
+			ModelTokens:  make(map[string]int64),
+			ModelMetrics: make(map[string]*ModelAggregation),
+		}
+
+		var sinceUnix int64
+		if !since.IsZero() {
+			sinceUnix = since.Unix()
+		}
+		untilUnix := unboundedUnix(until)
+		sourceFilter, sourceArgs := sourcePrefixFilter(sourcePrefixes)
+
+		rows, err := tc.db.QueryContext(ctx, `
+			SELECT
+				model,
+				COALESCE(SUM(input_tokens), 0),
+				COALESCE(SUM(cache_read_tokens), 0),
+				COALESCE(SUM(cache_creation_tokens), 0),
+				COUNT(*)
+			FROM user_token_events
+			WHERE timestamp_unix >= ? AND timestamp_unix < ?`+sourceFilter+`
+			GROUP BY model
+		`, append([]any{sinceUnix, untilUnix}, sourceArgs...)...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var model string
+			var input, cacheRead, cacheCreate, count int64
+			if err := rows.Scan(&model, &input, &cacheRead, &cacheCreate, &count); err != nil {
+				continue
+			}
+			result.InputTokens += input
+			result.CacheReadTokens += cacheRead
+			result.CacheCreationTokens += cacheCreate
+			result.EventCount += count
+			result.ModelTokens[model] = input + cacheRead + cacheCreate
+			result.ModelMetrics[model] = &ModelAggregation{
+				InputTokens:         input,
+				CacheReadTokens:     cacheRead,
+				CacheCreationTokens: cacheCreate,
+			}
+		}
+
+		return result, rows.Err()
+	})
+}
+
 // TokenEvent represents a single token usage event for batch insertion
 type TokenEvent struct {
-	Timestamp           time.Time
-	Model               string
-	InputTokens         int64
-	OutputTokens        int64
-	CacheReadTokens     int64
-	CacheCreationTokens int64
-	SourceFile          string
-	LineNumber          int64
+	Timestamp           time.Time `json:"timestamp"`
+	Model               string    `json:"model"`
+	InputTokens         int64     `json:"input_tokens"`
+	OutputTokens        int64     `json:"output_tokens"`
+	CacheReadTokens     int64     `json:"cache_read_tokens"`
+	CacheCreationTokens int64     `json:"cache_creation_tokens"`
+	SourceFile          string    `json:"source_file"`
+	LineNumber          int64     `json:"line_number"`
 }
 
 // QueryTokensSince returns aggregated token metrics since a given timestamp
@@ -474,23 +846,262 @@ func (tc *TokenCache) QueryTokensSinceContext(ctx context.Context, since time.Ti
 		}
 		defer rows.Close()
 
-		result.ModelMetrics = make(map[string]*ModelAggregation)
+		result.ModelMetrics = make(map[string]*ModelAggregation)
+		for rows.Next() {
+			var model string
+			var input, output, cacheRead, cacheCreate int64
+			if err := rows.Scan(&model, &input, &output, &cacheRead, &cacheCreate); err != nil {
+				continue
+			}
+			result.ModelTokens[model] = input + output + cacheRead + cacheCreate
+			result.ModelMetrics[model] = &ModelAggregation{
+				InputTokens:         input,
+				OutputTokens:        output,
+				CacheReadTokens:     cacheRead,
+				CacheCreationTokens: cacheCreate,
+			}
+		}
+
+		return result, nil
+	})
+}
+
+// HourOfDayUsage is the token usage and estimated cost that fell within one
+// local hour of the day, summed over every day in the queried range
+type HourOfDayUsage struct {
+	Hour   int
+	Tokens int64
+	Cost   float64
+}
+
+// QueryByHourOfDay buckets token usage since a given timestamp into 24 bins
+// by local hour of day
+func (tc *TokenCache) QueryByHourOfDay(since time.Time) ([24]HourOfDayUsage, error) {
+	return tc.QueryByHourOfDayContext(context.Background(), since)
+}
+
+// QueryByHourOfDayContext buckets token usage by local hour of day with context support
+func (tc *TokenCache) QueryByHourOfDayContext(ctx context.Context, since time.Time) ([24]HourOfDayUsage, error) {
+	return tc.QueryByHourOfDayBetweenContext(ctx, since, time.Time{})
+}
+
+// QueryByHourOfDayBetween buckets token usage from since up to, but not
+// including, until by local hour of day. A zero until means no end.
+func (tc *TokenCache) QueryByHourOfDayBetween(since, until time.Time) ([24]HourOfDayUsage, error) {
+	return tc.QueryByHourOfDayBetweenContext(context.Background(), since, until)
+}
+
+// QueryByHourOfDayBetweenContext buckets token usage in a range by local hour of day with context support
+func (tc *TokenCache) QueryByHourOfDayBetweenContext(ctx context.Context, since, until time.Time) ([24]HourOfDayUsage, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	var empty [24]HourOfDayUsage
+	for h := range empty {
+		empty[h].Hour = h
+	}
+	if tc.db == nil {
+		return empty, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() ([24]HourOfDayUsage, error) {
+		result := empty
+
+		var sinceUnix int64
+		if !since.IsZero() {
+			sinceUnix = since.Unix()
+		}
+
+		// Group into 15-minute slots and convert to local time in Go, so zones
+		// with :30 and :45 offsets and DST changes land in the right hour
+		rows, err := tc.db.QueryContext(ctx, `
+			SELECT
+				timestamp_unix / 900,
+				model,
+				SUM(input_tokens),
+				SUM(output_tokens),
+				SUM(cache_read_tokens),
+				SUM(cache_creation_tokens)
+			FROM token_events
+			WHERE timestamp_unix >= ? AND timestamp_unix < ?
+			GROUP BY 1, 2
+		`, sinceUnix, unboundedUnix(until))
+		if err != nil {
+			return empty, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var slot int64
+			var model string
+			var mm ModelAggregation
+			if err := rows.Scan(&slot, &model, &mm.InputTokens, &mm.OutputTokens, &mm.CacheReadTokens, &mm.CacheCreationTokens); err != nil {
+				continue
+			}
+			hour := time.Unix(slot*900, 0).Local().Hour()
+			result[hour].Tokens += mm.InputTokens + mm.OutputTokens + mm.CacheReadTokens + mm.CacheCreationTokens
+			result[hour].Cost += modelAggregationCost(model, &mm)
+		}
+
+		return result, rows.Err()
+	})
+}
+
+// QueryModelActivity returns when model was first and last used from since
+// up to, but not including, until; a zero until means no end. Compacted files
+// only keep their first and last timestamps, so one that used the model counts
+// from its first event to its last. Both times are zero when the model has no
+// usage in the range.
+func (tc *TokenCache) QueryModelActivity(model string, since, until time.Time, sourcePrefixes ...string) (first, last time.Time, err error) {
+	return tc.QueryModelActivityContext(context.Background(), model, since, until, sourcePrefixes...)
+}
+
+// QueryModelActivityContext returns a model's first and last activity in a range with context support
+func (tc *TokenCache) QueryModelActivityContext(ctx context.Context, model string, since, until time.Time, sourcePrefixes ...string) (first, last time.Time, err error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return time.Time{}, time.Time{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	var sinceUnix int64
+	if !since.IsZero() {
+		sinceUnix = since.Unix()
+	}
+	untilUnix := unboundedUnix(until)
+	sourceFilter, sourceArgs := sourcePrefixFilter(sourcePrefixes)
+	args := append([]any{model, sinceUnix, untilUnix}, sourceArgs...)
+	args = append(args, sinceUnix, untilUnix)
+	args = append(args, sourceArgs...)
+	args = append(args, model)
+
+	span, err := withRetry(ctx, func() ([2]sql.NullInt64, error) {
+		var span [2]sql.NullInt64
+		err := tc.db.QueryRowContext(ctx, `
+			SELECT MIN(lo), MAX(hi) FROM (
+				SELECT MIN(timestamp_unix) AS lo, MAX(timestamp_unix) AS hi
+				FROM token_events
+				WHERE model = ? AND timestamp_unix >= ? AND timestamp_unix < ?`+sourceFilter+`
+				UNION ALL
+				SELECT MIN(earliest_timestamp), MAX(latest_timestamp)
+				FROM file_aggregates
+				WHERE is_complete = 1 AND latest_timestamp >= ? AND earliest_timestamp < ?`+sourceFilter+`
+				  AND EXISTS (SELECT 1 FROM json_each(model_breakdown) WHERE key = ?)
+			)`, args...).Scan(&span[0], &span[1])
+		return span, err
+	})
+	if err != nil || !span[0].Valid {
+		return time.Time{}, time.Time{}, err
+	}
+
+	// A compacted file's span can reach past the range
+	lo, hi := max(span[0].Int64, sinceUnix), min(span[1].Int64, untilUnix-1)
+	return time.Unix(lo, 0), time.Unix(hi, 0), nil
+}
+
+// QueryHourlyCost returns the estimated cost of each hour-long bucket from
+// since, oldest first, skipping models for which skipModel (if set) returns
+// true. Compacted files only keep totals, so their cost is spread evenly over
+// the time between their first and last events.
+func (tc *TokenCache) QueryHourlyCost(since time.Time, hours int, skipModel func(string) bool, sourcePrefixes ...string) ([]float64, error) {
+	return tc.QueryHourlyCostContext(context.Background(), since, hours, skipModel, sourcePrefixes...)
+}
+
+// QueryHourlyCostContext returns the estimated cost per hour with context support
+func (tc *TokenCache) QueryHourlyCostContext(ctx context.Context, since time.Time, hours int, skipModel func(string) bool, sourcePrefixes ...string) ([]float64, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil || hours <= 0 {
+		return make([]float64, max(hours, 0)), nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() ([]float64, error) {
+		costs := make([]float64, hours)
+		sinceUnix := since.Unix()
+		untilUnix := sinceUnix + int64(hours)*3600
+		sourceFilter, sourceArgs := sourcePrefixFilter(sourcePrefixes)
+		args := append([]any{sinceUnix, untilUnix}, sourceArgs...)
+
+		aggRows, err := tc.db.QueryContext(ctx, `
+			SELECT earliest_timestamp, latest_timestamp, model_breakdown FROM file_aggregates
+			WHERE is_complete = 1 AND latest_timestamp >= ? AND earliest_timestamp < ?`+sourceFilter, args...)
+		if err != nil {
+			return nil, err
+		}
+		for aggRows.Next() {
+			var earliest, latest int64
+			var modelJSON string
+			if err := aggRows.Scan(&earliest, &latest, &modelJSON); err != nil {
+				continue
+			}
+			var breakdown map[string]*ModelAggregation
+			if json.Unmarshal([]byte(modelJSON), &breakdown) != nil {
+				continue
+			}
+			var cost float64
+			for model, mm := range breakdown {
+				if skipModel == nil || !skipModel(model) {
+					cost += modelAggregationCost(model, mm)
+				}
+			}
+			if latest <= earliest {
+				costs[(earliest-sinceUnix)/3600] += cost
+				continue
+			}
+			for i := range costs {
+				start := sinceUnix + int64(i)*3600
+				overlap := min(latest, start+3600) - max(earliest, start)
+				if overlap > 0 {
+					costs[i] += cost * float64(overlap) / float64(latest-earliest)
+				}
+			}
+		}
+		aggRows.Close()
+		if err := aggRows.Err(); err != nil {
+			return nil, err
+		}
+
+		rows, err := tc.db.QueryContext(ctx, `
+			SELECT
+				(timestamp_unix - ?) / 3600,
+				model,
+				SUM(input_tokens),
+				SUM(output_tokens),
+				SUM(cache_read_tokens),
+				SUM(cache_creation_tokens)
+			FROM token_events
+			WHERE timestamp_unix >= ? AND timestamp_unix < ?`+sourceFilter+`
+			GROUP BY 1, 2
+		`, append([]any{sinceUnix}, args...)...)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
 		for rows.Next() {
+			var bucket int64
 			var model string
-			var input, output, cacheRead, cacheCreate int64
-			if err := rows.Scan(&model, &input, &output, &cacheRead, &cacheCreate); err != nil {
+			var mm ModelAggregation
+			if err := rows.Scan(&bucket, &model, &mm.InputTokens, &mm.OutputTokens, &mm.CacheReadTokens, &mm.CacheCreationTokens); err != nil {
 				continue
 			}
-			result.ModelTokens[model] = input + output + cacheRead + cacheCreate
-			result.ModelMetrics[model] = &ModelAggregation{
-				InputTokens:         input,
-				OutputTokens:        output,
-				CacheReadTokens:     cacheRead,
-				CacheCreationTokens: cacheCreate,
+			if skipModel == nil || !skipModel(model) {
+				costs[bucket] += modelAggregationCost(model, &mm)
 			}
 		}
 
-		return result, nil
+		return costs, rows.Err()
 	})
 }
 
@@ -700,6 +1311,32 @@ func GetFileCompleteThreshold() time.Duration {
 	return fileCompleteThreshold
 }
 
+// unboundedUnix returns until as a Unix upper bound, or the largest timestamp
+// when until is zero
+func unboundedUnix(until time.Time) int64 {
+	if until.IsZero() {
+		return math.MaxInt64
+	}
+	return until.Unix()
+}
+
+// sourcePrefixFilter returns an "AND ..." condition, with its arguments, that
+// keeps rows whose source_file starts with one of the prefixes. Without
+// prefixes it returns "" and keeps every row. Like QuerySourceUsage, it
+// compares ranges rather than using LIKE so the source_file indexes apply.
+func sourcePrefixFilter(prefixes []string) (string, []any) {
+	if len(prefixes) == 0 {
+		return "", nil
+	}
+	conds := make([]string, 0, len(prefixes))
+	args := make([]any, 0, 2*len(prefixes))
+	for _, prefix := range prefixes {
+		conds = append(conds, "(source_file >= ? AND source_file < ?)")
+		args = append(args, prefix, prefix+"\xff")
+	}
+	return " AND (" + strings.Join(conds, " OR ") + ")", args
+}
+
 // QueryTokensHybrid returns aggregated token metrics using both pre-aggregated
 // complete files and individual events for active files
 func (tc *TokenCache) QueryTokensHybrid(since time.Time) (*AggregatedTokens, error) {
@@ -708,6 +1345,19 @@ func (tc *TokenCache) QueryTokensHybrid(since time.Time) (*AggregatedTokens, err
 
 // QueryTokensHybridContext returns aggregated token metrics with context support
 func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.Time) (*AggregatedTokens, error) {
+	return tc.QueryTokensBetweenContext(ctx, since, time.Time{})
+}
+
+// QueryTokensBetween returns aggregated token metrics for events from since up
+// to, but not including, until; a zero until means no upper bound. Like the
+// lower bound, the upper one counts a complete file's aggregate in full when
+// any of the file overlaps the range.
+func (tc *TokenCache) QueryTokensBetween(since, until time.Time, sourcePrefixes ...string) (*AggregatedTokens, error) {
+	return tc.QueryTokensBetweenContext(context.Background(), since, until, sourcePrefixes...)
+}
+
+// QueryTokensBetweenContext returns aggregated token metrics for a time range with context support
+func (tc *TokenCache) QueryTokensBetweenContext(ctx context.Context, since, until time.Time, sourcePrefixes ...string) (*AggregatedTokens, error) {
 	tc.ingestMu.RLock()
 	defer tc.ingestMu.RUnlock()
 
@@ -728,6 +1378,9 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 		if !since.IsZero() {
 			sinceUnix = since.Unix()
 		}
+		untilUnix := unboundedUnix(until)
+		sourceFilter, sourceArgs := sourcePrefixFilter(sourcePrefixes)
+		args := append([]any{sinceUnix, untilUnix}, sourceArgs...)
 
 		// Query 1: Sum from complete file aggregates (fast path)
 		aggQuery := `
@@ -735,13 +1388,12 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 			       COALESCE(SUM(total_cache_read_tokens), 0), COALESCE(SUM(total_cache_creation_tokens), 0),
 			       COALESCE(SUM(event_count), 0), MIN(earliest_timestamp), MAX(latest_timestamp)
 			FROM file_aggregates
-			WHERE is_complete = 1 AND latest_timestamp >= ?
-		`
+			WHERE is_complete = 1 AND latest_timestamp >= ? AND earliest_timestamp < ?` + sourceFilter
 
 		var aggInput, aggOutput, aggCacheRead, aggCacheCreate, aggCount int64
 		var aggMinTS, aggMaxTS sql.NullInt64
 
-		err := tc.db.QueryRowContext(ctx, aggQuery, sinceUnix).Scan(
+		err := tc.db.QueryRowContext(ctx, aggQuery, args...).Scan(
 			&aggInput, &aggOutput, &aggCacheRead, &aggCacheCreate,
 			&aggCount, &aggMinTS, &aggMaxTS,
 		)
@@ -752,9 +1404,8 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 		// Get model breakdown from complete files
 		aggModelQuery := `
 			SELECT model_breakdown FROM file_aggregates
-			WHERE is_complete = 1 AND latest_timestamp >= ?
-		`
-		aggModelRows, err := tc.db.QueryContext(ctx, aggModelQuery, sinceUnix)
+			WHERE is_complete = 1 AND latest_timestamp >= ? AND earliest_timestamp < ?` + sourceFilter
+		aggModelRows, err := tc.db.QueryContext(ctx, aggModelQuery, args...)
 		if err != nil && err != sql.ErrNoRows {
 			return nil, err
 		}
@@ -794,13 +1445,12 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 			       COALESCE(SUM(cache_read_tokens), 0), COALESCE(SUM(cache_creation_tokens), 0),
 			       MIN(timestamp_unix), MAX(timestamp_unix), COUNT(*)
 			FROM token_events
-			WHERE timestamp_unix >= ?
-		`
+			WHERE timestamp_unix >= ? AND timestamp_unix < ?` + sourceFilter
 
 		var evtInput, evtOutput, evtCacheRead, evtCacheCreate, evtCount int64
 		var evtMinTS, evtMaxTS sql.NullInt64
 
-		err = tc.db.QueryRowContext(ctx, eventQuery, sinceUnix).Scan(
+		err = tc.db.QueryRowContext(ctx, eventQuery, args...).Scan(
 			&evtInput, &evtOutput, &evtCacheRead, &evtCacheCreate,
 			&evtMinTS, &evtMaxTS, &evtCount,
 		)
@@ -812,10 +1462,10 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 		evtModelQuery := `
 			SELECT model, SUM(input_tokens), SUM(output_tokens),
 			       SUM(cache_read_tokens), SUM(cache_creation_tokens)
-			FROM token_events WHERE timestamp_unix >= ?
+			FROM token_events WHERE timestamp_unix >= ? AND timestamp_unix < ?` + sourceFilter + `
 			GROUP BY model
 		`
-		evtModelRows, err := tc.db.QueryContext(ctx, evtModelQuery, sinceUnix)
+		evtModelRows, err := tc.db.QueryContext(ctx, evtModelQuery, args...)
 		if err != nil && err != sql.ErrNoRows {
 			return nil, err
 		}
@@ -880,12 +1530,12 @@ func (tc *TokenCache) QueryTokensHybridContext(ctx context.Context, since time.T
 }
 
 // QueryRecentEvents returns token events from the last N seconds for rate calculation
-func (tc *TokenCache) QueryRecentEvents(seconds int64) ([]TimestampedTokens, error) {
-	return tc.QueryRecentEventsContext(context.Background(), seconds)
+func (tc *TokenCache) QueryRecentEvents(seconds int64, sourcePrefixes ...string) ([]TimestampedTokens, error) {
+	return tc.QueryRecentEventsContext(context.Background(), seconds, sourcePrefixes...)
 }
 
 // QueryRecentEventsContext returns token events with context support
-func (tc *TokenCache) QueryRecentEventsContext(ctx context.Context, seconds int64) ([]TimestampedTokens, error) {
+func (tc *TokenCache) QueryRecentEventsContext(ctx context.Context, seconds int64, sourcePrefixes ...string) ([]TimestampedTokens, error) {
 	tc.ingestMu.RLock()
 	defer tc.ingestMu.RUnlock()
 
@@ -898,15 +1548,16 @@ func (tc *TokenCache) QueryRecentEventsContext(ctx context.Context, seconds int6
 
 	return withRetry(ctx, func() ([]TimestampedTokens, error) {
 		cutoff := time.Now().Unix() - seconds
+		sourceFilter, sourceArgs := sourcePrefixFilter(sourcePrefixes)
 
 		query := `
 			SELECT timestamp_unix, input_tokens + output_tokens + cache_read_tokens + cache_creation_tokens
 			FROM token_events
-			WHERE timestamp_unix >= ?
+			WHERE timestamp_unix >= ?` + sourceFilter + `
 			ORDER BY timestamp_unix ASC
 		`
 
-		rows, err := tc.db.QueryContext(ctx, query, cutoff)
+		rows, err := tc.db.QueryContext(ctx, query, append([]any{cutoff}, sourceArgs...)...)
 		if err != nil {
 			return nil, err
 		}
@@ -935,6 +1586,446 @@ type TimestampedTokens struct {
 	Tokens    int64
 }
 
+// ContextUsage describes the context size of a session's most recent request
+type ContextUsage struct {
+	Model     string
+	Tokens    int64 // input + cache read + cache creation tokens sent with the request
+	Timestamp time.Time
+}
+
+// QueryLatestContext returns the context size of the most recent request in a
+// Claude Code session's JSONL file, <project dir>/<session_id>.jsonl
+func (tc *TokenCache) QueryLatestContext(sourceFile string) (*ContextUsage, error) {
+	return tc.QueryLatestContextContext(context.Background(), sourceFile)
+}
+
+// QueryLatestContextContext returns the latest context size with context support
+func (tc *TokenCache) QueryLatestContextContext(ctx context.Context, sourceFile string) (*ContextUsage, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil || sourceFile == "" {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (*ContextUsage, error) {
+		// The whole path, unlike LIKE, lets idx_source_line go straight to
+		// the file's last line rather than scanning every event
+		query := `
+			SELECT model, input_tokens + cache_read_tokens + cache_creation_tokens, timestamp_unix
+			FROM token_events
+			WHERE source_file = ?
+			ORDER BY line_number DESC
+			LIMIT 1
+		`
+
+		var usage ContextUsage
+		var ts int64
+		err := tc.db.QueryRowContext(ctx, query, sourceFile).
+			Scan(&usage.Model, &usage.Tokens, &ts)
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		usage.Timestamp = time.Unix(ts, 0)
+		return &usage, nil
+	})
+}
+
+// QueryLastModel returns the model of the last cached request in sourceFile
+// that names one, or "" when there is none
+func (tc *TokenCache) QueryLastModel(sourceFile string) (string, error) {
+	return tc.QueryLastModelContext(context.Background(), sourceFile)
+}
+
+// QueryLastModelContext returns the model of a file's last cached request with context support
+func (tc *TokenCache) QueryLastModelContext(ctx context.Context, sourceFile string) (string, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return "", nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (string, error) {
+		var model string
+		err := tc.db.QueryRowContext(ctx, `
+			SELECT model FROM token_events
+			WHERE source_file = ? AND model != ''
+			ORDER BY line_number DESC
+			LIMIT 1
+		`, sourceFile).Scan(&model)
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return model, err
+	})
+}
+
+// QueryNewestEventTime returns the timestamp of the newest token event,
+// compacted or not, or the zero time when there are none
+func (tc *TokenCache) QueryNewestEventTime() (time.Time, error) {
+	return tc.QueryNewestEventTimeContext(context.Background())
+}
+
+// QueryNewestEventTimeContext returns the newest event time with context support
+func (tc *TokenCache) QueryNewestEventTimeContext(ctx context.Context) (time.Time, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return time.Time{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (time.Time, error) {
+		query := `
+			SELECT MAX(ts) FROM (
+				SELECT MAX(timestamp_unix) AS ts FROM token_events
+				UNION ALL
+				SELECT MAX(latest_timestamp) FROM file_aggregates
+			)
+		`
+
+		var ts sql.NullInt64
+		if err := tc.db.QueryRowContext(ctx, query).Scan(&ts); err != nil {
+			return time.Time{}, err
+		}
+		if !ts.Valid || ts.Int64 == 0 {
+			return time.Time{}, nil
+		}
+		return time.Unix(ts.Int64, 0), nil
+	})
+}
+
+// SourceUsage is the token total and estimated cost of a set of source files
+type SourceUsage struct {
+	Tokens int64
+	Cost   float64
+}
+
+// QuerySourceUsage totals token usage since a given timestamp for source files
+// whose path starts with sourcePrefix: a single session's JSONL file, or a
+// project directory with a trailing separator.
+func (tc *TokenCache) QuerySourceUsage(sourcePrefix string, since time.Time) (SourceUsage, error) {
+	return tc.QuerySourceUsageContext(context.Background(), sourcePrefix, since)
+}
+
+// QuerySourceUsageContext totals token usage for source files with context support
+func (tc *TokenCache) QuerySourceUsageContext(ctx context.Context, sourcePrefix string, since time.Time) (SourceUsage, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil || sourcePrefix == "" {
+		return SourceUsage{}, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (SourceUsage, error) {
+		var usage SourceUsage
+
+		var sinceUnix int64
+		if !since.IsZero() {
+			sinceUnix = since.Unix()
+		}
+
+		// A range on source_file uses idx_source_line, unlike LIKE; 0xff never
+		// occurs in UTF-8, so it sorts after every path with the prefix
+		rows, err := tc.db.QueryContext(ctx, `
+			SELECT
+				model,
+				SUM(input_tokens),
+				SUM(output_tokens),
+				SUM(cache_read_tokens),
+				SUM(cache_creation_tokens)
+			FROM token_events
+			WHERE source_file >= ? AND source_file < ? AND timestamp_unix >= ?
+			GROUP BY model
+		`, sourcePrefix, sourcePrefix+"\xff", sinceUnix)
+		if err != nil {
+			return usage, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var model string
+			var mm ModelAggregation
+			if err := rows.Scan(&model, &mm.InputTokens, &mm.OutputTokens, &mm.CacheReadTokens, &mm.CacheCreationTokens); err != nil {
+				continue
+			}
+			usage.Tokens += mm.InputTokens + mm.OutputTokens + mm.CacheReadTokens + mm.CacheCreationTokens
+			usage.Cost += modelAggregationCost(model, &mm)
+		}
+
+		return usage, rows.Err()
+	})
+}
+
+// ProjectUsage is the token total and estimated cost of one Claude Code project
+type ProjectUsage struct {
+	Project string // Directory name under ~/.claude/projects, e.g. "-home-me-app"
+	Path    string // Working directory the name was made from, when known
+	Tokens  int64
+	Cost    float64
+}
+
+// QueryByProject totals token usage from since up to, but not including, until
+// per project directory, costliest first. A zero until means no end. Like
+// QueryTokensBetween, compacted files count in full when they overlap the range.
+func (tc *TokenCache) QueryByProject(since, until time.Time) ([]ProjectUsage, error) {
+	return tc.QueryByProjectContext(context.Background(), since, until)
+}
+
+// QueryByProjectContext totals token usage per project with context support
+func (tc *TokenCache) QueryByProjectContext(ctx context.Context, since, until time.Time) ([]ProjectUsage, error) {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return nil, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() ([]ProjectUsage, error) {
+		var sinceUnix int64
+		if !since.IsZero() {
+			sinceUnix = since.Unix()
+		}
+		untilUnix := unboundedUnix(until)
+
+		byProject := make(map[string]*ProjectUsage)
+		add := func(sourceFile, model string, mm *ModelAggregation) {
+			project := ProjectFromSourceFile(sourceFile)
+			usage, ok := byProject[project]
+			if !ok {
+				usage = &ProjectUsage{Project: project}
+				byProject[project] = usage
+			}
+			usage.Tokens += mm.InputTokens + mm.OutputTokens + mm.CacheReadTokens + mm.CacheCreationTokens
+			usage.Cost += modelAggregationCost(model, mm)
+		}
+
+		// Complete files only keep a per-model breakdown
+		aggRows, err := tc.db.QueryContext(ctx, `
+			SELECT source_file, model_breakdown FROM file_aggregates
+			WHERE is_complete = 1 AND latest_timestamp >= ? AND earliest_timestamp < ?
+		`, sinceUnix, untilUnix)
+		if err != nil {
+			return nil, err
+		}
+		for aggRows.Next() {
+			var sourceFile, modelJSON string
+			if err := aggRows.Scan(&sourceFile, &modelJSON); err != nil {
+				continue
+			}
+			var breakdown map[string]*ModelAggregation
+			if json.Unmarshal([]byte(modelJSON), &breakdown) != nil {
+				continue
+			}
+			for model, mm := range breakdown {
+				add(sourceFile, model, mm)
+			}
+		}
+		aggRows.Close()
+		if err := aggRows.Err(); err != nil {
+			return nil, err
+		}
+
+		rows, err := tc.db.QueryContext(ctx, `
+			SELECT
+				source_file,
+				model,
+				SUM(input_tokens),
+				SUM(output_tokens),
+				SUM(cache_read_tokens),
+				SUM(cache_creation_tokens)
+			FROM token_events
+			WHERE timestamp_unix >= ? AND timestamp_unix < ?
+			GROUP BY source_file, model
+		`, sinceUnix, untilUnix)
+		if err != nil {
+			return nil, err
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var sourceFile, model string
+			var mm ModelAggregation
+			if err := rows.Scan(&sourceFile, &model, &mm.InputTokens, &mm.OutputTokens, &mm.CacheReadTokens, &mm.CacheCreationTokens); err != nil {
+				continue
+			}
+			add(sourceFile, model, &mm)
+		}
+		if err := rows.Err(); err != nil {
+			return nil, err
+		}
+
+		result := make([]ProjectUsage, 0, len(byProject))
+		for _, usage := range byProject {
+			result = append(result, *usage)
+		}
+		sort.Slice(result, func(i, j int) bool {
+			if result[i].Cost != result[j].Cost {
+				return result[i].Cost > result[j].Cost
+			}
+			return result[i].Project < result[j].Project
+		})
+		return result, nil
+	})
+}
+
+// ExportJSONL writes the token events since a given timestamp to w, oldest
+// first, as one JSON-encoded TokenEvent per line. Timestamps are RFC3339Nano,
+// exactly as stored, so importing the output recreates the same events.
+func (tc *TokenCache) ExportJSONL(w io.Writer, since time.Time) error {
+	return tc.ExportJSONLContext(context.Background(), w, since)
+}
+
+// ExportJSONLContext writes token events as JSONL with context support. Unlike
+// queries it has no operation timeout, since it streams the whole history.
+func (tc *TokenCache) ExportJSONLContext(ctx context.Context, w io.Writer, since time.Time) error {
+	return tc.ExportJSONLWithContext(ctx, w, since, time.Time{}, nil)
+}
+
+// ExportJSONLWith is ExportJSONL for events before until (zero for no end),
+// each passed through rewrite before it's written, e.g. to redact source
+// paths. The cache itself is unchanged.
+func (tc *TokenCache) ExportJSONLWith(w io.Writer, since, until time.Time, rewrite func(*TokenEvent)) error {
+	return tc.ExportJSONLWithContext(context.Background(), w, since, until, rewrite)
+}
+
+// ExportJSONLWithContext writes rewritten token events as JSONL with context
+// support. A nil rewrite writes events as stored.
+func (tc *TokenCache) ExportJSONLWithContext(ctx context.Context, w io.Writer, since, until time.Time, rewrite func(*TokenEvent)) error {
+	tc.ingestMu.RLock()
+	defer tc.ingestMu.RUnlock()
+
+	if tc.db == nil {
+		return nil
+	}
+
+	var sinceUnix int64
+	if !since.IsZero() {
+		sinceUnix = since.Unix()
+	}
+
+	rows, err := tc.db.QueryContext(ctx, `
+		SELECT timestamp, timestamp_unix, model, input_tokens, output_tokens,
+			cache_read_tokens, cache_creation_tokens, source_file, line_number
+		FROM token_events
+		WHERE timestamp_unix >= ? AND timestamp_unix < ?
+		ORDER BY timestamp_unix, id
+	`, sinceUnix, unboundedUnix(until))
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	bw := bufio.NewWriter(w)
+	enc := json.NewEncoder(bw)
+	for rows.Next() {
+		var e TokenEvent
+		var timestamp string
+		var timestampUnix int64
+		if err := rows.Scan(&timestamp, &timestampUnix, &e.Model, &e.InputTokens, &e.OutputTokens,
+			&e.CacheReadTokens, &e.CacheCreationTokens, &e.SourceFile, &e.LineNumber); err != nil {
+			return err
+		}
+		if e.Timestamp, err = time.Parse(time.RFC3339Nano, timestamp); err != nil {
+			e.Timestamp = time.Unix(timestampUnix, 0).UTC()
+		}
+		if rewrite != nil {
+			rewrite(&e)
+		}
+		if err := enc.Encode(e); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	return bw.Flush()
+}
+
+// importBatchSize is the number of events ImportJSONL inserts per transaction
+const importBatchSize = 1000
+
+// ImportJSONL inserts the token events read from r, in the format written by
+// ExportJSONL. Events already in the cache are ignored, apart from a larger
+// cache write count, so importing the same file twice is harmless. Events from logs this cache has compacted are
+// already counted in the file's totals and are ignored too. Lines that aren't
+// a valid TokenEvent are skipped and counted. imported is the number of valid
+// events read, including ignored duplicates.
+func (tc *TokenCache) ImportJSONL(r io.Reader) (imported, skipped int, err error) {
+	compacted := make(map[string]bool)
+	batch := make([]TokenEvent, 0, importBatchSize)
+	flush := func() error {
+		if err := tc.InsertTokenEventBatch(batch); err != nil {
+			return err
+		}
+		batch = batch[:0]
+		return nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	buf := make([]byte, 0, 64*1024)
+	scanner.Buffer(buf, 10*1024*1024)
+	for scanner.Scan() {
+		line := bytes.TrimSpace(scanner.Bytes())
+		if len(line) == 0 {
+			continue
+		}
+
+		var e TokenEvent
+		if err := json.Unmarshal(line, &e); err != nil || !validImportEvent(e) {
+			skipped++
+			continue
+		}
+		imported++
+
+		isCompacted, seen := compacted[e.SourceFile]
+		if !seen {
+			isCompacted = tc.IsFileComplete(e.SourceFile)
+			compacted[e.SourceFile] = isCompacted
+		}
+		if isCompacted {
+			continue
+		}
+
+		batch = append(batch, e)
+		if len(batch) == importBatchSize {
+			if err := flush(); err != nil {
+				return imported, skipped, err
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return imported, skipped, err
+	}
+	return imported, skipped, flush()
+}
+
+// validImportEvent reports whether an imported event has what the cache needs
+// to store and deduplicate it
+func validImportEvent(e TokenEvent) bool {
+	return !e.Timestamp.IsZero() && e.Model != "" && e.SourceFile != "" &&
+		e.InputTokens >= 0 && e.OutputTokens >= 0 && e.CacheReadTokens >= 0 && e.CacheCreationTokens >= 0
+}
+
 // GetFileState returns the last processed line and modification time for a file
 func (tc *TokenCache) GetFileState(sourceFile string) (lastLine int64, lastModified time.Time, exists bool) {
 	return tc.GetFileStateContext(context.Background(), sourceFile)
@@ -1037,6 +2128,11 @@ func (tc *TokenCache) InvalidateFileContext(ctx context.Context, sourceFile stri
 			return err
 		}
 
+		_, err = tx.ExecContext(ctx, "DELETE FROM user_token_events WHERE source_file = ?", sourceFile)
+		if err != nil {
+			return err
+		}
+
 		_, err = tx.ExecContext(ctx, "DELETE FROM file_state WHERE source_file = ?", sourceFile)
 		if err != nil {
 			return err
@@ -1046,6 +2142,37 @@ func (tc *TokenCache) InvalidateFileContext(ctx context.Context, sourceFile stri
 	})
 }
 
+// ResetFileState forgets how far each JSONL file has been read, so the next
+// ingestion reads every file from its first line. Cached events are kept, and
+// the unique (source_file, line_number) index skips the lines already stored.
+// Aggregates of compacted files are left alone. Returns the number of files
+// whose state was dropped.
+func (tc *TokenCache) ResetFileState() (int, error) {
+	return tc.ResetFileStateContext(context.Background())
+}
+
+// ResetFileStateContext forgets file read positions with context support
+func (tc *TokenCache) ResetFileStateContext(ctx context.Context) (int, error) {
+	tc.ingestMu.Lock()
+	defer tc.ingestMu.Unlock()
+
+	if tc.db == nil {
+		return 0, nil
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, dbOperationTimeout)
+	defer cancel()
+
+	return withRetry(ctx, func() (int, error) {
+		res, err := tc.db.ExecContext(ctx, "DELETE FROM file_state")
+		if err != nil {
+			return 0, err
+		}
+		files, _ := res.RowsAffected()
+		return int(files), nil
+	})
+}
+
 // Clear removes all cached data
 func (tc *TokenCache) Clear() error {
 	return tc.ClearContext(context.Background())
@@ -1075,11 +2202,23 @@ func (tc *TokenCache) ClearContext(ctx context.Context) error {
 			return err
 		}
 
+		_, err = tx.ExecContext(ctx, "DELETE FROM user_token_events")
+		if err != nil {
+			return err
+		}
+
 		_, err = tx.ExecContext(ctx, "DELETE FROM file_state")
 		if err != nil {
 			return err
 		}
 
+		// Aggregates stand in for deleted events of complete files, so they
+		// must go too or a re-ingest would count those files twice
+		_, err = tx.ExecContext(ctx, "DELETE FROM file_aggregates")
+		if err != nil {
+			return err
+		}
+
 		return tx.Commit()
 	})
 }
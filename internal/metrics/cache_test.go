@@ -0,0 +1,719 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// createV1Database writes a database using the original version 1 layout,
+// before the leader election and file aggregate tables existed
+func createV1Database(t *testing.T, dir string) {
+	t.Helper()
+
+	db, err := sql.Open("sqlite", filepath.Join(dir, cacheDBName))
+	if err != nil {
+		t.Fatalf("Failed to open v1 database: %v", err)
+	}
+	defer db.Close()
+
+	schema := `
+	CREATE TABLE schema_version (version INTEGER PRIMARY KEY);
+	INSERT INTO schema_version (version) VALUES (1);
+
+	CREATE TABLE token_events (
+		id INTEGER PRIMARY KEY AUTOINCREMENT,
+		timestamp TEXT NOT NULL,
+		timestamp_unix INTEGER NOT NULL,
+		model TEXT NOT NULL,
+		input_tokens INTEGER DEFAULT 0,
+		output_tokens INTEGER DEFAULT 0,
+		cache_read_tokens INTEGER DEFAULT 0,
+		cache_creation_tokens INTEGER DEFAULT 0,
+		source_file TEXT NOT NULL,
+		line_number INTEGER NOT NULL
+	);
+	CREATE UNIQUE INDEX idx_source_line ON token_events(source_file, line_number);
+
+	CREATE TABLE file_state (
+		source_file TEXT PRIMARY KEY,
+		last_line INTEGER DEFAULT 0,
+		last_modified INTEGER DEFAULT 0
+	);
+	`
+	if _, err := db.Exec(schema); err != nil {
+		t.Fatalf("Failed to create v1 schema: %v", err)
+	}
+
+	now := time.Now()
+	for i := 1; i <= 3; i++ {
+		ts := now.Add(-time.Duration(i) * time.Minute)
+		_, err := db.Exec(`INSERT INTO token_events
+			(timestamp, timestamp_unix, model, input_tokens, output_tokens, cache_read_tokens, cache_creation_tokens, source_file, line_number)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+			ts.Format(time.RFC3339), ts.Unix(), "claude-sonnet-4", 100, 50, 10, 5, "/tmp/session.jsonl", i)
+		if err != nil {
+			t.Fatalf("Failed to insert v1 row: %v", err)
+		}
+	}
+	if _, err := db.Exec("INSERT INTO file_state (source_file, last_line, last_modified) VALUES (?, ?, ?)",
+		"/tmp/session.jsonl", 3, now.Unix()); err != nil {
+		t.Fatalf("Failed to insert v1 file state: %v", err)
+	}
+}
+
+func TestTokenCacheMigratesV1Database(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	createV1Database(t, tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	if tc.GetDB() == nil {
+		t.Fatal("Expected database to open after migration")
+	}
+
+	version, err := tc.SchemaVersion()
+	if err != nil {
+		t.Fatalf("Failed to read schema version: %v", err)
+	}
+	if version != schemaVersion {
+		t.Errorf("Expected schema version %d, got %d", schemaVersion, version)
+	}
+
+	// Rows written by the v1 schema must survive the upgrade
+	agg, err := tc.QueryTokensSince(time.Now().Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query migrated events: %v", err)
+	}
+	if agg.InputTokens != 300 || agg.OutputTokens != 150 {
+		t.Errorf("Expected 300 input / 150 output tokens after migration, got %d / %d", agg.InputTokens, agg.OutputTokens)
+	}
+
+	// The v5 migration drops file state so every log is read again
+	if _, _, exists := tc.GetFileState("/tmp/session.jsonl"); exists {
+		t.Error("Expected file state to be dropped for a re-read")
+	}
+	if !tc.takeRereadLogs() {
+		t.Error("Expected the migration to ask for every log to be read again")
+	}
+
+	// Tables introduced by later migrations must now be usable
+	if err := tc.SetCachedMetrics("system", []byte(`{}`)); err != nil {
+		t.Errorf("Expected metrics_cache table after migration: %v", err)
+	}
+	if !tc.TryAcquireLease("test-instance") {
+		t.Error("Expected to acquire collector lease after migration")
+	}
+	if err := tc.MarkFileComplete("/tmp/session.jsonl"); err != nil {
+		t.Errorf("Expected file_aggregates table after migration: %v", err)
+	}
+}
+
+func TestTokenCacheMigrationIsIdempotent(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Fresh database goes straight to the current version
+	tc := NewTokenCacheWithDir(tmpDir)
+	if version, err := tc.SchemaVersion(); err != nil || version != schemaVersion {
+		t.Fatalf("Expected fresh database at version %d, got %d (err=%v)", schemaVersion, version, err)
+	}
+	tc.Close()
+
+	// Reopening must not re-run migrations or fail
+	tc = NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+	if version, err := tc.SchemaVersion(); err != nil || version != schemaVersion {
+		t.Errorf("Expected reopened database at version %d, got %d (err=%v)", schemaVersion, version, err)
+	}
+}
+
+func TestMigrationsAreOrdered(t *testing.T) {
+	prev := 1
+	for _, m := range migrations {
+		if m.version != prev+1 {
+			t.Errorf("Migration %q has version %d, expected %d", m.description, m.version, prev+1)
+		}
+		prev = m.version
+	}
+	if prev != schemaVersion {
+		t.Errorf("Last migration is v%d but schemaVersion is %d", prev, schemaVersion)
+	}
+}
+
+func TestTokenCacheDiagnostics(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	if mode, err := tc.JournalMode(); err != nil || mode != "wal" {
+		t.Errorf("Expected journal mode wal, got %q (err=%v)", mode, err)
+	}
+	if err := tc.CheckWritable(); err != nil {
+		t.Errorf("Expected writable cache, got %v", err)
+	}
+	if err := tc.Ping(context.Background()); err != nil {
+		t.Errorf("Expected the cache to answer a query, got %v", err)
+	}
+	// The probe must not change anything
+	if version, err := tc.SchemaVersion(); err != nil || version != schemaVersion {
+		t.Errorf("Expected schema version %d after write probe, got %d (err=%v)", schemaVersion, version, err)
+	}
+}
+
+func TestTokenCacheRebuildsCorruptDB(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	dbPath := filepath.Join(tmpDir, cacheDBName)
+	garbage := []byte(strings.Repeat("this is not a SQLite database\n", 200))
+	if err := os.WriteFile(dbPath, garbage, 0644); err != nil {
+		t.Fatalf("Failed to write corrupt DB: %v", err)
+	}
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	if !tc.RebuiltAfterCorruption() {
+		t.Fatal("Expected the corrupt DB to be detected and rebuilt")
+	}
+	if tc.GetDB() == nil {
+		t.Fatal("Expected a usable DB after rebuild")
+	}
+
+	backup, err := os.ReadFile(tc.CorruptBackupPath())
+	if err != nil {
+		t.Fatalf("Expected corrupt DB backup at %s: %v", tc.CorruptBackupPath(), err)
+	}
+	if string(backup) != string(garbage) {
+		t.Error("Backup doesn't match the corrupt file")
+	}
+
+	events := []TokenEvent{
+		{Timestamp: time.Now(), Model: "claude-sonnet-4", InputTokens: 100, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert into rebuilt DB: %v", err)
+	}
+	if version, err := tc.SchemaVersion(); err != nil || version != schemaVersion {
+		t.Errorf("Expected schema version %d after rebuild, got %d (err=%v)", schemaVersion, version, err)
+	}
+	tc.Close()
+
+	// Reopening the rebuilt DB is a normal open
+	reopened := NewTokenCacheWithDir(tmpDir)
+	defer reopened.Close()
+	if reopened.RebuiltAfterCorruption() {
+		t.Error("Healthy DB was flagged as rebuilt")
+	}
+}
+
+func TestQuerySourceUsage(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-2 * time.Hour), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/p/-home-me-app/s1.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-sonnet-4", OutputTokens: 100, SourceFile: "/p/-home-me-app/s1.jsonl", LineNumber: 2},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-sonnet-4", InputTokens: 50, SourceFile: "/p/-home-me-app/s2.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-sonnet-4", InputTokens: 7, SourceFile: "/p/-home-me-app-other/s3.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		prefix     string
+		since      time.Time
+		wantTokens int64
+	}{
+		{"single session file", "/p/-home-me-app/s1.jsonl", time.Time{}, 1_000_100},
+		{"project directory", "/p/-home-me-app/", time.Time{}, 1_000_150},
+		{"project since", "/p/-home-me-app/", now.Add(-time.Hour), 150},
+		{"unknown file", "/p/-home-me-app/nope.jsonl", time.Time{}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			usage, err := tc.QuerySourceUsage(tt.prefix, tt.since)
+			if err != nil {
+				t.Fatalf("QuerySourceUsage failed: %v", err)
+			}
+			if usage.Tokens != tt.wantTokens {
+				t.Errorf("Expected %d tokens, got %d", tt.wantTokens, usage.Tokens)
+			}
+			if (usage.Cost > 0) != (tt.wantTokens > 0) {
+				t.Errorf("Expected a cost only when there are tokens, got $%f for %d tokens", usage.Cost, usage.Tokens)
+			}
+		})
+	}
+}
+
+func TestQueryLatestContext(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-2 * time.Minute), Model: "claude-sonnet-4", InputTokens: 10, CacheReadTokens: 1000, SourceFile: "/p/-home-me-app/s1.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-opus-4", InputTokens: 20, CacheReadTokens: 2000, CacheCreationTokens: 5, SourceFile: "/p/-home-me-app/s1.jsonl", LineNumber: 2},
+		{Timestamp: now, Model: "claude-sonnet-4", InputTokens: 99, SourceFile: "/p/-home-me-other/s1.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+
+	usage, err := tc.QueryLatestContext("/p/-home-me-app/s1.jsonl")
+	if err != nil {
+		t.Fatalf("QueryLatestContext failed: %v", err)
+	}
+	if usage == nil || usage.Model != "claude-opus-4" || usage.Tokens != 2025 {
+		t.Errorf("Expected the last request's 2025 tokens on claude-opus-4, got %+v", usage)
+	}
+	if usage, err := tc.QueryLatestContext("/p/-home-me-app/nope.jsonl"); err != nil || usage != nil {
+		t.Errorf("Expected nothing for an unknown file, got %+v, %v", usage, err)
+	}
+
+	// The lookup goes through the source_file index rather than a scan
+	rows, err := tc.db.Query("EXPLAIN QUERY PLAN SELECT model FROM token_events WHERE source_file = ? ORDER BY line_number DESC LIMIT 1", "x")
+	if err != nil {
+		t.Fatalf("EXPLAIN failed: %v", err)
+	}
+	defer rows.Close()
+	var plan strings.Builder
+	for rows.Next() {
+		var id, parent, notUsed int
+		var detail string
+		if err := rows.Scan(&id, &parent, &notUsed, &detail); err != nil {
+			t.Fatalf("Failed to read the plan: %v", err)
+		}
+		plan.WriteString(detail + "\n")
+	}
+	if !strings.Contains(plan.String(), "idx_source_line") {
+		t.Errorf("Expected the query to use idx_source_line, got:\n%s", plan.String())
+	}
+}
+
+func TestQueryByHourOfDay(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	// Two days at 9:xx and one at 14:xx local time
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	events := []TokenEvent{
+		{Timestamp: day.Add(9*time.Hour + 5*time.Minute), Model: "claude-sonnet-4", InputTokens: 100, OutputTokens: 50, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: day.AddDate(0, 0, 1).Add(9*time.Hour + 55*time.Minute), Model: "claude-opus-4", InputTokens: 200, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+		{Timestamp: day.Add(14*time.Hour + 30*time.Minute), Model: "claude-sonnet-4", OutputTokens: 10, CacheReadTokens: 1000, SourceFile: "/tmp/a.jsonl", LineNumber: 3},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+
+	hours, err := tc.QueryByHourOfDay(day)
+	if err != nil {
+		t.Fatalf("QueryByHourOfDay failed: %v", err)
+	}
+
+	for h, bucket := range hours {
+		if bucket.Hour != h {
+			t.Errorf("Bucket %d has Hour=%d", h, bucket.Hour)
+		}
+		var want int64
+		switch h {
+		case 9:
+			want = 350
+		case 14:
+			want = 1010
+		}
+		if bucket.Tokens != want {
+			t.Errorf("Hour %d: expected %d tokens, got %d", h, want, bucket.Tokens)
+		}
+		if (want > 0) != (bucket.Cost > 0) {
+			t.Errorf("Hour %d: expected cost only for hours with tokens, got %f", h, bucket.Cost)
+		}
+	}
+
+	// Events before since are excluded
+	hours, err = tc.QueryByHourOfDay(day.AddDate(0, 0, 1))
+	if err != nil {
+		t.Fatalf("QueryByHourOfDay failed: %v", err)
+	}
+	if hours[9].Tokens != 200 || hours[14].Tokens != 0 {
+		t.Errorf("Expected only the second day's 200 tokens at 9:00, got 9:00=%d 14:00=%d", hours[9].Tokens, hours[14].Tokens)
+	}
+}
+
+func TestQueryHourlyCost(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	since := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	events := []TokenEvent{
+		// A compacted file spanning hours 1 and 2, half in each
+		{Timestamp: since.Add(90 * time.Minute), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/old.jsonl", LineNumber: 1},
+		{Timestamp: since.Add(150 * time.Minute), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/old.jsonl", LineNumber: 2},
+		// An active file in hour 3, and an excluded model that isn't counted
+		{Timestamp: since.Add(3*time.Hour + time.Minute), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/new.jsonl", LineNumber: 1},
+		{Timestamp: since.Add(3*time.Hour + 2*time.Minute), Model: "claude-opus-4", InputTokens: 1_000_000, SourceFile: "/tmp/new.jsonl", LineNumber: 2},
+		// Past the last hour
+		{Timestamp: since.Add(4 * time.Hour), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/new.jsonl", LineNumber: 3},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	if err := tc.MarkFileComplete("/tmp/old.jsonl"); err != nil {
+		t.Fatalf("MarkFileComplete failed: %v", err)
+	}
+
+	skipOpus := func(model string) bool { return strings.HasPrefix(model, "claude-opus") }
+	costs, err := tc.QueryHourlyCost(since, 4, skipOpus)
+	if err != nil {
+		t.Fatalf("QueryHourlyCost failed: %v", err)
+	}
+
+	million := getPricingForModel("claude-sonnet-4").InputPerMillion
+	want := []float64{0, million, million, million}
+	if len(costs) != len(want) {
+		t.Fatalf("Expected %d hours, got %d", len(want), len(costs))
+	}
+	for i := range want {
+		if math.Abs(costs[i]-want[i]) > 1e-9 {
+			t.Errorf("Hour %d: expected $%.2f, got $%.2f", i, want[i], costs[i])
+		}
+	}
+}
+
+func TestQueryModelActivity(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	since := time.Date(2025, 3, 10, 0, 0, 0, 0, time.UTC)
+	events := []TokenEvent{
+		// A compacted file from before the range into it
+		{Timestamp: since.Add(-time.Hour), Model: "claude-opus-4", InputTokens: 10, SourceFile: "/p/a/old.jsonl", LineNumber: 1},
+		{Timestamp: since.Add(time.Hour), Model: "claude-sonnet-4", InputTokens: 10, SourceFile: "/p/a/old.jsonl", LineNumber: 2},
+		// An active file
+		{Timestamp: since.Add(2 * time.Hour), Model: "claude-sonnet-4", InputTokens: 10, SourceFile: "/p/b/new.jsonl", LineNumber: 1},
+		{Timestamp: since.Add(3 * time.Hour), Model: "claude-opus-4", InputTokens: 10, SourceFile: "/p/b/new.jsonl", LineNumber: 2},
+		{Timestamp: since.Add(5 * time.Hour), Model: "claude-opus-4", InputTokens: 10, SourceFile: "/p/b/new.jsonl", LineNumber: 3},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	if err := tc.MarkFileComplete("/p/a/old.jsonl"); err != nil {
+		t.Fatalf("MarkFileComplete failed: %v", err)
+	}
+
+	tests := []struct {
+		name        string
+		model       string
+		until       time.Time
+		sources     []string
+		first, last time.Duration // From since; both 0 for no activity
+	}{
+		{"compacted file clamped to the range", "claude-opus-4", time.Time{}, nil, 0, 5 * time.Hour},
+		{"range end", "claude-opus-4", since.Add(4 * time.Hour), nil, 0, 3 * time.Hour},
+		{"project scope", "claude-opus-4", time.Time{}, []string{"/p/b/"}, 3 * time.Hour, 5 * time.Hour},
+		{"scoped to the active file", "claude-sonnet-4", time.Time{}, []string{"/p/b/"}, 2 * time.Hour, 2 * time.Hour},
+		{"unused model", "claude-haiku-4", time.Time{}, nil, 0, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			first, last, err := tc.QueryModelActivity(tt.model, since, tt.until, tt.sources...)
+			if err != nil {
+				t.Fatalf("QueryModelActivity failed: %v", err)
+			}
+			if tt.first == 0 && tt.last == 0 {
+				if !first.IsZero() || !last.IsZero() {
+					t.Errorf("Expected no activity, got %v to %v", first, last)
+				}
+				return
+			}
+			if !first.Equal(since.Add(tt.first)) || !last.Equal(since.Add(tt.last)) {
+				t.Errorf("Expected %v to %v, got %v to %v", since.Add(tt.first), since.Add(tt.last), first, last)
+			}
+		})
+	}
+}
+
+func TestQueryTokensBetween(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	// One event late the day before, one during it, and one exactly at midnight after it
+	day := time.Date(2025, 3, 10, 0, 0, 0, 0, time.Local)
+	events := []TokenEvent{
+		{Timestamp: day.Add(-time.Minute), Model: "claude-sonnet-4", InputTokens: 1, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: day.Add(13 * time.Hour), Model: "claude-sonnet-4", InputTokens: 20, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+		{Timestamp: day.AddDate(0, 0, 1), Model: "claude-sonnet-4", InputTokens: 300, SourceFile: "/tmp/a.jsonl", LineNumber: 3},
+		// A compacted file that ended before the range
+		{Timestamp: day.AddDate(0, 0, -2), Model: "claude-opus-4", InputTokens: 4000, SourceFile: "/tmp/old.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	if err := tc.MarkFileComplete("/tmp/old.jsonl"); err != nil {
+		t.Fatalf("MarkFileComplete failed: %v", err)
+	}
+
+	tests := []struct {
+		name       string
+		since      time.Time
+		until      time.Time
+		wantTokens int64
+	}{
+		{"single day", day, day.AddDate(0, 0, 1), 20},
+		{"no end", day, time.Time{}, 320},
+		{"ends before the compacted file", time.Time{}, day.AddDate(0, 0, -3), 0},
+		{"all time", time.Time{}, time.Time{}, 4321},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			agg, err := tc.QueryTokensBetween(tt.since, tt.until)
+			if err != nil {
+				t.Fatalf("QueryTokensBetween failed: %v", err)
+			}
+			if agg.InputTokens != tt.wantTokens {
+				t.Errorf("Expected %d input tokens, got %d", tt.wantTokens, agg.InputTokens)
+			}
+		})
+	}
+}
+
+func TestQueryByProject(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-time.Hour), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/p/projects/-home-me-app/s1.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-sonnet-4", OutputTokens: 100, SourceFile: "/p/projects/-home-me-app/s1/subagents/agent-1.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Minute), Model: "claude-opus-4", InputTokens: 2_000_000, SourceFile: "/p/projects/-home-me-api/s2.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-48 * time.Hour), Model: "claude-sonnet-4", InputTokens: 7, SourceFile: "/p/projects/-home-me-old/s3.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	// Compacted files still count toward their project
+	if err := tc.MarkFileComplete("/p/projects/-home-me-api/s2.jsonl"); err != nil {
+		t.Fatalf("MarkFileComplete failed: %v", err)
+	}
+
+	projects, err := tc.QueryByProject(now.Add(-24*time.Hour), time.Time{})
+	if err != nil {
+		t.Fatalf("QueryByProject failed: %v", err)
+	}
+	if len(projects) != 2 {
+		t.Fatalf("Expected 2 projects in the last day, got %+v", projects)
+	}
+	if projects[0].Project != "-home-me-api" || projects[0].Tokens != 2_000_000 {
+		t.Errorf("Expected the opus project first with 2M tokens, got %+v", projects[0])
+	}
+	if projects[1].Project != "-home-me-app" || projects[1].Tokens != 1_000_100 {
+		t.Errorf("Expected subagent usage folded into its project, got %+v", projects[1])
+	}
+	if projects[0].Cost <= projects[1].Cost {
+		t.Errorf("Expected projects sorted by cost, got $%f then $%f", projects[0].Cost, projects[1].Cost)
+	}
+}
+
+func TestExportJSONL(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	base := time.Date(2026, 3, 2, 9, 30, 0, 123456789, time.FixedZone("CET", 3600))
+	events := []TokenEvent{
+		{Timestamp: base.Add(time.Hour), Model: "claude-opus-4-5-20251101", InputTokens: 10, OutputTokens: 20, CacheReadTokens: 30, CacheCreationTokens: 40, SourceFile: "/p/a.jsonl", LineNumber: 2},
+		{Timestamp: base, Model: "claude-sonnet-4", InputTokens: 1, SourceFile: "/p/a.jsonl", LineNumber: 1},
+		{Timestamp: base.Add(-48 * time.Hour), Model: "claude-sonnet-4", InputTokens: 5, SourceFile: "/p/old.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+
+	var buf strings.Builder
+	if err := tc.ExportJSONL(&buf, base.Add(-time.Hour)); err != nil {
+		t.Fatalf("ExportJSONL failed: %v", err)
+	}
+
+	lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n")
+	if len(lines) != 2 {
+		t.Fatalf("Expected 2 events since the cutoff, got %d:\n%s", len(lines), buf.String())
+	}
+	// Oldest first, every field intact and the timestamp lossless
+	for i, want := range []TokenEvent{events[1], events[0]} {
+		var got TokenEvent
+		if err := json.Unmarshal([]byte(lines[i]), &got); err != nil {
+			t.Fatalf("Line %d isn't a TokenEvent: %v", i+1, err)
+		}
+		if !got.Timestamp.Equal(want.Timestamp) {
+			t.Errorf("Line %d: timestamp %v, want %v", i+1, got.Timestamp, want.Timestamp)
+		}
+		got.Timestamp = want.Timestamp
+		if got != want {
+			t.Errorf("Line %d: got %+v, want %+v", i+1, got, want)
+		}
+	}
+	if !strings.Contains(lines[0], `"timestamp":"2026-03-02T09:30:00.123456789+01:00"`) {
+		t.Errorf("Expected an RFC3339Nano timestamp: %s", lines[0])
+	}
+
+	// The end is exclusive, so an event at until is left out
+	buf.Reset()
+	if err := tc.ExportJSONLWith(&buf, time.Time{}, base, nil); err != nil {
+		t.Fatalf("ExportJSONLWith failed: %v", err)
+	}
+	if lines := strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n"); len(lines) != 1 || !strings.Contains(lines[0], "/p/old.jsonl") {
+		t.Errorf("Expected only the event before until, got:\n%s", buf.String())
+	}
+}
+
+func TestImportJSONLRoundTrip(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-3 * time.Hour), Model: "claude-opus-4-5-20251101", InputTokens: 10, OutputTokens: 20, CacheReadTokens: 30, CacheCreationTokens: 40, SourceFile: "/p/a.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-2 * time.Hour), Model: "claude-sonnet-4", InputTokens: 1000, OutputTokens: 50, SourceFile: "/p/a.jsonl", LineNumber: 2},
+		{Timestamp: now.Add(-time.Hour), Model: "claude-haiku-4-5-20250929", InputTokens: 7, SourceFile: "/p/b.jsonl", LineNumber: 1},
+	}
+	if err := tc.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	since := now.Add(-24 * time.Hour)
+	before, err := tc.QueryTokensHybrid(since)
+	if err != nil {
+		t.Fatalf("Query before export failed: %v", err)
+	}
+
+	var exported strings.Builder
+	if err := tc.ExportJSONL(&exported, time.Time{}); err != nil {
+		t.Fatalf("ExportJSONL failed: %v", err)
+	}
+	if err := tc.Clear(); err != nil {
+		t.Fatalf("Clear failed: %v", err)
+	}
+
+	// Malformed lines are skipped and counted; valid ones still import
+	input := exported.String() + "not json\n" + `{"model":"claude-sonnet-4","input_tokens":5}` + "\n\n"
+	imported, skipped, err := tc.ImportJSONL(strings.NewReader(input))
+	if err != nil {
+		t.Fatalf("ImportJSONL failed: %v", err)
+	}
+	if imported != 3 || skipped != 2 {
+		t.Errorf("Expected 3 imported and 2 skipped, got %d and %d", imported, skipped)
+	}
+
+	after, err := tc.QueryTokensHybrid(since)
+	if err != nil {
+		t.Fatalf("Query after import failed: %v", err)
+	}
+	if after.InputTokens != before.InputTokens || after.OutputTokens != before.OutputTokens ||
+		after.CacheReadTokens != before.CacheReadTokens || after.CacheCreationTokens != before.CacheCreationTokens ||
+		after.EventCount != before.EventCount {
+		t.Errorf("Round trip changed totals: before %+v, after %+v", before, after)
+	}
+	for model, tokens := range before.ModelTokens {
+		if after.ModelTokens[model] != tokens {
+			t.Errorf("%s: expected %d tokens after round trip, got %d", model, tokens, after.ModelTokens[model])
+		}
+	}
+
+	// Importing again is a no-op
+	if _, _, err := tc.ImportJSONL(strings.NewReader(exported.String())); err != nil {
+		t.Fatalf("Second import failed: %v", err)
+	}
+	if again, _ := tc.QueryTokensHybrid(since); again.EventCount != before.EventCount {
+		t.Errorf("Re-import duplicated events: %d, want %d", again.EventCount, before.EventCount)
+	}
+
+	// Once a log is compacted its totals already include the imported events
+	if err := tc.MarkFileComplete("/p/b.jsonl"); err != nil {
+		t.Fatalf("MarkFileComplete failed: %v", err)
+	}
+	if _, _, err := tc.ImportJSONL(strings.NewReader(exported.String())); err != nil {
+		t.Fatalf("Import after compaction failed: %v", err)
+	}
+	if again, _ := tc.QueryTokensHybrid(since); again.InputTokens != before.InputTokens {
+		t.Errorf("Import double counted a compacted log: %d input tokens, want %d", again.InputTokens, before.InputTokens)
+	}
+}
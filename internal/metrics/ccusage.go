@@ -0,0 +1,197 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// Token source names accepted by --token-source
+	TokenSourceJSONL   = "jsonl"
+	TokenSourceCCUsage = "ccusage"
+
+	// ccusage re-reads every JSONL file on each run, so it is far too slow for
+	// the 2s refresh loop. Results are refreshed in the background at this interval.
+	ccusageRefreshInterval = 30 * time.Second
+	ccusageCommandTimeout  = 60 * time.Second
+)
+
+// CCUsageResponse is the output of `ccusage daily --json`
+type CCUsageResponse struct {
+	Daily  []CCUsageDaily `json:"daily"`
+	Totals CCUsageTotals  `json:"totals"`
+}
+
+// CCUsageDaily is one day of usage reported by ccusage
+type CCUsageDaily struct {
+	Date                string                  `json:"date"`
+	InputTokens         int64                   `json:"inputTokens"`
+	OutputTokens        int64                   `json:"outputTokens"`
+	CacheCreationTokens int64                   `json:"cacheCreationTokens"`
+	CacheReadTokens     int64                   `json:"cacheReadTokens"`
+	TotalTokens         int64                   `json:"totalTokens"`
+	TotalCost           float64                 `json:"totalCost"`
+	ModelsUsed          []string                `json:"modelsUsed"`
+	ModelBreakdowns     []CCUsageModelBreakdown `json:"modelBreakdowns"`
+}
+
+// CCUsageModelBreakdown is the per-model usage within a day
+type CCUsageModelBreakdown struct {
+	ModelName           string  `json:"modelName"`
+	InputTokens         int64   `json:"inputTokens"`
+	OutputTokens        int64   `json:"outputTokens"`
+	CacheCreationTokens int64   `json:"cacheCreationTokens"`
+	CacheReadTokens     int64   `json:"cacheReadTokens"`
+	Cost                float64 `json:"cost"`
+}
+
+// CCUsageTotals is the grand total across all reported days
+type CCUsageTotals struct {
+	InputTokens         int64   `json:"inputTokens"`
+	OutputTokens        int64   `json:"outputTokens"`
+	CacheCreationTokens int64   `json:"cacheCreationTokens"`
+	CacheReadTokens     int64   `json:"cacheReadTokens"`
+	TotalTokens         int64   `json:"totalTokens"`
+	TotalCost           float64 `json:"totalCost"`
+}
+
+// CCUsageCollector reads token usage from the ccusage CLI (https://github.com/ryoppippi/ccusage)
+// so the dashboard agrees with the numbers ccusage users already rely on
+type CCUsageCollector struct {
+	mu         sync.Mutex
+	last       *TokenMetrics // Most recent successful result
+	lastSince  string        // --since date the last result was computed for
+	lastRun    time.Time
+	refreshing bool
+}
+
+// NewCCUsageCollector creates a new ccusage-backed token collector
+func NewCCUsageCollector() *CCUsageCollector {
+	return &CCUsageCollector{}
+}
+
+// IsAvailable returns true if the ccusage binary is on PATH
+func (c *CCUsageCollector) IsAvailable() bool {
+	_, err := exec.LookPath("ccusage")
+	return err == nil
+}
+
+// Collect returns the latest ccusage snapshot for the lookback window and
+// starts a background refresh when it is stale. Returns nil until the first
+// run completes, so callers can fall back to another source meanwhile.
+// ccusage only filters by day, so the whole day of lookbackFrom is included.
+func (c *CCUsageCollector) Collect(lookbackFrom time.Time) *TokenMetrics {
+	since := ""
+	if !lookbackFrom.IsZero() {
+		since = lookbackFrom.Format("20060102")
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	stale := c.last == nil || c.lastSince != since || time.Since(c.lastRun) > ccusageRefreshInterval
+	if stale && !c.refreshing {
+		c.refreshing = true
+		go c.refresh(since, lookbackFrom)
+	}
+
+	if c.last == nil || c.lastSince != since {
+		return nil
+	}
+	result := *c.last
+	return &result
+}
+
+// refresh runs ccusage and stores the result
+func (c *CCUsageCollector) refresh(since string, lookbackFrom time.Time) {
+	metrics, err := runCCUsage(since)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.refreshing = false
+	c.lastRun = time.Now()
+	if err != nil {
+		// Keep serving the previous result; the caller falls back if there is none
+		return
+	}
+	metrics.LookbackFrom = lookbackFrom
+	c.last = metrics
+	c.lastSince = since
+}
+
+// runCCUsage executes `ccusage daily --json` and converts the output
+func runCCUsage(since string) (*TokenMetrics, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), ccusageCommandTimeout)
+	defer cancel()
+
+	args := []string{"daily", "--json"}
+	if since != "" {
+		args = append(args, "--since", since)
+	}
+
+	output, err := exec.CommandContext(ctx, "ccusage", args...).Output()
+	if err != nil {
+		return nil, fmt.Errorf("ccusage failed: %w", err)
+	}
+	return parseCCUsage(output)
+}
+
+// parseCCUsage converts `ccusage daily --json` output into token metrics
+func parseCCUsage(output []byte) (*TokenMetrics, error) {
+	var resp CCUsageResponse
+	if err := json.Unmarshal(output, &resp); err != nil {
+		return nil, fmt.Errorf("failed to parse ccusage output: %w", err)
+	}
+
+	return resp.toTokenMetrics(), nil
+}
+
+// toTokenMetrics converts a ccusage report into the dashboard's token metrics
+func (r *CCUsageResponse) toTokenMetrics() *TokenMetrics {
+	metrics := &TokenMetrics{
+		InputTokens:         r.Totals.InputTokens,
+		OutputTokens:        r.Totals.OutputTokens,
+		CacheReadTokens:     r.Totals.CacheReadTokens,
+		CacheCreationTokens: r.Totals.CacheCreationTokens,
+		TotalTokens:         r.Totals.TotalTokens,
+		TotalCost:           r.Totals.TotalCost,
+		Models:              []string{},
+		Available:           true,
+		Source:              TokenSourceCCUsage,
+		LastUpdate:          time.Now(),
+	}
+
+	// Merge per-model breakdowns across days
+	byModel := make(map[string]*ModelUsage)
+	for _, day := range r.Daily {
+		for _, mb := range day.ModelBreakdowns {
+			usage, ok := byModel[mb.ModelName]
+			if !ok {
+				usage = &ModelUsage{Model: mb.ModelName}
+				byModel[mb.ModelName] = usage
+			}
+			usage.InputTokens += mb.InputTokens
+			usage.OutputTokens += mb.OutputTokens
+			usage.CacheReadTokens += mb.CacheReadTokens
+			usage.CacheCreationTokens += mb.CacheCreationTokens
+			usage.TotalTokens += mb.InputTokens + mb.OutputTokens + mb.CacheReadTokens + mb.CacheCreationTokens
+			usage.Cost += mb.Cost
+		}
+	}
+
+	for model, usage := range byModel {
+		metrics.Models = append(metrics.Models, model)
+		metrics.ModelUsages = append(metrics.ModelUsages, *usage)
+	}
+	sort.Strings(metrics.Models)
+	sort.Slice(metrics.ModelUsages, func(i, j int) bool {
+		return metrics.ModelUsages[i].Cost > metrics.ModelUsages[j].Cost
+	})
+
+	return metrics
+}
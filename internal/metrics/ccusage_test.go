@@ -0,0 +1,59 @@
+package metrics
+
+import (
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestParseCCUsage(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("testdata", "ccusage-daily.json"))
+	if err != nil {
+		t.Fatalf("Failed to read fixture: %v", err)
+	}
+
+	m, err := parseCCUsage(data)
+	if err != nil {
+		t.Fatalf("parseCCUsage failed: %v", err)
+	}
+	if !m.Available || m.Source != TokenSourceCCUsage {
+		t.Errorf("Expected available ccusage metrics, got available=%v source=%q", m.Available, m.Source)
+	}
+	// Totals come from the report's totals, not the sum of the breakdowns
+	if m.InputTokens != 1700 || m.OutputTokens != 4900 || m.CacheCreationTokens != 70000 ||
+		m.CacheReadTokens != 600000 || m.TotalTokens != 676600 || m.TotalCost != 2.85 {
+		t.Errorf("Unexpected totals: %+v", m)
+	}
+
+	if len(m.Models) != 2 || m.Models[0] != "claude-opus-4-5-20251101" || m.Models[1] != "claude-sonnet-4-5-20250929" {
+		t.Errorf("Expected both models sorted by name, got %v", m.Models)
+	}
+
+	// Per-model usage is merged across days and ordered by cost
+	want := []ModelUsage{
+		{Model: "claude-opus-4-5-20251101", InputTokens: 1000, OutputTokens: 3000,
+			CacheCreationTokens: 40000, CacheReadTokens: 300000, TotalTokens: 344000, Cost: 1.5},
+		{Model: "claude-sonnet-4-5-20250929", InputTokens: 700, OutputTokens: 1900,
+			CacheCreationTokens: 30000, CacheReadTokens: 300000, TotalTokens: 332600, Cost: 1.35},
+	}
+	if len(m.ModelUsages) != len(want) {
+		t.Fatalf("Expected %d model usages, got %+v", len(want), m.ModelUsages)
+	}
+	for i, w := range want {
+		got := m.ModelUsages[i]
+		if math.Abs(got.Cost-w.Cost) > 1e-9 {
+			t.Errorf("%s: expected cost %v, got %v", w.Model, w.Cost, got.Cost)
+		}
+		got.Cost = w.Cost
+		if got != w {
+			t.Errorf("Model usage %d: expected %+v, got %+v", i, w, got)
+		}
+	}
+}
+
+func TestParseCCUsageRejectsInvalidJSON(t *testing.T) {
+	if _, err := parseCCUsage([]byte("ccusage: command failed")); err == nil {
+		t.Error("Expected non-JSON output to fail")
+	}
+}
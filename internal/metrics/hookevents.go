@@ -0,0 +1,240 @@
+package metrics
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// HookEvents lists the events `ccdash hook` handles. Each is installed as
+// <event>.sh in the hooks directory.
+var HookEvents = []string{
+	"session-start",
+	"session-end",
+	"prompt-submit",
+	"pre-tool-use",
+	"stop",
+	"post-tool-use",
+	"notification",
+	"permission-request",
+}
+
+// hookInput is the part of the JSON Claude Code passes a hook on stdin that
+// ccdash uses
+type hookInput struct {
+	SessionID string `json:"session_id"`
+	CWD       string `json:"cwd"`
+}
+
+// HandleHookEvent records a Claude Code hook event in the session files,
+// reading the hook's JSON input from r:
+//   - session-start writes the session file
+//   - session-end moves it to the session history
+//   - prompt-submit and post-tool-use mark the session working
+//   - stop marks it stopped (waiting for input)
+//   - notification and permission-request mark it waiting
+//   - pre-tool-use only refreshes its last activity
+//
+// Events other than session-start are ignored for sessions without a file.
+func (h *HookSessionCollector) HandleHookEvent(event string, r io.Reader) error {
+	var in hookInput
+	if err := json.NewDecoder(r).Decode(&in); err != nil {
+		return fmt.Errorf("invalid hook input: %w", err)
+	}
+	if in.SessionID == "" {
+		return nil
+	}
+	if strings.ContainsAny(in.SessionID, `/\`) || strings.HasPrefix(in.SessionID, ".") {
+		return fmt.Errorf("invalid session ID %q", in.SessionID)
+	}
+
+	// Second precision, as the session files have always been written
+	now := time.Now().UTC().Truncate(time.Second)
+	path := filepath.Join(h.sessionsDir, in.SessionID+".json")
+
+	switch event {
+	case "session-start":
+		return h.startSession(in, path, now)
+	case "session-end":
+		return h.endSession(path, now)
+	case "prompt-submit":
+		pid := claudePID()
+		return h.updateSessionFile(path, func(s *HookSession) {
+			s.LastActivity = now
+			s.Status = "working"
+			s.PID = pid
+		})
+	case "pre-tool-use":
+		return h.updateSessionFile(path, func(s *HookSession) {
+			s.LastActivity = now
+		})
+	case "post-tool-use":
+		return h.updateSessionFile(path, func(s *HookSession) {
+			s.LastActivity = now
+			s.Status = "working"
+		})
+	case "stop":
+		return h.updateSessionFile(path, func(s *HookSession) {
+			s.LastActivity = now
+			s.LastStop = now
+			s.Status = "stopped"
+		})
+	case "notification", "permission-request":
+		return h.updateSessionFile(path, func(s *HookSession) {
+			s.LastActivity = now
+			s.Status = "waiting"
+		})
+	default:
+		return fmt.Errorf("unknown hook event %q", event)
+	}
+}
+
+// startSession writes the session file for a new session, first removing
+// files left by an earlier session in the same tmux session, e.g. after
+// Claude Code restarted in the same window
+func (h *HookSessionCollector) startSession(in hookInput, path string, now time.Time) error {
+	if err := os.MkdirAll(h.sessionsDir, 0755); err != nil {
+		return err
+	}
+
+	session := &HookSession{
+		SessionID:       in.SessionID,
+		ProjectDir:      in.CWD,
+		TmuxSessionName: currentTmuxSession(),
+		StartedAt:       now,
+		LastActivity:    now,
+		PID:             claudePID(),
+		Status:          "active",
+	}
+
+	if session.TmuxSessionName != "" {
+		entries, _ := os.ReadDir(h.sessionsDir)
+		for _, entry := range entries {
+			other := filepath.Join(h.sessionsDir, entry.Name())
+			if entry.IsDir() || filepath.Ext(other) != ".json" || other == path {
+				continue
+			}
+			if old, err := h.readSessionFile(other); err == nil && old.TmuxSessionName == session.TmuxSessionName {
+				os.Remove(other)
+			}
+		}
+	}
+
+	return writeSessionFile(path, session)
+}
+
+// endSession records a finished session in the history and removes its file
+func (h *HookSessionCollector) endSession(path string, now time.Time) error {
+	session, err := h.readSessionFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err == nil {
+		err = h.writeHistoryEntry(SessionHistoryEntry{
+			SessionID:       session.SessionID,
+			ProjectDir:      session.ProjectDir,
+			TmuxSessionName: session.TmuxSessionName,
+			StartedAt:       session.StartedAt,
+			EndedAt:         now,
+		})
+	}
+
+	// Remove the file even when it couldn't be read or recorded
+	if rmErr := os.Remove(path); rmErr != nil && !errors.Is(rmErr, os.ErrNotExist) {
+		return rmErr
+	}
+	return err
+}
+
+// updateSessionFile applies update to an existing session file
+func (h *HookSessionCollector) updateSessionFile(path string, update func(*HookSession)) error {
+	session, err := h.readSessionFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	update(session)
+	return writeSessionFile(path, session)
+}
+
+// writeSessionFile writes a session file through a temp file and rename, so
+// the dashboard never reads a partly written one
+func writeSessionFile(path string, session *HookSession) error {
+	data, err := json.MarshalIndent(session, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".session-*.tmp")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(append(data, '\n')); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := os.Rename(tmp.Name(), path); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return nil
+}
+
+// hookTmuxTimeout bounds the tmux call a hook makes, so a wedged tmux server
+// can't hold up Claude Code
+const hookTmuxTimeout = 2 * time.Second
+
+// currentTmuxSession returns the name of the tmux session the hook runs in,
+// or "" outside tmux
+func currentTmuxSession() string {
+	if os.Getenv("TMUX") == "" {
+		return ""
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), hookTmuxTimeout)
+	defer cancel()
+	out, err := exec.CommandContext(ctx, "tmux", "display-message", "-p", "#S").Output()
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(out))
+}
+
+// claudePID returns the PID of the Claude Code process running the hook: the
+// nearest ancestor named "claude", or the parent process if there is none
+func claudePID() int {
+	parent := os.Getppid()
+	pid := int32(parent)
+	for pid > 1 {
+		p, err := process.NewProcess(pid)
+		if err != nil {
+			break
+		}
+		if name, err := p.Name(); err == nil && name == "claude" {
+			return int(pid)
+		}
+		if pid, err = p.Ppid(); err != nil {
+			break
+		}
+	}
+	return parent
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"bufio"
 	"encoding/json"
 	"fmt"
 	"os"
@@ -9,12 +10,14 @@ import (
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 	"time"
 )
 
 const (
-	// HooksDir is the directory name for hook-generated data
+	// HooksDir is the directory name for hook-generated data in the home
+	// directory when XDG_DATA_HOME is unset (see DataDir)
 	HooksDir = ".ccdash"
 	// SessionsSubdir is the subdirectory for session files
 	SessionsSubdir = "sessions"
@@ -22,8 +25,17 @@ const (
 	HooksSubdir = "hooks"
 	// InstancesSubdir is the subdirectory for instance PID files
 	InstancesSubdir = "instances"
+	// SessionsHistoryFile records one JSON line per completed session
+	SessionsHistoryFile = "sessions-history.jsonl"
 	// StaleSessionThreshold is how long before a session is considered stale
 	StaleSessionThreshold = 5 * time.Minute
+	// AbandonedSessionThreshold is how long a session file may go without a
+	// hook event before the periodic cleanup removes it, for sessions whose
+	// process and tmux session can't be checked
+	AbandonedSessionThreshold = 24 * time.Hour
+	// DefaultSessionCleanupInterval is the time between the dashboard's
+	// session cleanups
+	DefaultSessionCleanupInterval = time.Minute
 )
 
 // HookSession represents a Claude Code session tracked via hooks
@@ -33,26 +45,57 @@ type HookSession struct {
 	TmuxSessionName string    `json:"tmux_session_name,omitempty"` // Name of the tmux session
 	StartedAt       time.Time `json:"started_at"`
 	LastActivity    time.Time `json:"last_activity"`
-	LastStop        time.Time `json:"last_stop,omitempty"`
+	LastStop        time.Time `json:"last_stop,omitzero"`
 	PID             int       `json:"pid,omitempty"`
 	Status          string    `json:"status"` // "active", "stopped", "working", "waiting"
 }
 
+// SessionHistoryEntry is one completed session in sessions-history.jsonl,
+// appended by the session-end hook or when an orphaned session is cleaned up
+type SessionHistoryEntry struct {
+	SessionID       string    `json:"session_id"`
+	ProjectDir      string    `json:"project_dir,omitempty"`
+	TmuxSessionName string    `json:"tmux_session_name,omitempty"`
+	StartedAt       time.Time `json:"started_at"`
+	EndedAt         time.Time `json:"ended_at"`
+}
+
+// SessionHistoryStats summarizes every completed session on record
+type SessionHistoryStats struct {
+	Count       int           // Completed sessions
+	AvgDuration time.Duration // Mean start-to-end time of sessions with both timestamps
+}
+
 // HookSessionCollector reads session data from hook-generated files
 type HookSessionCollector struct {
-	baseDir     string // ~/.ccdash
-	sessionsDir string // ~/.ccdash/sessions
+	baseDir     string // DataDir(), e.g. ~/.ccdash
+	sessionsDir string // <baseDir>/sessions
 	available   bool
+
+	// History stats are re-read only when the history file changes
+	historyMu      sync.Mutex
+	historySize    int64
+	historyModTime time.Time
+	historyStats   SessionHistoryStats
 }
 
-// NewHookSessionCollector creates a new hook session collector
+// NewHookSessionCollector creates a new hook session collector for DataDir()
 func NewHookSessionCollector() (*HookSessionCollector, error) {
-	homeDir, err := os.UserHomeDir()
-	if err != nil {
+	if _, err := os.UserHomeDir(); err != nil {
 		return nil, fmt.Errorf("failed to get home directory: %w", err)
 	}
 
-	baseDir := filepath.Join(homeDir, HooksDir)
+	baseDir, _ := DataDir()
+	return NewHookSessionCollectorWithDir(baseDir)
+}
+
+// NewHookSessionCollectorWithDir creates a hook session collector that keeps
+// its hook scripts, session files and instance registrations under baseDir
+func NewHookSessionCollectorWithDir(baseDir string) (*HookSessionCollector, error) {
+	baseDir, err := filepath.Abs(baseDir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to resolve hooks directory: %w", err)
+	}
 	sessionsDir := filepath.Join(baseDir, SessionsSubdir)
 
 	// Check if the hooks directory exists
@@ -242,6 +285,7 @@ func (h *HookSessionCollector) CleanupOrphanedSessions() (int, error) {
 		}
 
 		if shouldRemove {
+			h.appendSessionHistory(session)
 			os.Remove(sessionPath)
 			cleaned++
 		}
@@ -250,6 +294,86 @@ func (h *HookSessionCollector) CleanupOrphanedSessions() (int, error) {
 	return cleaned, nil
 }
 
+// SessionHistory returns the lifetime session count and average duration from
+// sessions-history.jsonl. Malformed lines are skipped.
+func (h *HookSessionCollector) SessionHistory() (SessionHistoryStats, error) {
+	h.historyMu.Lock()
+	defer h.historyMu.Unlock()
+
+	path := filepath.Join(h.baseDir, SessionsHistoryFile)
+	info, err := os.Stat(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return SessionHistoryStats{}, nil
+		}
+		return SessionHistoryStats{}, err
+	}
+	if info.Size() == h.historySize && info.ModTime().Equal(h.historyModTime) {
+		return h.historyStats, nil
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return SessionHistoryStats{}, err
+	}
+	defer f.Close()
+
+	var stats SessionHistoryStats
+	var total time.Duration
+	timed := 0
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var entry SessionHistoryEntry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil || entry.SessionID == "" {
+			continue
+		}
+		stats.Count++
+		if !entry.StartedAt.IsZero() && entry.EndedAt.After(entry.StartedAt) {
+			total += entry.EndedAt.Sub(entry.StartedAt)
+			timed++
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return SessionHistoryStats{}, err
+	}
+	if timed > 0 {
+		stats.AvgDuration = total / time.Duration(timed)
+	}
+
+	h.historySize = info.Size()
+	h.historyModTime = info.ModTime()
+	h.historyStats = stats
+	return stats, nil
+}
+
+// appendSessionHistory records a session that ended without the session-end
+// hook, using its last activity as the end time
+func (h *HookSessionCollector) appendSessionHistory(session *HookSession) error {
+	return h.writeHistoryEntry(SessionHistoryEntry{
+		SessionID:       session.SessionID,
+		ProjectDir:      session.ProjectDir,
+		TmuxSessionName: session.TmuxSessionName,
+		StartedAt:       session.StartedAt,
+		EndedAt:         session.LastActivity,
+	})
+}
+
+// writeHistoryEntry appends a completed session to sessions-history.jsonl
+func (h *HookSessionCollector) writeHistoryEntry(entry SessionHistoryEntry) error {
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return err
+	}
+
+	f, err := os.OpenFile(filepath.Join(h.baseDir, SessionsHistoryFile), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
 // getActiveTmuxSessions returns a map of active tmux session names
 func (h *HookSessionCollector) getActiveTmuxSessions() map[string]bool {
 	sessions := make(map[string]bool)
@@ -305,296 +429,20 @@ func (hs *HookSession) ToTmuxSession() TmuxSession {
 		IdleDuration: time.Since(hs.LastActivity),
 		LastLines:    []string{fmt.Sprintf("Session: %s", hs.SessionID[:8])},
 		Source:       "hooks", // Mark as hook-sourced
+		SessionID:    hs.SessionID,
+		ProjectDir:   hs.ProjectDir,
 	}
 }
 
-// HookScripts contains the shell scripts to be installed as Claude Code hooks
-var HookScripts = map[string]string{
-	"session-start.sh": `#!/bin/bash
-# Claude Code SessionStart hook - registers session with ccdash
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-# Read hook input from stdin
-INPUT=$(cat)
-
-# Extract session info
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-CWD=$(echo "$INPUT" | jq -r '.cwd // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-# Get tmux session name if running inside tmux
-TMUX_SESSION=""
-if [ -n "$TMUX" ]; then
-    TMUX_SESSION=$(tmux display-message -p '#S' 2>/dev/null || echo "")
-fi
-
-# Find the actual Claude Code process PID by walking up the process tree
-# The hook runs as a child of Claude Code, so find the first "claude" process
-CLAUDE_PID=""
-CURRENT_PID=$PPID
-while [ -n "$CURRENT_PID" ] && [ "$CURRENT_PID" != "1" ]; do
-    PROC_NAME=$(ps -p "$CURRENT_PID" -o comm= 2>/dev/null || echo "")
-    if [ "$PROC_NAME" = "claude" ]; then
-        CLAUDE_PID="$CURRENT_PID"
-        break
-    fi
-    # Move up to parent
-    CURRENT_PID=$(ps -p "$CURRENT_PID" -o ppid= 2>/dev/null | tr -d ' ' || echo "")
-done
-
-# Fallback to PPID if we couldn't find claude process
-if [ -z "$CLAUDE_PID" ]; then
-    CLAUDE_PID=$PPID
-fi
-
-# Ensure directories exist
-mkdir -p "$SESSIONS_DIR"
-
-# Clean up old session files for the same tmux session if they exist
-# This handles the case where Claude Code restarted in the same tmux window
-if [ -n "$TMUX_SESSION" ]; then
-    for old_file in "$SESSIONS_DIR"/*.json; do
-        if [ -f "$old_file" ] && [ "$old_file" != "$SESSIONS_DIR/${SESSION_ID}.json" ]; then
-            OLD_TMUX=$(jq -r '.tmux_session_name // empty' "$old_file" 2>/dev/null || echo "")
-            if [ "$OLD_TMUX" = "$TMUX_SESSION" ]; then
-                rm -f "$old_file"
-            fi
-        fi
-    done
-fi
-
-# Write session file
-cat > "$SESSIONS_DIR/${SESSION_ID}.json" << EOF
-{
-  "session_id": "$SESSION_ID",
-  "project_dir": "$CWD",
-  "tmux_session_name": "$TMUX_SESSION",
-  "started_at": "$(date -u +%Y-%m-%dT%H:%M:%SZ)",
-  "last_activity": "$(date -u +%Y-%m-%dT%H:%M:%SZ)",
-  "pid": $CLAUDE_PID,
-  "status": "active"
-}
-EOF
-
-exit 0
-`,
-
-	"session-end.sh": `#!/bin/bash
-# Claude Code SessionEnd hook - unregisters session from ccdash
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-# Read hook input from stdin
-INPUT=$(cat)
-
-# Extract session ID
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-# Remove session file
-rm -f "$SESSIONS_DIR/${SESSION_ID}.json"
-
-exit 0
-`,
-
-	"stop.sh": `#!/bin/bash
-# Claude Code Stop hook - marks session as stopped (waiting for input)
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-# Read hook input from stdin
-INPUT=$(cat)
-
-# Extract session info
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-# Update status to stopped (waiting for input)
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       '.last_activity = $now | .last_stop = $now | .status = "stopped"' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-
-	"pre-tool-use.sh": `#!/bin/bash
-# Claude Code PreToolUse hook - refreshes last_activity during tool execution
-# Prevents long-running tasks from being marked stale mid-execution
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-INPUT=$(cat)
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       '.last_activity = $now' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-
-	"post-tool-use.sh": `#!/bin/bash
-# Claude Code PostToolUse hook - marks session as working again
-# Fires after any tool finishes, including ones that were gated on a
-# permission prompt, AskUserQuestion, or ExitPlanMode approval — this is
-# the earliest signal we get that Claude has resumed after human input.
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-INPUT=$(cat)
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       '.last_activity = $now | .status = "working"' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-
-	"notification.sh": `#!/bin/bash
-# Claude Code Notification hook - marks session as waiting for human input
-# Fires when Claude needs permission or has been idle 60s awaiting a reply.
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-INPUT=$(cat)
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       '.last_activity = $now | .status = "waiting"' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-
-	"permission-request.sh": `#!/bin/bash
-# Claude Code PermissionRequest hook - marks session as waiting for human input
-# Fires when the user is shown a permission dialog (tool approval, plan
-# approval via ExitPlanMode, AskUserQuestion, etc.).
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-INPUT=$(cat)
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       '.last_activity = $now | .status = "waiting"' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-
-	"prompt-submit.sh": `#!/bin/bash
-# Claude Code UserPromptSubmit hook - marks session as working
-set -e
-
-CCDASH_DIR="$HOME/.ccdash"
-SESSIONS_DIR="$CCDASH_DIR/sessions"
-
-# Read hook input from stdin
-INPUT=$(cat)
-
-# Extract session info
-SESSION_ID=$(echo "$INPUT" | jq -r '.session_id // empty')
-
-if [ -z "$SESSION_ID" ]; then
-    exit 0
-fi
-
-SESSION_FILE="$SESSIONS_DIR/${SESSION_ID}.json"
-
-# Find the actual Claude Code process PID
-CLAUDE_PID=""
-CURRENT_PID=$PPID
-while [ -n "$CURRENT_PID" ] && [ "$CURRENT_PID" != "1" ]; do
-    PROC_NAME=$(ps -p "$CURRENT_PID" -o comm= 2>/dev/null || echo "")
-    if [ "$PROC_NAME" = "claude" ]; then
-        CLAUDE_PID="$CURRENT_PID"
-        break
-    fi
-    CURRENT_PID=$(ps -p "$CURRENT_PID" -o ppid= 2>/dev/null | tr -d ' ' || echo "")
-done
-if [ -z "$CLAUDE_PID" ]; then
-    CLAUDE_PID=$PPID
-fi
-
-# Update status to working and refresh PID
-if [ -f "$SESSION_FILE" ]; then
-    TMP_FILE=$(mktemp)
-    jq --arg now "$(date -u +%Y-%m-%dT%H:%M:%SZ)" \
-       --argjson pid "$CLAUDE_PID" \
-       '.last_activity = $now | .status = "working" | .pid = $pid' \
-       "$SESSION_FILE" > "$TMP_FILE" && mv "$TMP_FILE" "$SESSION_FILE"
-fi
-
-exit 0
-`,
-}
+// hookScriptTemplate is the script installed for each hook event. It hands
+// the event to `ccdash hook`, so the hooks need nothing but the binary, and
+// exits quietly if the binary is gone.
+const hookScriptTemplate = `#!/bin/sh
+# ccdash %s hook, written by ccdash --install-hooks. Run it again after moving ccdash.
+CCDASH=%s
+[ -x "$CCDASH" ] || exit 0
+exec "$CCDASH" hook --hooks-dir=%s %s
+`
 
 // ClaudeHooksConfig represents the hooks section of Claude settings
 type ClaudeHooksConfig struct {
@@ -621,39 +469,152 @@ type HookConfig struct {
 	Timeout int    `json:"timeout,omitempty"`
 }
 
-// InstallHooks installs the ccdash hooks into Claude Code settings
+// SettingsFileResult is what installing the hooks did to one settings file
+type SettingsFileResult struct {
+	Path  string
+	Added bool  // Hooks were added; false when they were all there already
+	Err   error // The file couldn't be written
+}
+
+// InstallHooks installs the ccdash hooks into Claude Code settings. The hook
+// scripts run the current ccdash binary, or the one in PATH when this is a
+// go run or go test build, so run it again after moving ccdash.
 func (h *HookSessionCollector) InstallHooks() error {
+	_, err := h.InstallHooksIn("")
+	return err
+}
+
+// InstallHooksIn is InstallHooks limited to one settings file; with an empty
+// settingsFile it updates settings.json and every other settings*.json in
+// ~/.claude. It reports what it did to each file.
+func (h *HookSessionCollector) InstallHooksIn(settingsFile string) ([]SettingsFileResult, error) {
+	exe, transient, err := hookBinary()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate the ccdash binary: %w", err)
+	}
+
 	if err := h.EnsureDirectories(); err != nil {
-		return err
+		return nil, err
 	}
 
 	// Write hook scripts
 	hooksDir := filepath.Join(h.baseDir, HooksSubdir)
-	for name, content := range HookScripts {
-		scriptPath := filepath.Join(hooksDir, name)
-		if err := os.WriteFile(scriptPath, []byte(content), 0755); err != nil {
-			return fmt.Errorf("failed to write hook script %s: %w", name, err)
+	for _, event := range HookEvents {
+		scriptPath := filepath.Join(hooksDir, event+".sh")
+		// A binary from go run or go test is deleted with its build
+		// directory, so it only fills in scripts that are missing
+		if _, err := os.Stat(scriptPath); transient && err == nil {
+			continue
+		}
+		if err := os.WriteFile(scriptPath, []byte(h.hookScript(event, exe)), 0755); err != nil {
+			return nil, fmt.Errorf("failed to write hook script %s: %w", event+".sh", err)
 		}
 	}
 
 	// Update Claude settings
-	return h.updateClaudeSettings()
+	return h.updateClaudeSettings(settingsFile)
+}
+
+// hookBinary returns the ccdash binary the hook scripts run: this one, or the
+// ccdash in PATH when this one is a temporary build. transient reports that
+// only a temporary build was found.
+func hookBinary() (exe string, transient bool, err error) {
+	exe, err = os.Executable()
+	if err == nil {
+		exe, err = filepath.EvalSymlinks(exe)
+	}
+	if err != nil {
+		return "", false, err
+	}
+	if !transientBinary(exe) {
+		return exe, false, nil
+	}
+	if installed, err := exec.LookPath("ccdash"); err == nil {
+		if installed, err = filepath.EvalSymlinks(installed); err == nil && !transientBinary(installed) {
+			return installed, false, nil
+		}
+	}
+	return exe, true, nil
 }
 
-// updateClaudeSettings adds ccdash hooks to all ~/.claude/settings*.json files
-func (h *HookSessionCollector) updateClaudeSettings() error {
+// transientBinary reports whether path is under the temp directory or a Go
+// build directory, where go run and go test put the binaries they build
+func transientBinary(path string) bool {
+	for _, dir := range []string{os.TempDir(), os.Getenv("GOTMPDIR")} {
+		if dir == "" {
+			continue
+		}
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			dir = resolved
+		}
+		if rel, err := filepath.Rel(dir, path); err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+			return true
+		}
+	}
+	return strings.Contains(filepath.ToSlash(path), "/go-build")
+}
+
+// SettingsFilePath resolves a --settings-file value: a bare name like
+// settings.local.json is in ~/.claude, anything else is a path
+func SettingsFilePath(name string) (string, error) {
+	if strings.ContainsRune(name, filepath.Separator) {
+		return filepath.Abs(name)
+	}
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
-		return err
+		return "", err
 	}
+	return filepath.Join(homeDir, ".claude", name), nil
+}
 
-	claudeDir := filepath.Join(homeDir, ".claude")
+// SettingsFileKind describes a settings file by its name: settings.json holds
+// the user's settings, settings.local.json overrides meant to stay on this
+// machine, and other settings*.json files are profiles
+func SettingsFileKind(path string) string {
+	name := filepath.Base(path)
+	switch name {
+	case "settings.json":
+		return "user settings"
+	case "settings.local.json":
+		return "local overrides"
+	}
+	if ok, _ := filepath.Match("settings*.json", name); ok {
+		return "profile"
+	}
+	return "custom file"
+}
+
+// hookScript returns the script that runs `ccdash hook` for event with the
+// collector's data directory
+func (h *HookSessionCollector) hookScript(event, exe string) string {
+	return fmt.Sprintf(hookScriptTemplate, event, shellQuote(exe), shellQuote(h.baseDir), event)
+}
+
+// shellQuote quotes s for a POSIX shell
+func shellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
+
+// updateClaudeSettings adds ccdash hooks to settingsFile, or to all
+// ~/.claude/settings*.json files when it's empty
+func (h *HookSessionCollector) updateClaudeSettings(settingsFile string) ([]SettingsFileResult, error) {
 	hooksDir := filepath.Join(h.baseDir, HooksSubdir)
+	if settingsFile != "" {
+		added, err := h.updateSingleSettingsFile(settingsFile, hooksDir)
+		return []SettingsFileResult{{Path: settingsFile, Added: added, Err: err}}, err
+	}
+
+	homeDir, err := os.UserHomeDir()
+	if err != nil {
+		return nil, err
+	}
+
+	claudeDir := filepath.Join(homeDir, ".claude")
 
 	// Find all settings files
 	settingsFiles, err := filepath.Glob(filepath.Join(claudeDir, "settings*.json"))
 	if err != nil {
-		return err
+		return nil, err
 	}
 
 	// Always include settings.json even if it doesn't exist yet
@@ -670,17 +631,21 @@ func (h *HookSessionCollector) updateClaudeSettings() error {
 	}
 
 	// Update each settings file
+	results := make([]SettingsFileResult, 0, len(settingsFiles))
 	var lastErr error
 	for _, settingsPath := range settingsFiles {
-		if err := h.updateSingleSettingsFile(settingsPath, hooksDir); err != nil {
+		added, err := h.updateSingleSettingsFile(settingsPath, hooksDir)
+		if err != nil {
 			lastErr = err
 		}
+		results = append(results, SettingsFileResult{Path: settingsPath, Added: added, Err: err})
 	}
-	return lastErr
+	return results, lastErr
 }
 
-// updateSingleSettingsFile adds ccdash hooks to a single settings file
-func (h *HookSessionCollector) updateSingleSettingsFile(settingsPath, hooksDir string) error {
+// updateSingleSettingsFile adds ccdash hooks to a single settings file,
+// reporting whether any were missing. A file that has them all is left as is.
+func (h *HookSessionCollector) updateSingleSettingsFile(settingsPath, hooksDir string) (bool, error) {
 
 	// Read existing settings
 	var settings map[string]interface{}
@@ -783,6 +748,7 @@ func (h *HookSessionCollector) updateSingleSettingsFile(settingsPath, hooksDir s
 	}
 
 	// Merge hooks (append ccdash hooks to existing)
+	added := false
 	for event, hookList := range ccdashHooks {
 		existing, _ := hooks[event].([]interface{})
 
@@ -810,34 +776,43 @@ func (h *HookSessionCollector) updateSingleSettingsFile(settingsPath, hooksDir s
 				existing = append(existing, newHook)
 			}
 			hooks[event] = existing
+			added = true
 		}
 	}
+	if !added {
+		return false, nil
+	}
 
 	settings["hooks"] = hooks
 
 	// Write updated settings
 	data, err := json.MarshalIndent(settings, "", "  ")
 	if err != nil {
-		return err
+		return false, err
 	}
 
 	// Ensure .claude directory exists
 	if err := os.MkdirAll(filepath.Dir(settingsPath), 0755); err != nil {
-		return err
+		return false, err
 	}
 
-	return os.WriteFile(settingsPath, data, 0644)
+	if err := os.WriteFile(settingsPath, data, 0644); err != nil {
+		return false, err
+	}
+	return true, nil
 }
 
-// AreHooksInstalled checks if ccdash hooks are installed in the main settings.json
+// AreHooksInstalled checks if ccdash hooks are installed in settings.json or
+// settings.local.json, the settings files Claude Code always loads
 func (h *HookSessionCollector) AreHooksInstalled() bool {
 	homeDir, err := os.UserHomeDir()
 	if err != nil {
 		return false
 	}
 
-	settingsPath := filepath.Join(homeDir, ".claude", "settings.json")
-	return h.areHooksInSettingsFile(settingsPath)
+	claudeDir := filepath.Join(homeDir, ".claude")
+	return h.areHooksInSettingsFile(filepath.Join(claudeDir, "settings.json")) ||
+		h.areHooksInSettingsFile(filepath.Join(claudeDir, "settings.local.json"))
 }
 
 // GetSettingsFilesStatus returns status of hooks in all settings files
@@ -0,0 +1,280 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSessionHistory(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	h := &HookSessionCollector{baseDir: tmpDir, sessionsDir: filepath.Join(tmpDir, SessionsSubdir)}
+
+	// No history file yet
+	stats, err := h.SessionHistory()
+	if err != nil || stats.Count != 0 {
+		t.Fatalf("Expected empty history, got %+v (err=%v)", stats, err)
+	}
+
+	history := `{"session_id":"a","started_at":"2025-03-10T09:00:00Z","ended_at":"2025-03-10T09:30:00Z"}
+not json
+{"session_id":"b","started_at":"2025-03-10T10:00:00Z","ended_at":"2025-03-10T11:30:00Z"}
+{"session_id":"c","started_at":null,"ended_at":"2025-03-10T12:00:00Z"}
+`
+	if err := os.WriteFile(filepath.Join(tmpDir, SessionsHistoryFile), []byte(history), 0644); err != nil {
+		t.Fatalf("Failed to write history: %v", err)
+	}
+
+	stats, err = h.SessionHistory()
+	if err != nil {
+		t.Fatalf("SessionHistory failed: %v", err)
+	}
+	// "c" counts as a session but has no start time, so only a and b are averaged
+	if stats.Count != 3 {
+		t.Errorf("Expected 3 sessions (malformed lines skipped), got %d", stats.Count)
+	}
+	if stats.AvgDuration != time.Hour {
+		t.Errorf("Expected average duration 1h, got %v", stats.AvgDuration)
+	}
+
+	// An orphaned session is recorded with its last activity as the end time
+	start := time.Date(2025, 3, 11, 9, 0, 0, 0, time.UTC)
+	orphan := &HookSession{SessionID: "d", StartedAt: start, LastActivity: start.Add(2 * time.Hour)}
+	if err := h.appendSessionHistory(orphan); err != nil {
+		t.Fatalf("appendSessionHistory failed: %v", err)
+	}
+
+	stats, err = h.SessionHistory()
+	if err != nil {
+		t.Fatalf("SessionHistory failed: %v", err)
+	}
+	if stats.Count != 4 {
+		t.Errorf("Expected 4 sessions after append, got %d", stats.Count)
+	}
+	if want := (30*time.Minute + 90*time.Minute + 2*time.Hour) / 3; stats.AvgDuration != want {
+		t.Errorf("Expected average duration %v, got %v", want, stats.AvgDuration)
+	}
+}
+
+func TestHookScriptRunsCcdash(t *testing.T) {
+	h := &HookSessionCollector{baseDir: "/data/it's ccdash"}
+	got := h.hookScript("stop", "/opt/ccdash/bin/ccdash")
+
+	for _, want := range []string{
+		`CCDASH='/opt/ccdash/bin/ccdash'`,
+		`exec "$CCDASH" hook --hooks-dir='/data/it'\''s ccdash' stop`,
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("Expected %s in the script:\n%s", want, got)
+		}
+	}
+	if strings.Contains(got, "jq") {
+		t.Errorf("Expected the script not to need jq:\n%s", got)
+	}
+}
+
+func TestHandleHookEvent(t *testing.T) {
+	tmpDir := t.TempDir()
+	t.Setenv("TMUX", "")
+
+	h, err := NewHookSessionCollectorWithDir(tmpDir)
+	if err != nil {
+		t.Fatalf("NewHookSessionCollectorWithDir failed: %v", err)
+	}
+	const input = `{"session_id":"0123456789abcdef","cwd":"/work/api","hook_event_name":"Stop"}`
+	path := filepath.Join(tmpDir, SessionsSubdir, "0123456789abcdef.json")
+
+	// Events before session-start are ignored
+	if err := h.HandleHookEvent("stop", strings.NewReader(input)); err != nil {
+		t.Fatalf("stop without a session failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Fatalf("Expected no session file before session-start, got %v", err)
+	}
+
+	steps := []struct {
+		event  string
+		status string
+	}{
+		{"session-start", "active"},
+		{"prompt-submit", "working"},
+		{"pre-tool-use", "working"},
+		{"permission-request", "waiting"},
+		{"post-tool-use", "working"},
+		{"stop", "stopped"},
+		{"notification", "waiting"},
+	}
+	for _, step := range steps {
+		if err := h.HandleHookEvent(step.event, strings.NewReader(input)); err != nil {
+			t.Fatalf("%s failed: %v", step.event, err)
+		}
+		session, err := h.readSessionFile(path)
+		if err != nil {
+			t.Fatalf("Failed to read the session file after %s: %v", step.event, err)
+		}
+		if session.Status != step.status {
+			t.Errorf("Expected status %q after %s, got %q", step.status, step.event, session.Status)
+		}
+		if session.ProjectDir != "/work/api" || session.StartedAt.IsZero() || session.PID == 0 {
+			t.Errorf("Expected the session details to be kept after %s, got %+v", step.event, session)
+		}
+	}
+
+	if err := h.HandleHookEvent("session-end", strings.NewReader(input)); err != nil {
+		t.Fatalf("session-end failed: %v", err)
+	}
+	if _, err := os.Stat(path); !os.IsNotExist(err) {
+		t.Errorf("Expected session-end to remove the session file, got %v", err)
+	}
+	if stats, err := h.SessionHistory(); err != nil || stats.Count != 1 {
+		t.Errorf("Expected the ended session in the history, got %+v (%v)", stats, err)
+	}
+
+	if err := h.HandleHookEvent("stop", strings.NewReader(`{"session_id":"../escape"}`)); err == nil {
+		t.Errorf("Expected a session ID with a path to be rejected")
+	}
+}
+
+func TestInstallHooksWithDir(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	baseDir := filepath.Join(home, "custom hooks")
+
+	h, err := NewHookSessionCollectorWithDir(baseDir)
+	if err != nil {
+		t.Fatalf("NewHookSessionCollectorWithDir failed: %v", err)
+	}
+	if h.GetBaseDir() != baseDir || h.IsAvailable() {
+		t.Errorf("Expected an unavailable collector for %s, got %s (available=%v)", baseDir, h.GetBaseDir(), h.IsAvailable())
+	}
+	if err := h.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+
+	script, err := os.ReadFile(filepath.Join(baseDir, HooksSubdir, "stop.sh"))
+	if err != nil {
+		t.Fatalf("Expected the hook scripts under the custom dir: %v", err)
+	}
+	if want := "--hooks-dir='" + baseDir + "'"; !strings.Contains(string(script), want) {
+		t.Errorf("Expected %s in the script:\n%s", want, script)
+	}
+	settings, err := os.ReadFile(filepath.Join(home, ".claude", "settings.json"))
+	if err != nil {
+		t.Fatalf("Expected settings.json to be written: %v", err)
+	}
+	if !strings.Contains(string(settings), filepath.Join(baseDir, HooksSubdir)) {
+		t.Errorf("Expected the settings to point at the custom hooks dir:\n%s", settings)
+	}
+	if !h.AreHooksInstalled() {
+		t.Errorf("Expected the hooks to be reported as installed")
+	}
+}
+
+func TestInstallHooksFromTransientBinary(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv("PATH", home) // No installed ccdash to use instead
+
+	// The test binary is itself a go test build
+	if _, transient, err := hookBinary(); err != nil || !transient {
+		t.Fatalf("Expected the test binary to be transient, got %v, %v", transient, err)
+	}
+
+	h, err := NewHookSessionCollectorWithDir(filepath.Join(home, HooksDir))
+	if err != nil {
+		t.Fatalf("NewHookSessionCollectorWithDir failed: %v", err)
+	}
+	if err := h.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+
+	// Missing scripts are written, but existing ones keep their binary
+	stop := filepath.Join(home, HooksDir, HooksSubdir, "stop.sh")
+	kept := h.hookScript("stop", "/usr/local/bin/ccdash")
+	if err := os.WriteFile(stop, []byte(kept), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := h.InstallHooks(); err != nil {
+		t.Fatalf("InstallHooks failed: %v", err)
+	}
+	if script, _ := os.ReadFile(stop); string(script) != kept {
+		t.Errorf("Expected the script to keep the installed binary, got:\n%s", script)
+	}
+}
+
+func TestTransientBinary(t *testing.T) {
+	tests := []struct {
+		path string
+		want bool
+	}{
+		{filepath.Join(os.TempDir(), "go-build123", "b001", "exe", "ccdash"), true},
+		{"/home/me/.cache/go-build/ab/ccdash", true},
+		{"/usr/local/bin/ccdash", false},
+		{"/home/me/go/bin/ccdash", false},
+	}
+	for _, tt := range tests {
+		if got := transientBinary(tt.path); got != tt.want {
+			t.Errorf("transientBinary(%q) = %v, want %v", tt.path, got, tt.want)
+		}
+	}
+}
+
+func TestInstallHooksInOneSettingsFile(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+
+	h, err := NewHookSessionCollectorWithDir(filepath.Join(home, "ccdash"))
+	if err != nil {
+		t.Fatalf("NewHookSessionCollectorWithDir failed: %v", err)
+	}
+	local, err := SettingsFilePath("settings.local.json")
+	if err != nil || local != filepath.Join(home, ".claude", "settings.local.json") {
+		t.Fatalf("Expected settings.local.json in ~/.claude, got %q (%v)", local, err)
+	}
+	if err := os.MkdirAll(filepath.Dir(local), 0755); err != nil {
+		t.Fatalf("Failed to create .claude: %v", err)
+	}
+	if err := os.WriteFile(local, []byte(`{"model": "opus"}`), 0644); err != nil {
+		t.Fatalf("Failed to write settings: %v", err)
+	}
+
+	// Only the named file is touched
+	results, err := h.InstallHooksIn(local)
+	if err != nil || len(results) != 1 || results[0].Path != local || !results[0].Added {
+		t.Fatalf("Expected hooks added to %s only, got %+v (%v)", local, results, err)
+	}
+	if _, err := os.Stat(filepath.Join(home, ".claude", "settings.json")); !os.IsNotExist(err) {
+		t.Errorf("Expected settings.json to be left alone, got %v", err)
+	}
+	if data, _ := os.ReadFile(local); !strings.Contains(string(data), `"model": "opus"`) {
+		t.Errorf("Expected the existing settings kept:\n%s", data)
+	}
+	if !h.AreHooksInstalled() {
+		t.Errorf("Expected hooks in settings.local.json to count as installed")
+	}
+
+	// Without a file, every settings file; the local one already has them
+	results, err = h.InstallHooksIn("")
+	if err != nil || len(results) != 2 {
+		t.Fatalf("Expected two settings files, got %+v (%v)", results, err)
+	}
+	for _, r := range results {
+		if wantAdded := filepath.Base(r.Path) == "settings.json"; r.Added != wantAdded {
+			t.Errorf("Expected Added=%v for %s", wantAdded, r.Path)
+		}
+	}
+
+	for name, want := range map[string]string{
+		"settings.json":       "user settings",
+		"settings.local.json": "local overrides",
+		"settings.work.json":  "profile",
+		"hooks.json":          "custom file",
+	} {
+		if got := SettingsFileKind(filepath.Join(home, name)); got != want {
+			t.Errorf("SettingsFileKind(%s) = %q; want %q", name, got, want)
+		}
+	}
+}
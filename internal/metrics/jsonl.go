@@ -0,0 +1,77 @@
+package metrics
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"io"
+)
+
+// maxJSONLLineSize is the longest JSONL line kept for parsing. Claude Code
+// logs can hold lines far longer, e.g. a message with a large base64 image or
+// tool output; those are skipped rather than buffered.
+const maxJSONLLineSize = 10 * 1024 * 1024
+
+// jsonlReader reads the lines of a JSONL file, however long they are. Unlike
+// bufio.Scanner, which stops at the first line over its buffer size, it
+// consumes an oversized line, reports it with TooLong and carries on with the
+// next one, so line numbers stay in step with the file.
+type jsonlReader struct {
+	r       *bufio.Reader
+	line    []byte
+	tooLong bool
+	err     error
+}
+
+func newJSONLReader(r io.Reader) *jsonlReader {
+	return &jsonlReader{r: bufio.NewReaderSize(r, 1024*1024)}
+}
+
+// Next advances to the next line. It returns false at the end of the input or
+// on a read error, which Err reports.
+func (jr *jsonlReader) Next() bool {
+	jr.line = jr.line[:0]
+	jr.tooLong = false
+	for {
+		chunk, err := jr.r.ReadSlice('\n')
+		if !jr.tooLong {
+			if len(jr.line)+len(chunk) > maxJSONLLineSize {
+				jr.tooLong = true
+				jr.line = jr.line[:0]
+			} else {
+				jr.line = append(jr.line, chunk...)
+			}
+		}
+		if errors.Is(err, bufio.ErrBufferFull) {
+			continue // Same line, keep reading
+		}
+		if err != nil {
+			if !errors.Is(err, io.EOF) {
+				jr.err = err
+			}
+			// A final line without a trailing newline still counts
+			return len(jr.line) > 0 || jr.tooLong
+		}
+		return true
+	}
+}
+
+// Bytes returns the current line without its line ending, or nil when it was
+// too long. The slice is only valid until the next call to Next.
+func (jr *jsonlReader) Bytes() []byte {
+	if jr.tooLong {
+		return nil
+	}
+	line := bytes.TrimSuffix(jr.line, []byte("\n"))
+	return bytes.TrimSuffix(line, []byte("\r"))
+}
+
+// TooLong reports whether the current line exceeded maxJSONLLineSize
+func (jr *jsonlReader) TooLong() bool {
+	return jr.tooLong
+}
+
+// Err returns the first read error other than io.EOF
+func (jr *jsonlReader) Err() error {
+	return jr.err
+}
@@ -0,0 +1,80 @@
+package metrics
+
+import (
+	"os"
+	"path/filepath"
+)
+
+// XDGDir returns the ccdash directory under the XDG base directory named by
+// env (XDG_DATA_HOME or XDG_CONFIG_HOME), or legacy when the variable is unset
+// or not an absolute path, as the XDG spec requires. An install made before
+// the variable was set keeps working: when legacy contains marker and the XDG
+// directory doesn't, legacy is returned and stale reports it so the caller can
+// suggest moving it.
+func XDGDir(env, legacy, marker string) (dir string, stale bool) {
+	base := os.Getenv(env)
+	if base == "" || !filepath.IsAbs(base) {
+		return legacy, false
+	}
+
+	dir = filepath.Join(base, "ccdash")
+	if _, err := os.Stat(filepath.Join(dir, marker)); err == nil {
+		return dir, false
+	}
+	if _, err := os.Stat(filepath.Join(legacy, marker)); err == nil {
+		return legacy, true
+	}
+	return dir, false
+}
+
+// dataDirOverride replaces the default data directory (config hooks_dir)
+var dataDirOverride string
+
+// SetDataDir sets the directory DataDir returns. Relative paths resolve
+// against the working directory. Call before creating any collectors.
+func SetDataDir(dir string) {
+	dataDirOverride = dir
+}
+
+// DataDir returns the directory for hook scripts, session files and the log:
+// the hooks_dir setting, else $XDG_DATA_HOME/ccdash, or ~/.ccdash when
+// XDG_DATA_HOME is unset or hooks were installed in ~/.ccdash before it was set
+func DataDir() (dir string, stale bool) {
+	if dataDirOverride != "" {
+		if abs, err := filepath.Abs(dataDirOverride); err == nil {
+			return abs, false
+		}
+		return dataDirOverride, false
+	}
+
+	home, err := os.UserHomeDir()
+	if err != nil {
+		home = "."
+	}
+	return XDGDir("XDG_DATA_HOME", filepath.Join(home, HooksDir), SessionsSubdir)
+}
+
+// CacheDir returns the directory NewTokenCache uses. The default .ccdash in
+// the working directory moves to $XDG_DATA_HOME/ccdash when XDG_DATA_HOME is
+// set, unless the working directory already holds a cache; a cache_dir
+// setting always wins.
+func CacheDir() (dir string, stale bool) {
+	dir = cacheDirName
+	if cacheDirOverride != "" {
+		dir = cacheDirOverride
+	}
+	if filepath.IsAbs(dir) {
+		return dir, false
+	}
+
+	// Get directory where binary is invoked (current working directory)
+	cwd, err := os.Getwd()
+	if err != nil {
+		cwd = "."
+	}
+	dir = filepath.Join(cwd, dir)
+	if cacheDirOverride != "" && cacheDirOverride != cacheDirName {
+		return dir, false
+	}
+	return XDGDir("XDG_DATA_HOME", dir, cacheDBName)
+}
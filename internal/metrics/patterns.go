@@ -0,0 +1,79 @@
+package metrics
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// PatternsFile is the name of the custom status pattern file in ~/.ccdash
+const PatternsFile = "patterns.json"
+
+// StatusPatterns holds user-supplied pane content patterns that are checked in
+// addition to the built-in Claude Code indicators. Patterns are plain,
+// case-sensitive substrings.
+//
+// Example ~/.ccdash/patterns.json:
+//
+//	{
+//	  "working": ["Denkt nach", "agent: running"],
+//	  "waiting": ["awaiting approval"],
+//	  "error":   ["quota exceeded"]
+//	}
+type StatusPatterns struct {
+	Working []string `json:"working"`
+	Waiting []string `json:"waiting"`
+	Error   []string `json:"error"`
+}
+
+// LoadStatusPatterns reads custom status patterns from a JSON file.
+// Unknown keys and entries that aren't non-empty strings are ignored, so one
+// bad entry doesn't discard the rest of the file.
+func LoadStatusPatterns(path string) (*StatusPatterns, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var raw map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("invalid patterns file %s: %w", path, err)
+	}
+
+	return &StatusPatterns{
+		Working: parsePatternList(raw["working"]),
+		Waiting: parsePatternList(raw["waiting"]),
+		Error:   parsePatternList(raw["error"]),
+	}, nil
+}
+
+// parsePatternList extracts the non-empty strings from a JSON array
+func parsePatternList(data json.RawMessage) []string {
+	if len(data) == 0 {
+		return nil
+	}
+
+	var entries []interface{}
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil
+	}
+
+	var patterns []string
+	for _, entry := range entries {
+		if pattern, ok := entry.(string); ok && strings.TrimSpace(pattern) != "" {
+			patterns = append(patterns, pattern)
+		}
+	}
+	return patterns
+}
+
+// containsAny reports whether content contains any of the patterns
+func containsAny(content string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if strings.Contains(content, pattern) {
+			return true
+		}
+	}
+	return false
+}
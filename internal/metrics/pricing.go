@@ -0,0 +1,209 @@
+package metrics
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// PricingCacheFile is the copy of the last pricing fetched from
+	// pricing_url, kept in the data directory
+	PricingCacheFile = "pricing-cache.json"
+
+	// pricingCacheTTL is how long a cached copy is used before it's fetched again
+	pricingCacheTTL = 24 * time.Hour
+
+	// pricingFetchTimeout bounds the fetch at startup, so an unreachable
+	// URL only delays ccdash by this much before the cache or built-ins are used
+	pricingFetchTimeout = 5 * time.Second
+
+	// maxPricingSize bounds the response read from pricing_url
+	maxPricingSize = 1 << 20
+)
+
+// remotePricing holds rates loaded from pricing_url, keyed by model ID or
+// ID prefix. They take precedence over modelPricing. Set by LoadPricing
+// before any collector is created.
+var remotePricing map[string]ModelPricing
+
+// pricingFile is the pricing JSON format, in US dollars per million tokens:
+//
+//	{
+//	  "models": {
+//	    "claude-opus-4-5": {"input": 5, "output": 25, "cache_read": 0.5, "cache_write": 6.25},
+//	    "claude-sonnet-4-5": {"input": 3, "output": 15, "cache_read": 0.3, "cache_write": 3.75}
+//	  }
+//	}
+//
+// A key matches the model ID it names exactly, or any model ID it's a prefix
+// of, the longest key winning. input and output are required; the cache
+// rates default to 0.
+type pricingFile struct {
+	Models map[string]pricingRates `json:"models"`
+}
+
+type pricingRates struct {
+	Input      *float64 `json:"input"`
+	Output     *float64 `json:"output"`
+	CacheRead  float64  `json:"cache_read"`
+	CacheWrite float64  `json:"cache_write"`
+}
+
+// pricingCache is the file written to PricingCacheFile
+type pricingCache struct {
+	URL     string          `json:"url"`
+	Fetched time.Time       `json:"fetched"`
+	Pricing json.RawMessage `json:"pricing"`
+}
+
+// ParsePricing parses pricing JSON (see pricingFile) into rates by model
+func ParsePricing(data []byte) (map[string]ModelPricing, error) {
+	var file pricingFile
+	if err := json.Unmarshal(data, &file); err != nil {
+		return nil, fmt.Errorf("invalid pricing JSON: %w", err)
+	}
+	if len(file.Models) == 0 {
+		return nil, errors.New("pricing JSON has no models")
+	}
+
+	pricing := make(map[string]ModelPricing, len(file.Models))
+	for model, rates := range file.Models {
+		if model == "" {
+			return nil, errors.New("pricing JSON has a model with an empty name")
+		}
+		if rates.Input == nil || rates.Output == nil {
+			return nil, fmt.Errorf("model %q: input and output rates are required", model)
+		}
+		p := ModelPricing{
+			InputPerMillion:       *rates.Input,
+			OutputPerMillion:      *rates.Output,
+			CacheReadPerMillion:   rates.CacheRead,
+			CacheCreatePerMillion: rates.CacheWrite,
+		}
+		if p.InputPerMillion < 0 || p.OutputPerMillion < 0 || p.CacheReadPerMillion < 0 || p.CacheCreatePerMillion < 0 {
+			return nil, fmt.Errorf("model %q: rates can't be negative", model)
+		}
+		pricing[model] = p
+	}
+	return pricing, nil
+}
+
+// LoadPricing loads rates from the pricing JSON at url, which take precedence
+// over the built-in ones. A copy in cacheDir younger than a day is used
+// without fetching. When the fetch fails, an older copy is used if there is
+// one, else the built-in rates; the error says which.
+func LoadPricing(url, cacheDir string) error {
+	return loadPricing(url, filepath.Join(cacheDir, PricingCacheFile), time.Now())
+}
+
+func loadPricing(url, cachePath string, now time.Time) error {
+	cached, fetched, cacheErr := readPricingCache(cachePath, url)
+	if cacheErr == nil && now.Sub(fetched) < pricingCacheTTL {
+		remotePricing = cached
+		return nil
+	}
+
+	data, pricing, err := fetchPricing(url)
+	if err == nil {
+		remotePricing = pricing
+		// A cache that can't be written only means fetching again next time
+		writePricingCache(cachePath, pricingCache{URL: url, Fetched: now, Pricing: data})
+		return nil
+	}
+
+	if cacheErr == nil {
+		remotePricing = cached
+		return fmt.Errorf("%v; using rates cached %s ago", err, FormatDuration(now.Sub(fetched)))
+	}
+	return fmt.Errorf("%v; using built-in rates", err)
+}
+
+// fetchPricing downloads and parses the pricing JSON at url
+func fetchPricing(url string) ([]byte, map[string]ModelPricing, error) {
+	client := &http.Client{Timeout: pricingFetchTimeout}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch pricing: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, nil, fmt.Errorf("fetch pricing: %s returned status %d", url, resp.StatusCode)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxPricingSize))
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch pricing: %w", err)
+	}
+	pricing, err := ParsePricing(data)
+	if err != nil {
+		return nil, nil, fmt.Errorf("fetch pricing from %s: %w", url, err)
+	}
+	return data, pricing, nil
+}
+
+// readPricingCache returns the cached rates and when they were fetched. A
+// copy fetched from a different URL doesn't count.
+func readPricingCache(path, url string) (map[string]ModelPricing, time.Time, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	var cache pricingCache
+	if err := json.Unmarshal(data, &cache); err != nil {
+		return nil, time.Time{}, err
+	}
+	if cache.URL != url {
+		return nil, time.Time{}, errors.New("pricing cache is for another URL")
+	}
+	pricing, err := ParsePricing(cache.Pricing)
+	if err != nil {
+		return nil, time.Time{}, err
+	}
+	return pricing, cache.Fetched, nil
+}
+
+// writePricingCache replaces the cache file, via a temporary file so a
+// concurrent start never reads half of it
+func writePricingCache(path string, cache pricingCache) error {
+	data, err := json.Marshal(cache)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+	tmpPath := path + ".tmp"
+	if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+		return err
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// lookupRemotePricing returns the rates loaded from pricing_url for model:
+// an exact match, else the longest key the model ID starts with
+func lookupRemotePricing(model string) (ModelPricing, bool) {
+	if pricing, ok := remotePricing[model]; ok {
+		return pricing, true
+	}
+	best := ""
+	for key := range remotePricing {
+		if strings.HasPrefix(model, key) && len(key) > len(best) {
+			best = key
+		}
+	}
+	if best == "" {
+		return ModelPricing{}, false
+	}
+	return remotePricing[best], true
+}
@@ -0,0 +1,122 @@
+package metrics
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParsePricing(t *testing.T) {
+	pricing, err := ParsePricing([]byte(`{"models": {
+		"claude-sonnet-4-5": {"input": 3, "output": 15, "cache_read": 0.3, "cache_write": 3.75},
+		"claude-next": {"input": 2, "output": 8}
+	}}`))
+	if err != nil {
+		t.Fatalf("ParsePricing failed: %v", err)
+	}
+	want := ModelPricing{InputPerMillion: 3, OutputPerMillion: 15, CacheReadPerMillion: 0.3, CacheCreatePerMillion: 3.75}
+	if pricing["claude-sonnet-4-5"] != want {
+		t.Errorf("Expected %+v, got %+v", want, pricing["claude-sonnet-4-5"])
+	}
+	if p := pricing["claude-next"]; p.CacheReadPerMillion != 0 || p.OutputPerMillion != 8 {
+		t.Errorf("Expected cache rates to default to 0, got %+v", p)
+	}
+
+	tests := []struct {
+		name    string
+		data    string
+		wantErr string
+	}{
+		{"not json", `rates`, "invalid pricing JSON"},
+		{"no models", `{"models": {}}`, "no models"},
+		{"missing output", `{"models": {"m": {"input": 1}}}`, "input and output rates are required"},
+		{"negative", `{"models": {"m": {"input": 1, "output": 2, "cache_read": -1}}}`, "can't be negative"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParsePricing([]byte(tt.data))
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Expected error containing %q, got %v", tt.wantErr, err)
+			}
+		})
+	}
+}
+
+func TestLoadPricing(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+	defer func() { remotePricing = nil }()
+
+	fetches := 0
+	body := `{"models": {"claude-sonnet-4-5": {"input": 4, "output": 20}, "claude-sonnet-4-5-2025": {"input": 6, "output": 30}}}`
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+
+	cachePath := filepath.Join(tmpDir, PricingCacheFile)
+	now := time.Now()
+	if err := loadPricing(server.URL, cachePath, now); err != nil {
+		t.Fatalf("loadPricing failed: %v", err)
+	}
+	if fetches != 1 {
+		t.Fatalf("Expected 1 fetch, got %d", fetches)
+	}
+	// The longest matching prefix wins; unlisted models keep the built-in rates
+	if got := getPricingForModel("claude-sonnet-4-5-20250929").InputPerMillion; got != 6 {
+		t.Errorf("Expected the longest prefix's input rate 6, got %v", got)
+	}
+	if got := getPricingForModel("claude-sonnet-4-5").InputPerMillion; got != 4 {
+		t.Errorf("Expected the exact match's input rate 4, got %v", got)
+	}
+	if got := getPricingForModel("claude-haiku-4-5-20250929"); got != modelPricing["claude-haiku-4-5-20250929"] {
+		t.Errorf("Expected built-in haiku rates, got %+v", got)
+	}
+
+	// Within the TTL the cache is used without fetching
+	remotePricing = nil
+	if err := loadPricing(server.URL, cachePath, now.Add(time.Hour)); err != nil {
+		t.Fatalf("loadPricing from cache failed: %v", err)
+	}
+	if fetches != 1 || getPricingForModel("claude-sonnet-4-5").InputPerMillion != 4 {
+		t.Errorf("Expected cached rates without a fetch, got %d fetches", fetches)
+	}
+
+	// Past the TTL it's fetched again
+	body = `{"models": {"claude-sonnet-4-5": {"input": 5, "output": 25}}}`
+	if err := loadPricing(server.URL, cachePath, now.Add(2*pricingCacheTTL)); err != nil {
+		t.Fatalf("loadPricing refetch failed: %v", err)
+	}
+	if fetches != 2 || getPricingForModel("claude-sonnet-4-5").InputPerMillion != 5 {
+		t.Errorf("Expected refetched rates, got %d fetches", fetches)
+	}
+
+	// Offline with a stale cache: the cached rates are kept and the error says so
+	server.Close()
+	remotePricing = nil
+	err = loadPricing(server.URL, cachePath, now.Add(10*pricingCacheTTL))
+	if err == nil || !strings.Contains(err.Error(), "using rates cached") {
+		t.Errorf("Expected a note about cached rates, got %v", err)
+	}
+	if got := getPricingForModel("claude-sonnet-4-5").InputPerMillion; got != 5 {
+		t.Errorf("Expected stale cached input rate 5, got %v", got)
+	}
+
+	// Offline without a cache: built-in rates
+	remotePricing = nil
+	err = loadPricing(server.URL, filepath.Join(tmpDir, "missing", PricingCacheFile), now)
+	if err == nil || !strings.Contains(err.Error(), "using built-in rates") {
+		t.Errorf("Expected a note about built-in rates, got %v", err)
+	}
+	if got := getPricingForModel("claude-sonnet-4-5-20250929"); got != modelPricing["claude-sonnet-4-5-20250929"] {
+		t.Errorf("Expected built-in sonnet rates, got %+v", got)
+	}
+}
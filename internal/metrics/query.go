@@ -0,0 +1,155 @@
+package metrics
+
+import (
+	"context"
+	"database/sql"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// readOnlyStatements are the statements Query accepts, by first keyword
+var readOnlyStatements = []string{"SELECT", "WITH", "EXPLAIN", "VALUES"}
+
+// QueryResult is the outcome of an ad-hoc query: column names and each row's
+// values, formatted as text
+type QueryResult struct {
+	Columns []string
+	Rows    [][]QueryValue
+}
+
+// QueryValue is one cell of a query result. Null is set for SQL NULL, when
+// Text is empty.
+type QueryValue struct {
+	Text string
+	Null bool
+}
+
+// Query runs a read-only SQL statement against the cache, for `ccdash query`.
+// Only statements starting with SELECT, WITH, EXPLAIN or VALUES are accepted,
+// and the connection is switched to query_only, so SQLite refuses writes
+// hidden in a CTE or a second statement too.
+func (tc *TokenCache) Query(ctx context.Context, query string) (*QueryResult, error) {
+	if tc.db == nil {
+		return nil, fmt.Errorf("database not initialized")
+	}
+	if err := checkReadOnlyQuery(query); err != nil {
+		return nil, err
+	}
+
+	// The pragma applies to one connection, so keep the query on it
+	conn, err := tc.db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	if _, err := conn.ExecContext(ctx, "PRAGMA query_only = ON"); err != nil {
+		return nil, err
+	}
+	// Connections go back to the pool, which the rest of the cache writes through
+	defer conn.ExecContext(context.Background(), "PRAGMA query_only = OFF")
+
+	rows, err := conn.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	result := &QueryResult{Columns: columns}
+	values := make([]any, len(columns))
+	ptrs := make([]any, len(columns))
+	for i := range values {
+		ptrs[i] = &values[i]
+	}
+	for rows.Next() {
+		if err := rows.Scan(ptrs...); err != nil {
+			return nil, err
+		}
+		row := make([]QueryValue, len(values))
+		for i, v := range values {
+			row[i] = formatQueryValue(v)
+		}
+		result.Rows = append(result.Rows, row)
+	}
+	return result, rows.Err()
+}
+
+// checkReadOnlyQuery rejects statements Query doesn't run, with a reason
+func checkReadOnlyQuery(query string) error {
+	fields := strings.Fields(strings.TrimLeft(query, "( \t\r\n"))
+	if len(fields) == 0 {
+		return fmt.Errorf("empty query")
+	}
+	keyword := strings.ToUpper(strings.TrimRight(fields[0], "(;"))
+	for _, allowed := range readOnlyStatements {
+		if keyword == allowed {
+			return nil
+		}
+	}
+	return fmt.Errorf("only read-only queries are allowed (%s), not %s", strings.Join(readOnlyStatements, ", "), keyword)
+}
+
+// formatQueryValue turns a scanned column value into text. BLOBs that aren't
+// UTF-8 are written as SQL hex literals, e.g. x'00ff'.
+func formatQueryValue(v any) QueryValue {
+	switch v := v.(type) {
+	case nil:
+		return QueryValue{Null: true}
+	case string:
+		return QueryValue{Text: v}
+	case []byte:
+		if utf8.Valid(v) {
+			return QueryValue{Text: string(v)}
+		}
+		return QueryValue{Text: "x'" + hex.EncodeToString(v) + "'"}
+	case int64:
+		return QueryValue{Text: strconv.FormatInt(v, 10)}
+	case float64:
+		return QueryValue{Text: strconv.FormatFloat(v, 'f', -1, 64)}
+	case bool:
+		if v {
+			return QueryValue{Text: "1"}
+		}
+		return QueryValue{Text: "0"}
+	case time.Time:
+		return QueryValue{Text: v.Format(time.RFC3339Nano)}
+	default:
+		return QueryValue{Text: fmt.Sprint(v)}
+	}
+}
+
+// Schema returns the SQL that creates the cache's tables and indexes, tables
+// first, each statement ending in a semicolon
+func (tc *TokenCache) Schema(ctx context.Context) (string, error) {
+	if tc.db == nil {
+		return "", fmt.Errorf("database not initialized")
+	}
+
+	rows, err := tc.db.QueryContext(ctx, `
+		SELECT sql FROM sqlite_master
+		WHERE sql IS NOT NULL AND name NOT LIKE 'sqlite_%'
+		ORDER BY type = 'table' DESC, name
+	`)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	var b strings.Builder
+	for rows.Next() {
+		var stmt sql.NullString
+		if err := rows.Scan(&stmt); err != nil {
+			return "", err
+		}
+		b.WriteString(strings.TrimSpace(stmt.String))
+		b.WriteString(";\n")
+	}
+	return b.String(), rows.Err()
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTokenCacheQuery(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	ts := time.Date(2025, 11, 3, 9, 0, 0, 0, time.UTC)
+	if err := tc.InsertTokenEvent(ts, "claude-sonnet-4", 100, 50, 0, 0, "a.jsonl", 1); err != nil {
+		t.Fatalf("InsertTokenEvent: %v", err)
+	}
+
+	ctx := context.Background()
+	result, err := tc.Query(ctx, "SELECT model, input_tokens + output_tokens AS total, NULL AS missing, 1.5, x'00ff' FROM token_events")
+	if err != nil {
+		t.Fatalf("Query: %v", err)
+	}
+	if got := strings.Join(result.Columns, ","); got != "model,total,missing,1.5,x'00ff'" {
+		t.Errorf("Expected columns model,total,missing,1.5,x'00ff', got %s", got)
+	}
+	if len(result.Rows) != 1 {
+		t.Fatalf("Expected 1 row, got %d", len(result.Rows))
+	}
+	want := []QueryValue{{Text: "claude-sonnet-4"}, {Text: "150"}, {Null: true}, {Text: "1.5"}, {Text: "x'00ff'"}}
+	for i, v := range result.Rows[0] {
+		if v != want[i] {
+			t.Errorf("Column %s: expected %+v, got %+v", result.Columns[i], want[i], v)
+		}
+	}
+
+	// Writes are refused, by keyword and by SQLite for ones a keyword check misses
+	for _, query := range []string{
+		"DELETE FROM token_events",
+		"  drop table token_events",
+		"WITH doomed AS (SELECT 1) DELETE FROM token_events",
+		"",
+	} {
+		if _, err := tc.Query(ctx, query); err == nil {
+			t.Errorf("Expected %q to be rejected", query)
+		}
+	}
+	if count, _, _ := tc.GetStats(); count != 1 {
+		t.Errorf("Expected the event to survive rejected writes, got %d events", count)
+	}
+
+	// The cache's own writes still work once a query is done
+	if err := tc.InsertTokenEvent(ts, "claude-sonnet-4", 1, 1, 0, 0, "a.jsonl", 2); err != nil {
+		t.Errorf("Expected the cache to stay writable after a query, got %v", err)
+	}
+}
+
+func TestTokenCacheSchema(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	tc := NewTokenCacheWithDir(tmpDir)
+	defer tc.Close()
+
+	schema, err := tc.Schema(context.Background())
+	if err != nil {
+		t.Fatalf("Schema: %v", err)
+	}
+	for _, want := range []string{"CREATE TABLE token_events", "CREATE INDEX idx_timestamp_unix"} {
+		if !strings.Contains(schema, want) {
+			t.Errorf("Expected schema to contain %q, got:\n%s", want, schema)
+		}
+	}
+	if strings.Contains(schema, "sqlite_sequence") {
+		t.Errorf("Expected SQLite's internal tables to be left out, got:\n%s", schema)
+	}
+}
@@ -1,6 +1,7 @@
 package metrics
 
 import (
+	"context"
 	"fmt"
 	"time"
 
@@ -17,7 +18,8 @@ type SystemMetrics struct {
 	Load       LoadMetrics
 	Memory     MemoryMetrics
 	Swap       SwapMetrics
-	DiskUsage  DiskUsageMetrics
+	DiskUsage  DiskUsageMetrics   // First monitored path (root by default)
+	DiskUsages []DiskUsageMetrics // All monitored paths, in configured order
 	DiskIO     DiskIOMetrics
 	NetIO      NetIOMetrics
 	LastUpdate time.Time
@@ -44,6 +46,22 @@ type MemoryMetrics struct {
 	Total      uint64
 	Percentage float64
 	Error      error
+
+	// Available is what new processes can use without swapping, including
+	// reclaimable page cache; 0 where the platform doesn't report it
+	Available uint64
+	Cached    uint64
+	Buffers   uint64
+}
+
+// UnavailablePercent is the share of memory that isn't available, which unlike
+// Percentage doesn't count reclaimable page cache as taken. It falls back to
+// Percentage when Available isn't known.
+func (m MemoryMetrics) UnavailablePercent() float64 {
+	if m.Available == 0 || m.Total == 0 || m.Available > m.Total {
+		return m.Percentage
+	}
+	return float64(m.Total-m.Available) / float64(m.Total) * 100
 }
 
 // SwapMetrics holds swap usage information
@@ -86,6 +104,10 @@ type NetIOMetrics struct {
 	SentBytesPerSec float64
 	Interfaces      []NetInterface
 	Error           error
+
+	// Primary is the interface with the most traffic, e.g. "eth0"; empty
+	// when there are no interfaces
+	Primary string
 }
 
 // SystemCollector collects system metrics
@@ -96,19 +118,60 @@ type SystemCollector struct {
 	// Previous network I/O counters for rate calculation (per-interface)
 	prevNetCounters map[string]net.IOCountersStat
 	prevNetTime     time.Time
+	// Previous per-core CPU times for utilization between collections
+	prevCPUTimes []cpu.TimesStat
+	// Filesystem paths monitored for disk capacity
+	diskPaths []string
+	// Weight of the previous reading when smoothing CPU percentages; 0 is off
+	cpuSmoothing float64
+	// Smoothed per-core CPU percentages from the previous collection
+	smoothedPerCore []float64
 }
 
 // NewSystemCollector creates a new SystemCollector instance
 func NewSystemCollector() *SystemCollector {
-	return &SystemCollector{
+	sc := &SystemCollector{
 		prevIOCounters:  make(map[string]disk.IOCountersStat),
 		prevNetCounters: make(map[string]net.IOCountersStat),
 		prevIOTime:      time.Now(),
+		diskPaths:       []string{"/"},
 	}
+	// Baseline for the first CPU reading
+	sc.prevCPUTimes, _ = cpu.Times(true)
+	return sc
+}
+
+// SetDiskPaths sets the filesystem paths monitored for disk capacity.
+// An empty list keeps the default of the root filesystem.
+func (sc *SystemCollector) SetDiskPaths(paths []string) {
+	if len(paths) == 0 {
+		paths = []string{"/"}
+	}
+	sc.diskPaths = paths
+}
+
+// SetCPUSmoothing smooths CPU percentages across collections with an
+// exponential moving average: each reading is factor times the previous
+// reading plus (1 - factor) times the new sample. 0 turns smoothing off; values
+// closer to 1 give calmer but slower bars.
+func (sc *SystemCollector) SetCPUSmoothing(factor float64) error {
+	if factor < 0 || factor >= 1 {
+		return fmt.Errorf("CPU smoothing must be at least 0 and below 1, got %v", factor)
+	}
+	sc.cpuSmoothing = factor
+	sc.smoothedPerCore = nil
+	return nil
 }
 
 // Collect gathers all system metrics
 func (sc *SystemCollector) Collect() SystemMetrics {
+	return sc.CollectContext(context.Background())
+}
+
+// CollectContext is Collect bounded by ctx. A slow filesystem can still block
+// an individual reading, but once ctx is done the remaining disk paths are
+// skipped and reported as timed out.
+func (sc *SystemCollector) CollectContext(ctx context.Context) SystemMetrics {
 	now := time.Now()
 
 	metrics := SystemMetrics{
@@ -116,39 +179,47 @@ func (sc *SystemCollector) Collect() SystemMetrics {
 	}
 
 	// Collect CPU metrics
-	metrics.CPU = sc.collectCPU()
+	metrics.CPU = sc.collectCPU(ctx)
 
 	// Collect load averages
-	metrics.Load = sc.collectLoad()
+	metrics.Load = sc.collectLoad(ctx)
 
 	// Collect memory metrics
-	metrics.Memory = sc.collectMemory()
+	metrics.Memory = sc.collectMemory(ctx)
 
 	// Collect swap metrics
-	metrics.Swap = sc.collectSwap()
+	metrics.Swap = sc.collectSwap(ctx)
 
-	// Collect disk usage metrics
-	metrics.DiskUsage = sc.collectDiskUsage()
+	// Collect disk usage metrics for every monitored path
+	for _, path := range sc.diskPaths {
+		metrics.DiskUsages = append(metrics.DiskUsages, sc.collectDiskUsage(ctx, path))
+	}
+	if len(metrics.DiskUsages) > 0 {
+		metrics.DiskUsage = metrics.DiskUsages[0]
+	}
 
 	// Collect disk I/O metrics
-	metrics.DiskIO = sc.collectDiskIO()
+	metrics.DiskIO = sc.collectDiskIO(ctx)
 
 	// Collect network I/O metrics
-	metrics.NetIO = sc.collectNetIO()
+	metrics.NetIO = sc.collectNetIO(ctx)
 
 	return metrics
 }
 
-// collectCPU collects CPU usage metrics
-func (sc *SystemCollector) collectCPU() CPUMetrics {
+// collectCPU collects CPU usage metrics. Utilization is computed from the
+// change in per-core CPU times since the previous call (like disk and network
+// I/O), so it never blocks; the first call measures since NewSystemCollector.
+func (sc *SystemCollector) collectCPU(ctx context.Context) CPUMetrics {
 	cpuMetrics := CPUMetrics{}
 
-	// Get per-core CPU percentages (1 second interval)
-	perCore, err := cpu.Percent(time.Second, true)
+	times, err := cpu.TimesWithContext(ctx, true)
 	if err != nil {
 		cpuMetrics.Error = fmt.Errorf("failed to collect per-core CPU: %w", err)
 		return cpuMetrics
 	}
+
+	perCore := sc.cpuSample(times)
 	cpuMetrics.PerCore = perCore
 
 	// Calculate total CPU percentage as average of all cores
@@ -163,11 +234,69 @@ func (sc *SystemCollector) collectCPU() CPUMetrics {
 	return cpuMetrics
 }
 
+// cpuSample returns per-core utilization since the previous CPU times, and
+// keeps times for the next call. A sample with no time elapsed on any core,
+// like the first collection right after NewSystemCollector, reads all zeros
+// and is left out of smoothing, so the first real reading seeds it.
+func (sc *SystemCollector) cpuSample(times []cpu.TimesStat) []float64 {
+	perCore := make([]float64, len(times))
+	measured := false
+	for i, t := range times {
+		if i < len(sc.prevCPUTimes) && cpuTotalTime(t) > cpuTotalTime(sc.prevCPUTimes[i]) {
+			perCore[i] = cpuBusyPercent(sc.prevCPUTimes[i], t)
+			measured = true
+		}
+	}
+	sc.prevCPUTimes = times
+	if !measured {
+		return perCore
+	}
+	return sc.smoothCPU(perCore)
+}
+
+// smoothCPU applies CPU smoothing to a new per-core sample. The first sample,
+// or one after the core count changed, is taken as is.
+func (sc *SystemCollector) smoothCPU(perCore []float64) []float64 {
+	if sc.cpuSmoothing == 0 {
+		return perCore
+	}
+	if len(sc.smoothedPerCore) == len(perCore) {
+		for i, pct := range perCore {
+			perCore[i] = sc.cpuSmoothing*sc.smoothedPerCore[i] + (1-sc.cpuSmoothing)*pct
+		}
+	}
+	sc.smoothedPerCore = append(sc.smoothedPerCore[:0], perCore...)
+	return perCore
+}
+
+// cpuBusyPercent returns the share of time a core was busy between two
+// samples, clamped to 0-100. Idle and iowait count as idle, matching gopsutil.
+func cpuBusyPercent(prev, cur cpu.TimesStat) float64 {
+	totalDelta := cpuTotalTime(cur) - cpuTotalTime(prev)
+	if totalDelta <= 0 {
+		return 0
+	}
+	idleDelta := (cur.Idle + cur.Iowait) - (prev.Idle + prev.Iowait)
+	pct := (totalDelta - idleDelta) / totalDelta * 100
+	if pct < 0 {
+		return 0
+	}
+	if pct > 100 {
+		return 100
+	}
+	return pct
+}
+
+// cpuTotalTime returns the CPU time a core has spent in every state
+func cpuTotalTime(t cpu.TimesStat) float64 {
+	return t.User + t.System + t.Idle + t.Nice + t.Iowait + t.Irq + t.Softirq + t.Steal
+}
+
 // collectLoad collects system load averages
-func (sc *SystemCollector) collectLoad() LoadMetrics {
+func (sc *SystemCollector) collectLoad(ctx context.Context) LoadMetrics {
 	loadMetrics := LoadMetrics{}
 
-	loadAvg, err := load.Avg()
+	loadAvg, err := load.AvgWithContext(ctx)
 	if err != nil {
 		loadMetrics.Error = fmt.Errorf("failed to collect load averages: %w", err)
 		return loadMetrics
@@ -181,10 +310,10 @@ func (sc *SystemCollector) collectLoad() LoadMetrics {
 }
 
 // collectMemory collects memory usage metrics
-func (sc *SystemCollector) collectMemory() MemoryMetrics {
+func (sc *SystemCollector) collectMemory(ctx context.Context) MemoryMetrics {
 	memMetrics := MemoryMetrics{}
 
-	vmem, err := mem.VirtualMemory()
+	vmem, err := mem.VirtualMemoryWithContext(ctx)
 	if err != nil {
 		memMetrics.Error = fmt.Errorf("failed to collect memory metrics: %w", err)
 		return memMetrics
@@ -193,15 +322,18 @@ func (sc *SystemCollector) collectMemory() MemoryMetrics {
 	memMetrics.Used = vmem.Used
 	memMetrics.Total = vmem.Total
 	memMetrics.Percentage = vmem.UsedPercent
+	memMetrics.Available = vmem.Available
+	memMetrics.Cached = vmem.Cached
+	memMetrics.Buffers = vmem.Buffers
 
 	return memMetrics
 }
 
 // collectSwap collects swap usage metrics
-func (sc *SystemCollector) collectSwap() SwapMetrics {
+func (sc *SystemCollector) collectSwap(ctx context.Context) SwapMetrics {
 	swapMetrics := SwapMetrics{}
 
-	swap, err := mem.SwapMemory()
+	swap, err := mem.SwapMemoryWithContext(ctx)
 	if err != nil {
 		swapMetrics.Error = fmt.Errorf("failed to collect swap metrics: %w", err)
 		return swapMetrics
@@ -214,13 +346,17 @@ func (sc *SystemCollector) collectSwap() SwapMetrics {
 	return swapMetrics
 }
 
-// collectDiskUsage collects disk space usage metrics for root filesystem
-func (sc *SystemCollector) collectDiskUsage() DiskUsageMetrics {
+// collectDiskUsage collects disk space usage metrics for the filesystem containing path
+func (sc *SystemCollector) collectDiskUsage(ctx context.Context, path string) DiskUsageMetrics {
 	diskMetrics := DiskUsageMetrics{
-		Path: "/", // Monitor root filesystem
+		Path: path,
+	}
+	if err := ctx.Err(); err != nil {
+		diskMetrics.Error = fmt.Errorf("skipped disk usage: %w", err)
+		return diskMetrics
 	}
 
-	usage, err := disk.Usage("/")
+	usage, err := disk.UsageWithContext(ctx, path)
 	if err != nil {
 		diskMetrics.Error = fmt.Errorf("failed to collect disk usage: %w", err)
 		return diskMetrics
@@ -235,11 +371,11 @@ func (sc *SystemCollector) collectDiskUsage() DiskUsageMetrics {
 }
 
 // collectDiskIO collects disk I/O rate metrics
-func (sc *SystemCollector) collectDiskIO() DiskIOMetrics {
+func (sc *SystemCollector) collectDiskIO(ctx context.Context) DiskIOMetrics {
 	ioMetrics := DiskIOMetrics{}
 
 	// Get current I/O counters
-	ioCounters, err := disk.IOCounters()
+	ioCounters, err := disk.IOCountersWithContext(ctx)
 	if err != nil {
 		ioMetrics.Error = fmt.Errorf("failed to collect disk I/O: %w", err)
 		return ioMetrics
@@ -271,11 +407,11 @@ func (sc *SystemCollector) collectDiskIO() DiskIOMetrics {
 }
 
 // collectNetIO collects network I/O rate metrics
-func (sc *SystemCollector) collectNetIO() NetIOMetrics {
+func (sc *SystemCollector) collectNetIO(ctx context.Context) NetIOMetrics {
 	netMetrics := NetIOMetrics{}
 
 	// Get current network I/O counters (per-interface)
-	netCounters, err := net.IOCounters(true) // true = per-interface
+	netCounters, err := net.IOCountersWithContext(ctx, true) // true = per-interface
 	if err != nil {
 		netMetrics.Error = fmt.Errorf("failed to collect network I/O: %w", err)
 		return netMetrics
@@ -338,6 +474,7 @@ func (sc *SystemCollector) collectNetIO() NetIOMetrics {
 		}
 		netMetrics.Interfaces = interfaces
 	}
+	netMetrics.Primary = busiestInterface(netMetrics.Interfaces)
 
 	// Store current counters for next collection
 	sc.prevNetCounters = make(map[string]net.IOCountersStat)
@@ -349,6 +486,69 @@ func (sc *SystemCollector) collectNetIO() NetIOMetrics {
 	return netMetrics
 }
 
+// busiestInterface returns the name of the interface moving the most bytes per
+// second. Before there are rates, or when every interface is idle, it's the one
+// that has moved the most bytes since boot. Ties go to the first name
+// alphabetically, so the choice doesn't flicker between refreshes.
+func busiestInterface(interfaces []NetInterface) string {
+	var best *NetInterface
+	for i := range interfaces {
+		iface := &interfaces[i]
+		if best == nil {
+			best = iface
+			continue
+		}
+		rate, bestRate := iface.RecvBytesPerSec+iface.SentBytesPerSec, best.RecvBytesPerSec+best.SentBytesPerSec
+		total, bestTotal := iface.TotalRecvBytes+iface.TotalSentBytes, best.TotalRecvBytes+best.TotalSentBytes
+		switch {
+		case rate != bestRate:
+			if rate > bestRate {
+				best = iface
+			}
+		case total != bestTotal:
+			if total > bestTotal {
+				best = iface
+			}
+		case iface.Name < best.Name:
+			best = iface
+		}
+	}
+	if best == nil {
+		return ""
+	}
+	return best.Name
+}
+
+// Default usage thresholds for bar colors, in percent
+const (
+	DefaultWarnThreshold = 80.0
+	DefaultCritThreshold = 95.0
+)
+
+// GetStatusColor returns the bar color for a usage percentage: red at or above
+// crit, orange at or above warn, yellow from three quarters of warn (60% with
+// the defaults) and green below that
+func GetStatusColor(percent, warn, crit float64) string {
+	switch {
+	case percent >= crit:
+		return "#ff0000" // Red
+	case percent >= warn:
+		return "#ffaa00" // Orange
+	case percent >= warn*0.75:
+		return "#ffff00" // Yellow
+	default:
+		return "#00ff00" // Green
+	}
+}
+
+// ValidateThresholds checks that 0 < warn < crit <= 100
+func ValidateThresholds(warn, crit float64) error {
+	if warn <= 0 || crit > 100 || warn >= crit {
+		return fmt.Errorf("thresholds must satisfy 0 < warn < crit <= 100 (got warn=%g, crit=%g)", warn, crit)
+	}
+	return nil
+}
+
 // FormatBytes formats bytes as human-readable string (KB/MB/GB/TB)
 func FormatBytes(bytes uint64) string {
 	const unit = 1024
@@ -1,8 +1,13 @@
 package metrics
 
 import (
+	"context"
+	"errors"
+	"math"
 	"testing"
 	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
 )
 
 func TestNewSystemCollector(t *testing.T) {
@@ -155,6 +160,33 @@ func TestFormatBytes(t *testing.T) {
 	}
 }
 
+func TestGetStatusColor(t *testing.T) {
+	tests := []struct {
+		name     string
+		percent  float64
+		warn     float64
+		crit     float64
+		expected string
+	}{
+		{"Default green", 59.9, 80, 95, "#00ff00"},
+		{"Default yellow", 60, 80, 95, "#ffff00"},
+		{"Default orange", 80, 80, 95, "#ffaa00"},
+		{"Default red", 95, 80, 95, "#ff0000"},
+		{"Custom warn orange", 70, 70, 90, "#ffaa00"},
+		{"Custom crit red", 90, 70, 90, "#ff0000"},
+		{"Custom below yellow", 52, 70, 90, "#00ff00"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := GetStatusColor(tt.percent, tt.warn, tt.crit)
+			if result != tt.expected {
+				t.Errorf("GetStatusColor(%.1f, %.0f, %.0f) = %s; want %s", tt.percent, tt.warn, tt.crit, result, tt.expected)
+			}
+		})
+	}
+}
+
 func TestFormatRate(t *testing.T) {
 	tests := []struct {
 		name         string
@@ -184,9 +216,9 @@ func TestFormatRate(t *testing.T) {
 
 func TestCollectCPU(t *testing.T) {
 	collector := NewSystemCollector()
-	cpuMetrics := collector.collectCPU()
+	time.Sleep(100 * time.Millisecond) // Let the CPU times advance past the baseline
+	cpuMetrics := collector.collectCPU(context.Background())
 
-	// CPU collection may take time due to 1-second interval
 	if cpuMetrics.Error != nil {
 		t.Logf("CPU collection error (may be expected on some systems): %v", cpuMetrics.Error)
 		return
@@ -215,9 +247,71 @@ func TestCollectCPU(t *testing.T) {
 	}
 }
 
+func TestCPUSmoothingConverges(t *testing.T) {
+	collector := &SystemCollector{}
+	if err := collector.SetCPUSmoothing(1); err == nil {
+		t.Error("Expected a smoothing factor of 1 to be rejected, it would never change")
+	}
+	if err := collector.SetCPUSmoothing(0.5); err != nil {
+		t.Fatalf("SetCPUSmoothing failed: %v", err)
+	}
+
+	// The first sample is taken as is
+	if got := collector.smoothCPU([]float64{0, 80}); got[0] != 0 || got[1] != 80 {
+		t.Fatalf("Expected the first sample unchanged, got %v", got)
+	}
+
+	// A step to 100% approaches it from below, halving the gap each time
+	prev := 0.0
+	for i := 1; i <= 10; i++ {
+		got := collector.smoothCPU([]float64{100, 80})
+		if got[0] <= prev || got[0] >= 100 {
+			t.Fatalf("Sample %d: expected a value between %v and 100, got %v", i, prev, got[0])
+		}
+		if want := 100 - 100/math.Pow(2, float64(i)); math.Abs(got[0]-want) > 1e-9 {
+			t.Errorf("Sample %d: expected %v, got %v", i, want, got[0])
+		}
+		if got[1] != 80 {
+			t.Errorf("Expected a steady core to stay at 80, got %v", got[1])
+		}
+		prev = got[0]
+	}
+	if prev < 99.9 {
+		t.Errorf("Expected the smoothed value to converge on 100, got %v", prev)
+	}
+
+	// Turned off, samples pass through
+	collector.SetCPUSmoothing(0)
+	if got := collector.smoothCPU([]float64{3, 4}); got[0] != 3 || got[1] != 4 {
+		t.Errorf("Expected raw samples with smoothing off, got %v", got)
+	}
+}
+
+func TestCPUSmoothingSkipsEmptySample(t *testing.T) {
+	start := []cpu.TimesStat{{User: 100, Idle: 100}}
+	collector := &SystemCollector{prevCPUTimes: start}
+	if err := collector.SetCPUSmoothing(0.5); err != nil {
+		t.Fatalf("SetCPUSmoothing failed: %v", err)
+	}
+
+	// Collecting right after creation measures no time, so it reads zero
+	// without seeding the average
+	if got := collector.cpuSample(start); got[0] != 0 {
+		t.Fatalf("Expected an empty sample to read 0, got %v", got)
+	}
+
+	// The first real reading, a fully busy core, is taken as is
+	if got := collector.cpuSample([]cpu.TimesStat{{User: 110, Idle: 100}}); got[0] != 100 {
+		t.Errorf("Expected the first real reading to seed the average at 100, got %v", got)
+	}
+	if got := collector.cpuSample([]cpu.TimesStat{{User: 110, Idle: 110}}); got[0] != 50 {
+		t.Errorf("Expected an idle sample to halve the average, got %v", got)
+	}
+}
+
 func TestCollectLoad(t *testing.T) {
 	collector := NewSystemCollector()
-	loadMetrics := collector.collectLoad()
+	loadMetrics := collector.collectLoad(context.Background())
 
 	// Load averages may not be available on all platforms
 	if loadMetrics.Error != nil {
@@ -238,7 +332,7 @@ func TestCollectLoad(t *testing.T) {
 
 func TestCollectMemory(t *testing.T) {
 	collector := NewSystemCollector()
-	memMetrics := collector.collectMemory()
+	memMetrics := collector.collectMemory(context.Background())
 
 	if memMetrics.Error != nil {
 		t.Fatalf("Memory collection failed: %v", memMetrics.Error)
@@ -265,7 +359,7 @@ func TestCollectMemory(t *testing.T) {
 
 func TestCollectSwap(t *testing.T) {
 	collector := NewSystemCollector()
-	swapMetrics := collector.collectSwap()
+	swapMetrics := collector.collectSwap(context.Background())
 
 	if swapMetrics.Error != nil {
 		t.Logf("Swap collection error (may be expected): %v", swapMetrics.Error)
@@ -293,7 +387,7 @@ func TestCollectDiskIO(t *testing.T) {
 	collector := NewSystemCollector()
 
 	// First collection
-	ioMetrics1 := collector.collectDiskIO()
+	ioMetrics1 := collector.collectDiskIO(context.Background())
 	if ioMetrics1.Error != nil {
 		t.Fatalf("First disk I/O collection failed: %v", ioMetrics1.Error)
 	}
@@ -306,7 +400,7 @@ func TestCollectDiskIO(t *testing.T) {
 	// Wait and collect again
 	time.Sleep(500 * time.Millisecond)
 
-	ioMetrics2 := collector.collectDiskIO()
+	ioMetrics2 := collector.collectDiskIO(context.Background())
 	if ioMetrics2.Error != nil {
 		t.Fatalf("Second disk I/O collection failed: %v", ioMetrics2.Error)
 	}
@@ -348,3 +442,123 @@ func BenchmarkFormatRate(b *testing.B) {
 		_ = FormatRate(testValue)
 	}
 }
+
+func TestCollectCPUNonBlocking(t *testing.T) {
+	collector := NewSystemCollector()
+
+	for i := 0; i < 5; i++ {
+		start := time.Now()
+		cpuMetrics := collector.collectCPU(context.Background())
+		elapsed := time.Since(start)
+
+		if cpuMetrics.Error != nil {
+			t.Skipf("CPU collection unavailable: %v", cpuMetrics.Error)
+		}
+		if elapsed > 500*time.Millisecond {
+			t.Errorf("Call %d took %v, expected a non-blocking read", i, elapsed)
+		}
+		if cpuMetrics.TotalPercent < 0 || cpuMetrics.TotalPercent > 100 {
+			t.Errorf("Call %d: invalid TotalPercent %f", i, cpuMetrics.TotalPercent)
+		}
+		for core, pct := range cpuMetrics.PerCore {
+			if pct < 0 || pct > 100 {
+				t.Errorf("Call %d: invalid percentage for core %d: %f", i, core, pct)
+			}
+		}
+
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestCPUBusyPercent(t *testing.T) {
+	prev := cpu.TimesStat{User: 100, System: 50, Idle: 850}
+	tests := []struct {
+		name string
+		cur  cpu.TimesStat
+		want float64
+	}{
+		{"half busy", cpu.TimesStat{User: 140, System: 60, Idle: 900}, 50},
+		{"iowait counts as idle", cpu.TimesStat{User: 100, System: 50, Idle: 850, Iowait: 10}, 0},
+		{"no time elapsed", prev, 0},
+		{"counter went backwards", cpu.TimesStat{User: 90, System: 50, Idle: 840}, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := cpuBusyPercent(prev, tt.cur); got != tt.want {
+				t.Errorf("cpuBusyPercent() = %f, want %f", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMemoryUnavailablePercent(t *testing.T) {
+	tests := []struct {
+		name string
+		mem  MemoryMetrics
+		want float64
+	}{
+		{"page cache not counted", MemoryMetrics{Total: 100, Used: 90, Percentage: 90, Available: 60}, 40},
+		{"available unknown", MemoryMetrics{Total: 100, Used: 90, Percentage: 90}, 90},
+		{"nothing available", MemoryMetrics{Total: 100, Used: 100, Percentage: 100, Available: 100}, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.mem.UnavailablePercent(); got != tt.want {
+				t.Errorf("UnavailablePercent() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectContextSkipsDisksWhenDone(t *testing.T) {
+	collector := NewSystemCollector()
+	collector.SetDiskPaths([]string{"/", "/tmp"})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	metrics := collector.CollectContext(ctx)
+	if len(metrics.DiskUsages) != 2 {
+		t.Fatalf("Expected an entry per disk path, got %d", len(metrics.DiskUsages))
+	}
+	for _, usage := range metrics.DiskUsages {
+		if !errors.Is(usage.Error, context.Canceled) {
+			t.Errorf("Expected %s to be skipped after cancellation, got error %v", usage.Path, usage.Error)
+		}
+	}
+
+	if metrics := collector.Collect(); metrics.DiskUsage.Error != nil {
+		t.Errorf("Expected Collect to read disk usage, got %v", metrics.DiskUsage.Error)
+	}
+}
+
+func TestBusiestInterface(t *testing.T) {
+	tests := []struct {
+		name       string
+		interfaces []NetInterface
+		want       string
+	}{
+		{"none", nil, ""},
+		{"highest rate", []NetInterface{
+			{Name: "eth0", RecvBytesPerSec: 100, TotalRecvBytes: 1 << 30},
+			{Name: "wg0", RecvBytesPerSec: 50, SentBytesPerSec: 80, TotalRecvBytes: 1 << 20},
+		}, "wg0"},
+		{"no rates yet", []NetInterface{
+			{Name: "eth0", TotalRecvBytes: 1 << 20},
+			{Name: "wlan0", TotalRecvBytes: 1 << 30, TotalSentBytes: 1},
+		}, "wlan0"},
+		{"tie", []NetInterface{
+			{Name: "eth1", TotalRecvBytes: 5},
+			{Name: "eth0", TotalRecvBytes: 5},
+		}, "eth0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := busiestInterface(tt.interfaces); got != tt.want {
+				t.Errorf("busiestInterface() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,78 @@
+package metrics
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// timeBoundLayouts are the absolute forms ParseTimeBound accepts, read in the
+// local time zone
+var timeBoundLayouts = []string{
+	"2006-01-02",
+	"2006-01-02T15:04",
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04",
+	"2006-01-02 15:04:05",
+}
+
+// ParseTimeBound parses the start or end of a time range: a time back from now
+// such as 90m, 24h, 7d or 2w, or a date or date and time such as 2025-11-01,
+// 2025-11-01T09:00 or 2025-11-01 09:00:30 in now's time zone. RFC 3339 times
+// with their own zone, like 2025-11-01T09:00:00Z, are also accepted. Days and
+// weeks are calendar days, so 7d is the same time of day a week ago across a
+// DST change.
+func ParseTimeBound(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, fmt.Errorf("empty time")
+	}
+
+	// Relative: a number of days or weeks, or a Go duration
+	if n, unit := s[:len(s)-1], s[len(s)-1]; unit == 'd' || unit == 'w' {
+		if days, err := strconv.Atoi(n); err == nil && days >= 0 {
+			if unit == 'w' {
+				days *= 7
+			}
+			return now.AddDate(0, 0, -days), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		if d < 0 {
+			return time.Time{}, fmt.Errorf("invalid time %q: durations count back from now, so they can't be negative", s)
+		}
+		return now.Add(-d), nil
+	}
+
+	// Absolute
+	for _, layout := range timeBoundLayouts {
+		if t, err := time.ParseInLocation(layout, s, now.Location()); err == nil {
+			return t, nil
+		}
+	}
+	if t, err := time.Parse(time.RFC3339, s); err == nil {
+		return t, nil
+	}
+	return time.Time{}, fmt.Errorf("invalid time %q (want a duration like 24h or 7d, or a date like 2025-11-01 or 2025-11-01T09:00)", s)
+}
+
+// ParseTimeRange parses --since and --until values with ParseTimeBound. An
+// empty value leaves that end open and returns the zero time for it.
+func ParseTimeRange(since, until string, now time.Time) (from, to time.Time, err error) {
+	if since != "" {
+		if from, err = ParseTimeBound(since, now); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--since: %w", err)
+		}
+	}
+	if until != "" {
+		if to, err = ParseTimeBound(until, now); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("--until: %w", err)
+		}
+	}
+	if !from.IsZero() && !to.IsZero() && !from.Before(to) {
+		return time.Time{}, time.Time{}, fmt.Errorf("--since (%s) must be before --until (%s)",
+			from.Format("2006-01-02 15:04"), to.Format("2006-01-02 15:04"))
+	}
+	return from, to, nil
+}
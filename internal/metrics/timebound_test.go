@@ -0,0 +1,102 @@
+package metrics
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseTimeBound(t *testing.T) {
+	tokyo := time.FixedZone("JST", 9*3600)
+	now := time.Date(2025, 11, 8, 14, 30, 0, 0, tokyo)
+
+	tests := []struct {
+		in   string
+		want time.Time
+	}{
+		// Relative to now
+		{"90m", now.Add(-90 * time.Minute)},
+		{"24h", now.Add(-24 * time.Hour)},
+		{"7d", time.Date(2025, 11, 1, 14, 30, 0, 0, tokyo)},
+		{"2w", time.Date(2025, 10, 25, 14, 30, 0, 0, tokyo)},
+		{"0d", now},
+		// Absolute, in now's zone
+		{"2025-11-01", time.Date(2025, 11, 1, 0, 0, 0, 0, tokyo)},
+		{"2025-11-01T09:00", time.Date(2025, 11, 1, 9, 0, 0, 0, tokyo)},
+		{"2025-11-01T09:00:30", time.Date(2025, 11, 1, 9, 0, 30, 0, tokyo)},
+		{"2025-11-01 09:00", time.Date(2025, 11, 1, 9, 0, 0, 0, tokyo)},
+		{" 2025-11-01 ", time.Date(2025, 11, 1, 0, 0, 0, 0, tokyo)},
+		// Absolute with its own zone
+		{"2025-11-01T09:00:00Z", time.Date(2025, 11, 1, 9, 0, 0, 0, time.UTC)},
+		{"2025-11-01T09:00:00-05:00", time.Date(2025, 11, 1, 14, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		got, err := ParseTimeBound(tt.in, now)
+		if err != nil {
+			t.Errorf("ParseTimeBound(%q) failed: %v", tt.in, err)
+			continue
+		}
+		if !got.Equal(tt.want) {
+			t.Errorf("ParseTimeBound(%q) = %v, want %v", tt.in, got, tt.want)
+		}
+	}
+
+	for _, in := range []string{"", "-24h", "-3d", "yesterday", "3x", "d", "2025-13-01", "2025-11-01T25:00", "11/01/2025"} {
+		if got, err := ParseTimeBound(in, now); err == nil {
+			t.Errorf("ParseTimeBound(%q) = %v, want an error", in, got)
+		}
+	}
+}
+
+func TestParseTimeBoundAcrossDST(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Skipf("No time zone data: %v", err)
+	}
+	// DST ended on 2025-11-02, so that day had 25 hours
+	now := time.Date(2025, 11, 5, 9, 0, 0, 0, ny)
+
+	days, err := ParseTimeBound("7d", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 10, 29, 9, 0, 0, 0, ny); !days.Equal(want) {
+		t.Errorf("Expected 7d to be 9am a week ago, got %v", days)
+	}
+	hours, err := ParseTimeBound("168h", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := time.Date(2025, 10, 29, 10, 0, 0, 0, ny); !hours.Equal(want) {
+		t.Errorf("Expected 168h to be exactly 168 hours back, got %v", hours)
+	}
+
+	date, err := ParseTimeBound("2025-11-01", now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if date.Location() != ny || date.Hour() != 0 || date.UTC().Hour() != 4 {
+		t.Errorf("Expected midnight New York time, got %v", date)
+	}
+}
+
+func TestParseTimeRange(t *testing.T) {
+	now := time.Date(2025, 11, 8, 14, 30, 0, 0, time.UTC)
+
+	from, to, err := ParseTimeRange("2025-11-01", "24h", now)
+	if err != nil {
+		t.Fatalf("ParseTimeRange failed: %v", err)
+	}
+	if !from.Equal(time.Date(2025, 11, 1, 0, 0, 0, 0, time.UTC)) || !to.Equal(now.Add(-24*time.Hour)) {
+		t.Errorf("Got %v to %v", from, to)
+	}
+
+	if from, to, err := ParseTimeRange("", "", now); err != nil || !from.IsZero() || !to.IsZero() {
+		t.Errorf("Expected an open range, got %v to %v, %v", from, to, err)
+	}
+	if _, _, err := ParseTimeRange("1d", "7d", now); err == nil {
+		t.Error("Expected an error for --since after --until")
+	}
+	if _, _, err := ParseTimeRange("bogus", "", now); err == nil {
+		t.Error("Expected an error for an invalid --since")
+	}
+}
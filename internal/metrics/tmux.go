@@ -4,16 +4,32 @@ import (
 	"bytes"
 	"context"
 	"fmt"
+	"log"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
 )
 
 const (
 	// Timeout for tmux commands to prevent hanging
 	tmuxCommandTimeout = 2 * time.Second
+	// paneCaptureWorkers is how many tmux capture-pane commands run at once
+	paneCaptureWorkers = 8
+)
+
+const (
+	// DefaultCaptureLines is how far above the visible pane status detection
+	// reads, in lines (same as unified-dashboard)
+	DefaultCaptureLines = 15
+	// MaxCaptureLines caps --capture-lines, since every session is captured
+	// on every refresh
+	MaxCaptureLines = 1000
 )
 
 // SessionStatus represents the current status of a tmux session
@@ -30,6 +46,25 @@ const (
 	StatusError SessionStatus = "ERROR"
 )
 
+// NeedsAttention reports whether a session in this status is waiting on the
+// user: ready for its next message, or stopped on an error
+func (s SessionStatus) NeedsAttention() bool {
+	return s == StatusReady || s == StatusError
+}
+
+// CountStatuses recomputes Total, StatusCounts and NeedsAttention from Sessions
+func (m *TmuxMetrics) CountStatuses() {
+	m.Total = len(m.Sessions)
+	m.StatusCounts = make(map[SessionStatus]int)
+	m.NeedsAttention = 0
+	for _, session := range m.Sessions {
+		m.StatusCounts[session.Status]++
+		if session.Status.NeedsAttention() {
+			m.NeedsAttention++
+		}
+	}
+}
+
 // GetColor returns the ANSI color code for the status
 func (s SessionStatus) GetColor() string {
 	switch s {
@@ -46,9 +81,21 @@ func (s SessionStatus) GetColor() string {
 	}
 }
 
+// plainIndicators replaces status emoji with text labels (--no-emoji)
+var plainIndicators bool
+
+// SetPlainIndicators makes GetEmoji return text labels instead of emoji, for
+// screen readers and terminals that render emoji poorly. Call before rendering starts.
+func SetPlainIndicators(enabled bool) {
+	plainIndicators = enabled
+}
+
 // GetEmoji returns the emoji representation for the status
 // All emojis use single codepoints for consistent terminal rendering
 func (s SessionStatus) GetEmoji() string {
+	if plainIndicators {
+		return s.GetLabel()
+	}
 	switch s {
 	case StatusWorking:
 		return "🟢" // U+1F7E2 - Green circle
@@ -63,6 +110,23 @@ func (s SessionStatus) GetEmoji() string {
 	}
 }
 
+// GetLabel returns a plain-text indicator for the status. All labels are
+// 5 columns wide so they line up like the emoji do.
+func (s SessionStatus) GetLabel() string {
+	switch s {
+	case StatusWorking:
+		return "[WRK]"
+	case StatusReady:
+		return "[RDY]"
+	case StatusActive:
+		return "[ACT]"
+	case StatusError:
+		return "[ERR]"
+	default:
+		return "[???]"
+	}
+}
+
 // TmuxSession represents a single tmux session
 type TmuxSession struct {
 	Name              string        `json:"name"`
@@ -73,7 +137,24 @@ type TmuxSession struct {
 	LastContentChange time.Time     `json:"last_content_change"`
 	IdleDuration      time.Duration `json:"idle_duration"` // How long content unchanged
 	LastLines         []string      `json:"last_lines,omitempty"`
-	Source            string        `json:"source,omitempty"` // "tmux" or "hooks"
+	Source            string        `json:"source,omitempty"`         // "tmux" or "hooks"
+	SessionID         string        `json:"session_id,omitempty"`     // Claude Code session ID (hook-tracked only)
+	ProjectDir        string        `json:"project_dir,omitempty"`    // Working directory (hook-tracked only)
+	ContextTokens     int64         `json:"context_tokens,omitempty"` // Context size of the latest request
+	ContextLimit      int64         `json:"context_limit,omitempty"`  // Context window of the latest request's model
+	Model             string        `json:"model,omitempty"`          // Model the session is running, if detected
+
+	// Approximate usage attributed to the session from its JSONL log
+	Tokens int64   `json:"tokens,omitempty"`
+	Cost   float64 `json:"cost,omitempty"`
+}
+
+// ContextPercent returns how full the session's context window is (0 if unknown)
+func (s TmuxSession) ContextPercent() float64 {
+	if s.ContextLimit <= 0 {
+		return 0
+	}
+	return float64(s.ContextTokens) / float64(s.ContextLimit) * 100
 }
 
 // TmuxMetrics holds information about all tmux sessions
@@ -87,6 +168,14 @@ type TmuxMetrics struct {
 	HooksInstalled   bool          `json:"hooks_installed"`   // Whether hooks are installed
 	Source           string        `json:"source"`            // "hooks", "tmux", or "hybrid"
 	RunningProcesses int           `json:"running_processes"` // Number of running claude processes
+
+	// Per-status session counts, and how many sessions are waiting on the user
+	StatusCounts   map[SessionStatus]int `json:"status_counts,omitempty"`
+	NeedsAttention int                   `json:"needs_attention"`
+
+	// Completed sessions recorded by the session-end hook
+	LifetimeSessions   int           `json:"lifetime_sessions,omitempty"`
+	AvgSessionDuration time.Duration `json:"avg_session_duration,omitempty"`
 }
 
 // TmuxCollector collects metrics about tmux sessions
@@ -95,18 +184,65 @@ type TmuxCollector struct {
 	sessionActivityMap map[string]time.Time
 	// sessionContentCache stores recent pane content for change detection
 	sessionContentCache map[string]string
+	// sessionModelCache keeps the last model seen in each pane, since the
+	// indicator scrolls out of the captured lines
+	sessionModelCache map[string]string
 	// hookCollector handles hook-based session tracking
 	hookCollector *HookSessionCollector
+	// patterns are user-supplied additions to the built-in status patterns
+	patterns StatusPatterns
+	// ignoreAttached stops an attached client from marking a session ACTIVE
+	ignoreAttached bool
+	// captureLines is how many lines above the visible pane are captured
+	captureLines int
+	// capturePane replaces capturePaneContent in tests and benchmarks
+	capturePane func(ctx context.Context, sessionName string) (string, error)
 }
 
 // NewTmuxCollector creates a new TmuxCollector instance
 func NewTmuxCollector() *TmuxCollector {
 	hookCollector, _ := NewHookSessionCollector()
-	return &TmuxCollector{
+	tc := &TmuxCollector{
 		sessionActivityMap:  make(map[string]time.Time),
 		sessionContentCache: make(map[string]string),
+		sessionModelCache:   make(map[string]string),
 		hookCollector:       hookCollector,
+		captureLines:        DefaultCaptureLines,
+	}
+
+	// Custom patterns are optional - a missing or malformed file just means built-ins only
+	if hookCollector != nil {
+		patterns, err := LoadStatusPatterns(filepath.Join(hookCollector.GetBaseDir(), PatternsFile))
+		if err == nil {
+			tc.patterns = *patterns
+		} else if !os.IsNotExist(err) {
+			log.Printf("ignoring custom status patterns: %v", err)
+		}
+	}
+
+	return tc
+}
+
+// SetStatusPatterns replaces the custom status patterns
+func (tc *TmuxCollector) SetStatusPatterns(patterns StatusPatterns) {
+	tc.patterns = patterns
+}
+
+// SetIgnoreAttached classifies attached sessions by pane content and idle
+// time alone, for setups where a persistent client keeps every session attached
+func (tc *TmuxCollector) SetIgnoreAttached(enabled bool) {
+	tc.ignoreAttached = enabled
+}
+
+// SetCaptureLines sets how many lines above the visible pane are captured for
+// status detection. More lines catch indicators a TUI has pushed up, at the
+// cost of more output to read from every session on every refresh.
+func (tc *TmuxCollector) SetCaptureLines(lines int) error {
+	if lines < 1 || lines > MaxCaptureLines {
+		return fmt.Errorf("capture lines must be from 1 to %d, got %d", MaxCaptureLines, lines)
 	}
+	tc.captureLines = lines
+	return nil
 }
 
 // GetHookCollector returns the hook session collector
@@ -117,6 +253,13 @@ func (tc *TmuxCollector) GetHookCollector() *HookSessionCollector {
 // Collect gathers current tmux session information using a hybrid approach
 // that merges both hook-based and tmux-based session tracking
 func (tc *TmuxCollector) Collect() *TmuxMetrics {
+	return tc.CollectContext(context.Background())
+}
+
+// CollectContext is Collect bounded by ctx. Each tmux command still has its own
+// timeout; once ctx is done, panes not yet captured fall back to basic
+// detection, so a wedged pane can't hold up the rest of the dashboard.
+func (tc *TmuxCollector) CollectContext(ctx context.Context) *TmuxMetrics {
 	metrics := &TmuxMetrics{
 		Sessions:   make([]TmuxSession, 0),
 		LastUpdate: time.Now(),
@@ -127,6 +270,10 @@ func (tc *TmuxCollector) Collect() *TmuxMetrics {
 	if tc.hookCollector != nil {
 		metrics.HooksInstalled = tc.hookCollector.AreHooksInstalled()
 		metrics.HooksAvailable = tc.hookCollector.IsAvailable()
+		if history, err := tc.hookCollector.SessionHistory(); err == nil {
+			metrics.LifetimeSessions = history.Count
+			metrics.AvgSessionDuration = history.AvgDuration
+		}
 	}
 
 	// Collect hook-based sessions (these have accurate status from Claude Code)
@@ -145,7 +292,7 @@ func (tc *TmuxCollector) Collect() *TmuxMetrics {
 	// Collect tmux-based sessions
 	tmuxSessions := make([]TmuxSession, 0)
 	if tc.isTmuxAvailable() {
-		sessions, err := tc.listSessions()
+		sessions, err := tc.listSessions(ctx)
 		if err == nil {
 			for i := range sessions {
 				sessions[i].Source = "tmux"
@@ -177,6 +324,7 @@ func (tc *TmuxCollector) Collect() *TmuxMetrics {
 
 		// Use actual tmux attached status (hooks don't track this)
 		session.Attached = tmuxSession.Attached
+		session.Model = tmuxSession.Model
 
 		// Merge hook status with tmux observations.
 		// Hooks are authoritative for working/stopped state (Stop hook fires when
@@ -226,8 +374,8 @@ func (tc *TmuxCollector) Collect() *TmuxMetrics {
 	})
 
 	metrics.Available = hasTmux || hasHooks
-	metrics.Total = len(metrics.Sessions)
-	metrics.RunningProcesses = tc.countRunningClaudeProcesses()
+	metrics.CountStatuses()
+	metrics.RunningProcesses = tc.countRunningClaudeProcesses(ctx)
 
 	if !metrics.Available && !tc.isTmuxAvailable() {
 		metrics.Error = "tmux is not installed or not available in PATH"
@@ -236,20 +384,18 @@ func (tc *TmuxCollector) Collect() *TmuxMetrics {
 	return metrics
 }
 
-// isTmuxAvailable checks if tmux is installed and available
+// isTmuxAvailable checks if tmux is installed and available. It searches PATH
+// itself rather than running `which`, which minimal container images lack.
 func (tc *TmuxCollector) isTmuxAvailable() bool {
-	ctx, cancel := context.WithTimeout(context.Background(), tmuxCommandTimeout)
-	defer cancel()
-	cmd := exec.CommandContext(ctx, "which", "tmux")
-	err := cmd.Run()
+	_, err := exec.LookPath("tmux")
 	return err == nil
 }
 
 // listSessions executes tmux list-sessions and parses the output
-func (tc *TmuxCollector) listSessions() ([]TmuxSession, error) {
+func (tc *TmuxCollector) listSessions(ctx context.Context) ([]TmuxSession, error) {
 	// Execute tmux list-sessions with formatted output
 	// Format: session_name:windows:attached:created
-	ctx, cancel := context.WithTimeout(context.Background(), tmuxCommandTimeout)
+	ctx, cancel := context.WithTimeout(ctx, tmuxCommandTimeout)
 	defer cancel()
 
 	cmd := exec.CommandContext(ctx, "tmux", "list-sessions", "-F", "#{session_name}:#{session_windows}:#{session_attached}:#{session_created}")
@@ -260,7 +406,7 @@ func (tc *TmuxCollector) listSessions() ([]TmuxSession, error) {
 
 	err := cmd.Run()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if ctx.Err() != nil {
 			return nil, fmt.Errorf("tmux list-sessions timed out")
 		}
 		stderrStr := stderr.String()
@@ -275,7 +421,12 @@ func (tc *TmuxCollector) listSessions() ([]TmuxSession, error) {
 		return []TmuxSession{}, nil
 	}
 
-	return tc.parseSessions(output)
+	sessions, err := tc.parseSessions(output)
+	if err != nil {
+		return nil, err
+	}
+	tc.determineStatuses(ctx, sessions)
+	return sessions, nil
 }
 
 // parseSessions parses the tmux list-sessions output
@@ -333,25 +484,22 @@ func (tc *TmuxCollector) parseSessionLine(line string) (TmuxSession, error) {
 	}
 	session.Created = time.Unix(createdUnix, 0)
 
-	// Determine session status and populate fields
-	session = tc.determineStatus(session)
-
 	return session, nil
 }
 
-// capturePaneContent captures the visible content of a tmux pane
-func (tc *TmuxCollector) capturePaneContent(sessionName string) (string, error) {
-	// Capture last 15 lines of the pane (same as unified-dashboard)
-	ctx, cancel := context.WithTimeout(context.Background(), tmuxCommandTimeout)
+// capturePaneContent captures the visible content of a tmux pane, starting
+// captureLines lines above it
+func (tc *TmuxCollector) capturePaneContent(ctx context.Context, sessionName string) (string, error) {
+	ctx, cancel := context.WithTimeout(ctx, tmuxCommandTimeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "tmux", "capture-pane", "-t", sessionName, "-p", "-S", "-15")
+	cmd := exec.CommandContext(ctx, "tmux", "capture-pane", "-t", sessionName, "-p", "-S", tc.captureStart())
 	var stdout bytes.Buffer
 	cmd.Stdout = &stdout
 
 	err := cmd.Run()
 	if err != nil {
-		if ctx.Err() == context.DeadlineExceeded {
+		if ctx.Err() != nil {
 			return "", fmt.Errorf("tmux capture-pane timed out")
 		}
 		return "", err
@@ -360,18 +508,129 @@ func (tc *TmuxCollector) capturePaneContent(sessionName string) (string, error)
 	return stdout.String(), nil
 }
 
-// determineStatus determines the status of a session based on Claude Code activity
-func (tc *TmuxCollector) determineStatus(session TmuxSession) TmuxSession {
+// captureStart is capture-pane's -S argument for captureLines, e.g. "-15".
+// Collectors built without NewTmuxCollector use the default.
+func (tc *TmuxCollector) captureStart() string {
+	lines := tc.captureLines
+	if lines == 0 {
+		lines = DefaultCaptureLines
+	}
+	return "-" + strconv.Itoa(lines)
+}
+
+// determineStatuses sets the status of each session based on Claude Code
+// activity. Panes are captured concurrently, then classified in order, since
+// classification updates the collector's activity and content caches.
+func (tc *TmuxCollector) determineStatuses(ctx context.Context, sessions []TmuxSession) {
+	names := make([]string, len(sessions))
+	for i, session := range sessions {
+		names[i] = session.Name
+	}
+	contents, errs := tc.capturePanes(ctx, names, paneCaptureWorkers)
+
 	now := time.Now()
+	for i := range sessions {
+		sessions[i] = tc.statusFromCapture(sessions[i], contents[i], errs[i], now)
+	}
+	tc.forgetEndedSessions(names)
+}
 
-	// Capture pane content to analyze Claude Code state (last 15 lines like unified-dashboard)
-	content, err := tc.capturePaneContent(session.Name)
+// forgetEndedSessions drops the cached activity, content and model of sessions
+// that are no longer listed, so short-lived sessions don't accumulate and a
+// reused name starts fresh
+func (tc *TmuxCollector) forgetEndedSessions(names []string) {
+	live := make(map[string]bool, len(names))
+	for _, name := range names {
+		live[name] = true
+	}
+	for name := range tc.sessionActivityMap {
+		if !live[name] {
+			delete(tc.sessionActivityMap, name)
+		}
+	}
+	for name := range tc.sessionContentCache {
+		if !live[name] {
+			delete(tc.sessionContentCache, name)
+		}
+	}
+	for name := range tc.sessionModelCache {
+		if !live[name] {
+			delete(tc.sessionModelCache, name)
+		}
+	}
+}
+
+// capturePanes captures the named panes, running at most workers tmux
+// commands at once. Results are in the order of names.
+func (tc *TmuxCollector) capturePanes(ctx context.Context, names []string, workers int) ([]string, []error) {
+	capture := tc.capturePane
+	if capture == nil {
+		capture = tc.capturePaneContent
+	}
+
+	contents := make([]string, len(names))
+	errs := make([]error, len(names))
+	sem := make(chan struct{}, workers)
+	var wg sync.WaitGroup
+	for i, name := range names {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func() {
+			defer wg.Done()
+			defer func() { <-sem }()
+			contents[i], errs[i] = capture(ctx, name)
+		}()
+	}
+	wg.Wait()
+	return contents, errs
+}
+
+// statusFromCapture determines a session's status from its captured pane
+// (see SetCaptureLines), or from basic detection when the capture failed
+func (tc *TmuxCollector) statusFromCapture(session TmuxSession, content string, err error, now time.Time) TmuxSession {
 	if err != nil {
 		// If we can't capture content, fall back to basic detection
 		session.Status = tc.fallbackStatus(session, now)
+		session.Model = tc.sessionModelCache[session.Name]
 		return session
 	}
 
+	if model := detectModel(content); model != "" {
+		tc.sessionModelCache[session.Name] = model
+	}
+	session.Model = tc.sessionModelCache[session.Name]
+
+	return tc.classifyStatus(session, content, now)
+}
+
+var (
+	// modelIDPattern matches full model IDs, e.g. "claude-opus-4-5-20251101"
+	// or "claude-3-5-sonnet-20241022", as shown by /model and /status
+	modelIDPattern = regexp.MustCompile(`claude-(?:(?:opus|sonnet|haiku)-[0-9][\w.-]*|[0-9](?:-[0-9])?-(?:opus|sonnet|haiku)[\w.-]*)`)
+	// modelNamePattern matches display names, e.g. "Opus 4.5" in the welcome
+	// banner and status line
+	modelNamePattern = regexp.MustCompile(`\b(?:Opus|Sonnet|Haiku) [0-9](?:\.[0-9])?\b`)
+)
+
+// detectModel returns the model indicator closest to the bottom of the pane,
+// which is the most recent one, or "" when there is none
+func detectModel(content string) string {
+	model, pos := "", -1
+	for _, re := range []*regexp.Regexp{modelIDPattern, modelNamePattern} {
+		matches := re.FindAllStringIndex(content, -1)
+		if len(matches) == 0 {
+			continue
+		}
+		last := matches[len(matches)-1]
+		if last[0] > pos {
+			model, pos = strings.TrimRight(content[last[0]:last[1]], ".-"), last[0]
+		}
+	}
+	return model
+}
+
+// classifyStatus determines session status from captured pane content
+func (tc *TmuxCollector) classifyStatus(session TmuxSession, content string, now time.Time) TmuxSession {
 	// Check if content has changed (indicates activity)
 	lastContent, hasCache := tc.sessionContentCache[session.Name]
 	contentChanged := !hasCache || lastContent != content
@@ -422,7 +681,7 @@ func (tc *TmuxCollector) determineStatus(session TmuxSession) TmuxSession {
 	}
 
 	// Priority 5: Check if user is actively in the session
-	if session.Attached {
+	if session.Attached && !tc.ignoreAttached {
 		session.Status = StatusActive
 		return session
 	}
@@ -454,7 +713,7 @@ func (tc *TmuxCollector) isClaudeWorking(content string) bool {
 	if strings.Contains(content, "(running)") {
 		return true
 	}
-	return false
+	return containsAny(content, tc.patterns.Working)
 }
 
 // isClaudeWaiting checks if Claude Code is at a prompt waiting for input
@@ -478,7 +737,7 @@ func (tc *TmuxCollector) isClaudeWaiting(content string) bool {
 			return true
 		}
 	}
-	return false
+	return containsAny(content, tc.patterns.Waiting)
 }
 
 // hasError checks for Claude Code specific error states
@@ -535,13 +794,13 @@ func (tc *TmuxCollector) hasError(content string) bool {
 			return true
 		}
 	}
-	return false
+	return containsAny(lastLines, tc.patterns.Error)
 }
 
 // fallbackStatus provides basic status detection when pane content can't be captured
 func (tc *TmuxCollector) fallbackStatus(session TmuxSession, now time.Time) SessionStatus {
 	// If attached, assume active
-	if session.Attached {
+	if session.Attached && !tc.ignoreAttached {
 		tc.sessionActivityMap[session.Name] = now
 		return StatusActive
 	}
@@ -560,8 +819,8 @@ func (tc *TmuxCollector) fallbackStatus(session TmuxSession, now time.Time) Sess
 
 // countRunningClaudeProcesses counts the number of running claude processes
 // This provides a reliable count independent of hooks or tmux
-func (tc *TmuxCollector) countRunningClaudeProcesses() int {
-	ctx, cancel := context.WithTimeout(context.Background(), tmuxCommandTimeout)
+func (tc *TmuxCollector) countRunningClaudeProcesses(ctx context.Context) int {
+	ctx, cancel := context.WithTimeout(ctx, tmuxCommandTimeout)
 	defer cancel()
 
 	// Use pgrep to count claude processes (exact match to avoid false positives)
@@ -0,0 +1,309 @@
+package metrics
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestTmuxCollector() *TmuxCollector {
+	return &TmuxCollector{
+		sessionActivityMap:  make(map[string]time.Time),
+		sessionContentCache: make(map[string]string),
+		sessionModelCache:   make(map[string]string),
+	}
+}
+
+func TestClassifyStatusCustomWorkingPattern(t *testing.T) {
+	now := time.Now()
+	content := "my-agent v2\n[agent] planning step 3/7...\n"
+	session := TmuxSession{Name: "agent", Created: now.Add(-time.Hour)}
+
+	// Unchanged content for a minute with no known indicator classifies as idle
+	tc := newTestTmuxCollector()
+	tc.sessionContentCache["agent"] = content
+	tc.sessionActivityMap["agent"] = now.Add(-time.Minute)
+
+	if got := tc.classifyStatus(session, content, now).Status; got != StatusReady {
+		t.Fatalf("Expected %s without custom patterns, got %s", StatusReady, got)
+	}
+
+	// A custom working pattern must override the idle classification
+	tc.SetStatusPatterns(StatusPatterns{Working: []string{"[agent] planning"}})
+	if got := tc.classifyStatus(session, content, now).Status; got != StatusWorking {
+		t.Errorf("Expected %s with custom working pattern, got %s", StatusWorking, got)
+	}
+}
+
+func TestClassifyStatusCustomErrorPattern(t *testing.T) {
+	now := time.Now()
+	content := "running batch\nquota exceeded for project\n"
+	session := TmuxSession{Name: "batch", Created: now.Add(-time.Hour)}
+
+	tc := newTestTmuxCollector()
+	tc.sessionContentCache["batch"] = content
+	tc.sessionActivityMap["batch"] = now.Add(-time.Minute)
+	tc.SetStatusPatterns(StatusPatterns{Error: []string{"quota exceeded"}})
+
+	if got := tc.classifyStatus(session, content, now).Status; got != StatusError {
+		t.Errorf("Expected %s with custom error pattern, got %s", StatusError, got)
+	}
+}
+
+func TestLoadStatusPatterns(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(tmpDir)
+
+	// Malformed entries (numbers, empty strings, wrong types) are skipped
+	path := filepath.Join(tmpDir, PatternsFile)
+	data := `{
+		"working": ["thinking", 42, "", "  ", "agent: busy"],
+		"waiting": "not-a-list",
+		"error": ["quota exceeded"],
+		"unknown": ["ignored"]
+	}`
+	if err := os.WriteFile(path, []byte(data), 0644); err != nil {
+		t.Fatalf("Failed to write patterns file: %v", err)
+	}
+
+	patterns, err := LoadStatusPatterns(path)
+	if err != nil {
+		t.Fatalf("LoadStatusPatterns failed: %v", err)
+	}
+	if len(patterns.Working) != 2 || patterns.Working[0] != "thinking" || patterns.Working[1] != "agent: busy" {
+		t.Errorf("Expected 2 valid working patterns, got %q", patterns.Working)
+	}
+	if len(patterns.Waiting) != 0 {
+		t.Errorf("Expected non-list waiting patterns to be ignored, got %q", patterns.Waiting)
+	}
+	if len(patterns.Error) != 1 {
+		t.Errorf("Expected 1 error pattern, got %q", patterns.Error)
+	}
+
+	// A file that isn't JSON at all is rejected
+	if err := os.WriteFile(path, []byte("working: thinking"), 0644); err != nil {
+		t.Fatalf("Failed to write patterns file: %v", err)
+	}
+	if _, err := LoadStatusPatterns(path); err == nil {
+		t.Error("Expected error for invalid JSON")
+	}
+}
+
+func TestPlainIndicators(t *testing.T) {
+	SetPlainIndicators(true)
+	defer SetPlainIndicators(false)
+
+	for _, status := range []SessionStatus{StatusWorking, StatusReady, StatusActive, StatusError, SessionStatus("")} {
+		label := status.GetEmoji()
+		if label != status.GetLabel() {
+			t.Errorf("%q: expected GetEmoji to return the text label %q, got %q", status, status.GetLabel(), label)
+		}
+		if len(label) != 5 {
+			t.Errorf("%q: expected a 5-column label, got %q", status, label)
+		}
+	}
+}
+
+func TestDetectModel(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    string
+	}{
+		{"none", "❯ \n  ? for shortcuts", ""},
+		{"banner", " ✻ Welcome to Claude Code\n   Opus 4.5 · Claude Max\n❯ ", "Opus 4.5"},
+		{"model id", "  ⎿  Set model to claude-sonnet-4-5-20250929.", "claude-sonnet-4-5-20250929"},
+		{"legacy id", "Model: claude-3-5-haiku-20241022", "claude-3-5-haiku-20241022"},
+		{"latest wins", "Opus 4.5\n⎿  Set model to Sonnet 4.5\n❯ ", "Sonnet 4.5"},
+		{"id after name", "Sonnet 4.5\nModel: claude-opus-4-5-20251101", "claude-opus-4-5-20251101"},
+		{"prose", "the opus of sonnets", ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := detectModel(tt.content); got != tt.want {
+				t.Errorf("detectModel(%q) = %q, want %q", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCountStatuses(t *testing.T) {
+	m := &TmuxMetrics{Sessions: []TmuxSession{
+		{Name: "a", Status: StatusWorking},
+		{Name: "b", Status: StatusReady},
+		{Name: "c", Status: StatusReady},
+		{Name: "d", Status: StatusError},
+		{Name: "e", Status: StatusActive},
+	}}
+	m.CountStatuses()
+
+	if m.Total != 5 {
+		t.Errorf("Expected Total 5, got %d", m.Total)
+	}
+	if m.StatusCounts[StatusReady] != 2 || m.StatusCounts[StatusWorking] != 1 {
+		t.Errorf("Unexpected status counts: %v", m.StatusCounts)
+	}
+	if m.NeedsAttention != 3 {
+		t.Errorf("Expected 3 sessions needing attention (2 READY, 1 ERROR), got %d", m.NeedsAttention)
+	}
+}
+
+func TestClassifyStatusIgnoreAttached(t *testing.T) {
+	now := time.Now()
+	content := "$ tail -f build.log\nbuild finished\n"
+	session := TmuxSession{Name: "build", Attached: true, Created: now.Add(-time.Hour)}
+
+	// Unchanged content with a client attached is ACTIVE by default
+	tc := newTestTmuxCollector()
+	tc.sessionContentCache["build"] = content
+	tc.sessionActivityMap["build"] = now.Add(-time.Minute)
+	if got := tc.classifyStatus(session, content, now).Status; got != StatusActive {
+		t.Fatalf("Expected %s for an attached idle session, got %s", StatusActive, got)
+	}
+	if got := tc.fallbackStatus(session, now); got != StatusActive {
+		t.Fatalf("Expected fallback %s for an attached session, got %s", StatusActive, got)
+	}
+
+	// Ignoring attachment falls through to the idle timing
+	tc = newTestTmuxCollector()
+	tc.SetIgnoreAttached(true)
+	tc.sessionContentCache["build"] = content
+	tc.sessionActivityMap["build"] = now.Add(-time.Minute)
+	if got := tc.classifyStatus(session, content, now).Status; got != StatusReady {
+		t.Errorf("Expected %s with attachment ignored, got %s", StatusReady, got)
+	}
+	tc.sessionActivityMap["build"] = now.Add(-10 * time.Minute)
+	if got := tc.fallbackStatus(session, now); got != StatusReady {
+		t.Errorf("Expected fallback %s with attachment ignored, got %s", StatusReady, got)
+	}
+}
+
+// fakeCapture stands in for tmux capture-pane, taking latency per pane like a
+// real tmux round trip
+func fakeCapture(latency time.Duration) func(context.Context, string) (string, error) {
+	return func(ctx context.Context, sessionName string) (string, error) {
+		time.Sleep(latency)
+		if sessionName == "broken" {
+			return "", fmt.Errorf("can't find session: %s", sessionName)
+		}
+		return "Claude Code v2.0\n> " + sessionName + "\n", nil
+	}
+}
+
+func TestDetermineStatusesKeepsOrder(t *testing.T) {
+	tc := newTestTmuxCollector()
+	tc.capturePane = fakeCapture(time.Millisecond)
+
+	var sessions []TmuxSession
+	for i := 0; i < 3*paneCaptureWorkers; i++ {
+		sessions = append(sessions, TmuxSession{Name: fmt.Sprintf("s%02d", i)})
+	}
+	sessions = append(sessions, TmuxSession{Name: "broken"})
+	tc.determineStatuses(context.Background(), sessions)
+
+	for _, session := range sessions[:len(sessions)-1] {
+		if got := tc.sessionContentCache[session.Name]; got != "Claude Code v2.0\n> "+session.Name+"\n" {
+			t.Errorf("Expected %s to be classified from its own pane, got %q", session.Name, got)
+		}
+		if session.Status == "" {
+			t.Errorf("Expected %s to get a status", session.Name)
+		}
+	}
+	broken := sessions[len(sessions)-1]
+	if _, cached := tc.sessionContentCache[broken.Name]; cached || broken.Status == "" {
+		t.Errorf("Expected a failed capture to fall back to basic detection, got %+v", broken)
+	}
+}
+
+func TestDetermineStatusesForgetsEndedSessions(t *testing.T) {
+	tc := newTestTmuxCollector()
+	tc.capturePane = func(ctx context.Context, name string) (string, error) {
+		return "Claude Code v2.0\n   Opus 4.5 · Claude Max\n> " + name + "\n", nil
+	}
+	tc.determineStatuses(context.Background(), []TmuxSession{{Name: "agent-1"}, {Name: "agent-2"}})
+	if tc.sessionModelCache["agent-1"] != "Opus 4.5" {
+		t.Fatalf("Expected agent-1's model to be cached, got %q", tc.sessionModelCache["agent-1"])
+	}
+
+	tc.determineStatuses(context.Background(), []TmuxSession{{Name: "agent-2"}})
+	if _, ok := tc.sessionModelCache["agent-1"]; ok {
+		t.Error("Expected the ended session's model to be forgotten")
+	}
+	if _, ok := tc.sessionContentCache["agent-1"]; ok {
+		t.Error("Expected the ended session's pane content to be forgotten")
+	}
+	if _, ok := tc.sessionActivityMap["agent-1"]; ok {
+		t.Error("Expected the ended session's activity to be forgotten")
+	}
+	if tc.sessionModelCache["agent-2"] != "Opus 4.5" {
+		t.Errorf("Expected the live session's model to be kept, got %q", tc.sessionModelCache["agent-2"])
+	}
+}
+
+func BenchmarkCapturePanes(b *testing.B) {
+	for _, workers := range []int{1, paneCaptureWorkers} {
+		b.Run(fmt.Sprintf("workers=%d", workers), func(b *testing.B) {
+			tc := newTestTmuxCollector()
+			tc.capturePane = fakeCapture(2 * time.Millisecond)
+			names := make([]string, 30)
+			for i := range names {
+				names[i] = fmt.Sprintf("s%02d", i)
+			}
+			b.ResetTimer()
+
+			for i := 0; i < b.N; i++ {
+				_, _ = tc.capturePanes(context.Background(), names, workers)
+			}
+		})
+	}
+}
+
+func TestSetCaptureLines(t *testing.T) {
+	tc := newTestTmuxCollector()
+	if got := tc.captureStart(); got != "-15" {
+		t.Errorf("Expected the default capture to start at -15, got %s", got)
+	}
+	if err := tc.SetCaptureLines(60); err != nil {
+		t.Fatalf("SetCaptureLines(60): %v", err)
+	}
+	if got := tc.captureStart(); got != "-60" {
+		t.Errorf("Expected the capture to start at -60, got %s", got)
+	}
+	for _, lines := range []int{0, -5, MaxCaptureLines + 1} {
+		if err := tc.SetCaptureLines(lines); err == nil {
+			t.Errorf("Expected %d capture lines to be rejected", lines)
+		}
+	}
+	if got := tc.captureStart(); got != "-60" {
+		t.Errorf("Expected a rejected value to keep -60, got %s", got)
+	}
+}
+
+func TestTmuxCollector_isTmuxAvailable(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Only the directory holding the fake tmux is on PATH, so `which` isn't
+	// available either, as in a distroless image
+	t.Setenv("PATH", dir)
+	tc := newTestTmuxCollector()
+	if tc.isTmuxAvailable() {
+		t.Error("Expected tmux to be unavailable when it isn't on PATH")
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "tmux"), []byte("#!/bin/sh\n"), 0755); err != nil {
+		t.Fatalf("Failed to write fake tmux: %v", err)
+	}
+	if !tc.isTmuxAvailable() {
+		t.Error("Expected tmux on PATH to be found without `which`")
+	}
+}
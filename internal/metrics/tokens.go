@@ -1,13 +1,15 @@
 package metrics
 
 import (
-	"bufio"
 	"encoding/json"
 	"fmt"
+	"log"
 	"os"
 	"path/filepath"
+	"slices"
 	"sort"
 	"strings"
+	"sync/atomic"
 	"time"
 )
 
@@ -20,8 +22,21 @@ type ModelUsage struct {
 	CacheCreationTokens int64   `json:"cache_creation_tokens"`
 	TotalTokens         int64   `json:"total_tokens"`
 	Cost                float64 `json:"cost"`
+
+	// Excluded models stay in the breakdown but not in the totals
+	Excluded bool `json:"excluded,omitempty"`
 }
 
+// Orders for the per-model breakdown
+const (
+	ModelSortCost   = "cost"   // Costliest first (default)
+	ModelSortTokens = "tokens" // Most tokens first
+	ModelSortName   = "name"   // By model name
+)
+
+// ModelSorts lists the model orders, in the order the dashboard cycles through them
+var ModelSorts = []string{ModelSortCost, ModelSortTokens, ModelSortName}
+
 // TokenMetrics represents aggregated token usage metrics
 type TokenMetrics struct {
 	InputTokens         int64         `json:"input_tokens"`
@@ -31,25 +46,109 @@ type TokenMetrics struct {
 	TotalTokens         int64         `json:"total_tokens"`
 	Prompts             int64         `json:"prompts"` // Number of prompt/response cycles
 	TotalCost           float64       `json:"total_cost"`
+	CostPer1K           float64       `json:"cost_per_1k"`      // TotalCost per thousand tokens (0 when no tokens)
 	Rate                float64       `json:"rate"`             // tokens/min over 60s window
 	SessionAvgRate      float64       `json:"session_avg_rate"` // average tokens/min for entire session
 	TimeSpan            time.Duration `json:"time_span"`
 	EarliestTimestamp   time.Time     `json:"earliest_timestamp"`
 	LatestTimestamp     time.Time     `json:"latest_timestamp"`
 	LookbackFrom        time.Time     `json:"lookback_from"` // Start of measurement period
+	LookbackTo          time.Time     `json:"lookback_to"`   // End of measurement period; zero for now
 	Models              []string      `json:"models"`
 	ModelUsages         []ModelUsage  `json:"model_usages"` // Per-model breakdown
 	Available           bool          `json:"available"`
 	Error               string        `json:"error,omitempty"`
 	LastUpdate          time.Time     `json:"last_update"`
+	Source              string        `json:"source,omitempty"` // "jsonl" or "ccusage"
+
+	// Usage reported on user messages (opt-in). Included in TotalTokens and
+	// TotalCost, costed at the input rates of the model the turn was sent to.
+	UserInputTokens int64   `json:"user_input_tokens,omitempty"`
+	UserInputCost   float64 `json:"user_input_cost,omitempty"`
+
+	// Cost since midnight local time, whatever the lookback window
+	TodayCost float64 `json:"today_cost"`
+
+	// Working directory of the project the totals are limited to; empty for
+	// all projects
+	Project string `json:"project,omitempty"`
+
+	// Estimated cost of each of the last 24 hours, oldest first, whatever
+	// the lookback window
+	HourlyCost []float64 `json:"hourly_cost,omitempty"`
+
+	// How far the newest logged event is ahead of the local clock, when by
+	// more than ClockSkewTolerance; 0 otherwise. Lookback windows and rates
+	// are unreliable while the clock is wrong.
+	ClockSkew time.Duration `json:"clock_skew,omitempty"`
+
+	// Average tokens per assistant response, usage on user messages left
+	// out; 0 without responses
+	TokensPerPrompt float64 `json:"tokens_per_prompt"`
+
+	// The parts of TotalCost spent writing to the prompt cache and reading
+	// from it, over the models counted in the totals
+	CacheWriteCost float64 `json:"cache_write_cost"`
+	CacheReadCost  float64 `json:"cache_read_cost"`
 }
 
+// ClockSkewTolerance is how far in the future the newest event may be before
+// the local clock is taken to be behind. Claude Code stamps events with the
+// same clock, so any real lead means the clock was set back, for example by
+// a resume from suspend or a container with a wrong time.
+const ClockSkewTolerance = 5 * time.Minute
+
+// hourlyCostHours is how many hours TokenMetrics.HourlyCost covers
+const hourlyCostHours = 24
+
 // TokenCollector collects and aggregates token usage from Claude Code sessions
 type TokenCollector struct {
 	projectsDirs  []string  // Root directories to scan for JSONL files
 	lookbackFrom  time.Time // Only include data from this time onwards
+	lookbackTo    time.Time // Only include data before this time; zero for no end
 	cache         *TokenCache
-	stopIngestion chan struct{} // Closed to stop the background ingestion goroutine
+	stopIngestion chan struct{}     // Closed to stop the background ingestion goroutine
+	ingestNow     chan struct{}     // Wakes the background goroutine for an immediate cycle
+	ccusage       *CCUsageCollector // Alternate token source; nil means JSONL
+
+	// includeUserTokens also ingests usage reported on user messages
+	includeUserTokens bool
+
+	// excludedModels are model name prefixes left out of the totals
+	excludedModels []string
+
+	// modelSort orders ModelUsages: one of ModelSorts; empty for cost
+	modelSort string
+
+	// keepEvents stops ingestion from compacting files into aggregates
+	keepEvents bool
+
+	// rescanCompacted makes the next ingestion cycle re-read compacted files
+	// even when their modification time says nothing changed
+	rescanCompacted atomic.Bool
+
+	// lastScan is what the last ingestion cycle found on disk; nil until the
+	// first one finishes
+	lastScan atomic.Pointer[ingestScan]
+
+	// projectScope limits Collect to the project of this working directory;
+	// empty for all projects. Every project is still ingested, so switching
+	// scope only changes the queries.
+	projectScope string
+}
+
+// ingestScan records what an ingestion cycle found on disk, to explain an
+// empty token panel
+type ingestScan struct {
+	roots       []string // Configured roots that exist
+	projectDirs int
+	files       int
+}
+
+// StartOfToday returns midnight at the start of today, local time
+func StartOfToday() time.Time {
+	now := time.Now()
+	return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 }
 
 // GetMondayNineAM returns the most recent Monday at 9am local time
@@ -75,12 +174,36 @@ func GetMondayNineAM() time.Time {
 	return monday
 }
 
+// projectsDirOverride replaces ~/.claude/projects as the primary root (config projects_dir)
+var projectsDirOverride string
+
+// SetProjectsDir sets the primary Claude Code projects directory used by new
+// collectors. Call before creating any collectors.
+func SetProjectsDir(dir string) {
+	projectsDirOverride = dir
+}
+
+// ClaudeProjectsDir returns the primary projects directory: the one set with
+// SetProjectsDir, or ~/.claude/projects
+func ClaudeProjectsDir() string {
+	if projectsDirOverride != "" {
+		return projectsDirOverride
+	}
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return ""
+	}
+	return filepath.Join(home, ".claude", "projects")
+}
+
 // buildDefaultProjectsDirs returns the list of root directories to scan.
-// Always includes ~/.claude/projects as the primary directory.
+// Always includes the primary directory (~/.claude/projects unless overridden).
 // Also reads CCDASH_EXTRA_DIRS (colon-separated, supports glob patterns) for additional roots.
 func buildDefaultProjectsDirs(home string) []string {
 	var dirs []string
-	if home != "" {
+	if projectsDirOverride != "" {
+		dirs = append(dirs, projectsDirOverride)
+	} else if home != "" {
 		dirs = append(dirs, filepath.Join(home, ".claude", "projects"))
 	}
 	if extra := os.Getenv("CCDASH_EXTRA_DIRS"); extra != "" {
@@ -174,11 +297,38 @@ func NewTokenCollectorWithPath(path string) *TokenCollector {
 	return tc
 }
 
+// NewOneShotTokenCollector creates a TokenCollector without background ingestion,
+// for commands that take a single snapshot. Call Ingest before Collect to bring
+// the cache up to date.
+func NewOneShotTokenCollector(lookbackFrom time.Time) *TokenCollector {
+	home, _ := os.UserHomeDir()
+	return &TokenCollector{
+		projectsDirs: buildDefaultProjectsDirs(home),
+		lookbackFrom: lookbackFrom,
+		cache:        NewTokenCache(),
+	}
+}
+
+// Ingest synchronously scans all JSONL files and ingests new data into the cache
+func (tc *TokenCollector) Ingest() {
+	tc.runIngestionCycle()
+}
+
+// IngestEvents is like Ingest, but leaves every event in token_events, for
+// exports. Files already compacted into aggregates are read again, and none
+// are compacted; the next regular ingestion compacts them as usual.
+func (tc *TokenCollector) IngestEvents() {
+	tc.keepEvents = true
+	defer func() { tc.keepEvents = false }()
+	tc.runIngestionCycle()
+}
+
 // startBackgroundIngestion starts a goroutine that ingests JSONL files into SQLite
 // independently of the UI refresh cycle. This decouples slow file I/O from the
 // fast DB query that populates the token panel.
 func (tc *TokenCollector) startBackgroundIngestion() {
 	tc.stopIngestion = make(chan struct{})
+	tc.ingestNow = make(chan struct{}, 1)
 	go func() {
 		// Run immediately so data is available as soon as possible
 		tc.runIngestionCycle()
@@ -191,6 +341,8 @@ func (tc *TokenCollector) startBackgroundIngestion() {
 				return
 			case <-ticker.C:
 				tc.runIngestionCycle()
+			case <-tc.ingestNow:
+				tc.runIngestionCycle()
 			}
 		}
 	}()
@@ -204,6 +356,51 @@ func (tc *TokenCollector) StopBackgroundIngestion() {
 	}
 }
 
+// TriggerIngestion asks the background goroutine to run an ingestion cycle now
+// instead of waiting for the next 30s tick. Never blocks.
+func (tc *TokenCollector) TriggerIngestion() {
+	if tc.ingestNow == nil {
+		return
+	}
+	select {
+	case tc.ingestNow <- struct{}{}:
+	default:
+		// A cycle is already pending
+	}
+}
+
+// ClearCache wipes the token cache and triggers a full re-ingestion of all JSONL files
+func (tc *TokenCollector) ClearCache() error {
+	if tc.cache == nil {
+		return fmt.Errorf("token cache not available")
+	}
+	if err := tc.cache.Clear(); err != nil {
+		return err
+	}
+	tc.TriggerIngestion()
+	return nil
+}
+
+// ForceReingest re-reads every JSONL file from the start, for when new usage
+// isn't showing up because the cache's record of file modification times is out
+// of step with the files (clock skew, a restored backup). Unlike ClearCache,
+// cached events are kept, and so is the history of compacted files whose logs
+// have since been deleted. Returns the number of files whose state was dropped.
+func (tc *TokenCollector) ForceReingest() (int, error) {
+	if tc.cache == nil {
+		return 0, fmt.Errorf("token cache not available")
+	}
+	files, err := tc.cache.ResetFileState()
+	if err != nil {
+		return 0, err
+	}
+	// Only after the reset: a compacted file reopened while it still had its
+	// read position would be compacted again from just the lines after it
+	tc.rescanCompacted.Store(true)
+	tc.TriggerIngestion()
+	return files, nil
+}
+
 // runIngestionCycle scans all JSONL files and ingests new data into SQLite.
 // Called by the background goroutine; uses ingestMu so it never blocks fast
 // cache/lease operations.
@@ -211,10 +408,19 @@ func (tc *TokenCollector) runIngestionCycle() {
 	if len(tc.projectsDirs) == 0 {
 		return
 	}
+	scan := &ingestScan{}
+	defer tc.lastScan.Store(scan)
+	for _, root := range tc.projectsDirs {
+		if info, err := os.Stat(root); err == nil && info.IsDir() {
+			scan.roots = append(scan.roots, root)
+		}
+	}
+
 	projectDirs, err := tc.findAllProjectDirs()
 	if err != nil || len(projectDirs) == 0 {
 		return
 	}
+	scan.projectDirs = len(projectDirs)
 
 	var files []string
 	for _, projectDir := range projectDirs {
@@ -224,8 +430,13 @@ func (tc *TokenCollector) runIngestionCycle() {
 		}
 		files = append(files, dirFiles...)
 	}
+	scan.files = len(files)
 
 	completeThreshold := GetFileCompleteThreshold()
+	rescan := tc.rescanCompacted.Swap(false)
+	if tc.cache.takeRereadLogs() {
+		rescan = true
+	}
 	for _, file := range files {
 		fileInfo, err := os.Stat(file)
 		if err != nil {
@@ -233,13 +444,18 @@ func (tc *TokenCollector) runIngestionCycle() {
 		}
 
 		if agg, ok := tc.cache.GetFileAggregate(file); ok && agg.IsComplete {
-			if !fileInfo.ModTime().After(agg.CompletedAt) {
+			if tc.keepEvents {
+				// Only the totals are left; drop the file's state so it's read from the start
+				tc.cache.MarkFileActive(file)
+				tc.cache.InvalidateFile(file)
+			} else if !rescan && !fileInfo.ModTime().After(agg.CompletedAt) {
 				continue
+			} else {
+				tc.cache.MarkFileActive(file)
 			}
-			tc.cache.MarkFileActive(file)
 		}
 
-		if time.Since(fileInfo.ModTime()) > completeThreshold {
+		if !tc.keepEvents && time.Since(fileInfo.ModTime()) > completeThreshold {
 			if err := tc.ingestJSONLFile(file); err == nil {
 				tc.cache.MarkFileComplete(file)
 			}
@@ -255,9 +471,42 @@ func (tc *TokenCollector) AddProjectsDir(path string) {
 	tc.projectsDirs = append(tc.projectsDirs, path)
 }
 
-// SetLookback sets the lookback time filter
+// SetProjectScope limits the token totals to the Claude Code project of a
+// working directory, or lifts the limit when cwd is empty
+func (tc *TokenCollector) SetProjectScope(cwd string) {
+	tc.projectScope = cwd
+}
+
+// ProjectScope returns the working directory the totals are limited to, or ""
+// for all projects
+func (tc *TokenCollector) ProjectScope() string {
+	return tc.projectScope
+}
+
+// scopeSourcePrefixes returns the source file prefixes of the project scope:
+// its directory under each root. Nil means all projects.
+func (tc *TokenCollector) scopeSourcePrefixes() []string {
+	if tc.projectScope == "" {
+		return nil
+	}
+	name := ProjectDirName(tc.projectScope)
+	prefixes := make([]string, 0, len(tc.projectsDirs))
+	for _, root := range tc.projectsDirs {
+		prefixes = append(prefixes, filepath.Join(root, name)+string(filepath.Separator))
+	}
+	return prefixes
+}
+
+// SetLookback sets the lookback time filter, with no end
 func (tc *TokenCollector) SetLookback(t time.Time) {
-	tc.lookbackFrom = t
+	tc.SetLookbackRange(t, time.Time{})
+}
+
+// SetLookbackRange limits token totals to events from from up to, but not
+// including, to. A zero to means no end.
+func (tc *TokenCollector) SetLookbackRange(from, to time.Time) {
+	tc.lookbackFrom = from
+	tc.lookbackTo = to
 }
 
 // GetLookback returns the current lookback time
@@ -265,6 +514,118 @@ func (tc *TokenCollector) GetLookback() time.Time {
 	return tc.lookbackFrom
 }
 
+// GetLookbackEnd returns the end of the lookback range, zero when it has none
+func (tc *TokenCollector) GetLookbackEnd() time.Time {
+	return tc.lookbackTo
+}
+
+// SetTokenSource selects where token totals come from: "jsonl" (default) or
+// "ccusage". JSONL ingestion keeps running either way, since the 60s rate and
+// per-session context estimates are always computed from the local cache.
+func (tc *TokenCollector) SetTokenSource(source string) error {
+	switch source {
+	case "", TokenSourceJSONL:
+		tc.ccusage = nil
+	case TokenSourceCCUsage:
+		c := NewCCUsageCollector()
+		if !c.IsAvailable() {
+			return fmt.Errorf("ccusage not found on PATH")
+		}
+		tc.ccusage = c
+	default:
+		return fmt.Errorf("unknown token source %q (want %s or %s)", source, TokenSourceJSONL, TokenSourceCCUsage)
+	}
+	return nil
+}
+
+// SetModelSort sets the order of the per-model breakdown: ModelSortCost,
+// ModelSortTokens or ModelSortName. Excluded models are listed last either way.
+func (tc *TokenCollector) SetModelSort(by string) error {
+	if !slices.Contains(ModelSorts, by) {
+		return fmt.Errorf("unknown model sort %q (want %s)", by, strings.Join(ModelSorts, ", "))
+	}
+	tc.modelSort = by
+	return nil
+}
+
+// GetModelSort returns the order of the per-model breakdown
+func (tc *TokenCollector) GetModelSort() string {
+	if tc.modelSort == "" {
+		return ModelSortCost
+	}
+	return tc.modelSort
+}
+
+// SetIncludeUserTokens enables ingesting token usage reported on user messages.
+// Off by default so totals match the assistant-only numbers. Only lines ingested
+// while enabled are counted; clear the cache to pick up older history.
+func (tc *TokenCollector) SetIncludeUserTokens(enabled bool) {
+	tc.includeUserTokens = enabled
+}
+
+// SetExcludedModels leaves models whose names start with any of the prefixes
+// out of the totals, e.g. "claude-3-5-haiku" or "claude-haiku", for usage
+// billed elsewhere. They still appear in the per-model breakdown, marked
+// Excluded.
+func (tc *TokenCollector) SetExcludedModels(prefixes []string) {
+	tc.excludedModels = prefixes
+}
+
+// isExcludedModel reports whether a model matches an excluded prefix
+func (tc *TokenCollector) isExcludedModel(model string) bool {
+	for _, prefix := range tc.excludedModels {
+		if strings.HasPrefix(model, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// applyExcludedModels marks the excluded model usages and takes them out of
+// the token and cost totals, then puts the breakdown in the chosen order
+func (tc *TokenCollector) applyExcludedModels(m *TokenMetrics) {
+	for i := range m.ModelUsages {
+		usage := &m.ModelUsages[i]
+		if !tc.isExcludedModel(usage.Model) {
+			continue
+		}
+		usage.Excluded = true
+		m.InputTokens -= usage.InputTokens
+		m.OutputTokens -= usage.OutputTokens
+		m.CacheReadTokens -= usage.CacheReadTokens
+		m.CacheCreationTokens -= usage.CacheCreationTokens
+		m.TotalTokens -= usage.TotalTokens
+		m.TotalCost -= usage.Cost
+	}
+
+	// Counted models first, each group in the chosen order
+	sortModelUsages(m.ModelUsages, tc.modelSort)
+	sort.SliceStable(m.ModelUsages, func(i, j int) bool {
+		return !m.ModelUsages[i].Excluded && m.ModelUsages[j].Excluded
+	})
+}
+
+// sortModelUsages orders usages by one of ModelSorts, costliest first for an
+// empty or unknown order. Ties go by model name, so the order is stable
+// between refreshes.
+func sortModelUsages(usages []ModelUsage, by string) {
+	sort.Slice(usages, func(i, j int) bool {
+		a, b := usages[i], usages[j]
+		switch by {
+		case ModelSortTokens:
+			if a.TotalTokens != b.TotalTokens {
+				return a.TotalTokens > b.TotalTokens
+			}
+		case ModelSortName:
+		default:
+			if a.Cost != b.Cost {
+				return a.Cost > b.Cost
+			}
+		}
+		return a.Model < b.Model
+	})
+}
+
 // GetCache returns the underlying token cache for shared metrics operations
 func (tc *TokenCollector) GetCache() *TokenCache {
 	return tc.cache
@@ -298,6 +659,16 @@ type cacheCreation struct {
 	Ephemeral1hInputTokens int64 `json:"ephemeral_1h_input_tokens"`
 }
 
+// cacheCreationTokens returns the tokens written to the cache. The API reports
+// the total as cache_creation_input_tokens and splits the same tokens by TTL
+// in cache_creation, so the two are never added. Claude Code logs carry both,
+// and they agree; when only one is present, or the total is the smaller of the
+// two, the larger is counted so neither shape undercounts.
+func (u usageData) cacheCreationTokens() int64 {
+	return max(u.CacheCreationInputTokens,
+		u.CacheCreation.Ephemeral5mInputTokens+u.CacheCreation.Ephemeral1hInputTokens)
+}
+
 // Collect returns token metrics from the SQLite cache. File ingestion runs in a
 // background goroutine (started by the constructor) so this method only executes
 // the fast DB query and never blocks on file I/O.
@@ -306,16 +677,41 @@ func (tc *TokenCollector) Collect() (*TokenMetrics, error) {
 		Available:    false,
 		LastUpdate:   time.Now(),
 		LookbackFrom: tc.lookbackFrom,
+		LookbackTo:   tc.lookbackTo,
 		Models:       []string{},
+		Project:      tc.projectScope,
+	}
+	sources := tc.scopeSourcePrefixes()
+
+	// ccusage is only asked for a start day and reports every project, so
+	// ranges with an end and a project scope use the cache
+	if tc.ccusage != nil && tc.lookbackTo.IsZero() && sources == nil {
+		if m := tc.ccusage.Collect(tc.lookbackFrom); m != nil {
+			// ccusage only reports daily totals, so the live rate still comes from the cache
+			recentEvents, err := tc.cache.QueryRecentEvents(60)
+			if err == nil && len(recentEvents) > 0 {
+				m.Rate = tc.calculate60sRate(recentEvents)
+			}
+			tc.applyExcludedModels(m)
+			m.CostPer1K = costPer1K(m.TotalCost, m.TotalTokens)
+			m.CacheWriteCost, m.CacheReadCost = cacheCosts(m.ModelUsages)
+			m.TodayCost, _ = tc.CostSince(StartOfToday())
+			m.HourlyCost = tc.hourlyCost(nil)
+			m.ClockSkew = tc.clockSkew(time.Now())
+			return m, nil
+		}
+		// First ccusage run still in progress (or failing): fall back to JSONL
 	}
 
+	metrics.Source = TokenSourceJSONL
+
 	if len(tc.projectsDirs) == 0 {
 		metrics.Error = "No projects directories configured"
 		return metrics, nil
 	}
 
 	// Query SQLite using hybrid approach (pre-aggregated + active events)
-	aggregated, err := tc.cache.QueryTokensHybrid(tc.lookbackFrom)
+	aggregated, err := tc.cache.QueryTokensBetween(tc.lookbackFrom, tc.lookbackTo, sources...)
 	if err != nil {
 		metrics.Error = fmt.Sprintf("Failed to query token cache: %v", err)
 		return metrics, nil
@@ -329,12 +725,13 @@ func (tc *TokenCollector) Collect() (*TokenMetrics, error) {
 	metrics.TotalTokens = aggregated.InputTokens + aggregated.OutputTokens +
 		aggregated.CacheReadTokens + aggregated.CacheCreationTokens
 	metrics.Prompts = aggregated.EventCount
-	metrics.EarliestTimestamp = aggregated.EarliestTimestamp
-	metrics.LatestTimestamp = aggregated.LatestTimestamp
+	metrics.EarliestTimestamp, metrics.LatestTimestamp = tc.clampToLookback(aggregated.EarliestTimestamp, aggregated.LatestTimestamp)
 
-	if !aggregated.EarliestTimestamp.IsZero() && !aggregated.LatestTimestamp.IsZero() {
-		metrics.TimeSpan = aggregated.LatestTimestamp.Sub(aggregated.EarliestTimestamp)
+	if !metrics.EarliestTimestamp.IsZero() && !metrics.LatestTimestamp.IsZero() {
+		// A window starting after its newest event means the clock moved back
+		metrics.TimeSpan = max(metrics.LatestTimestamp.Sub(metrics.EarliestTimestamp), 0)
 	}
+	metrics.ClockSkew = tc.clockSkew(time.Now())
 
 	// Build model list and per-model usage
 	var totalCost float64
@@ -343,12 +740,7 @@ func (tc *TokenCollector) Collect() (*TokenMetrics, error) {
 	for model, mm := range aggregated.ModelMetrics {
 		metrics.Models = append(metrics.Models, model)
 
-		pricing := getPricingForModel(model)
-		inputCost := float64(mm.InputTokens) * pricing.InputPerMillion / 1_000_000
-		outputCost := float64(mm.OutputTokens) * pricing.OutputPerMillion / 1_000_000
-		cacheReadCost := float64(mm.CacheReadTokens) * pricing.CacheReadPerMillion / 1_000_000
-		cacheCreateCost := float64(mm.CacheCreationTokens) * pricing.CacheCreatePerMillion / 1_000_000
-		modelCost := inputCost + outputCost + cacheReadCost + cacheCreateCost
+		modelCost := modelAggregationCost(model, mm)
 
 		usage := ModelUsage{
 			Model:               model,
@@ -365,12 +757,30 @@ func (tc *TokenCollector) Collect() (*TokenMetrics, error) {
 
 	sort.Strings(metrics.Models)
 
-	// Sort model usages by cost (highest first)
-	sort.Slice(metrics.ModelUsages, func(i, j int) bool {
-		return metrics.ModelUsages[i].Cost > metrics.ModelUsages[j].Cost
-	})
+	// User turns are billed as input to the model they were sent to
+	if tc.includeUserTokens {
+		if userAgg, err := tc.cache.QueryUserTokensBetween(tc.lookbackFrom, tc.lookbackTo, sources...); err == nil {
+			metrics.UserInputTokens, metrics.UserInputCost = tc.userInputUsage(userAgg)
+			metrics.TotalTokens += metrics.UserInputTokens
+			totalCost += metrics.UserInputCost
+		}
+	}
 
 	metrics.TotalCost = totalCost
+	tc.applyExcludedModels(metrics)
+	metrics.CostPer1K = costPer1K(metrics.TotalCost, metrics.TotalTokens)
+	metrics.TokensPerPrompt = tokensPerPrompt(metrics.TotalTokens-metrics.UserInputTokens, metrics.Prompts)
+	metrics.CacheWriteCost, metrics.CacheReadCost = cacheCosts(metrics.ModelUsages)
+
+	// The status bar shows today's spend whatever the lookback and scope;
+	// skip the second query when the lookback is today
+	if today := StartOfToday(); tc.lookbackFrom.Equal(today) && tc.lookbackTo.IsZero() && sources == nil {
+		metrics.TodayCost = metrics.TotalCost
+	} else {
+		metrics.TodayCost, _ = tc.CostSince(today)
+	}
+
+	metrics.HourlyCost = tc.hourlyCost(sources)
 
 	// Calculate session average rate
 	if metrics.TimeSpan > 0 {
@@ -380,16 +790,198 @@ func (tc *TokenCollector) Collect() (*TokenMetrics, error) {
 		}
 	}
 
-	// Calculate 60-second window rate from recent events
-	recentEvents, err := tc.cache.QueryRecentEvents(60)
-	if err == nil && len(recentEvents) > 0 {
-		metrics.Rate = tc.calculate60sRate(recentEvents)
+	// Calculate 60-second window rate from recent events, unless the range
+	// ended before them
+	if tc.lookbackTo.IsZero() || tc.lookbackTo.After(time.Now()) {
+		recentEvents, err := tc.cache.QueryRecentEvents(60, sources...)
+		if err == nil && len(recentEvents) > 0 {
+			metrics.Rate = tc.calculate60sRate(recentEvents)
+		}
+	}
+
+	if metrics.TotalTokens == 0 {
+		if reason := tc.noUsageReason(); reason != "" {
+			metrics.Error = reason
+			return metrics, nil
+		}
 	}
 
 	metrics.Available = true
 	return metrics, nil
 }
 
+// noUsageReason explains why there's no token usage at all, telling apart a
+// missing projects directory, one without any project, projects without
+// JSONL logs, and logs without a single assistant response, and with a
+// project scope, a project Claude Code hasn't run in. Returns "" when
+// there is usage, just not in the lookback window, or before the first
+// ingestion has finished.
+func (tc *TokenCollector) noUsageReason() string {
+	scan := tc.lastScan.Load()
+	if scan == nil {
+		return ""
+	}
+
+	switch {
+	case len(scan.roots) == 0:
+		return fmt.Sprintf("No Claude Code logs: %s not found. Set projects_dir if Claude Code keeps them elsewhere.",
+			strings.Join(tc.projectsDirs, ", "))
+	case scan.projectDirs == 0:
+		return fmt.Sprintf("No projects in %s yet. Claude Code adds one the first time it runs in a directory.",
+			strings.Join(scan.roots, ", "))
+	case scan.files == 0:
+		return fmt.Sprintf("%d project director%s in %s but no .jsonl logs in them. Check that Claude Code can write there.",
+			scan.projectDirs, pluralSuffix(scan.projectDirs, "y", "ies"), strings.Join(scan.roots, ", "))
+	}
+
+	sources := tc.scopeSourcePrefixes()
+	if sources != nil && !slices.ContainsFunc(sources, func(dir string) bool {
+		_, err := os.Stat(dir)
+		return err == nil
+	}) {
+		return fmt.Sprintf("No Claude Code project for %s in %s. Press a to show all projects.",
+			tc.projectScope, strings.Join(scan.roots, ", "))
+	}
+
+	all, err := tc.cache.QueryTokensBetween(time.Time{}, time.Time{}, sources...)
+	if err != nil || all.EventCount > 0 {
+		return ""
+	}
+	if sources != nil {
+		return fmt.Sprintf("No assistant responses with token usage for %s yet. Press a to show all projects.", tc.projectScope)
+	}
+	return fmt.Sprintf("%d JSONL log%s but no assistant responses with token usage yet. Usage appears once Claude replies.",
+		scan.files, pluralSuffix(scan.files, "", "s"))
+}
+
+// pluralSuffix returns one for a count of 1, else many
+func pluralSuffix(n int, one, many string) string {
+	if n == 1 {
+		return one
+	}
+	return many
+}
+
+// CostSince returns the estimated cost of all usage since the given time,
+// ignoring the lookback window but not excluded models
+func (tc *TokenCollector) CostSince(since time.Time) (float64, error) {
+	aggregated, err := tc.cache.QueryTokensBetween(since, time.Time{})
+	if err != nil {
+		return 0, err
+	}
+
+	var cost float64
+	for model, mm := range aggregated.ModelMetrics {
+		if !tc.isExcludedModel(model) {
+			cost += modelAggregationCost(model, mm)
+		}
+	}
+	if tc.includeUserTokens {
+		if userAgg, err := tc.cache.QueryUserTokensBetween(since, time.Time{}); err == nil {
+			_, userCost := tc.userInputUsage(userAgg)
+			cost += userCost
+		}
+	}
+	return cost, nil
+}
+
+// hourlyCost returns the cost of each of the last hourlyCostHours hours in the
+// source files with the prefixes, or nil if the query fails
+func (tc *TokenCollector) hourlyCost(sources []string) []float64 {
+	since := time.Now().Add(-hourlyCostHours * time.Hour)
+	costs, err := tc.cache.QueryHourlyCost(since, hourlyCostHours, tc.isExcludedModel, sources...)
+	if err != nil {
+		return nil
+	}
+	return costs
+}
+
+// clockSkew returns how far the newest cached event is ahead of now, when by
+// more than ClockSkewTolerance, and 0 otherwise
+func (tc *TokenCollector) clockSkew(now time.Time) time.Duration {
+	newest, err := tc.cache.QueryNewestEventTime()
+	if err != nil || newest.IsZero() {
+		return 0
+	}
+	if ahead := newest.Sub(now); ahead > ClockSkewTolerance {
+		return ahead
+	}
+	return 0
+}
+
+// clampToLookback limits the earliest and latest timestamps of a query to the
+// lookback range. Compacted files are counted whole, so their timestamps can
+// fall outside it.
+func (tc *TokenCollector) clampToLookback(earliest, latest time.Time) (time.Time, time.Time) {
+	if !tc.lookbackFrom.IsZero() && earliest.Before(tc.lookbackFrom) {
+		earliest = tc.lookbackFrom
+	}
+	if !tc.lookbackTo.IsZero() && latest.After(tc.lookbackTo) {
+		latest = tc.lookbackTo
+	}
+	return earliest, latest
+}
+
+// userTokenEvent converts a user message with reported usage into a token event.
+// Returns false when the message carries no usage.
+func userTokenEvent(msg claudeMessage, lastModel, filename string, lineNumber int64) (TokenEvent, bool) {
+	usage := msg.Message.Usage
+	cacheCreation := usage.cacheCreationTokens()
+	if usage.InputTokens+usage.CacheReadInputTokens+cacheCreation == 0 {
+		return TokenEvent{}, false
+	}
+
+	timestamp, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
+	if err != nil {
+		return TokenEvent{}, false
+	}
+
+	model := msg.Message.Model
+	if model == "" {
+		model = lastModel
+	}
+
+	return TokenEvent{
+		Timestamp:           timestamp,
+		Model:               model,
+		InputTokens:         usage.InputTokens,
+		CacheReadTokens:     usage.CacheReadInputTokens,
+		CacheCreationTokens: cacheCreation,
+		SourceFile:          filename,
+		LineNumber:          lineNumber,
+	}, true
+}
+
+// costPer1K returns the effective cost per thousand tokens, or 0 without tokens
+func costPer1K(cost float64, tokens int64) float64 {
+	if tokens <= 0 {
+		return 0
+	}
+	return cost / (float64(tokens) / 1000)
+}
+
+// cacheCosts returns the estimated cost of the cache writes and reads of the
+// models not excluded from the totals
+func cacheCosts(usages []ModelUsage) (write, read float64) {
+	for _, usage := range usages {
+		if usage.Excluded {
+			continue
+		}
+		pricing := getPricingForModel(usage.Model)
+		write += float64(usage.CacheCreationTokens) * pricing.CacheCreatePerMillion / 1_000_000
+		read += float64(usage.CacheReadTokens) * pricing.CacheReadPerMillion / 1_000_000
+	}
+	return write, read
+}
+
+// tokensPerPrompt returns the average tokens per response (0 without responses)
+func tokensPerPrompt(tokens, prompts int64) float64 {
+	if prompts <= 0 {
+		return 0
+	}
+	return float64(tokens) / float64(prompts)
+}
+
 // ingestJSONLFile reads a JSONL file and inserts new events into SQLite
 // Returns an error if database operations fail (for proper error handling)
 func (tc *TokenCollector) ingestJSONLFile(filename string) error {
@@ -424,10 +1016,8 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 
 		// Count current lines
 		var currentLineCount int64
-		scanner := bufio.NewScanner(file)
-		buf := make([]byte, 0, 1024*1024)
-		scanner.Buffer(buf, 10*1024*1024)
-		for scanner.Scan() {
+		lines := newJSONLReader(file)
+		for lines.Next() {
 			currentLineCount++
 		}
 
@@ -447,14 +1037,20 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 	}
 	defer file.Close()
 
-	scanner := bufio.NewScanner(file)
-	buf := make([]byte, 0, 1024*1024)
-	scanner.Buffer(buf, 10*1024*1024)
+	lines := newJSONLReader(file)
 
 	var lineNumber int64
 	var events []TokenEvent
+	var userEvents []TokenEvent
+	lastLineFailed := false // whether the most recent line failed to parse
+	lastModel := ""         // model of the latest assistant message, for user turns without one
+
+	// Resuming mid-file, the latest assistant message is already in the cache
+	if lastLine > 0 && tc.includeUserTokens {
+		lastModel, _ = tc.cache.QueryLastModel(filename)
+	}
 
-	for scanner.Scan() {
+	for lines.Next() {
 		lineNumber++
 
 		// Skip already processed lines
@@ -462,8 +1058,26 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 			continue
 		}
 
+		// Oversized lines are skipped for good; the lines after them still count
+		if lines.TooLong() {
+			log.Printf("skipping line %d of %s: longer than %d bytes", lineNumber, filename, maxJSONLLineSize)
+			lastLineFailed = false
+			continue
+		}
+
 		var msg claudeMessage
-		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
+		if err := json.Unmarshal(lines.Bytes(), &msg); err != nil {
+			lastLineFailed = true
+			continue
+		}
+		lastLineFailed = false
+
+		// User messages rarely carry usage; when they do and it's enabled,
+		// record it separately from assistant requests
+		if msg.Type == "user" && tc.includeUserTokens {
+			if event, ok := userTokenEvent(msg, lastModel, filename, lineNumber); ok {
+				userEvents = append(userEvents, event)
+			}
 			continue
 		}
 
@@ -471,6 +1085,9 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 		if msg.Type != "assistant" {
 			continue
 		}
+		if msg.Message.Model != "" {
+			lastModel = msg.Message.Model
+		}
 
 		// Parse timestamp
 		timestamp, err := time.Parse(time.RFC3339Nano, msg.Timestamp)
@@ -479,19 +1096,13 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 		}
 
 		usage := msg.Message.Usage
-		cacheCreation := usage.CacheCreationInputTokens
-		if cacheCreation == 0 {
-			cacheCreation = usage.CacheCreation.Ephemeral5mInputTokens +
-				usage.CacheCreation.Ephemeral1hInputTokens
-		}
-
 		events = append(events, TokenEvent{
 			Timestamp:           timestamp,
 			Model:               msg.Message.Model,
 			InputTokens:         usage.InputTokens,
 			OutputTokens:        usage.OutputTokens,
 			CacheReadTokens:     usage.CacheReadInputTokens,
-			CacheCreationTokens: cacheCreation,
+			CacheCreationTokens: usage.cacheCreationTokens(),
 			SourceFile:          filename,
 			LineNumber:          lineNumber,
 		})
@@ -511,9 +1122,27 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 			return fmt.Errorf("failed to insert final batch for %s: %w", filename, err)
 		}
 	}
+	if len(userEvents) > 0 {
+		if err := tc.cache.InsertUserTokenEventBatch(userEvents); err != nil {
+			return fmt.Errorf("failed to insert user token events for %s: %w", filename, err)
+		}
+	}
+
+	// Leave the file state alone after a read error, so the unread lines are
+	// tried again next cycle
+	if err := lines.Err(); err != nil {
+		return fmt.Errorf("failed to read %s: %w", filename, err)
+	}
+
+	// If the final line didn't parse, Claude is most likely still writing it.
+	// Don't mark it processed so it's re-read once the write completes.
+	processedLine := lineNumber
+	if lastLineFailed && processedLine > lastLine {
+		processedLine--
+	}
 
 	// Update file state
-	if err := tc.cache.SetFileState(filename, lineNumber, fileInfo.ModTime()); err != nil {
+	if err := tc.cache.SetFileState(filename, processedLine, fileInfo.ModTime()); err != nil {
 		return fmt.Errorf("failed to set file state for %s: %w", filename, err)
 	}
 
@@ -523,7 +1152,7 @@ func (tc *TokenCollector) ingestJSONLFile(filename string) error {
 // findProjectDir finds the Claude project directory for the given working directory,
 // searching across all configured roots.
 func (tc *TokenCollector) findProjectDir(cwd string) string {
-	projectName := strings.ReplaceAll(cwd, "/", "-")
+	projectName := ProjectDirName(cwd)
 	for _, root := range tc.projectsDirs {
 		projectPath := filepath.Join(root, projectName)
 		if _, err := os.Stat(projectPath); err == nil {
@@ -533,6 +1162,49 @@ func (tc *TokenCollector) findProjectDir(cwd string) string {
 	return ""
 }
 
+// ProjectDirName returns the directory name Claude Code uses under
+// ~/.claude/projects for a working directory ("/home/me/app" -> "-home-me-app")
+func ProjectDirName(cwd string) string {
+	return strings.ReplaceAll(cwd, "/", "-")
+}
+
+// ProjectFromSourceFile returns the project directory name a JSONL file belongs
+// to: the directory right under a "projects" root, or else the nearest one
+// named like an encoded path. Subagent files are nested deeper than sessions.
+func ProjectFromSourceFile(sourceFile string) string {
+	for dir := filepath.Dir(sourceFile); dir != filepath.Dir(dir); dir = filepath.Dir(dir) {
+		name := filepath.Base(dir)
+		if filepath.Base(filepath.Dir(dir)) == "projects" || strings.HasPrefix(name, "-") {
+			return name
+		}
+	}
+	return filepath.Base(filepath.Dir(sourceFile))
+}
+
+// ProjectPath turns a project directory name back into the working directory
+// it was made from ("-home-me-app" -> "/home/me/app"). The encoding is lossy, so
+// dashes that belong to a directory name are recovered by checking which paths
+// exist; the rest become slashes.
+func ProjectPath(name string) string {
+	if !strings.HasPrefix(name, "-") {
+		return name
+	}
+	parts := strings.Split(name[1:], "-")
+	path := "/"
+	for i := 0; i < len(parts); {
+		// Take the longest run of parts that names an existing entry
+		j := len(parts)
+		for ; j > i+1; j-- {
+			if _, err := os.Stat(filepath.Join(path, strings.Join(parts[i:j], "-"))); err == nil {
+				break
+			}
+		}
+		path = filepath.Join(path, strings.Join(parts[i:j], "-"))
+		i = j
+	}
+	return path
+}
+
 // findAllProjectDirs returns all project directories found under all configured roots.
 func (tc *TokenCollector) findAllProjectDirs() ([]string, error) {
 	var dirs []string
@@ -812,8 +1484,45 @@ var defaultPricing = ModelPricing{
 	CacheCreatePerMillion: 0.00,
 }
 
+// modelAggregationCost returns the estimated cost of a model's token breakdown
+func modelAggregationCost(model string, mm *ModelAggregation) float64 {
+	pricing := getPricingForModel(model)
+	inputCost := float64(mm.InputTokens) * pricing.InputPerMillion / 1_000_000
+	outputCost := float64(mm.OutputTokens) * pricing.OutputPerMillion / 1_000_000
+	cacheReadCost := float64(mm.CacheReadTokens) * pricing.CacheReadPerMillion / 1_000_000
+	cacheCreateCost := float64(mm.CacheCreationTokens) * pricing.CacheCreatePerMillion / 1_000_000
+	return inputCost + outputCost + cacheReadCost + cacheCreateCost
+}
+
+// userInputUsage returns the tokens and cost of usage reported on user
+// messages, billed at the input rates of the model each turn was sent to.
+// Turns sent to excluded models aren't counted.
+func (tc *TokenCollector) userInputUsage(userAgg *AggregatedTokens) (tokens int64, cost float64) {
+	for model, mm := range userAgg.ModelMetrics {
+		if tc.isExcludedModel(model) {
+			continue
+		}
+		pricing := getPricingForModel(model)
+		tokens += mm.InputTokens + mm.CacheReadTokens + mm.CacheCreationTokens
+		cost += float64(mm.InputTokens)*pricing.InputPerMillion/1_000_000 +
+			float64(mm.CacheReadTokens)*pricing.CacheReadPerMillion/1_000_000 +
+			float64(mm.CacheCreationTokens)*pricing.CacheCreatePerMillion/1_000_000
+	}
+	return tokens, cost
+}
+
+// PricingForModel returns the rates a model's cost is estimated at
+func PricingForModel(model string) ModelPricing {
+	return getPricingForModel(model)
+}
+
 // getPricingForModel returns the pricing for a given model name
 func getPricingForModel(model string) ModelPricing {
+	// Rates from pricing_url take precedence
+	if pricing, ok := lookupRemotePricing(model); ok {
+		return pricing
+	}
+
 	// Check exact match first
 	if pricing, ok := modelPricing[model]; ok {
 		return pricing
@@ -887,6 +1596,104 @@ func getPricingForModel(model string) ModelPricing {
 	return defaultPricing
 }
 
+// modelContextLimit returns the context window size in tokens for a model.
+// Claude models share a 200K window unless a 1M-context variant is selected.
+func modelContextLimit(model string) int64 {
+	switch {
+	case strings.Contains(model, "[1m]"):
+		return 1_000_000
+	case strings.Contains(model, "glm-4.5") || strings.Contains(model, "glm-4-5"),
+		strings.Contains(model, "glm-4-plus"), strings.Contains(model, "glm-4-air"),
+		strings.Contains(model, "glm-4-flash"), strings.Contains(model, "glm-4-9b"):
+		return 128_000
+	default:
+		return 200_000
+	}
+}
+
+// AttachContextUsage fills in the context-window estimate for sessions with a
+// known Claude Code session ID and project directory. The estimate is the
+// context size of the session's most recent request, so it tracks how close
+// the session is to auto-compaction.
+func (tc *TokenCollector) AttachContextUsage(sessions []TmuxSession) {
+	if tc.cache == nil {
+		return
+	}
+
+	for i := range sessions {
+		if sessions[i].SessionID == "" || sessions[i].ProjectDir == "" {
+			continue
+		}
+		projectDir := tc.findProjectDir(sessions[i].ProjectDir)
+		if projectDir == "" {
+			continue
+		}
+		usage, err := tc.cache.QueryLatestContext(filepath.Join(projectDir, sessions[i].SessionID+".jsonl"))
+		if err != nil || usage == nil {
+			continue
+		}
+		sessions[i].ContextTokens = usage.Tokens
+		sessions[i].ContextLimit = modelContextLimit(usage.Model)
+		// The log's model is exact, unlike the one read off the pane
+		sessions[i].Model = usage.Model
+	}
+}
+
+// AttachSessionUsage fills in approximate tokens and cost for sessions with a
+// known project directory. Hook-tracked sessions are matched to their own
+// <session_id>.jsonl; otherwise every log in the session's Claude project
+// since the session was created is counted.
+func (tc *TokenCollector) AttachSessionUsage(sessions []TmuxSession) {
+	if tc.cache == nil {
+		return
+	}
+
+	for i := range sessions {
+		if sessions[i].ProjectDir == "" {
+			continue
+		}
+		projectDir := tc.findProjectDir(sessions[i].ProjectDir)
+		if projectDir == "" {
+			continue
+		}
+
+		prefix := projectDir + string(filepath.Separator)
+		since := sessions[i].Created
+		if sessions[i].SessionID != "" {
+			prefix = filepath.Join(projectDir, sessions[i].SessionID+".jsonl")
+			since = time.Time{}
+		}
+
+		usage, err := tc.cache.QuerySourceUsage(prefix, since)
+		if err != nil {
+			continue
+		}
+		sessions[i].Tokens = usage.Tokens
+		sessions[i].Cost = usage.Cost
+	}
+}
+
+// CollectProjectUsage returns token usage within the lookback window per
+// project, costliest first, with each project's working directory decoded
+func (tc *TokenCollector) CollectProjectUsage() ([]ProjectUsage, error) {
+	projects, err := tc.cache.QueryByProject(tc.lookbackFrom, tc.lookbackTo)
+	for i := range projects {
+		projects[i].Path = ProjectPath(projects[i].Project)
+	}
+	return projects, err
+}
+
+// CollectHourOfDay returns token usage within the lookback window bucketed by local hour of day
+func (tc *TokenCollector) CollectHourOfDay() ([24]HourOfDayUsage, error) {
+	return tc.cache.QueryByHourOfDayBetween(tc.lookbackFrom, tc.lookbackTo)
+}
+
+// CollectModelActivity returns when model was first and last used in the
+// lookback window and project scope
+func (tc *TokenCollector) CollectModelActivity(model string) (first, last time.Time, err error) {
+	return tc.cache.QueryModelActivity(model, tc.lookbackFrom, tc.lookbackTo, tc.scopeSourcePrefixes()...)
+}
+
 // GetCacheDBPath returns the path to the SQLite database for external tools like DuckDB
 func (tc *TokenCollector) GetCacheDBPath() string {
 	if tc.cache != nil {
@@ -956,6 +1763,37 @@ func FormatCost(cost float64) string {
 	return fmt.Sprintf("$%.2f", cost)
 }
 
+// currencySymbols maps ISO 4217 codes to the symbol written before amounts.
+// Other codes are written after the amount, e.g. "98.20 SEK".
+var currencySymbols = map[string]string{
+	"USD": "$",
+	"GBP": "£",
+	"EUR": "€",
+	"JPY": "¥",
+	"CNY": "¥",
+	"INR": "₹",
+	"KRW": "₩",
+	"AUD": "A$",
+	"CAD": "C$",
+	"NZD": "NZ$",
+	"BRL": "R$",
+}
+
+// FormatCurrency formats an amount in the given currency the way FormatCost
+// formats dollars, e.g. FormatCurrency(9.8, "GBP") = "£9.80"
+func FormatCurrency(amount float64, code string) string {
+	digits := strings.TrimPrefix(FormatCost(amount), "$")
+	if symbol, ok := currencySymbols[code]; ok {
+		return symbol + digits
+	}
+	return digits + " " + code
+}
+
+// FormatCostPer1K formats a per-1K-token cost with enough precision for sub-cent values
+func FormatCostPer1K(cost float64) string {
+	return fmt.Sprintf("$%.4f", cost)
+}
+
 // FormatTokenRate formats a token rate as tokens/min
 func FormatTokenRate(rate float64) string {
 	if rate == 0 {
@@ -984,6 +1822,10 @@ func FormatTokenRateCompact(rate float64) string {
 
 // FormatDuration formats a duration in a human-readable format
 func FormatDuration(d time.Duration) string {
+	// A negative span only comes from a clock that was set back
+	if d < 0 {
+		d = 0
+	}
 	if d < time.Minute {
 		return fmt.Sprintf("%.0fs", d.Seconds())
 	}
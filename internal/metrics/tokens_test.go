@@ -1,9 +1,14 @@
 package metrics
 
 import (
+	"fmt"
+	"math"
 	"os"
 	"path/filepath"
+	"slices"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestExpandGlobPatterns(t *testing.T) {
@@ -93,3 +98,747 @@ func TestExpandGlobPatternsDeduplication(t *testing.T) {
 		t.Errorf("Expected 1 unique path after deduplication, got %d", len(expanded))
 	}
 }
+
+// newTestCollector returns a TokenCollector with its own cache, and the
+// temporary directory it lives in. projectsDirs is the directory's projects
+// subdirectory, which tests create as needed.
+func newTestCollector(t *testing.T) (*TokenCollector, string) {
+	t.Helper()
+	dir := t.TempDir()
+	tc := &TokenCollector{
+		projectsDirs: []string{filepath.Join(dir, "projects")},
+		cache:        NewTokenCacheWithDir(filepath.Join(dir, cacheDirName)),
+	}
+	t.Cleanup(func() { tc.cache.Close() })
+	return tc, dir
+}
+
+func TestIngestJSONLFilePartialLastLine(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	now := time.Now().UTC()
+	line := func(ts time.Time, input int) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":%d,"output_tokens":10}}}`,
+			ts.Format(time.RFC3339Nano), input)
+	}
+	first := line(now.Add(-2*time.Minute), 100)
+	second := line(now.Add(-time.Minute), 200)
+
+	// Write one complete line and half of the next, as if Claude is mid-write
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(first+"\n"+second[:len(second)/2]), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("First ingest failed: %v", err)
+	}
+	assertEventCount := func(want int64, wantInput int64) {
+		t.Helper()
+		agg, err := tc.cache.QueryTokensSince(now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to query events: %v", err)
+		}
+		if agg.EventCount != want || agg.InputTokens != wantInput {
+			t.Errorf("Expected %d events / %d input tokens, got %d / %d", want, wantInput, agg.EventCount, agg.InputTokens)
+		}
+	}
+	assertEventCount(1, 100)
+
+	// Complete the partial line; bump mtime so the file is seen as modified
+	if err := os.WriteFile(jsonlPath, []byte(first+"\n"+second+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to complete JSONL: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(jsonlPath, later, later); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Second ingest failed: %v", err)
+	}
+	assertEventCount(2, 300)
+
+	// Re-ingesting a modified file must not count the completed line again
+	evenLater := later.Add(time.Minute)
+	if err := os.Chtimes(jsonlPath, evenLater, evenLater); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Third ingest failed: %v", err)
+	}
+	assertEventCount(2, 300)
+}
+
+func TestIngestJSONLFileOversizedLine(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	now := time.Now().UTC()
+	line := func(ts time.Time, input int) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":%d,"output_tokens":10}}}`,
+			ts.Format(time.RFC3339Nano), input)
+	}
+	// A tool result with a huge base64 image between two requests
+	huge := `{"type":"user","message":{"content":"` + strings.Repeat("A", maxJSONLLineSize+1) + `"}}`
+	content := line(now.Add(-2*time.Minute), 100) + "\n" + huge + "\n" + line(now.Add(-time.Minute), 200) + "\n"
+
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	agg, err := tc.cache.QueryTokensSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query events: %v", err)
+	}
+	if agg.EventCount != 2 || agg.InputTokens != 300 {
+		t.Errorf("Expected both requests around the oversized line, got %d events / %d input tokens", agg.EventCount, agg.InputTokens)
+	}
+	if processed, _, _ := tc.cache.GetFileState(jsonlPath); processed != 3 {
+		t.Errorf("Expected 3 lines processed, got %d", processed)
+	}
+}
+
+func TestIngestEventsExpandsCompactedFiles(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	projectDir := filepath.Join(tmpDir, "projects", "-home-me-app")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	ts := time.Now().Add(-2 * time.Hour).UTC()
+	jsonlPath := filepath.Join(projectDir, "s1.jsonl")
+	content := fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":100,"output_tokens":10}}}`+"\n", ts.Format(time.RFC3339Nano))
+	if err := os.WriteFile(jsonlPath, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+	// Old enough to be compacted into an aggregate
+	if err := os.Chtimes(jsonlPath, ts, ts); err != nil {
+		t.Fatalf("Failed to update mtime: %v", err)
+	}
+
+	exported := func() int {
+		t.Helper()
+		var buf strings.Builder
+		if err := tc.cache.ExportJSONL(&buf, time.Time{}); err != nil {
+			t.Fatalf("ExportJSONL failed: %v", err)
+		}
+		return strings.Count(buf.String(), "\n")
+	}
+	totalInput := func() int64 {
+		t.Helper()
+		agg, err := tc.cache.QueryTokensHybrid(ts.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to query: %v", err)
+		}
+		return agg.InputTokens
+	}
+
+	tc.Ingest()
+	if n := exported(); n != 0 {
+		t.Fatalf("Expected the old file to be compacted, got %d events", n)
+	}
+
+	tc.IngestEvents()
+	if n := exported(); n != 1 {
+		t.Errorf("Expected IngestEvents to restore 1 event, got %d", n)
+	}
+	if got := totalInput(); got != 100 {
+		t.Errorf("Expected totals unchanged after IngestEvents, got %d input tokens", got)
+	}
+
+	// A regular ingestion compacts the file again without double counting
+	tc.Ingest()
+	if got := totalInput(); got != 100 {
+		t.Errorf("Expected totals unchanged after re-compaction, got %d input tokens", got)
+	}
+}
+
+func TestForceReingestPicksUpMissedLines(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	projectDir := filepath.Join(tmpDir, "projects", "-home-me-app")
+	if err := os.MkdirAll(projectDir, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+	line := func(ts time.Time, input int) string {
+		return fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":%d}}}`+"\n", ts.Format(time.RFC3339Nano), input)
+	}
+	// One file old enough to be compacted, one still active
+	// Whole seconds, the precision file state records mtimes at
+	oldTS := time.Now().Add(-2 * time.Hour).Truncate(time.Second).UTC()
+	newTS := time.Now().Add(-time.Minute).Truncate(time.Second).UTC()
+	files := map[string]time.Time{
+		filepath.Join(projectDir, "old.jsonl"): oldTS,
+		filepath.Join(projectDir, "new.jsonl"): newTS,
+	}
+	for path, ts := range files {
+		if err := os.WriteFile(path, []byte(line(ts, 100)), 0644); err != nil {
+			t.Fatalf("Failed to write JSONL: %v", err)
+		}
+		if err := os.Chtimes(path, ts, ts); err != nil {
+			t.Fatalf("Failed to update mtime: %v", err)
+		}
+	}
+
+	totalInput := func() int64 {
+		t.Helper()
+		agg, err := tc.cache.QueryTokensHybrid(time.Time{})
+		if err != nil {
+			t.Fatalf("Failed to query: %v", err)
+		}
+		return agg.InputTokens
+	}
+
+	tc.Ingest()
+	if got := totalInput(); got != 200 {
+		t.Fatalf("Expected 200 input tokens after the first ingestion, got %d", got)
+	}
+
+	// New lines land, but the mtimes look unchanged, as after clock skew
+	for path, ts := range files {
+		f, err := os.OpenFile(path, os.O_APPEND|os.O_WRONLY, 0644)
+		if err != nil {
+			t.Fatalf("Failed to open JSONL: %v", err)
+		}
+		f.WriteString(line(ts.Add(time.Second), 10))
+		f.Close()
+		os.Chtimes(path, ts, ts)
+	}
+	tc.Ingest()
+	if got := totalInput(); got != 200 {
+		t.Fatalf("Expected the appended lines to be missed, got %d input tokens", got)
+	}
+
+	if _, err := tc.ForceReingest(); err != nil {
+		t.Fatalf("ForceReingest failed: %v", err)
+	}
+	tc.Ingest()
+	if got := totalInput(); got != 220 {
+		t.Errorf("Expected 220 input tokens after a forced re-ingest, without double counting, got %d", got)
+	}
+	if !tc.cache.IsFileComplete(filepath.Join(projectDir, "old.jsonl")) {
+		t.Error("Expected the old file to be compacted again")
+	}
+}
+
+func TestCollectHistoricalRange(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	// A 9am-5pm window last week, with usage inside it and happening now
+	now := time.Now()
+	from := time.Date(now.Year(), now.Month(), now.Day()-7, 9, 0, 0, 0, now.Location())
+	to := from.Add(8 * time.Hour)
+	events := []TokenEvent{
+		{Timestamp: from.Add(time.Hour), Model: "claude-sonnet-4", InputTokens: 100, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: from.Add(3 * time.Hour), Model: "claude-sonnet-4", InputTokens: 200, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+		{Timestamp: now.Add(-40 * time.Second), Model: "claude-sonnet-4", InputTokens: 2500, SourceFile: "/tmp/b.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-10 * time.Second), Model: "claude-sonnet-4", InputTokens: 2500, SourceFile: "/tmp/b.jsonl", LineNumber: 2},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+
+	tc.SetLookbackRange(from, to)
+	m, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if m.TotalTokens != 300 {
+		t.Errorf("Expected 300 tokens inside the window, got %d", m.TotalTokens)
+	}
+	if m.TimeSpan != 2*time.Hour {
+		t.Errorf("Expected a 2h span between the window's events, got %v", m.TimeSpan)
+	}
+	if m.Rate != 0 {
+		t.Errorf("Expected no live rate for a window that has ended, got %v", m.Rate)
+	}
+
+	// Without an end the live rate comes back
+	tc.SetLookback(from)
+	if m, _ := tc.Collect(); m.Rate == 0 || m.TotalTokens != 5300 {
+		t.Errorf("Expected a live rate and 5300 tokens with no end, got rate %v and %d tokens", m.Rate, m.TotalTokens)
+	}
+}
+
+func TestProjectPath(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	// A directory with a dash in its name, which the encoding can't tell apart from a slash
+	project := filepath.Join(tmpDir, "my-app", "web")
+	if err := os.MkdirAll(project, 0755); err != nil {
+		t.Fatalf("Failed to create project dir: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		dir  string
+		want string
+	}{
+		{"existing path with dashes", ProjectDirName(project), project},
+		{"missing path", "-nonexistent-ccdash-a-b", "/nonexistent/ccdash/a/b"},
+		{"not an encoded path", "scratch", "scratch"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := ProjectPath(tt.dir); got != tt.want {
+				t.Errorf("ProjectPath(%q) = %q, want %q", tt.dir, got, tt.want)
+			}
+		})
+	}
+
+	if got := ProjectFromSourceFile("/root/.claude/projects/-home-me-app/abc/subagents/agent-1.jsonl"); got != "-home-me-app" {
+		t.Errorf("Expected a subagent file to belong to -home-me-app, got %q", got)
+	}
+}
+
+func TestIngestJSONLFileUserTokens(t *testing.T) {
+	tmpDir := t.TempDir()
+
+	now := time.Now().UTC()
+	lines := []string{
+		fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":100,"output_tokens":10}}}`,
+			now.Add(-3*time.Minute).Format(time.RFC3339Nano)),
+		fmt.Sprintf(`{"type":"user","timestamp":%q,"message":{"role":"user","usage":{"input_tokens":40,"cache_read_input_tokens":60}}}`,
+			now.Add(-2*time.Minute).Format(time.RFC3339Nano)),
+		fmt.Sprintf(`{"type":"user","timestamp":%q,"message":{"role":"user","content":"no usage here"}}`,
+			now.Add(-time.Minute).Format(time.RFC3339Nano)),
+	}
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+
+	for _, include := range []bool{false, true} {
+		tc, _ := newTestCollector(t)
+		tc.SetIncludeUserTokens(include)
+
+		if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+			t.Fatalf("Ingest failed (include=%v): %v", include, err)
+		}
+
+		// Assistant totals are the same either way
+		agg, err := tc.cache.QueryTokensSince(now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to query events: %v", err)
+		}
+		if agg.EventCount != 1 || agg.InputTokens != 100 {
+			t.Errorf("include=%v: expected 1 assistant event with 100 input tokens, got %d / %d", include, agg.EventCount, agg.InputTokens)
+		}
+
+		user, err := tc.cache.QueryUserTokensSince(now.Add(-time.Hour))
+		if err != nil {
+			t.Fatalf("Failed to query user tokens: %v", err)
+		}
+		wantEvents, wantInput, wantCacheRead := int64(0), int64(0), int64(0)
+		if include {
+			wantEvents, wantInput, wantCacheRead = 1, 40, 60
+		}
+		if user.EventCount != wantEvents || user.InputTokens != wantInput || user.CacheReadTokens != wantCacheRead {
+			t.Errorf("include=%v: expected %d user events (%d input, %d cache read), got %d (%d, %d)",
+				include, wantEvents, wantInput, wantCacheRead, user.EventCount, user.InputTokens, user.CacheReadTokens)
+		}
+		// User turns without a model inherit the preceding assistant model for pricing
+		if include && user.ModelMetrics["claude-sonnet-4"] == nil {
+			t.Errorf("Expected user tokens attributed to claude-sonnet-4, got %v", user.ModelTokens)
+		}
+	}
+}
+
+func TestIngestJSONLFileUserTokensAfterResume(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	now := time.Now().UTC()
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	assistant := fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-opus-4","usage":{"input_tokens":100,"output_tokens":10}}}`,
+		now.Add(-2*time.Minute).Format(time.RFC3339Nano))
+	if err := os.WriteFile(jsonlPath, []byte(assistant+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+
+	tc.SetIncludeUserTokens(true)
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	// The user turn arrives in a later pass, which starts after the assistant line
+	user := fmt.Sprintf(`{"type":"user","timestamp":%q,"message":{"role":"user","usage":{"input_tokens":40}}}`,
+		now.Add(-time.Minute).Format(time.RFC3339Nano))
+	if err := os.WriteFile(jsonlPath, []byte(assistant+"\n"+user+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to append to JSONL: %v", err)
+	}
+	later := time.Now().Add(time.Minute)
+	if err := os.Chtimes(jsonlPath, later, later); err != nil {
+		t.Fatalf("Failed to touch JSONL: %v", err)
+	}
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Second ingest failed: %v", err)
+	}
+
+	agg, err := tc.cache.QueryUserTokensSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query user tokens: %v", err)
+	}
+	if agg.EventCount != 1 || agg.ModelMetrics["claude-opus-4"] == nil {
+		t.Errorf("Expected the user turn attributed to the cached claude-opus-4 request, got %d events on %v", agg.EventCount, agg.ModelTokens)
+	}
+}
+
+func TestIngestJSONLFileCacheCreationShapes(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	// The total and its TTL breakdown describe the same tokens, so each line
+	// counts once, whichever fields it has
+	now := time.Now().UTC()
+	usages := []struct {
+		usage string
+		want  int64
+	}{
+		{`"cache_creation_input_tokens":1000,"cache_creation":{"ephemeral_5m_input_tokens":0,"ephemeral_1h_input_tokens":1000}`, 1000},
+		{`"cache_creation_input_tokens":500`, 500},
+		{`"cache_creation":{"ephemeral_5m_input_tokens":200,"ephemeral_1h_input_tokens":300}`, 500},
+		{`"cache_creation_input_tokens":100,"cache_creation":{"ephemeral_5m_input_tokens":0,"ephemeral_1h_input_tokens":400}`, 400},
+	}
+	var lines []string
+	var want int64
+	for i, u := range usages {
+		lines = append(lines, fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":1,%s}}}`,
+			now.Add(-time.Duration(len(usages)-i)*time.Minute).Format(time.RFC3339Nano), u.usage))
+		want += u.want
+	}
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(strings.Join(lines, "\n")+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	agg, err := tc.cache.QueryTokensSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query events: %v", err)
+	}
+	if agg.EventCount != int64(len(usages)) || agg.CacheCreationTokens != want {
+		t.Errorf("Expected %d events with %d cache creation tokens, got %d / %d", len(usages), want, agg.EventCount, agg.CacheCreationTokens)
+	}
+}
+
+func TestReingestRaisesUndercountedCacheWrites(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	now := time.Now().UTC().Add(-time.Minute)
+	line := fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":1,"cache_creation_input_tokens":100,"cache_creation":{"ephemeral_5m_input_tokens":0,"ephemeral_1h_input_tokens":400}}}}`,
+		now.Format(time.RFC3339Nano))
+	jsonlPath := filepath.Join(tmpDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(line+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+
+	// The line as an older version stored it, counting only the total
+	old := TokenEvent{Timestamp: now, Model: "claude-sonnet-4", InputTokens: 1, CacheCreationTokens: 100, SourceFile: jsonlPath, LineNumber: 1}
+	if err := tc.cache.InsertTokenEventBatch([]TokenEvent{old}); err != nil {
+		t.Fatalf("Failed to insert old event: %v", err)
+	}
+	if err := tc.ingestJSONLFile(jsonlPath); err != nil {
+		t.Fatalf("Ingest failed: %v", err)
+	}
+
+	agg, err := tc.cache.QueryTokensSince(now.Add(-time.Hour))
+	if err != nil {
+		t.Fatalf("Failed to query events: %v", err)
+	}
+	if agg.EventCount != 1 || agg.CacheCreationTokens != 400 {
+		t.Errorf("Expected the re-read line to raise its count to 400, got %d events / %d", agg.EventCount, agg.CacheCreationTokens)
+	}
+
+	// An import of the old count can't lower it again
+	if err := tc.cache.InsertTokenEventBatch([]TokenEvent{old}); err != nil {
+		t.Fatalf("Failed to insert old event: %v", err)
+	}
+	if agg, _ := tc.cache.QueryTokensSince(now.Add(-time.Hour)); agg.CacheCreationTokens != 400 {
+		t.Errorf("Expected the larger count to be kept, got %d", agg.CacheCreationTokens)
+	}
+}
+
+func TestFormatCurrency(t *testing.T) {
+	tests := []struct {
+		name   string
+		amount float64
+		code   string
+		want   string
+	}{
+		{"symbol", 9.8, "GBP", "£9.80"},
+		{"thousands", 1234.5, "EUR", "€1,234.50"},
+		{"multi-character symbol", 12, "CAD", "C$12.00"},
+		{"code suffix", 98.2, "SEK", "98.20 SEK"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := FormatCurrency(tt.amount, tt.code); got != tt.want {
+				t.Errorf("FormatCurrency(%v, %q) = %q, want %q", tt.amount, tt.code, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCollectTodayCost(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	today := StartOfToday()
+	events := []TokenEvent{
+		{Timestamp: today.AddDate(0, 0, -2), Model: "claude-sonnet-4", InputTokens: 3_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: today.Add(time.Second), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	todayCost := getPricingForModel("claude-sonnet-4").InputPerMillion
+
+	// A week-long lookback still reports only today's spend
+	tc.SetLookback(today.AddDate(0, 0, -7))
+	m, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if m.TodayCost != todayCost || m.TotalCost != 4*todayCost {
+		t.Errorf("Expected today $%.2f of $%.2f total, got today $%.2f of $%.2f", todayCost, 4*todayCost, m.TodayCost, m.TotalCost)
+	}
+	if m.Prompts != 2 || m.TokensPerPrompt != 2_000_000 {
+		t.Errorf("Expected 2 requests averaging 2M tokens, got %d averaging %.0f", m.Prompts, m.TokensPerPrompt)
+	}
+
+	tc.SetLookback(today)
+	if m, _ := tc.Collect(); m.TodayCost != todayCost {
+		t.Errorf("Expected today $%.2f with a today lookback, got $%.2f", todayCost, m.TodayCost)
+	}
+}
+
+func TestCollectExcludedModels(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-time.Hour), Model: "claude-sonnet-4", InputTokens: 1_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Hour), Model: "claude-haiku-4-5-20250929", InputTokens: 4_000_000, OutputTokens: 100, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	sonnetCost := getPricingForModel("claude-sonnet-4").InputPerMillion
+
+	tc.SetLookback(now.Add(-24 * time.Hour))
+	tc.SetExcludedModels([]string{"claude-haiku"})
+	m, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if m.TotalTokens != 1_000_000 || m.InputTokens != 1_000_000 || m.OutputTokens != 0 {
+		t.Errorf("Expected only Sonnet's tokens in the totals, got total %d input %d output %d", m.TotalTokens, m.InputTokens, m.OutputTokens)
+	}
+	if math.Abs(m.TotalCost-sonnetCost) > 1e-9 {
+		t.Errorf("Expected total cost $%.2f without Haiku, got $%.2f", sonnetCost, m.TotalCost)
+	}
+	if len(m.ModelUsages) != 2 || m.ModelUsages[0].Excluded || !m.ModelUsages[1].Excluded || m.ModelUsages[1].TotalTokens != 4_000_100 {
+		t.Errorf("Expected Haiku kept last in the breakdown and marked excluded, got %+v", m.ModelUsages)
+	}
+	if cost, _ := tc.CostSince(now.Add(-24 * time.Hour)); math.Abs(cost-sonnetCost) > 1e-9 {
+		t.Errorf("Expected CostSince to leave Haiku out too, got $%.2f", cost)
+	}
+}
+
+func TestCollectModelSort(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	// Sonnet costs the most, Haiku uses the most tokens
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-time.Hour), Model: "claude-opus-4-5-20251101", InputTokens: 100_000, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Hour), Model: "claude-sonnet-4-5-20250929", InputTokens: 1_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+		{Timestamp: now.Add(-time.Hour), Model: "claude-haiku-4-5-20250929", InputTokens: 2_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 3},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	tc.SetLookback(now.Add(-24 * time.Hour))
+
+	tests := []struct {
+		sort     string
+		excluded []string
+		want     []string
+	}{
+		{ModelSortCost, nil, []string{"claude-sonnet-4-5-20250929", "claude-haiku-4-5-20250929", "claude-opus-4-5-20251101"}},
+		{ModelSortTokens, nil, []string{"claude-haiku-4-5-20250929", "claude-sonnet-4-5-20250929", "claude-opus-4-5-20251101"}},
+		{ModelSortName, nil, []string{"claude-haiku-4-5-20250929", "claude-opus-4-5-20251101", "claude-sonnet-4-5-20250929"}},
+		// Excluded models stay last
+		{ModelSortTokens, []string{"claude-haiku"}, []string{"claude-sonnet-4-5-20250929", "claude-opus-4-5-20251101", "claude-haiku-4-5-20250929"}},
+	}
+	for _, tt := range tests {
+		if err := tc.SetModelSort(tt.sort); err != nil {
+			t.Fatalf("SetModelSort(%q) failed: %v", tt.sort, err)
+		}
+		tc.SetExcludedModels(tt.excluded)
+		m, err := tc.Collect()
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		var got []string
+		for _, usage := range m.ModelUsages {
+			got = append(got, usage.Model)
+		}
+		if !slices.Equal(got, tt.want) {
+			t.Errorf("Sorted by %s excluding %v: got %v, want %v", tt.sort, tt.excluded, got, tt.want)
+		}
+	}
+
+	if err := tc.SetModelSort("price"); err == nil {
+		t.Error("Expected an error for an unknown sort")
+	}
+}
+
+func TestCollectCacheCosts(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now.Add(-time.Hour), Model: "claude-opus-4-5-20251101", CacheCreationTokens: 2_000_000, CacheReadTokens: 10_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 1},
+		{Timestamp: now.Add(-time.Hour), Model: "claude-haiku-4-5-20250929", CacheCreationTokens: 1_000_000, SourceFile: "/tmp/a.jsonl", LineNumber: 2},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	opus := getPricingForModel("claude-opus-4-5-20251101")
+
+	// Excluded models' cache use isn't counted
+	tc.SetLookback(now.Add(-24 * time.Hour))
+	tc.SetExcludedModels([]string{"claude-haiku"})
+	m, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if want := 2 * opus.CacheCreatePerMillion; math.Abs(m.CacheWriteCost-want) > 1e-9 {
+		t.Errorf("Expected cache build cost $%.2f, got $%.2f", want, m.CacheWriteCost)
+	}
+	if want := 10 * opus.CacheReadPerMillion; math.Abs(m.CacheReadCost-want) > 1e-9 {
+		t.Errorf("Expected cache read cost $%.2f, got $%.2f", want, m.CacheReadCost)
+	}
+}
+
+func TestCollectExplainsNoUsage(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	projectsDir := filepath.Join(tmpDir, "projects")
+	projectDir := filepath.Join(projectsDir, "-home-me-app")
+
+	collect := func(want string) {
+		t.Helper()
+		tc.Ingest()
+		m, err := tc.Collect()
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if want == "" {
+			if !m.Available || m.Error != "" {
+				t.Errorf("Expected usage without an error, got available=%v error %q", m.Available, m.Error)
+			}
+			return
+		}
+		if m.Available || !strings.Contains(m.Error, want) {
+			t.Errorf("Expected unavailable with an error containing %q, got available=%v error %q", want, m.Available, m.Error)
+		}
+	}
+
+	collect("not found")
+
+	os.MkdirAll(projectsDir, 0755)
+	collect("No projects in")
+
+	os.MkdirAll(projectDir, 0755)
+	collect("1 project directory in")
+
+	userLine := fmt.Sprintf(`{"type":"user","timestamp":%q,"message":{"role":"user","content":"hi"}}`,
+		time.Now().UTC().Format(time.RFC3339Nano))
+	jsonlPath := filepath.Join(projectDir, "session.jsonl")
+	if err := os.WriteFile(jsonlPath, []byte(userLine+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+	collect("1 JSONL log but no assistant responses")
+
+	// Usage outside the lookback window is no reason to complain
+	assistantLine := fmt.Sprintf(`{"type":"assistant","timestamp":%q,"message":{"model":"claude-sonnet-4","usage":{"input_tokens":100,"output_tokens":10}}}`,
+		time.Now().Add(-48*time.Hour).UTC().Format(time.RFC3339Nano))
+	if err := os.WriteFile(jsonlPath, []byte(userLine+"\n"+assistantLine+"\n"), 0644); err != nil {
+		t.Fatalf("Failed to write JSONL: %v", err)
+	}
+	tc.SetLookback(time.Now().Add(-time.Hour))
+	collect("")
+}
+
+func TestCollectProjectScope(t *testing.T) {
+	tc, tmpDir := newTestCollector(t)
+
+	projectsDir := filepath.Join(tmpDir, "projects")
+
+	now := time.Now()
+	events := []TokenEvent{
+		{Timestamp: now, Model: "claude-sonnet-4", InputTokens: 100, SourceFile: filepath.Join(projectsDir, "-home-me-app", "a.jsonl"), LineNumber: 1},
+		{Timestamp: now, Model: "claude-sonnet-4", InputTokens: 20, SourceFile: filepath.Join(projectsDir, "-home-me-app", "agent", "subagents", "b.jsonl"), LineNumber: 1},
+		{Timestamp: now, Model: "claude-sonnet-4", InputTokens: 3, SourceFile: filepath.Join(projectsDir, "-home-me-app2", "c.jsonl"), LineNumber: 1},
+	}
+	if err := tc.cache.InsertTokenEventBatch(events); err != nil {
+		t.Fatalf("Failed to insert events: %v", err)
+	}
+	tc.SetLookback(now.Add(-time.Hour))
+
+	tests := []struct {
+		scope string
+		want  int64
+	}{
+		{"/home/me/app", 120}, // Not -home-me-app2
+		{"", 123},
+	}
+	for _, tt := range tests {
+		tc.SetProjectScope(tt.scope)
+		m, err := tc.Collect()
+		if err != nil {
+			t.Fatalf("Collect failed: %v", err)
+		}
+		if m.TotalTokens != tt.want || m.Project != tt.scope {
+			t.Errorf("Scope %q: expected %d tokens, got %d (project %q)", tt.scope, tt.want, m.TotalTokens, m.Project)
+		}
+	}
+}
+
+func TestCollectClockSkew(t *testing.T) {
+	tc, _ := newTestCollector(t)
+
+	tc.SetLookback(time.Now().Add(-time.Hour))
+
+	insert := func(ts time.Time, line int64) {
+		t.Helper()
+		event := TokenEvent{Timestamp: ts, Model: "claude-sonnet-4", InputTokens: 1000, SourceFile: "/tmp/a.jsonl", LineNumber: line}
+		if err := tc.cache.InsertTokenEventBatch([]TokenEvent{event}); err != nil {
+			t.Fatalf("Failed to insert event: %v", err)
+		}
+	}
+
+	// A lead within the tolerance isn't reported
+	insert(time.Now().Add(time.Minute), 1)
+	if m, _ := tc.Collect(); m.ClockSkew != 0 {
+		t.Errorf("Expected no skew within the tolerance, got %s", m.ClockSkew)
+	}
+
+	insert(time.Now().Add(3*time.Hour), 2)
+	m, err := tc.Collect()
+	if err != nil {
+		t.Fatalf("Collect failed: %v", err)
+	}
+	if m.ClockSkew < 2*time.Hour || m.TimeSpan < 0 {
+		t.Errorf("Expected about 3h of skew and no negative span, got skew %s span %s", m.ClockSkew, m.TimeSpan)
+	}
+}
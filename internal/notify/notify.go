@@ -0,0 +1,97 @@
+package notify
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"time"
+)
+
+const (
+	// Timeout for the notification helper - these return almost immediately,
+	// but a wedged notification daemon must never pile up processes
+	notifyCommandTimeout = 5 * time.Second
+)
+
+// Desktop shows an OS-native desktop notification.
+// Uses notify-send on Linux/BSD, osascript on macOS and a PowerShell toast on Windows.
+func Desktop(title, body string) error {
+	name, args, err := desktopCommand(runtime.GOOS, title, body)
+	if err != nil {
+		return err
+	}
+
+	if _, err := exec.LookPath(name); err != nil {
+		return fmt.Errorf("%s not found: %w", name, err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), notifyCommandTimeout)
+	defer cancel()
+
+	if out, err := exec.CommandContext(ctx, name, args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("%s failed: %w (%s)", name, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// desktopCommand returns the command used to show a notification on the given OS
+func desktopCommand(goos, title, body string) (string, []string, error) {
+	switch goos {
+	case "darwin":
+		script := fmt.Sprintf("display notification %s with title %s", appleScriptQuote(body), appleScriptQuote(title))
+		return "osascript", []string{"-e", script}, nil
+	case "windows":
+		script := fmt.Sprintf(`[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] > $null
+$template = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $template.GetElementsByTagName('text')
+$text.Item(0).AppendChild($template.CreateTextNode(%s)) > $null
+$text.Item(1).AppendChild($template.CreateTextNode(%s)) > $null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier('ccdash').Show([Windows.UI.Notifications.ToastNotification]::new($template))`,
+			powerShellQuote(title), powerShellQuote(body))
+		return "powershell", []string{"-NoProfile", "-NonInteractive", "-Command", script}, nil
+	case "linux", "freebsd", "openbsd", "netbsd":
+		return "notify-send", []string{"--app-name=ccdash", title, body}, nil
+	default:
+		return "", nil, fmt.Errorf("desktop notifications not supported on %s", goos)
+	}
+}
+
+// appleScriptQuote returns s as an AppleScript string literal
+func appleScriptQuote(s string) string {
+	s = strings.ReplaceAll(s, `\`, `\\`)
+	s = strings.ReplaceAll(s, `"`, `\"`)
+	return `"` + s + `"`
+}
+
+// powerShellQuote returns s as a single-quoted PowerShell string literal
+func powerShellQuote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", "''") + "'"
+}
+
+// Exec starts a shell command in the background with extra environment
+// variables (KEY=value) and returns without waiting for it to finish.
+// A non-zero exit is logged once the command completes.
+func Exec(command string, env ...string) error {
+	var cmd *exec.Cmd
+	if runtime.GOOS == "windows" {
+		cmd = exec.Command("cmd", "/C", command)
+	} else {
+		cmd = exec.Command("sh", "-c", command)
+	}
+	cmd.Env = append(os.Environ(), env...)
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to start %q: %w", command, err)
+	}
+
+	go func() {
+		if err := cmd.Wait(); err != nil {
+			log.Printf("command %q failed: %v", command, err)
+		}
+	}()
+	return nil
+}
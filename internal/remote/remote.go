@@ -0,0 +1,132 @@
+package remote
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jedarden/ccdash/internal/export"
+)
+
+const (
+	// DefaultCommand is run on each remote host to take a snapshot
+	DefaultCommand = "ccdash --json"
+
+	// Remote ccdash ingests new JSONL lines before answering, so the first run
+	// on a host with a large archive can take a while
+	sshCommandTimeout = 30 * time.Second
+	sshConnectTimeout = 5 // seconds
+)
+
+// HostSnapshot is the latest result from one remote host
+type HostSnapshot struct {
+	Host     string
+	Snapshot *export.Snapshot // nil when the host is unreachable
+	Err      error
+	Updated  time.Time
+}
+
+// Reachable returns true if the last collection from the host succeeded
+func (h HostSnapshot) Reachable() bool {
+	return h.Err == nil && h.Snapshot != nil
+}
+
+// Collector gathers snapshots from remote machines by running ccdash over SSH
+type Collector struct {
+	hosts   []string
+	command string
+}
+
+// NewCollector creates a collector for the given SSH hosts (anything ssh accepts,
+// e.g. "user@host" or an alias from ~/.ssh/config). An empty command uses DefaultCommand.
+func NewCollector(hosts []string, command string) *Collector {
+	if command == "" {
+		command = DefaultCommand
+	}
+	return &Collector{hosts: hosts, command: command}
+}
+
+// Hosts returns the configured hosts
+func (c *Collector) Hosts() []string {
+	return c.hosts
+}
+
+// Collect queries every host in parallel and returns results in configured order.
+// A failing host never affects the others.
+func (c *Collector) Collect() []HostSnapshot {
+	results := make([]HostSnapshot, len(c.hosts))
+	var wg sync.WaitGroup
+	for i, host := range c.hosts {
+		wg.Add(1)
+		go func(i int, host string) {
+			defer wg.Done()
+			results[i] = c.collectHost(host)
+		}(i, host)
+	}
+	wg.Wait()
+	return results
+}
+
+// collectHost runs the snapshot command on one host
+func (c *Collector) collectHost(host string) HostSnapshot {
+	result := HostSnapshot{Host: host, Updated: time.Now()}
+
+	ctx, cancel := context.WithTimeout(context.Background(), sshCommandTimeout)
+	defer cancel()
+
+	// BatchMode fails instead of prompting for a password the TUI can't show
+	cmd := exec.CommandContext(ctx, "ssh",
+		"-o", "BatchMode=yes",
+		"-o", fmt.Sprintf("ConnectTimeout=%d", sshConnectTimeout),
+		host, c.command)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	output, err := cmd.Output()
+	if err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			err = fmt.Errorf("%w: %s", err, lastLine(msg))
+		}
+		result.Err = err
+		return result
+	}
+
+	snap, err := ParseSnapshot(output)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+	result.Snapshot = snap
+	return result
+}
+
+// ParseSnapshot decodes `ccdash --json` output. System metric errors are
+// serialized as empty objects that can't be decoded back into an error; those
+// fields are left nil rather than failing the whole snapshot.
+func ParseSnapshot(data []byte) (*export.Snapshot, error) {
+	var snap export.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		var typeErr *json.UnmarshalTypeError
+		if !errors.As(err, &typeErr) {
+			return nil, fmt.Errorf("invalid snapshot: %w", err)
+		}
+	}
+	if snap.Timestamp.IsZero() {
+		return nil, fmt.Errorf("invalid snapshot: missing timestamp")
+	}
+	return &snap, nil
+}
+
+// lastLine returns the last line of multi-line command output
+func lastLine(s string) string {
+	if i := strings.LastIndex(s, "\n"); i >= 0 {
+		return s[i+1:]
+	}
+	return s
+}
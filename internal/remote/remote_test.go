@@ -0,0 +1,40 @@
+package remote
+
+import (
+	"testing"
+)
+
+func TestParseSnapshotToleratesSerializedErrors(t *testing.T) {
+	// error values marshal as {} and can't be decoded back into an error
+	data := []byte(`{
+		"system": {"CPU": {"TotalPercent": 42.5, "Error": null}, "Swap": {"Error": {}}},
+		"tokens": {"total_cost": 12.5, "available": true},
+		"tmux": {"sessions": [{"name": "agent-1", "status": "WORKING"}]},
+		"timestamp": "2025-06-02T10:00:00Z"
+	}`)
+
+	snap, err := ParseSnapshot(data)
+	if err != nil {
+		t.Fatalf("ParseSnapshot failed: %v", err)
+	}
+	if snap.System.CPU.TotalPercent != 42.5 {
+		t.Errorf("Expected CPU 42.5%%, got %.1f", snap.System.CPU.TotalPercent)
+	}
+	if snap.Tokens == nil || snap.Tokens.TotalCost != 12.5 {
+		t.Errorf("Expected token cost 12.5, got %+v", snap.Tokens)
+	}
+	if snap.Tmux == nil || len(snap.Tmux.Sessions) != 1 || snap.Tmux.Sessions[0].Name != "agent-1" {
+		t.Errorf("Expected one session named agent-1, got %+v", snap.Tmux)
+	}
+}
+
+func TestParseSnapshotRejectsGarbage(t *testing.T) {
+	for _, data := range []string{
+		"bash: ccdash: command not found",
+		`{"system": {}}`,
+	} {
+		if _, err := ParseSnapshot([]byte(data)); err == nil {
+			t.Errorf("Expected error for %q", data)
+		}
+	}
+}
@@ -0,0 +1,51 @@
+package ui
+
+import "github.com/charmbracelet/lipgloss"
+
+// The palette. lipgloss picks the color for the terminal's profile, detected
+// from $COLORTERM and $TERM: 24-bit where supported, else the 256-color or
+// 16-color value given here. Left to downsample on their own, orange and
+// yellow both become yellow in 16 colors, so bars at the warn threshold look
+// like bars below it, and the dim gray becomes white.
+var (
+	colorGreen  = lipgloss.CompleteColor{TrueColor: "#00ff00", ANSI256: "46", ANSI: "10"}
+	colorYellow = lipgloss.CompleteColor{TrueColor: "#ffff00", ANSI256: "226", ANSI: "11"}
+	colorOrange = lipgloss.CompleteColor{TrueColor: "#ffaa00", ANSI256: "214", ANSI: "3"}
+	colorRed    = lipgloss.CompleteColor{TrueColor: "#ff0000", ANSI256: "196", ANSI: "9"}
+	colorCyan   = lipgloss.CompleteColor{TrueColor: "#00ffff", ANSI256: "51", ANSI: "14"}
+	colorBlue   = lipgloss.CompleteColor{TrueColor: "#00aaff", ANSI256: "39", ANSI: "12"}
+	colorGray   = lipgloss.CompleteColor{TrueColor: "#888888", ANSI256: "245", ANSI: "8"}
+	colorWhite  = lipgloss.CompleteColor{TrueColor: "#ffffff", ANSI256: "231", ANSI: "15"}
+	colorBlack  = lipgloss.CompleteColor{TrueColor: "#000000", ANSI256: "16", ANSI: "0"}
+
+	// Status bar background
+	colorStatusBar = lipgloss.CompleteColor{TrueColor: "#1a1a1a", ANSI256: "234", ANSI: "0"}
+
+	// Session statuses, which are defined as ANSI codes: 16 colors use them as is
+	colorWorking = lipgloss.CompleteColor{TrueColor: "#00ff00", ANSI256: "46", ANSI: "2"}
+	colorReady   = lipgloss.CompleteColor{TrueColor: "#ff0000", ANSI256: "196", ANSI: "1"}
+	colorActive  = lipgloss.CompleteColor{TrueColor: "#ffff00", ANSI256: "226", ANSI: "3"}
+	colorError   = lipgloss.CompleteColor{TrueColor: "#ff5555", ANSI256: "203", ANSI: "9"}
+
+	// Model families in the token panel
+	colorOpus   = lipgloss.CompleteColor{TrueColor: "#ff6b6b", ANSI256: "203", ANSI: "9"}
+	colorSonnet = lipgloss.CompleteColor{TrueColor: "#4ecdc4", ANSI256: "80", ANSI: "6"}
+	colorHaiku  = lipgloss.CompleteColor{TrueColor: "#95e1d3", ANSI256: "115", ANSI: "10"}
+	colorGLM    = lipgloss.CompleteColor{TrueColor: "#00bfff", ANSI256: "39", ANSI: "12"}
+)
+
+// barColors maps the colors metrics.GetStatusColor returns to the palette
+var barColors = map[string]lipgloss.TerminalColor{
+	colorGreen.TrueColor:  colorGreen,
+	colorYellow.TrueColor: colorYellow,
+	colorOrange.TrueColor: colorOrange,
+	colorRed.TrueColor:    colorRed,
+}
+
+// barColor returns the palette color for a metrics.GetStatusColor color
+func barColor(hex string) lipgloss.TerminalColor {
+	if c, ok := barColors[hex]; ok {
+		return c
+	}
+	return lipgloss.Color(hex)
+}
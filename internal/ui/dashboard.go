@@ -1,16 +1,29 @@
 package ui
 
 import (
+	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log"
+	"math"
 	"math/rand"
 	"os"
+	"path/filepath"
+	"regexp"
+	"runtime"
+	"slices"
 	"strings"
 	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+	"github.com/jedarden/ccdash/internal/clipboard"
+	"github.com/jedarden/ccdash/internal/export"
 	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/notify"
+	"github.com/jedarden/ccdash/internal/remote"
 	"github.com/jedarden/ccdash/internal/updater"
 )
 
@@ -24,14 +37,24 @@ const (
 	LayoutCompact                       // <120 cols: tmux top, tokens middle, system bottom
 )
 
-// tickMsg is sent every 2 seconds to trigger refresh
+// TokenDisplayMode selects which figure the token panel emphasizes
+type TokenDisplayMode int
+
+const (
+	TokenDisplayTokens TokenDisplayMode = iota // Total leads in bold (the default)
+	TokenDisplayCost                           // Cost leads in bold, model costs bold
+)
+
+// tickMsg is sent every refresh interval to trigger collection
 type tickMsg time.Time
 
 // LookbackPreset represents a predefined lookback period
 type LookbackPreset struct {
+	Key         string // Identifier for the lookback config key and --lookback, e.g. "7d"
 	Name        string
 	Description string
 	GetTime     func() time.Time
+	GetEnd      func() time.Time // End of the range; nil for up to now
 }
 
 // Dashboard is the main Bubble Tea model
@@ -42,6 +65,9 @@ type Dashboard struct {
 	version       string
 	instanceID    string // Unique ID for leader election
 
+	// Running ccdash instances, polled each refresh; more than one contend for the cache
+	instanceCount int
+
 	// Metrics collectors
 	systemCollector *metrics.SystemCollector
 	tokenCollector  *metrics.TokenCollector
@@ -56,6 +82,140 @@ type Dashboard struct {
 	lastUpdate    time.Time
 	err           error
 	helpMode      int // 0=none, 1=system, 2=tokens, 3=tmux
+	focusedPanel  int // 0=all panels, 1=system, 2=tokens, 3=tmux filling the screen
+	paused        bool // true while the terminal reports the window as unfocused
+	inspectMode   bool // true when the session inspector is open
+	hourlyMode    bool // true when the hour-of-day histogram is open
+	projectsMode  bool // true when the per-project cost view is open
+	modelMode     bool // true when the model detail view is open
+	keyHelpMode   bool // true when the keybinding cheat sheet is open
+	minimalMode   bool // --compact: dense key:value lines instead of bordered panels
+	noEmoji       bool // --no-emoji: text status labels instead of emoji
+	hideStatusBar bool
+	manual        bool // --manual: collect at startup and on r only
+	warnThreshold float64 // Bar turns orange at this usage percent
+	critThreshold float64 // Bar turns red at this usage percent
+	confirmClear  bool // true after the first X press, waiting for confirmation
+
+	// --bell-on-error: ring the bell when a session enters ERROR, and flash the
+	// sessions panel border red for the next errorFlash refreshes. bellPending
+	// holds the bell until the next frame, which carries it.
+	bellOnError bool
+	errorFlash  int
+	bellPending bool
+
+	// --flash-thresholds (toggled with f): a metric's label is shown in reverse
+	// video for one refresh after it climbs into a higher band (see
+	// metricBands). bands holds the previous refresh's, and costWindow the
+	// lookback and project the cost band was measured over.
+	flashThresholds bool
+	bands           map[string]float64
+	flashing        map[string]bool
+	costWindow      string
+
+	// --snapshot-file: the JSON snapshot --json prints is also written to
+	// this path after every refresh, redacted with --redact. snapshotErr is
+	// the last write error, so a failure is reported once rather than every
+	// refresh.
+	snapshotFile    string
+	snapshotRedact  bool
+	snapshotWriting bool
+	snapshotErr     string
+
+	// Model highlighted in the token panel with ↑/↓, opened with Enter; empty for none
+	selectedModel string
+	// When the opened model was first and last used, loaded each time its detail opens; nil while loading
+	modelActivity *modelActivityMsg
+
+	// memByAvailable fills the memory bar by Total-Available instead of Used
+	memByAvailable bool
+
+	// --full-model-names (toggled with M): show raw model IDs like
+	// claude-opus-4-5-20251101 in the token panel instead of "Opus 4.5"
+	fullModelNames bool
+
+	// --pin: sessions listed first in the sessions panel whatever their status
+	pinnedSessions map[string]bool
+
+	// g / --group-by-prefix: sessions sharing a name prefix up to the last
+	// groupDelimiter collapse into one row per group. expandedGroup is the
+	// prefix whose members are listed under its row, cycled with G.
+	groupByPrefix  bool
+	groupDelimiter string
+	expandedGroup  string
+
+	// Per-core CPU bars: lines shown, and cores per line (0 fits as many as the width allows)
+	cpuCoreLines    int
+	cpuCoresPerLine int
+
+	// --rate-smoothing: weight of the previous value in the moving average of
+	// the 60s token rate shown as Trend; 0 is off. smoothedRate carries the
+	// average across refreshes from the first sample, when hasSmoothedRate is set.
+	rateSmoothing   float64
+	smoothedRate    float64
+	hasSmoothedRate bool
+
+	// Whether the token panel leads with tokens or cost, toggled with $
+	tokenDisplayMode TokenDisplayMode
+
+	// k / --exact-tokens: token counts as 1,234,567 instead of 1.2M in the
+	// token panel and the compact view
+	exactTokens bool
+
+	// Session cells show how long ago each session was created, toggled with A
+	showSessionAge bool
+
+	// Secondary currency shown after USD costs, e.g. "$12.30 (£9.80)"; off when empty
+	secondaryCurrency string
+	fxRate            float64
+
+	// --max-width: width caps the layout at maxWidth, and View centers the
+	// result in the terminal's full termWidth
+	maxWidth  int
+	termWidth int
+
+	// --system-panel-width and --token-panel-width: fixed widths for the
+	// three-column layout; 0 leaves a panel's width to the heuristics
+	systemPanelWidth int
+	tokenPanelWidth  int
+
+	// Terminal resize coalescing: the newest size waits here until the debounce
+	// tick, and View repeats the last frame meanwhile
+	pendingWidth  int
+	pendingHeight int
+	resizePending bool
+	lastView      string
+
+	// Time between collection ticks; data older than a few intervals is flagged stale
+	refreshInterval time.Duration
+
+	// How long a collection may take before its slow parts are abandoned, and
+	// the context that abandons all of them on quit
+	collectTimeout time.Duration
+	quitCtx        context.Context
+	quit           context.CancelFunc
+
+	// Hook session files left by sessions that ended without the session-end
+	// hook are removed in the background every sessionCleanupInterval (0 for never)
+	sessionCleanupInterval time.Duration
+	cleanupInFlight        bool
+	lastCleanup            time.Time
+	cleanedSessions        int // Session files removed since startup
+
+	// Hour-of-day histogram data, loaded each time the view opens
+	hourOfDay    []metrics.HourOfDayUsage
+	hourOfDayErr error
+
+	// Per-project usage, loaded each time the view opens
+	projectUsage    []metrics.ProjectUsage
+	projectUsageErr error
+
+	// Transient status bar message (e.g. cache clear confirmation)
+	statusMessage      string
+	statusMessageUntil time.Time
+
+	// --status-format: template replacing the default status bar layout
+	statusFormat string
 
 	// Lookback picker state
 	lookbackMode          bool   // true when lookback picker is open
@@ -63,15 +223,79 @@ type Dashboard struct {
 	lookbackSelectedIndex int
 	lookbackCustomMode    bool      // true when editing custom date/time
 	lookbackCustomDate    time.Time // the custom date being edited
-	lookbackEditField     int       // 0=year, 1=month, 2=day, 3=hour, 4=minute
+	lookbackEditField     int       // 0=year, 1=month, 2=day, 3=hour, 4=minute; 5-9 the same for the end
+
+	// Optional end of the custom lookback, toggled with e in the custom picker
+	lookbackCustomHasEnd bool
+	lookbackCustomEnd    time.Time
 
 	// Update checking
 	updater      *updater.Updater
 	updateInfo   *updater.UpdateInfo
 	updating     bool
 	updateStatus string
+
+	// updateStatusUntil is when a failed update's error leaves the status
+	// bar, so u to retry comes back; zero keeps updateStatus until replaced
+	updateStatusUntil time.Time
+
+	// Session state notifications
+	notifyEnabled   bool
+	onReadyCommand  string                           // Shell command run when a session becomes READY
+	sessionStatuses map[string]metrics.SessionStatus // Last seen status per session name
+	alertCooldown   time.Duration                    // Shortest gap between alerts of one kind for a session
+	lastNotified    map[string]time.Time             // Last READY alert (notification, --on-ready) per session name
+	lastBell        map[string]time.Time             // Last --bell-on-error alert per session name
+
+	// Remote machines (--remote)
+	remoteCollector *remote.Collector
+	remoteHosts     []remote.HostSnapshot
+	remoteInFlight  bool
+	lastRemote      time.Time
+	localTmux       *metrics.TmuxMetrics // Local sessions before remote ones are merged in
 }
 
+// defaultRefreshInterval is the time between collection ticks
+const defaultRefreshInterval = 2 * time.Second
+
+// defaultCollectTimeout is how long one collection may run before the panels
+// are updated with whatever has arrived, and slow tmux or system calls are
+// abandoned
+const defaultCollectTimeout = 3 * time.Second
+
+// defaultCPUCoreLines is how many lines of per-core bars the system panel shows
+// before summarising the rest as "+N more cores"
+const defaultCPUCoreLines = 6
+
+// staleAfterIntervals is how many missed refreshes mark the data as stale,
+// e.g. when a collection is stuck on slow ingestion or a tmux call
+const staleAfterIntervals = 3
+
+// remoteRefreshInterval is how often remote hosts are polled over SSH. Each poll
+// samples the remote CPU for a second, so this is slower than the local refresh.
+const remoteRefreshInterval = 10 * time.Second
+
+// resizeDebounce is the minimum gap between layout recomputations while the
+// terminal is being resized; a drag or a burst of SIGWINCH is coalesced into
+// one re-render per interval, always ending at the final size
+const resizeDebounce = 100 * time.Millisecond
+
+// defaultAlertCooldown is the minimum gap between alerts for the same session,
+// so a session flapping between WORKING and READY doesn't spam the desktop
+const defaultAlertCooldown = 30 * time.Second
+
+// minMaxWidth is the narrowest --max-width accepted; below it the panels
+// would be squeezed harder than any real terminal squeezes them
+const minMaxWidth = 80
+
+// minPanelWidth is the narrowest the three-column layout makes a panel, and
+// the narrowest --system-panel-width and --token-panel-width accepted
+const minPanelWidth = 30
+
+// errorFlashRefreshes is how many refreshes the sessions panel border stays red
+// after a session enters ERROR with --bell-on-error
+const errorFlashRefreshes = 2
+
 // generateInstanceID creates a unique identifier for this dashboard instance
 func generateInstanceID() string {
 	return fmt.Sprintf("%d-%d", os.Getpid(), rand.Int63())
@@ -81,19 +305,32 @@ func generateInstanceID() string {
 func NewDashboard(version string) *Dashboard {
 	presets := []LookbackPreset{
 		{
+			Key:         "monday",
 			Name:        "Monday 9am",
 			Description: "Since this week's Monday at 9:00 AM",
 			GetTime:     metrics.GetMondayNineAM,
 		},
 		{
+			Key:         "today",
 			Name:        "Today",
 			Description: "Since midnight today",
+			GetTime:     metrics.StartOfToday,
+		},
+		{
+			Key:         "yesterday",
+			Name:        "Yesterday",
+			Description: "Midnight to midnight yesterday",
 			GetTime: func() time.Time {
+				now := time.Now()
+				return time.Date(now.Year(), now.Month(), now.Day()-1, 0, 0, 0, 0, now.Location())
+			},
+			GetEnd: func() time.Time {
 				now := time.Now()
 				return time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
 			},
 		},
 		{
+			Key:         "5h",
 			Name:        "5 hours",
 			Description: "Last 5 hours (2400 req limit)",
 			GetTime: func() time.Time {
@@ -101,6 +338,7 @@ func NewDashboard(version string) *Dashboard {
 			},
 		},
 		{
+			Key:         "24h",
 			Name:        "24 hours",
 			Description: "Last 24 hours",
 			GetTime: func() time.Time {
@@ -108,6 +346,7 @@ func NewDashboard(version string) *Dashboard {
 			},
 		},
 		{
+			Key:         "7d",
 			Name:        "7 days",
 			Description: "Last 7 days",
 			GetTime: func() time.Time {
@@ -115,6 +354,7 @@ func NewDashboard(version string) *Dashboard {
 			},
 		},
 		{
+			Key:         "30d",
 			Name:        "30 days",
 			Description: "Last 30 days",
 			GetTime: func() time.Time {
@@ -122,6 +362,16 @@ func NewDashboard(version string) *Dashboard {
 			},
 		},
 		{
+			Key:         "month",
+			Name:        "This month",
+			Description: "Since the 1st of this month",
+			GetTime: func() time.Time {
+				now := time.Now()
+				return time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+			},
+		},
+		{
+			Key:         "all",
 			Name:        "All time",
 			Description: "Show all available data",
 			GetTime: func() time.Time {
@@ -135,7 +385,7 @@ func NewDashboard(version string) *Dashboard {
 		},
 	}
 
-	return &Dashboard{
+	d := &Dashboard{
 		version:            version,
 		instanceID:         generateInstanceID(),
 		systemCollector:    metrics.NewSystemCollector(),
@@ -145,6 +395,409 @@ func NewDashboard(version string) *Dashboard {
 		lastUpdate:         time.Now(),
 		lookbackPresets:    presets,
 		lookbackCustomDate: time.Now().AddDate(0, 0, -1), // Default custom to yesterday
+		sessionStatuses:    make(map[string]metrics.SessionStatus),
+		alertCooldown:      defaultAlertCooldown,
+		lastNotified:       make(map[string]time.Time),
+		lastBell:           make(map[string]time.Time),
+		refreshInterval:    defaultRefreshInterval,
+		collectTimeout:     defaultCollectTimeout,
+		warnThreshold:      metrics.DefaultWarnThreshold,
+		critThreshold:      metrics.DefaultCritThreshold,
+		cpuCoreLines:       defaultCPUCoreLines,
+		groupDelimiter:     "-",
+
+		// main cleans up once at startup, so the first pass waits an interval
+		sessionCleanupInterval: metrics.DefaultSessionCleanupInterval,
+		lastCleanup:            time.Now(),
+	}
+
+	d.quitCtx, d.quit = context.WithCancel(context.Background())
+
+	// Instances share the data directory, so they also share the update lock,
+	// the update log and the last release fetched
+	if dataDir, _ := metrics.DataDir(); dataDir != "" {
+		d.updater.SetLockDir(filepath.Join(dataDir, metrics.InstancesSubdir))
+		d.updater.SetHistoryDir(dataDir)
+		d.updater.SetCacheDir(dataDir)
+	}
+
+	// Recovery from a corrupt cache is automatic, but explain the empty token panel
+	if cache := d.tokenCollector.GetCache(); cache.RebuiltAfterCorruption() {
+		log.Printf("token cache was corrupt; moved to %s and rebuilt", cache.CorruptBackupPath())
+		d.setStatusMessage("Token cache rebuilt after corruption, re-ingesting logs", 30*time.Second)
+	}
+
+	return d
+}
+
+// SetLookback applies the lookback preset with the given key (e.g. "today",
+// "7d"), as if it had been picked with l. Other keys are parsed with
+// metrics.ParseTimeBound, like --since, and start a custom range.
+func (d *Dashboard) SetLookback(key string) error {
+	var keys []string
+	custom := -1
+	for i, preset := range d.lookbackPresets {
+		if preset.GetTime == nil {
+			custom = i
+			continue
+		}
+		if preset.Key == key {
+			d.lookbackSelectedIndex = i
+			d.applyLookbackPreset(preset)
+			return nil
+		}
+		keys = append(keys, preset.Key)
+	}
+
+	// Anything else is a custom start, as a duration back from now or a date
+	start, err := metrics.ParseTimeBound(key, time.Now())
+	if err != nil {
+		return fmt.Errorf("unknown lookback %q (available: %s, a duration like 12h or 3d, or a date like 2025-11-01)", key, strings.Join(keys, ", "))
+	}
+	if custom >= 0 {
+		d.lookbackSelectedIndex = custom
+	}
+	d.lookbackCustomDate = start
+	d.lookbackCustomHasEnd = false
+	d.tokenCollector.SetLookback(start)
+	return nil
+}
+
+// applyLookbackPreset points the token collector at the preset's range
+func (d *Dashboard) applyLookbackPreset(preset LookbackPreset) {
+	var end time.Time
+	if preset.GetEnd != nil {
+		end = preset.GetEnd()
+	}
+	d.tokenCollector.SetLookbackRange(preset.GetTime(), end)
+}
+
+// ForceReingest re-reads every JSONL file from the start, as R does, for when
+// new usage isn't showing up
+func (d *Dashboard) ForceReingest() error {
+	files, err := d.tokenCollector.ForceReingest()
+	if err != nil {
+		return err
+	}
+	d.setStatusMessage(fmt.Sprintf("Re-reading %d log files from the start…", files), 10*time.Second)
+	return nil
+}
+
+// SetBuildInfo passes the commit and build date of this binary to the updater,
+// which reports them when checking for releases
+func (d *Dashboard) SetBuildInfo(commit, buildDate string) {
+	d.updater.SetBuildInfo(commit, buildDate)
+}
+
+// SetSecondaryCurrency shows costs in a second currency as well, converted from
+// USD at rate units per dollar. An empty code turns it off.
+func (d *Dashboard) SetSecondaryCurrency(code string, rate float64) error {
+	if code != "" && rate <= 0 {
+		return fmt.Errorf("secondary currency %s needs a positive fx_rate (units per USD), got %v", code, rate)
+	}
+	d.secondaryCurrency = code
+	d.fxRate = rate
+	return nil
+}
+
+// secondaryCost returns a USD cost converted to the secondary currency, as
+// " (£9.80)", or "" when no secondary currency is configured
+func (d *Dashboard) secondaryCost(usd float64) string {
+	if d.secondaryCurrency == "" {
+		return ""
+	}
+	return dimStyle.Render(" (" + metrics.FormatCurrency(usd*d.fxRate, d.secondaryCurrency) + ")")
+}
+
+// SetRefreshInterval sets the time between collection ticks. Every tick
+// captures each tmux pane, so intervals under a second are rejected.
+func (d *Dashboard) SetRefreshInterval(interval time.Duration) error {
+	if interval < time.Second {
+		return fmt.Errorf("refresh interval must be at least 1s, got %s", interval)
+	}
+	d.refreshInterval = interval
+	return nil
+}
+
+// SetCollectTimeout sets how long one collection may run. A tmux pane or
+// system reading that takes longer is abandoned, and the panels show what
+// arrived in time.
+func (d *Dashboard) SetCollectTimeout(timeout time.Duration) error {
+	if timeout < 100*time.Millisecond {
+		return fmt.Errorf("collect timeout must be at least 100ms, got %s", timeout)
+	}
+	d.collectTimeout = timeout
+	return nil
+}
+
+// SetSessionCleanupInterval sets the time between removals of hook session
+// files whose process or tmux session is gone, or that have had no hook event
+// for metrics.AbandonedSessionThreshold. 0 turns the cleanup off.
+func (d *Dashboard) SetSessionCleanupInterval(interval time.Duration) error {
+	if interval < 0 {
+		return fmt.Errorf("session cleanup interval can't be negative, got %s", interval)
+	}
+	d.sessionCleanupInterval = interval
+	return nil
+}
+
+// SetAlertCooldown sets the shortest gap between alerts for the same session.
+// A session that becomes READY again within it gets no notification or
+// --on-ready run, and one that re-enters ERROR doesn't ring the bell or flash
+// the border. 0 alerts on every transition.
+func (d *Dashboard) SetAlertCooldown(cooldown time.Duration) error {
+	if cooldown < 0 {
+		return fmt.Errorf("alert cooldown can't be negative, got %s", cooldown)
+	}
+	d.alertCooldown = cooldown
+	return nil
+}
+
+// SetThresholds sets the usage percentages at which bars turn orange (warn) and red (crit)
+func (d *Dashboard) SetThresholds(warn, crit float64) error {
+	if err := metrics.ValidateThresholds(warn, crit); err != nil {
+		return err
+	}
+	d.warnThreshold = warn
+	d.critThreshold = crit
+	return nil
+}
+
+// SetCPUCoreLayout sets how many lines of per-core CPU bars are shown and how
+// many cores share a line; coresPerLine 0 fits as many as the panel width allows
+func (d *Dashboard) SetCPUCoreLayout(lines, coresPerLine int) error {
+	if lines < 1 {
+		return fmt.Errorf("CPU core lines must be at least 1, got %d", lines)
+	}
+	if coresPerLine < 0 {
+		return fmt.Errorf("CPU cores per line can't be negative, got %d", coresPerLine)
+	}
+	d.cpuCoreLines = lines
+	d.cpuCoresPerLine = coresPerLine
+	return nil
+}
+
+// SetMaxWidth caps the width the dashboard is drawn at; wider terminals get
+// even margins on both sides. 0 uses the full terminal width.
+func (d *Dashboard) SetMaxWidth(width int) error {
+	if width != 0 && width < minMaxWidth {
+		return fmt.Errorf("max width must be 0 (no limit) or at least %d, got %d", minMaxWidth, width)
+	}
+	d.maxWidth = width
+	return nil
+}
+
+// SetPanelWidths fixes the widths of the system and token panels in the
+// three-column layout, the sessions panel taking the rest; 0 leaves a panel's
+// width automatic. At widths where they'd leave the sessions panel under
+// minPanelWidth, both are set aside for the automatic ones.
+func (d *Dashboard) SetPanelWidths(system, token int) error {
+	if system != 0 && system < minPanelWidth {
+		return fmt.Errorf("system panel width must be 0 (automatic) or at least %d, got %d", minPanelWidth, system)
+	}
+	if token != 0 && token < minPanelWidth {
+		return fmt.Errorf("token panel width must be 0 (automatic) or at least %d, got %d", minPanelWidth, token)
+	}
+	d.systemPanelWidth = system
+	d.tokenPanelWidth = token
+	return nil
+}
+
+// SetBellOnError rings the terminal bell and flashes the sessions panel red
+// when a session enters ERROR
+func (d *Dashboard) SetBellOnError(enabled bool) {
+	d.bellOnError = enabled
+}
+
+// SetIgnoreAttached stops attached sessions from being shown as ACTIVE just
+// because a client is attached; pane content and idle time decide instead
+func (d *Dashboard) SetIgnoreAttached(enabled bool) {
+	d.tmuxCollector.SetIgnoreAttached(enabled)
+}
+
+// SetCaptureLines sets how many lines above each visible pane are read for
+// status detection (see TmuxCollector.SetCaptureLines)
+func (d *Dashboard) SetCaptureLines(lines int) error {
+	return d.tmuxCollector.SetCaptureLines(lines)
+}
+
+// SetMemoryByAvailable fills the memory bar with the memory that isn't
+// available rather than the memory that is used, so page cache doesn't count
+func (d *Dashboard) SetMemoryByAvailable(enabled bool) {
+	d.memByAvailable = enabled
+}
+
+// SetPinnedSessions lists the named sessions first in the sessions panel, in
+// their usual order, so they are never cut off behind "+N more"
+func (d *Dashboard) SetPinnedSessions(names []string) {
+	d.pinnedSessions = make(map[string]bool, len(names))
+	for _, name := range names {
+		d.pinnedSessions[name] = true
+	}
+}
+
+// SetSessionGrouping sets --group-by-prefix and the delimiter that ends a
+// session name's prefix, e.g. "-" to group agent-1 ... agent-20 as agent-*
+func (d *Dashboard) SetSessionGrouping(enabled bool, delimiter string) error {
+	if delimiter == "" {
+		return fmt.Errorf("group delimiter can't be empty")
+	}
+	d.groupByPrefix = enabled
+	d.groupDelimiter = delimiter
+	return nil
+}
+
+// SetMinimalMode switches to the dense three-line view used by --compact
+func (d *Dashboard) SetMinimalMode(enabled bool) {
+	d.minimalMode = enabled
+}
+
+// SetManualRefresh stops all automatic collection when enabled: metrics are
+// collected once at startup and then only when r is pressed. Background log
+// ingestion stops too; r ingests before collecting.
+func (d *Dashboard) SetManualRefresh(enabled bool) {
+	d.manual = enabled
+	if enabled {
+		d.tokenCollector.StopBackgroundIngestion()
+	}
+}
+
+// SetStatusBar shows or hides the status bar
+func (d *Dashboard) SetStatusBar(visible bool) {
+	d.hideStatusBar = !visible
+}
+
+// SetDiskPaths sets the filesystem paths shown as disk capacity bars
+func (d *Dashboard) SetDiskPaths(paths []string) {
+	d.systemCollector.SetDiskPaths(paths)
+}
+
+// SetCPUSmoothing smooths the CPU bars across refreshes: 0 shows raw
+// readings, values closer to 1 calmer ones (see SystemCollector.SetCPUSmoothing)
+func (d *Dashboard) SetCPUSmoothing(factor float64) error {
+	return d.systemCollector.SetCPUSmoothing(factor)
+}
+
+// SetRateSmoothing adds a Trend line to the token panel: the 60s rate averaged
+// across refreshes, each new rate weighted 1 - factor. 0 turns it off.
+func (d *Dashboard) SetRateSmoothing(factor float64) error {
+	if factor < 0 || factor >= 1 {
+		return fmt.Errorf("rate smoothing must be at least 0 and below 1, got %v", factor)
+	}
+	d.rateSmoothing = factor
+	d.hasSmoothedRate = false
+	return nil
+}
+
+// sampleTokenRate folds a refresh's 60s token rate into the smoothed trend.
+// Refreshes without a usable rate leave the trend as it was rather than
+// pulling it toward zero.
+func (d *Dashboard) sampleTokenRate(t *metrics.TokenMetrics) {
+	if d.rateSmoothing == 0 || t == nil || !t.Available || t.Rate < 0 || math.IsNaN(t.Rate) || math.IsInf(t.Rate, 0) {
+		return
+	}
+	if !d.hasSmoothedRate {
+		d.smoothedRate, d.hasSmoothedRate = t.Rate, true
+		return
+	}
+	d.smoothedRate = d.rateSmoothing*d.smoothedRate + (1-d.rateSmoothing)*t.Rate
+}
+
+// SetTokenSource selects the token data source ("jsonl" or "ccusage")
+func (d *Dashboard) SetTokenSource(source string) error {
+	return d.tokenCollector.SetTokenSource(source)
+}
+
+// SetNoEmoji replaces the status emoji and attached marker with plain text
+func (d *Dashboard) SetNoEmoji(enabled bool) {
+	d.noEmoji = enabled
+	metrics.SetPlainIndicators(enabled)
+}
+
+// statusTokenPattern matches a {token} in a --status-format template
+var statusTokenPattern = regexp.MustCompile(`\{([a-z]+)\}`)
+
+// StatusFormatTokens lists the tokens a --status-format template can use
+var StatusFormatTokens = []string{"time", "version", "cost", "tokens", "today", "project", "sessions", "attention", "update", "size", "keys"}
+
+// SetStatusFormat replaces the status bar layout with a template such as
+// "{time} {cost} | {update} | {sessions} sessions {keys}". Up to three
+// sections separated by | are aligned left, center and right. An empty
+// template keeps the default layout.
+func (d *Dashboard) SetStatusFormat(format string) error {
+	if strings.Count(format, "|") > 2 {
+		return fmt.Errorf("status format has more than three |-separated sections: %q", format)
+	}
+	for _, m := range statusTokenPattern.FindAllStringSubmatch(format, -1) {
+		known := false
+		for _, token := range StatusFormatTokens {
+			known = known || m[1] == token
+		}
+		if !known {
+			return fmt.Errorf("unknown status format token %s (available: {%s})", m[0], strings.Join(StatusFormatTokens, "}, {"))
+		}
+	}
+	d.statusFormat = format
+	return nil
+}
+
+// SetFullModelNames shows the raw model ID in the token panel's per-model
+// breakdown instead of the shortened name
+func (d *Dashboard) SetFullModelNames(enabled bool) {
+	d.fullModelNames = enabled
+}
+
+// SetExactTokens shows token counts with every digit (1,234,567) instead of
+// the short form (1.2M) in the token panel and the compact view
+func (d *Dashboard) SetExactTokens(enabled bool) {
+	d.exactTokens = enabled
+}
+
+// formatTokens formats a token count for the token panel and the compact
+// view, in the short form unless exact counts are on
+func (d *Dashboard) formatTokens(count int64) string {
+	if d.exactTokens {
+		return metrics.FormatTokens(count)
+	}
+	return metrics.FormatTokensCompact(count)
+}
+
+// SetModelSort orders the per-model breakdown by cost (the default), tokens
+// or name
+func (d *Dashboard) SetModelSort(by string) error {
+	return d.tokenCollector.SetModelSort(by)
+}
+
+// SetExcludedModels leaves models matching the name prefixes out of the token
+// and cost totals; they stay in the breakdown, struck through
+func (d *Dashboard) SetExcludedModels(prefixes []string) {
+	d.tokenCollector.SetExcludedModels(prefixes)
+}
+
+// SetIncludeUserTokens also counts token usage reported on user messages
+func (d *Dashboard) SetIncludeUserTokens(enabled bool) {
+	d.tokenCollector.SetIncludeUserTokens(enabled)
+}
+
+// SetNotify enables desktop notifications when a session becomes READY
+func (d *Dashboard) SetNotify(enabled bool) {
+	d.notifyEnabled = enabled
+}
+
+// SetOnReadyCommand sets a shell command to run when a session becomes READY.
+// The session name is passed in the CCDASH_SESSION environment variable.
+func (d *Dashboard) SetOnReadyCommand(command string) {
+	d.onReadyCommand = command
+}
+
+// SetRemoteHosts enables collection from remote machines over SSH. Their sessions
+// are merged into the sessions panel as "host:session", and the system and token
+// panels gain one row per host.
+func (d *Dashboard) SetRemoteHosts(hosts []string, command string) {
+	d.remoteCollector = remote.NewCollector(hosts, command)
+	d.remoteHosts = make([]remote.HostSnapshot, len(hosts))
+	for i, host := range hosts {
+		d.remoteHosts[i] = remote.HostSnapshot{Host: host}
 	}
 }
 
@@ -158,13 +811,96 @@ func (d *Dashboard) AddProjectsDirs(dirs []string) {
 
 // Init initializes the dashboard
 func (d *Dashboard) Init() tea.Cmd {
+	if d.manual {
+		// No tick: show what's cached at once, then collect again once the
+		// startup ingestion is done
+		return tea.Batch(
+			d.collectMetrics(),
+			d.ingestAndCollect(),
+			d.checkForUpdates(),
+			d.collectRemote(),
+		)
+	}
 	return tea.Batch(
 		d.tick(),
 		d.collectMetrics(),
 		d.checkForUpdates(),
+		d.collectRemote(),
 	)
 }
 
+// remoteMsg carries results from remote hosts
+type remoteMsg struct {
+	hosts []remote.HostSnapshot
+}
+
+// collectRemote polls remote hosts unless disabled, already running or recently done
+func (d *Dashboard) collectRemote() tea.Cmd {
+	if d.remoteCollector == nil || d.remoteInFlight || time.Since(d.lastRemote) < remoteRefreshInterval {
+		return nil
+	}
+	d.remoteInFlight = true
+	d.lastRemote = time.Now()
+	collector := d.remoteCollector
+	return func() tea.Msg {
+		return remoteMsg{hosts: collector.Collect()}
+	}
+}
+
+// sessionCleanupMsg reports how many hook session files a cleanup removed
+type sessionCleanupMsg struct {
+	cleaned int
+	err     error
+}
+
+// cleanupSessions removes hook session files whose process or tmux session is
+// gone, and those abandoned for metrics.AbandonedSessionThreshold, unless
+// cleanup is off, already running or recently done
+func (d *Dashboard) cleanupSessions() tea.Cmd {
+	if d.sessionCleanupInterval <= 0 || d.cleanupInFlight || time.Since(d.lastCleanup) < d.sessionCleanupInterval {
+		return nil
+	}
+	if d.tmuxCollector == nil || d.tmuxCollector.GetHookCollector() == nil {
+		return nil
+	}
+	d.cleanupInFlight = true
+	d.lastCleanup = time.Now()
+	hooks := d.tmuxCollector.GetHookCollector()
+	return func() tea.Msg {
+		orphaned, err := hooks.CleanupOrphanedSessions()
+		abandoned, staleErr := hooks.CleanupStaleSessions(metrics.AbandonedSessionThreshold)
+		return sessionCleanupMsg{cleaned: orphaned + abandoned, err: errors.Join(err, staleErr)}
+	}
+}
+
+// mergeRemoteSessions returns the local tmux metrics with sessions from reachable
+// remote hosts appended, named "host:session"
+func (d *Dashboard) mergeRemoteSessions(local *metrics.TmuxMetrics) *metrics.TmuxMetrics {
+	if d.remoteCollector == nil {
+		return local
+	}
+
+	merged := &metrics.TmuxMetrics{Available: true, LastUpdate: time.Now()}
+	if local != nil {
+		*merged = *local
+		merged.Sessions = append([]metrics.TmuxSession(nil), local.Sessions...)
+	}
+	for _, h := range d.remoteHosts {
+		if !h.Reachable() || h.Snapshot.Tmux == nil {
+			continue
+		}
+		for _, session := range h.Snapshot.Tmux.Sessions {
+			session.Name = h.Host + ":" + session.Name
+			merged.Sessions = append(merged.Sessions, session)
+		}
+	}
+	merged.CountStatuses()
+	return merged
+}
+
+// resizeMsg applies the latest pending terminal size once resizeDebounce has passed
+type resizeMsg struct{}
+
 // updateCheckMsg carries update check results
 type updateCheckMsg struct {
 	info *updater.UpdateInfo
@@ -175,6 +911,12 @@ type updateCompleteMsg struct {
 	err error
 }
 
+// updateErrorExpiredMsg clears a failed update's error once its time is up
+type updateErrorExpiredMsg struct{}
+
+// updateErrorDuration is how long a failed update's error stays in the status bar
+const updateErrorDuration = 10 * time.Second
+
 // checkForUpdates returns a command that checks for updates
 func (d *Dashboard) checkForUpdates() tea.Cmd {
 	return func() tea.Msg {
@@ -187,9 +929,24 @@ func (d *Dashboard) checkForUpdates() tea.Cmd {
 func (d *Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.WindowSizeMsg:
-		d.width = msg.Width
-		d.height = msg.Height
-		d.updateLayout()
+		// The first size is applied at once so the dashboard can draw
+		if d.width == 0 {
+			d.setSize(msg.Width, msg.Height)
+			return d, nil
+		}
+		d.pendingWidth = msg.Width
+		d.pendingHeight = msg.Height
+		if d.resizePending {
+			return d, nil // Already scheduled; it will pick up this size
+		}
+		d.resizePending = true
+		return d, tea.Tick(resizeDebounce, func(time.Time) tea.Msg {
+			return resizeMsg{}
+		})
+
+	case resizeMsg:
+		d.resizePending = false
+		d.setSize(d.pendingWidth, d.pendingHeight)
 		return d, nil
 
 	case tea.KeyMsg:
@@ -198,55 +955,367 @@ func (d *Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return d.handleLookbackKey(msg)
 		}
 
+		// Session inspector: close on its own key or Esc
+		if d.inspectMode {
+			switch msg.String() {
+			case "ctrl+c":
+				d.stopCollection()
+				return d, tea.Quit
+			case "esc", "i", "q":
+				d.inspectMode = false
+			}
+			return d, nil
+		}
+
+		// Hour-of-day histogram: close on its own key or Esc
+		if d.hourlyMode {
+			switch msg.String() {
+			case "ctrl+c":
+				d.stopCollection()
+				return d, tea.Quit
+			case "esc", "t", "q":
+				d.hourlyMode = false
+			}
+			return d, nil
+		}
+
+		// Per-project costs: close on its own key or Esc
+		if d.projectsMode {
+			switch msg.String() {
+			case "ctrl+c":
+				d.stopCollection()
+				return d, tea.Quit
+			case "esc", "p", "q":
+				d.projectsMode = false
+			}
+			return d, nil
+		}
+
+		// Model detail: close on Enter or Esc
+		if d.modelMode {
+			switch msg.String() {
+			case "ctrl+c":
+				d.stopCollection()
+				return d, tea.Quit
+			case "esc", "enter", "q":
+				d.modelMode = false
+			}
+			return d, nil
+		}
+
+		// Keybinding cheat sheet: any key dismisses it
+		if d.keyHelpMode {
+			if msg.String() == "ctrl+c" {
+				d.stopCollection()
+				return d, tea.Quit
+			}
+			d.keyHelpMode = false
+			return d, nil
+		}
+
+		// Any key other than X cancels a pending cache clear
+		if d.confirmClear && msg.String() != "X" {
+			d.confirmClear = false
+			d.setStatusMessage("", 0)
+		}
+
 		switch msg.String() {
 		case "q", "ctrl+c":
+			d.stopCollection()
 			return d, tea.Quit
 		case "r":
+			if d.manual {
+				return d, tea.Batch(d.ingestAndCollect(), d.collectRemote(), d.cleanupSessions())
+			}
 			return d, d.collectMetrics()
+		case "X":
+			// Destructive: require a second press to confirm
+			if !d.confirmClear {
+				d.confirmClear = true
+				d.setStatusMessage("Press X again to clear the token cache (any other key cancels)", 5*time.Second)
+				return d, nil
+			}
+			d.confirmClear = false
+			d.setStatusMessage("Clearing token cache...", 10*time.Second)
+			return d, d.clearCache()
+		case "R":
+			// Re-read every log from the start, keeping cached events
+			d.setStatusMessage("Re-reading all logs from the start…", 10*time.Second)
+			return d, d.forceReingest()
 		case "h":
 			// Cycle through help modes: 0 -> 1 -> 2 -> 3 -> 0
 			d.helpMode = (d.helpMode + 1) % 4
 			return d, nil
+		case "1", "2", "3":
+			// Show one panel full screen
+			d.focusedPanel = int(msg.String()[0] - '0')
+			d.helpMode = 0
+			return d, nil
+		case "0", "esc":
+			// Back to all panels
+			d.focusedPanel = 0
+			d.helpMode = 0
+			d.selectedModel = ""
+			return d, nil
+		case "up", "down":
+			// Move the highlight through the token panel's model list
+			d.moveModelSelection(msg.String() == "down")
+			return d, nil
+		case "enter":
+			// Open the highlighted model's detail
+			if d.selectedModelUsage() != nil {
+				d.modelMode = true
+				d.modelActivity = nil
+				d.helpMode = 0
+				return d, d.loadModelActivity(d.selectedModel)
+			}
+			return d, nil
 		case "l", "L":
 			// Open lookback picker
 			d.lookbackMode = true
 			d.helpMode = 0 // Close help if open
 			return d, nil
+		case "i":
+			// Open session inspector
+			d.inspectMode = true
+			d.helpMode = 0
+			return d, nil
+		case "t":
+			// Open hour-of-day histogram
+			d.hourlyMode = true
+			d.hourOfDay = nil
+			d.hourOfDayErr = nil
+			d.helpMode = 0
+			return d, d.loadHourOfDay()
+		case "p":
+			// Open per-project cost comparison
+			d.projectsMode = true
+			d.projectUsage = nil
+			d.projectUsageErr = nil
+			d.helpMode = 0
+			return d, d.loadProjectUsage()
+		case "$":
+			// Toggle token panel emphasis between tokens and cost
+			if d.tokenDisplayMode == TokenDisplayCost {
+				d.tokenDisplayMode = TokenDisplayTokens
+			} else {
+				d.tokenDisplayMode = TokenDisplayCost
+			}
+			return d, nil
+		case "k":
+			// Toggle token counts between 1.2M and 1,234,567
+			d.exactTokens = !d.exactTokens
+			return d, nil
+		case "g":
+			// Toggle grouping sessions by name prefix
+			d.groupByPrefix = !d.groupByPrefix
+			d.expandedGroup = ""
+			return d, nil
+		case "G":
+			// Expand the next session group, collapsing the others
+			d.expandNextGroup()
+			return d, nil
+		case "f":
+			// Toggle flashing metrics that cross a threshold
+			d.flashThresholds = !d.flashThresholds
+			if d.flashThresholds {
+				d.setStatusMessage("Flashing metrics that cross a threshold", 3*time.Second)
+			} else {
+				d.setStatusMessage("Threshold flashes off", 3*time.Second)
+			}
+			return d, nil
+		case "A":
+			// Toggle the session age column
+			d.showSessionAge = !d.showSessionAge
+			return d, nil
+		case "M":
+			// Toggle raw model IDs in the token panel
+			d.fullModelNames = !d.fullModelNames
+			return d, nil
+		case "a":
+			// Toggle token totals between the current project and all projects.
+			// The trend follows the new scope from its first rate.
+			d.hasSmoothedRate = false
+			if d.tokenCollector.ProjectScope() != "" {
+				d.tokenCollector.SetProjectScope("")
+				return d, d.collectMetrics()
+			}
+			cwd, err := os.Getwd()
+			if err != nil {
+				d.setStatusMessage(fmt.Sprintf("Can't scope to the current project: %v", err), 5*time.Second)
+				return d, nil
+			}
+			d.tokenCollector.SetProjectScope(cwd)
+			return d, d.collectMetrics()
+		case "o":
+			// Cycle the order of the per-model breakdown
+			next := metrics.ModelSorts[(slices.Index(metrics.ModelSorts, d.tokenCollector.GetModelSort())+1)%len(metrics.ModelSorts)]
+			d.tokenCollector.SetModelSort(next)
+			d.setStatusMessage("Models sorted by "+next, 3*time.Second)
+			return d, d.collectMetrics()
+		case "m":
+			// Copy the current snapshot as markdown
+			return d, d.copyMarkdown()
+		case "?":
+			// Open keybinding cheat sheet
+			d.keyHelpMode = true
+			d.helpMode = 0
+			return d, nil
 		case "u", "U":
 			// Perform update if available
 			if d.updateInfo != nil && d.updateInfo.UpdateAvailable && !d.updating {
 				d.updating = true
 				d.updateStatus = "Downloading update..."
+				d.updateStatusUntil = time.Time{}
 				return d, d.performUpdate()
 			}
 			return d, nil
 		}
 
+	case tea.BlurMsg:
+		// Window hidden - stop collecting but keep rendering the last snapshot
+		d.paused = !d.manual
+		return d, nil
+
+	case tea.FocusMsg:
+		// Window visible again - refresh immediately instead of waiting for the next tick
+		if d.paused {
+			d.paused = false
+			return d, d.collectMetrics()
+		}
+		return d, nil
+
 	case tickMsg:
-		return d, tea.Batch(d.tick(), d.collectMetrics(), d.checkForUpdates())
+		if d.paused {
+			// Keep the tick loop alive so collection resumes on focus,
+			// but skip the CPU sample and tmux captures while hidden
+			return d, d.tick()
+		}
+		return d, tea.Batch(d.tick(), d.collectMetrics(), d.checkForUpdates(), d.collectRemote(), d.cleanupSessions())
 
 	case metricsMsg:
 		d.systemMetrics = msg.system
 		d.tokenMetrics = msg.tokens
-		d.tmuxMetrics = msg.tmux
+		d.sampleTokenRate(msg.tokens)
+		d.localTmux = msg.tmux
+		d.tmuxMetrics = d.mergeRemoteSessions(msg.tmux)
+		d.updateFlashes()
+		d.instanceCount = msg.instances
 		d.lastUpdate = time.Now()
+		return d, tea.Batch(d.handleSessionTransitions(d.tmuxMetrics), d.writeSnapshot())
+
+	case remoteMsg:
+		d.remoteInFlight = false
+		for _, h := range msg.hosts {
+			if h.Err != nil {
+				log.Printf("remote %s unreachable: %v", h.Host, h.Err)
+			}
+		}
+		d.remoteHosts = msg.hosts
+		d.tmuxMetrics = d.mergeRemoteSessions(d.localTmux)
 		return d, nil
 
 	case updateCheckMsg:
 		d.updateInfo = msg.info
 		return d, nil
 
+	case sessionCleanupMsg:
+		d.cleanupInFlight = false
+		if msg.err != nil {
+			log.Printf("session cleanup: %v", msg.err)
+		}
+		if msg.cleaned == 0 {
+			return d, nil
+		}
+		d.cleanedSessions += msg.cleaned
+		// Drop the removed sessions from the list now rather than next tick
+		return d, d.collectMetrics()
+
 	case updateCompleteMsg:
 		d.updating = false
-		if msg.err != nil {
+		if errors.Is(msg.err, updater.ErrUpdateInProgress) {
+			d.updateStatus = "Another instance is updating; restart ccdash once it's done"
+		} else if msg.err != nil {
 			d.updateStatus = fmt.Sprintf("Update failed: %v", msg.err)
+			d.updateStatusUntil = time.Now().Add(updateErrorDuration)
+			return d, tea.Tick(updateErrorDuration, func(time.Time) tea.Msg {
+				return updateErrorExpiredMsg{}
+			})
 		} else {
 			d.updateStatus = "Update complete! Restarting..."
 			// The app should restart automatically
+			d.stopCollection()
 			return d, tea.Quit
 		}
 		return d, nil
 
+	case updateErrorExpiredMsg:
+		// A retry since the failure has its own status
+		if !d.updating && !d.updateStatusUntil.IsZero() && !time.Now().Before(d.updateStatusUntil) {
+			d.updateStatus = ""
+			d.updateStatusUntil = time.Time{}
+		}
+		return d, nil
+
+	case hourOfDayMsg:
+		d.hourOfDay = msg.hours[:]
+		d.hourOfDayErr = msg.err
+		return d, nil
+
+	case modelActivityMsg:
+		if msg.model == d.selectedModel {
+			d.modelActivity = &msg
+		}
+		return d, nil
+
+	case projectUsageMsg:
+		d.projectUsage = msg.projects
+		d.projectUsageErr = msg.err
+		if d.projectUsage == nil {
+			d.projectUsage = []metrics.ProjectUsage{} // Loaded, just empty
+		}
+		return d, nil
+
+	case reingestMsg:
+		if msg.err != nil {
+			d.setStatusMessage(fmt.Sprintf("Re-ingest failed: %v", msg.err), 10*time.Second)
+			return d, nil
+		}
+		d.setStatusMessage(fmt.Sprintf("Re-reading %d log files from the start…", msg.files), 10*time.Second)
+		return d, d.collectAfterReset()
+
+	case markdownCopiedMsg:
+		switch {
+		case msg.path != "":
+			d.setStatusMessage(fmt.Sprintf("No clipboard available; stats saved to %s", msg.path), 10*time.Second)
+		case msg.err != nil:
+			d.setStatusMessage(fmt.Sprintf("Copy failed: %v", msg.err), 10*time.Second)
+		default:
+			d.setStatusMessage(fmt.Sprintf("Stats copied as markdown (%s)", msg.tool), 5*time.Second)
+		}
+		return d, nil
+
+	case snapshotWrittenMsg:
+		d.snapshotWriting = false
+		errText := ""
+		if msg.err != nil {
+			errText = msg.err.Error()
+		}
+		if errText != d.snapshotErr && errText != "" {
+			log.Printf("snapshot file: %v", msg.err)
+			d.setStatusMessage(fmt.Sprintf("Snapshot write failed: %v", msg.err), 10*time.Second)
+		}
+		d.snapshotErr = errText
+		return d, nil
+
+	case cacheClearedMsg:
+		if msg.err != nil {
+			d.setStatusMessage(fmt.Sprintf("Cache clear failed: %v", msg.err), 10*time.Second)
+			return d, nil
+		}
+		d.setStatusMessage("Cache cleared, re-ingesting…", 10*time.Second)
+		return d, d.collectAfterReset()
+
 	case errMsg:
 		d.err = msg.err
 		return d, nil
@@ -255,6 +1324,477 @@ func (d *Dashboard) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return d, nil
 }
 
+// statusTransitions diffs session statuses against the previous refresh and
+// returns the names of sessions that just became READY and of those that just
+// entered ERROR. Sessions seen for the first time never count as a transition,
+// so startup doesn't fire for every session that is already waiting.
+func (d *Dashboard) statusTransitions(tmux *metrics.TmuxMetrics) (ready, errored []string) {
+	if tmux == nil {
+		// Collection timed out - keep the previous statuses for the next diff
+		return nil, nil
+	}
+
+	current := make(map[string]metrics.SessionStatus, len(tmux.Sessions))
+	for _, session := range tmux.Sessions {
+		current[session.Name] = session.Status
+		prev, seen := d.sessionStatuses[session.Name]
+		if !seen || prev == session.Status {
+			continue
+		}
+		switch session.Status {
+		case metrics.StatusReady:
+			ready = append(ready, session.Name)
+		case metrics.StatusError:
+			errored = append(errored, session.Name)
+		}
+	}
+	d.sessionStatuses = current
+
+	return ready, errored
+}
+
+// handleSessionTransitions fires the configured alerts for sessions that became
+// READY, and the --bell-on-error alert for sessions that entered ERROR, except
+// for sessions given the same alert within the cooldown
+func (d *Dashboard) handleSessionTransitions(tmux *metrics.TmuxMetrics) tea.Cmd {
+	if d.errorFlash > 0 {
+		d.errorFlash--
+	}
+	ready, errored := d.statusTransitions(tmux)
+
+	var cmds []tea.Cmd
+	now := time.Now()
+	if d.bellOnError {
+		var ring []string
+		for _, name := range errored {
+			if d.alertDue(d.lastBell, name, now) {
+				ring = append(ring, name)
+			}
+		}
+		if len(ring) > 0 {
+			log.Printf("sessions entered ERROR: %s", strings.Join(ring, ", "))
+			d.errorFlash = errorFlashRefreshes
+			d.bellPending = true
+		}
+	}
+	if len(ready) == 0 || (!d.notifyEnabled && d.onReadyCommand == "") {
+		return tea.Batch(cmds...)
+	}
+
+	for _, name := range ready {
+		if !d.alertDue(d.lastNotified, name, now) {
+			continue
+		}
+		if d.notifyEnabled {
+			cmds = append(cmds, notifyReady(name))
+		}
+		if d.onReadyCommand != "" {
+			cmds = append(cmds, runOnReady(d.onReadyCommand, name))
+		}
+	}
+
+	return tea.Batch(cmds...)
+}
+
+// alertDue reports whether the session's last alert in last is at least the
+// cooldown ago, and if so records now as its last
+func (d *Dashboard) alertDue(last map[string]time.Time, name string, now time.Time) bool {
+	if prev, ok := last[name]; ok && now.Sub(prev) < d.alertCooldown {
+		return false
+	}
+	last[name] = now
+	return true
+}
+
+// SetFlashThresholds sets --flash-thresholds: CPU, memory, cost and the
+// attention badge flash for a refresh when they cross into a higher band
+func (d *Dashboard) SetFlashThresholds(enabled bool) {
+	d.flashThresholds = enabled
+}
+
+// metricBands places each flashable metric in a band that only rises when the
+// metric crosses something worth a glance: CPU and memory are 0 below the
+// warn threshold, 1 from it and 2 from the critical one; cost is the last
+// round amount passed (see costMilestone); sessions is how many need you.
+// Metrics without a reading are left out.
+func (d *Dashboard) metricBands() map[string]float64 {
+	bands := make(map[string]float64)
+	band := func(percent float64) float64 {
+		switch {
+		case percent >= d.critThreshold:
+			return 2
+		case percent >= d.warnThreshold:
+			return 1
+		}
+		return 0
+	}
+	if d.systemMetrics.CPU.Error == nil {
+		bands["cpu"] = band(d.systemMetrics.CPU.TotalPercent)
+	}
+	if mem := d.systemMetrics.Memory; mem.Error == nil && mem.Total > 0 {
+		percent := mem.Percentage
+		if d.memByAvailable {
+			percent = mem.UnavailablePercent()
+		}
+		bands["mem"] = band(percent)
+	}
+	if t := d.tokenMetrics; t != nil && t.Available {
+		bands["cost"] = costMilestone(t.TotalCost)
+	}
+	if d.tmuxMetrics != nil {
+		bands["sessions"] = float64(d.tmuxMetrics.NeedsAttention)
+	}
+	return bands
+}
+
+// costMilestone returns the largest of $1, $2, $5, $10, $20, $50, $100, …
+// that cost has reached, or 0 below $1
+func costMilestone(cost float64) float64 {
+	if cost < 1 {
+		return 0
+	}
+	magnitude := math.Pow(10, math.Floor(math.Log10(cost)))
+	for _, step := range []float64{5, 2} {
+		if cost >= step*magnitude {
+			return step * magnitude
+		}
+	}
+	return magnitude
+}
+
+// updateFlashes compares this refresh's metric bands with the last one's and
+// flashes those that rose. Cost only counts within one lookback window and
+// project, so changing either doesn't flash it.
+func (d *Dashboard) updateFlashes() {
+	bands := d.metricBands()
+	if t := d.tokenMetrics; t != nil {
+		window := fmt.Sprint(t.LookbackFrom.Unix(), t.LookbackTo.Unix(), t.Project)
+		if window != d.costWindow {
+			delete(d.bands, "cost")
+			d.costWindow = window
+		}
+	}
+	d.flashing = make(map[string]bool)
+	for key, band := range bands {
+		if prev, ok := d.bands[key]; ok && band > prev {
+			d.flashing[key] = true
+		}
+	}
+	d.bands = bands
+}
+
+// flashLabel renders label in reverse video while the metric named key is
+// flashing, and as is otherwise
+func (d *Dashboard) flashLabel(key, label string) string {
+	if !d.flashThresholds || !d.flashing[key] {
+		return label
+	}
+	return flashStyle.Render(label)
+}
+
+// notifyReady returns a command that shows a desktop notification for a READY session
+func notifyReady(name string) tea.Cmd {
+	return func() tea.Msg {
+		// Best effort: a missing notify-send shouldn't disturb the dashboard
+		err := notify.Desktop(
+			fmt.Sprintf("ccdash: %s is ready", name),
+			fmt.Sprintf("Session %s is waiting for input", name),
+		)
+		if err != nil {
+			log.Printf("notification for %s failed: %v", name, err)
+		}
+		return nil
+	}
+}
+
+// runOnReady returns a command that starts the --on-ready command for a session
+func runOnReady(command, name string) tea.Cmd {
+	return func() tea.Msg {
+		if err := notify.Exec(command, "CCDASH_SESSION="+name); err != nil {
+			log.Printf("on-ready command for %s: %v", name, err)
+		}
+		return nil
+	}
+}
+
+// cacheClearedMsg reports the result of clearing the token cache
+type cacheClearedMsg struct {
+	err error
+}
+
+// clearCache returns a command that wipes the token cache and starts a re-ingest
+func (d *Dashboard) clearCache() tea.Cmd {
+	return func() tea.Msg {
+		return cacheClearedMsg{err: d.tokenCollector.ClearCache()}
+	}
+}
+
+// reingestMsg reports the result of resetting file state for a full re-read
+type reingestMsg struct {
+	files int
+	err   error
+}
+
+// forceReingest returns a command that makes the next ingestion re-read every file
+func (d *Dashboard) forceReingest() tea.Cmd {
+	return func() tea.Msg {
+		files, err := d.tokenCollector.ForceReingest()
+		return reingestMsg{files: files, err: err}
+	}
+}
+
+// hourOfDayMsg carries token usage bucketed by local hour of day
+type hourOfDayMsg struct {
+	hours [24]metrics.HourOfDayUsage
+	err   error
+}
+
+// loadHourOfDay returns a command that queries the hour-of-day histogram for the lookback window
+func (d *Dashboard) loadHourOfDay() tea.Cmd {
+	return func() tea.Msg {
+		hours, err := d.tokenCollector.CollectHourOfDay()
+		return hourOfDayMsg{hours: hours, err: err}
+	}
+}
+
+// modelActivityMsg carries when a model was first and last used in the lookback window
+type modelActivityMsg struct {
+	model       string
+	first, last time.Time // Zero when the cache has no events for the model
+	err         error
+}
+
+// loadModelActivity returns a command that queries a model's first and last activity
+func (d *Dashboard) loadModelActivity(model string) tea.Cmd {
+	return func() tea.Msg {
+		first, last, err := d.tokenCollector.CollectModelActivity(model)
+		return modelActivityMsg{model: model, first: first, last: last, err: err}
+	}
+}
+
+// projectUsageMsg carries token usage per project
+type projectUsageMsg struct {
+	projects []metrics.ProjectUsage
+	err      error
+}
+
+// loadProjectUsage returns a command that queries per-project usage for the lookback window
+func (d *Dashboard) loadProjectUsage() tea.Cmd {
+	return func() tea.Msg {
+		projects, err := d.tokenCollector.CollectProjectUsage()
+		return projectUsageMsg{projects: projects, err: err}
+	}
+}
+
+// statsMarkdownFile is where the markdown summary is saved, in the data
+// directory, when no clipboard tool is available
+const statsMarkdownFile = "stats.md"
+
+// markdownCopiedMsg reports where the markdown summary went: the clipboard
+// tool that took it, or the file it was saved to instead
+type markdownCopiedMsg struct {
+	tool string
+	path string
+	err  error
+}
+
+// copyMarkdown returns a command that copies the current snapshot as
+// markdown. Without a clipboard tool it's saved to stats.md in the data
+// directory so it can still be copied by hand.
+func (d *Dashboard) copyMarkdown() tea.Cmd {
+	text := d.markdownSummary(time.Now())
+	return func() tea.Msg {
+		tool, err := clipboard.Copy(text)
+		if err == nil {
+			return markdownCopiedMsg{tool: tool}
+		}
+		dataDir, _ := metrics.DataDir()
+		path := filepath.Join(dataDir, statsMarkdownFile)
+		writeErr := os.MkdirAll(dataDir, 0755)
+		if writeErr == nil {
+			writeErr = export.WriteFileAtomic(path, []byte(text))
+		}
+		if writeErr != nil {
+			return markdownCopiedMsg{err: fmt.Errorf("%v; saving to %s: %v", err, path, writeErr)}
+		}
+		return markdownCopiedMsg{path: path, err: err}
+	}
+}
+
+// snapshotWrittenMsg reports the outcome of a --snapshot-file write
+type snapshotWrittenMsg struct {
+	err error
+}
+
+// SetSnapshotFile sets --snapshot-file: after each refresh the combined
+// snapshot is written to path as JSON, replacing the file atomically so
+// readers never see half of it. redact pseudonymizes it like --json --redact.
+// The directory must already exist.
+func (d *Dashboard) SetSnapshotFile(path string, redact bool) error {
+	if path != "" {
+		info, err := os.Stat(filepath.Dir(path))
+		if err != nil {
+			return fmt.Errorf("invalid snapshot file %q: %w", path, err)
+		}
+		if !info.IsDir() {
+			return fmt.Errorf("invalid snapshot file %q: %s is not a directory", path, filepath.Dir(path))
+		}
+	}
+	d.snapshotFile = path
+	d.snapshotRedact = redact
+	return nil
+}
+
+// writeSnapshot returns a command that writes the latest metrics to the
+// snapshot file, or nil when there's no file or the last write is still going.
+// The snapshot is encoded here, since redacting rewrites sessions in place
+// and the copy it works on mustn't share them with the dashboard.
+func (d *Dashboard) writeSnapshot() tea.Cmd {
+	if d.snapshotFile == "" || d.snapshotWriting {
+		return nil
+	}
+	snap := &export.Snapshot{
+		System:    d.systemMetrics,
+		Timestamp: d.lastUpdate,
+	}
+	if d.tokenMetrics != nil {
+		tokens := *d.tokenMetrics
+		snap.Tokens = &tokens
+	}
+	// Local sessions only, as in --json; remote hosts have their own
+	if d.localTmux != nil {
+		tmux := *d.localTmux
+		tmux.Sessions = slices.Clone(tmux.Sessions)
+		snap.Tmux = &tmux
+	}
+	if d.snapshotRedact {
+		export.RedactSnapshot(snap)
+	}
+	data, err := json.Marshal(snap)
+	if err != nil {
+		return func() tea.Msg { return snapshotWrittenMsg{err: err} }
+	}
+	d.snapshotWriting = true
+	path := d.snapshotFile
+	return func() tea.Msg {
+		return snapshotWrittenMsg{err: export.WriteFileAtomic(path, append(data, '\n'))}
+	}
+}
+
+// markdownSummary renders the system, token and session panels as markdown
+// tables for pasting into an issue or chat
+func (d *Dashboard) markdownSummary(now time.Time) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "## ccdash %s — %s\n\n", d.version, now.Format("2006-01-02 15:04 MST"))
+
+	// A pipe in a session or model name would end the table cell
+	cell := strings.NewReplacer("|", `\|`, "\n", " ").Replace
+	table := func(title string, header string, rows [][]string) {
+		if len(rows) == 0 {
+			return
+		}
+		fmt.Fprintf(&b, "### %s\n\n", title)
+		b.WriteString("| " + header + " |\n|" + strings.Repeat(" --- |", strings.Count(header, "|")+1) + "\n")
+		for _, row := range rows {
+			for i := range row {
+				row[i] = cell(row[i])
+			}
+			b.WriteString("| " + strings.Join(row, " | ") + " |\n")
+		}
+		b.WriteString("\n")
+	}
+
+	sys := d.systemMetrics
+	var system [][]string
+	if sys.CPU.Error == nil && !sys.LastUpdate.IsZero() {
+		system = append(system, []string{"CPU", fmt.Sprintf("%.1f%% (%d cores)", sys.CPU.TotalPercent, len(sys.CPU.PerCore))})
+	}
+	if sys.Load.Error == nil && !sys.LastUpdate.IsZero() {
+		system = append(system, []string{"Load", fmt.Sprintf("%.2f %.2f %.2f", sys.Load.Load1, sys.Load.Load5, sys.Load.Load15)})
+	}
+	if sys.Memory.Error == nil && sys.Memory.Total > 0 {
+		system = append(system, []string{"Memory", fmt.Sprintf("%.1f%% (%s / %s)",
+			sys.Memory.Percentage, metrics.FormatBytes(sys.Memory.Used), metrics.FormatBytes(sys.Memory.Total))})
+	}
+	if sys.Swap.Error == nil && sys.Swap.Total > 0 {
+		system = append(system, []string{"Swap", fmt.Sprintf("%.1f%% (%s / %s)",
+			sys.Swap.Percentage, metrics.FormatBytes(sys.Swap.Used), metrics.FormatBytes(sys.Swap.Total))})
+	}
+	for _, disk := range sys.DiskUsages {
+		if disk.Error == nil && disk.Total > 0 {
+			system = append(system, []string{"Disk " + disk.Path, fmt.Sprintf("%.1f%% (%s / %s)",
+				disk.Percentage, metrics.FormatBytes(disk.Used), metrics.FormatBytes(disk.Total))})
+		}
+	}
+	table("System", "Metric | Value", system)
+
+	if t := d.tokenMetrics; t != nil && t.Available {
+		scope := "All projects"
+		if t.Project != "" {
+			scope = t.Project
+		}
+		tokens := [][]string{
+			{"Window", d.lookbackLabel(now)},
+			{"Projects", scope},
+			{"Total", metrics.FormatTokens(t.TotalTokens)},
+			{"Input", metrics.FormatTokens(t.InputTokens)},
+			{"Output", metrics.FormatTokens(t.OutputTokens)},
+		}
+		if t.CacheReadTokens > 0 {
+			tokens = append(tokens, []string{"Cache read", metrics.FormatTokens(t.CacheReadTokens)})
+		}
+		if t.CacheCreationTokens > 0 {
+			tokens = append(tokens, []string{"Cache write", metrics.FormatTokens(t.CacheCreationTokens)})
+		}
+		cost := metrics.FormatCost(t.TotalCost)
+		if d.secondaryCurrency != "" {
+			cost += " (" + metrics.FormatCurrency(t.TotalCost*d.fxRate, d.secondaryCurrency) + ")"
+		}
+		tokens = append(tokens, []string{"Cost", cost})
+		if t.CostPer1K > 0 {
+			tokens = append(tokens, []string{"Cost/1K", metrics.FormatCostPer1K(t.CostPer1K)})
+		}
+		if t.CacheWriteCost > 0 || t.CacheReadCost > 0 {
+			tokens = append(tokens, []string{"Cache build", metrics.FormatCost(t.CacheWriteCost)})
+			tokens = append(tokens, []string{"Cache read", metrics.FormatCost(t.CacheReadCost)})
+		}
+		tokens = append(tokens, []string{"Requests", fmt.Sprintf("%d", t.Prompts)})
+		if t.TokensPerPrompt > 0 {
+			tokens = append(tokens, []string{"Tokens/request", metrics.FormatTokensCompact(int64(t.TokensPerPrompt))})
+		}
+		if t.Rate > 0 {
+			tokens = append(tokens, []string{"Rate", metrics.FormatTokenRateCompact(t.Rate)})
+		}
+		table("Tokens", "Metric | Value", tokens)
+
+		var models [][]string
+		for _, usage := range t.ModelUsages {
+			name := d.modelDisplayName(usage.Model)
+			if usage.Excluded {
+				name += " (excluded)"
+			}
+			models = append(models, []string{name, metrics.FormatTokens(usage.TotalTokens), metrics.FormatCost(usage.Cost)})
+		}
+		table("Models", "Model | Tokens | Cost", models)
+	}
+
+	if d.tmuxMetrics != nil {
+		var sessions [][]string
+		for _, s := range d.tmuxMetrics.Sessions {
+			sessions = append(sessions, []string{s.Name, s.Status.GetLabel(), metrics.FormatDuration(s.IdleDuration)})
+		}
+		table("Sessions", "Session | Status | Idle", sessions)
+	}
+
+	return strings.TrimRight(b.String(), "\n") + "\n"
+}
+
+// setStatusMessage shows a transient message in the status bar for the given duration
+func (d *Dashboard) setStatusMessage(msg string, duration time.Duration) {
+	d.statusMessage = msg
+	d.statusMessageUntil = time.Now().Add(duration)
+}
+
 // performUpdate returns a command that applies the update
 func (d *Dashboard) performUpdate() tea.Cmd {
 	return func() tea.Msg {
@@ -272,16 +1812,34 @@ func (d *Dashboard) handleLookbackKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			d.lookbackCustomMode = false
 			return d, nil
 		case "enter":
-			// Apply custom date and close picker
-			d.tokenCollector.SetLookback(d.lookbackCustomDate)
+			// Apply custom range and close picker
+			if !d.lookbackCustomHasEnd {
+				d.tokenCollector.SetLookback(d.lookbackCustomDate)
+			} else if d.lookbackCustomEnd.After(d.lookbackCustomDate) {
+				d.tokenCollector.SetLookbackRange(d.lookbackCustomDate, d.lookbackCustomEnd)
+			} else {
+				return d, nil // The picker shows why
+			}
 			d.lookbackCustomMode = false
 			d.lookbackMode = false
 			return d, d.collectMetrics()
+		case "e":
+			// Toggle the end bound, starting from a working day after the start
+			d.lookbackCustomHasEnd = !d.lookbackCustomHasEnd
+			if d.lookbackCustomHasEnd {
+				d.lookbackCustomEnd = d.lookbackCustomDate.Add(8 * time.Hour)
+				if d.lookbackCustomEnd.After(time.Now()) {
+					d.lookbackCustomEnd = time.Now()
+				}
+			} else if d.lookbackEditField >= 5 {
+				d.lookbackEditField -= 5
+			}
+			return d, nil
 		case "tab", "right":
-			d.lookbackEditField = (d.lookbackEditField + 1) % 5
+			d.lookbackEditField = (d.lookbackEditField + 1) % d.customFieldCount()
 			return d, nil
 		case "shift+tab", "left":
-			d.lookbackEditField = (d.lookbackEditField + 4) % 5
+			d.lookbackEditField = (d.lookbackEditField + d.customFieldCount() - 1) % d.customFieldCount()
 			return d, nil
 		case "up":
 			d.adjustCustomDate(1)
@@ -317,31 +1875,45 @@ func (d *Dashboard) handleLookbackKey(msg tea.KeyMsg) (tea.Model, tea.Cmd) {
 			return d, nil
 		}
 		// Apply preset and close picker
-		d.tokenCollector.SetLookback(preset.GetTime())
+		d.applyLookbackPreset(preset)
 		d.lookbackMode = false
 		return d, d.collectMetrics()
 	}
 	return d, nil
 }
 
-// adjustCustomDate adjusts the custom date based on current edit field
+// customFieldCount returns how many fields the custom picker cycles through:
+// the start's five, plus five more when the range has an end
+func (d *Dashboard) customFieldCount() int {
+	if d.lookbackCustomHasEnd {
+		return 10
+	}
+	return 5
+}
+
+// adjustCustomDate adjusts the custom start or end based on current edit field
 func (d *Dashboard) adjustCustomDate(delta int) {
-	switch d.lookbackEditField {
+	target := &d.lookbackCustomDate
+	if d.lookbackEditField >= 5 {
+		target = &d.lookbackCustomEnd
+	}
+
+	switch d.lookbackEditField % 5 {
 	case 0: // Year
-		d.lookbackCustomDate = d.lookbackCustomDate.AddDate(delta, 0, 0)
+		*target = target.AddDate(delta, 0, 0)
 	case 1: // Month
-		d.lookbackCustomDate = d.lookbackCustomDate.AddDate(0, delta, 0)
+		*target = target.AddDate(0, delta, 0)
 	case 2: // Day
-		d.lookbackCustomDate = d.lookbackCustomDate.AddDate(0, 0, delta)
+		*target = target.AddDate(0, 0, delta)
 	case 3: // Hour
-		d.lookbackCustomDate = d.lookbackCustomDate.Add(time.Duration(delta) * time.Hour)
+		*target = target.Add(time.Duration(delta) * time.Hour)
 	case 4: // Minute
-		d.lookbackCustomDate = d.lookbackCustomDate.Add(time.Duration(delta) * time.Minute)
+		*target = target.Add(time.Duration(delta) * time.Minute)
 	}
 
 	// Clamp to not be in the future
-	if d.lookbackCustomDate.After(time.Now()) {
-		d.lookbackCustomDate = time.Now()
+	if target.After(time.Now()) {
+		*target = time.Now()
 	}
 }
 
@@ -350,15 +1922,33 @@ func (d *Dashboard) View() string {
 	if d.width == 0 {
 		return "Initializing..."
 	}
+	// Mid-resize, skip rendering at a size that is about to change
+	if d.resizePending && d.lastView != "" {
+		return d.lastView
+	}
 
 	var content string
 
 	// Check if in lookback picker mode
 	if d.lookbackMode {
 		content = d.renderLookbackPicker()
+	} else if d.inspectMode {
+		content = d.renderSessionInspector()
+	} else if d.hourlyMode {
+		content = d.renderHourOfDay()
+	} else if d.projectsMode {
+		content = d.renderProjectUsage()
+	} else if d.modelMode {
+		content = d.renderModelDetail()
+	} else if d.keyHelpMode {
+		content = d.renderKeyHelp()
 	} else if d.helpMode > 0 {
 		// Check if in help mode
 		content = d.renderHelpView()
+	} else if d.focusedPanel > 0 {
+		content = d.renderFocusedPanel()
+	} else if d.minimalMode {
+		content = d.renderMinimal()
 	} else {
 		switch d.layoutMode {
 		case LayoutUltraWide:
@@ -373,10 +1963,15 @@ func (d *Dashboard) View() string {
 	}
 
 	// Add status bar
-	statusBar := d.renderStatusBar()
+	output := content
+	if !d.hideStatusBar {
+		output = lipgloss.JoinVertical(lipgloss.Left, content, d.renderStatusBar())
+	}
 
-	// Join content and status bar
-	output := lipgloss.JoinVertical(lipgloss.Left, content, statusBar)
+	// With --max-width, center the capped layout in the wider terminal
+	if margin := (d.termWidth - d.width) / 2; margin > 0 {
+		output = lipgloss.NewStyle().PaddingLeft(margin).Render(output)
+	}
 
 	// CRITICAL: Ensure output fills the entire terminal height to prevent
 	// external process output (like Tailscale logs) from bleeding through
@@ -389,9 +1984,35 @@ func (d *Dashboard) View() string {
 		output = output + padding
 	}
 
+	d.lastView = output
+
+	// The bell rides on the next frame, through the program's own output.
+	// BEL moves no cursor, so it can't disturb the frame being drawn.
+	if d.bellPending {
+		d.bellPending = false
+		return "\a" + output
+	}
 	return output
 }
 
+// setSize records a new terminal size. The layout works with the width capped
+// by --max-width, so every panel calculation sees the narrower width.
+func (d *Dashboard) setSize(width, height int) {
+	d.termWidth = width
+	d.width = width
+	if d.maxWidth > 0 && width > d.maxWidth {
+		d.width = d.maxWidth
+	}
+	d.height = height
+	d.updateLayout()
+
+	if d.layoutMode == LayoutUltraWide && (d.systemPanelWidth > 0 || d.tokenPanelWidth > 0) {
+		if _, _, ok := d.fixedPanelWidths(d.width - 6); !ok {
+			d.setStatusMessage(fmt.Sprintf("Panel widths don't fit in %d columns; using automatic widths", d.width), 5*time.Second)
+		}
+	}
+}
+
 // updateLayout determines the current layout mode based on terminal size
 func (d *Dashboard) updateLayout() {
 	if d.width < 120 {
@@ -403,18 +2024,39 @@ func (d *Dashboard) updateLayout() {
 	}
 }
 
-// tick returns a command that sends a tick message every 2 seconds
+// tick returns a command that sends a tick message every refresh interval
 func (d *Dashboard) tick() tea.Cmd {
-	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
+	return tea.Tick(d.refreshInterval, func(t time.Time) tea.Msg {
 		return tickMsg(t)
 	})
 }
 
+// ingestAndCollect returns a command that brings the token cache up to date
+// before collecting, for --manual, where nothing ingests in the background
+func (d *Dashboard) ingestAndCollect() tea.Cmd {
+	collect := d.collectMetrics()
+	return func() tea.Msg {
+		d.tokenCollector.Ingest()
+		return collect()
+	}
+}
+
+// collectAfterReset returns a command that collects once the token cache has
+// been cleared or reset. With --manual nothing ingests in the background to
+// refill it, so the logs are ingested first.
+func (d *Dashboard) collectAfterReset() tea.Cmd {
+	if d.manual {
+		return d.ingestAndCollect()
+	}
+	return d.collectMetrics()
+}
+
 // metricsMsg carries collected metrics
 type metricsMsg struct {
-	system metrics.SystemMetrics
-	tokens *metrics.TokenMetrics
-	tmux   *metrics.TmuxMetrics
+	system    metrics.SystemMetrics
+	tokens    *metrics.TokenMetrics
+	tmux      *metrics.TmuxMetrics
+	instances int
 }
 
 // errMsg carries errors
@@ -434,6 +2076,15 @@ func (d *Dashboard) collectMetrics() tea.Cmd {
 	return func() tea.Msg {
 		cache := d.tokenCollector.GetCache()
 		isLeader := cache.TryAcquireLease(d.instanceID)
+		instances := d.activeInstances()
+
+		// Abandon slow tmux and system calls at the deadline, or at once on quit
+		parent := d.quitCtx
+		if parent == nil {
+			parent = context.Background()
+		}
+		ctx, cancel := context.WithTimeout(parent, d.collectTimeout)
+		defer cancel()
 
 		var system metrics.SystemMetrics
 		var tokens *metrics.TokenMetrics
@@ -466,7 +2117,7 @@ func (d *Dashboard) collectMetrics() tea.Cmd {
 				}
 			}
 			// Leader or cache miss: collect fresh
-			m := d.systemCollector.Collect()
+			m := d.systemCollector.CollectContext(ctx)
 			if isLeader {
 				if data, err := json.Marshal(m); err == nil {
 					cache.SetCachedMetrics(metricTypeSystem, data)
@@ -493,7 +2144,7 @@ func (d *Dashboard) collectMetrics() tea.Cmd {
 				}
 			}
 			// Leader or cache miss: collect fresh
-			m := d.tmuxCollector.Collect()
+			m := d.tmuxCollector.CollectContext(ctx)
 			if isLeader {
 				if data, err := json.Marshal(m); err == nil {
 					cache.SetCachedMetrics(metricTypeTmux, data)
@@ -502,10 +2153,7 @@ func (d *Dashboard) collectMetrics() tea.Cmd {
 			tmuxChan <- tmuxResult{metrics: m}
 		}()
 
-		// Wait for results with 3 second timeout
-		timeout := time.After(3 * time.Second)
-
-		// Collect results as they come in, or timeout
+		// Collect results as they come in, or until the collect timeout
 		for i := 0; i < 3; i++ {
 			select {
 			case r := <-systemChan:
@@ -514,24 +2162,104 @@ func (d *Dashboard) collectMetrics() tea.Cmd {
 				tokens = r.metrics
 			case r := <-tmuxChan:
 				tmux = r.metrics
-			case <-timeout:
+			case <-ctx.Done():
 				// Return whatever we have so far
 				return metricsMsg{
-					system: system,
-					tokens: tokens,
-					tmux:   tmux,
+					system:    system,
+					tokens:    tokens,
+					tmux:      tmux,
+					instances: instances,
 				}
 			}
 		}
 
+		// Estimate context-window usage and spend for hook-tracked sessions
+		if tmux != nil {
+			d.tokenCollector.AttachContextUsage(tmux.Sessions)
+			d.tokenCollector.AttachSessionUsage(tmux.Sessions)
+		}
+
 		return metricsMsg{
-			system: system,
-			tokens: tokens,
-			tmux:   tmux,
+			system:    system,
+			tokens:    tokens,
+			tmux:      tmux,
+			instances: instances,
 		}
 	}
 }
 
+// stopCollection abandons any collection still running, killing the tmux
+// commands it started, so quitting never waits on a wedged pane
+func (d *Dashboard) stopCollection() {
+	if d.quit != nil {
+		d.quit()
+	}
+}
+
+// activeInstances returns how many ccdash instances are running, from the PID
+// files they register at startup; 0 when hook tracking is unavailable
+func (d *Dashboard) activeInstances() int {
+	if d.tmuxCollector == nil || d.tmuxCollector.GetHookCollector() == nil {
+		return 0
+	}
+	return d.tmuxCollector.GetHookCollector().GetActiveInstanceCount()
+}
+
+// renderMinimal renders the --compact view: one unbordered line each for
+// system, tokens and sessions, for embedding in a small tmux pane
+func (d *Dashboard) renderMinimal() string {
+	sep := dimStyle.Render(" │ ")
+	var lines []string
+
+	// System: CPU, memory, load
+	sys := d.systemMetrics
+	var sysParts []string
+	if sys.CPU.Error == nil {
+		sysParts = append(sysParts, fmt.Sprintf("%s%.0f%%", d.flashLabel("cpu", "CPU:"), sys.CPU.TotalPercent))
+	}
+	if sys.Memory.Error == nil && sys.Memory.Total > 0 {
+		sysParts = append(sysParts, fmt.Sprintf("%s%.0f%% %s/%s",
+			d.flashLabel("mem", "Mem:"), sys.Memory.Percentage, metrics.FormatBytes(sys.Memory.Used), metrics.FormatBytes(sys.Memory.Total)))
+	}
+	if sys.Load.Error == nil {
+		sysParts = append(sysParts, "Load:"+d.renderLoad(sys.Load.Load1))
+	}
+	if len(sysParts) == 0 {
+		sysParts = append(sysParts, dimStyle.Render("System: loading..."))
+	}
+	lines = append(lines, strings.Join(sysParts, sep))
+
+	// Tokens: total, cost, rate
+	switch {
+	case d.tokenMetrics == nil:
+		lines = append(lines, dimStyle.Render("Tokens: loading..."))
+	case !d.tokenMetrics.Available:
+		lines = append(lines, errorStyle.Render("Tokens: ")+dimStyle.Render(d.tokenMetrics.Error))
+	default:
+		lines = append(lines, strings.Join([]string{
+			"Tokens:" + d.formatTokens(d.tokenMetrics.TotalTokens),
+			d.flashLabel("cost", "Cost:") + costStyle.Render(metrics.FormatCost(d.tokenMetrics.TotalCost)),
+			"Rate:" + metrics.FormatTokenRate(d.tokenMetrics.Rate),
+		}, sep))
+	}
+
+	// Sessions: total and per-status counts
+	if d.tmuxMetrics == nil {
+		lines = append(lines, dimStyle.Render("Sessions: loading..."))
+	} else {
+		sessions := fmt.Sprintf("Sessions:%d", d.tmuxMetrics.Total)
+		if summary := d.sessionStatusSummary(); summary != "" {
+			sessions += sep + summary
+		}
+		if badge := d.attentionBadge(); badge != "" {
+			sessions += sep + badge
+		}
+		lines = append(lines, sessions)
+	}
+
+	return lipgloss.NewStyle().MaxWidth(d.width).Render(strings.Join(lines, "\n"))
+}
+
 // calculateTokenPanelWidth determines the optimal width for the token panel
 // based on actual content needs, returning the minimum width that displays well
 func (d *Dashboard) calculateTokenPanelWidth() int {
@@ -645,6 +2373,10 @@ func (d *Dashboard) renderUltraWide() string {
 	if totalPanelWidth < 180 {
 		systemWidth = 55
 	}
+	fixedSystem, fixedToken, _ := d.fixedPanelWidths(totalPanelWidth)
+	if fixedSystem > 0 {
+		systemWidth = fixedSystem
+	}
 
 	// Step 2: Calculate minimum and ideal widths for both panels
 	minTokenWidth := 46
@@ -721,6 +2453,12 @@ func (d *Dashboard) renderUltraWide() string {
 		tmuxWidth += excess
 	}
 
+	// A fixed token width overrides all of the above
+	if fixedToken > 0 {
+		tokenWidth = fixedToken
+		tmuxWidth = availableWidth - tokenWidth
+	}
+
 	// Final safety: ensure widths are positive
 	if tokenWidth < 1 {
 		tokenWidth = 1
@@ -748,6 +2486,29 @@ func (d *Dashboard) renderUltraWide() string {
 	)
 }
 
+// fixedPanelWidths returns the --system-panel-width and --token-panel-width
+// widths to use in a three-column layout totalWidth wide, 0 for those not
+// set. When they'd leave the sessions panel under minPanelWidth, both are 0
+// and ok is false.
+func (d *Dashboard) fixedPanelWidths(totalWidth int) (system, token int, ok bool) {
+	used := d.systemPanelWidth
+	if used == 0 {
+		used = 60
+		if totalWidth < 180 {
+			used = 55
+		}
+	}
+	if d.tokenPanelWidth > 0 {
+		used += d.tokenPanelWidth
+	} else {
+		used += minPanelWidth
+	}
+	if used+minPanelWidth > totalWidth {
+		return 0, 0, false
+	}
+	return d.systemPanelWidth, d.tokenPanelWidth, true
+}
+
 // renderWide renders 2 panels on top, 1 on bottom
 func (d *Dashboard) renderWide() string {
 	panelWidth := (d.width - 3) / 2 // 2 panels with spacing
@@ -814,6 +2575,26 @@ func (d *Dashboard) renderCompact() string {
 	)
 }
 
+// renderFocusedPanel renders the panel picked with 1/2/3 across the whole
+// terminal, leaving room for the status bar
+func (d *Dashboard) renderFocusedPanel() string {
+	panelWidth := d.width - 2
+	panelHeight := d.height - 2 // -2 borders
+	if !d.hideStatusBar {
+		// One line, or two below 120 columns
+		panelHeight -= lipgloss.Height(d.renderStatusBar())
+	}
+
+	switch d.focusedPanel {
+	case 1:
+		return d.renderSystemPanel(panelWidth, panelHeight)
+	case 2:
+		return d.renderTokenPanel(panelWidth, panelHeight)
+	default:
+		return d.renderTmuxPanel(panelWidth, panelHeight)
+	}
+}
+
 // renderSystemPanel renders the system resources panel
 func (d *Dashboard) renderSystemPanel(width, height int) string {
 	style := panelStyle
@@ -825,10 +2606,10 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 
 	// Load average
 	if d.systemMetrics.Load.Error == nil {
-		lines = append(lines, fmt.Sprintf("Load: %.2f %.2f %.2f",
-			d.systemMetrics.Load.Load1,
-			d.systemMetrics.Load.Load5,
-			d.systemMetrics.Load.Load15))
+		lines = append(lines, fmt.Sprintf("Load: %s %s %s",
+			d.renderLoad(d.systemMetrics.Load.Load1),
+			d.renderLoad(d.systemMetrics.Load.Load5),
+			d.renderLoad(d.systemMetrics.Load.Load15)))
 	} else {
 		lines = append(lines, errorStyle.Render("Load: N/A"))
 	}
@@ -843,10 +2624,13 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 		if cpuBarWidth < 10 {
 			cpuBarWidth = 10
 		}
-		lines = append(lines, fmt.Sprintf("CPU %s", d.renderBar(d.systemMetrics.CPU.TotalPercent, cpuBarWidth)))
+		lines = append(lines, fmt.Sprintf("%s %s", d.flashLabel("cpu", "CPU"), d.renderBar(d.systemMetrics.CPU.TotalPercent, cpuBarWidth)))
 
-		// CPU per-core - use up to 6 lines for CPU display
-		maxCoreLines := 6
+		// CPU per-core - use up to cpuCoreLines lines for CPU display
+		maxCoreLines := d.cpuCoreLines
+		if maxCoreLines < 1 {
+			maxCoreLines = defaultCPUCoreLines
+		}
 		totalCores := len(d.systemMetrics.CPU.PerCore)
 
 		// Determine label width based on total cores (for alignment)
@@ -859,7 +2643,10 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 
 		// Determine cores per line
 		var coresPerLine int
-		if totalCores <= 6 {
+		if d.cpuCoresPerLine > 0 {
+			// Fixed by --cpu-cores-per-line, as long as each bar keeps its minimum width
+			coresPerLine = min(d.cpuCoresPerLine, max(1, (contentWidth+1)/(labelWidth+3+8+1)))
+		} else if totalCores <= maxCoreLines {
 			coresPerLine = 1 // One core per line - bars stretch full width
 		} else {
 			// Multiple cores per line - calculate how many fit
@@ -894,7 +2681,7 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 			}
 		}
 
-		// Max cores we can display with 6 lines
+		// Max cores we can display with maxCoreLines lines
 		maxDisplayCores := coresPerLine * maxCoreLines
 		maxCores := totalCores
 		if maxCores > maxDisplayCores {
@@ -935,19 +2722,30 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 		lines = append(lines, errorStyle.Render("CPU: N/A"))
 	}
 
-	// Memory - always compact (one line)
+	// Memory - one line, plus an available/cache breakdown when there's room
+	memDetail, memDetailAt := "", 0
 	if d.systemMetrics.Memory.Error == nil {
-		memUsed := metrics.FormatBytes(d.systemMetrics.Memory.Used)
-		memTotal := metrics.FormatBytes(d.systemMetrics.Memory.Total)
+		mem := d.systemMetrics.Memory
+		memUsed := metrics.FormatBytes(mem.Used)
+		memTotal := metrics.FormatBytes(mem.Total)
 		// Format: "Mem [||||...] XX.XX GB/XX.XX GB"
 		// Calculate bar width: contentWidth - "Mem " (4) - " " (1) - "used/total" - margins
 		barWidth := contentWidth - 5 - len(memUsed) - 1 - len(memTotal)
 		if barWidth < 10 {
 			barWidth = 10
 		}
-		lines = append(lines, fmt.Sprintf("Mem %s %s/%s",
-			d.renderBar(d.systemMetrics.Memory.Percentage, barWidth),
+		percent := mem.Percentage
+		if d.memByAvailable {
+			percent = mem.UnavailablePercent()
+		}
+		lines = append(lines, fmt.Sprintf("%s %s %s/%s",
+			d.flashLabel("mem", "Mem"), d.renderBar(percent, barWidth),
 			memUsed, memTotal))
+		if mem.Available > 0 {
+			memDetail = dimStyle.Render(truncateToWidth(fmt.Sprintf("    Avail %s · Cache %s · Buf %s",
+				metrics.FormatBytes(mem.Available), metrics.FormatBytes(mem.Cached), metrics.FormatBytes(mem.Buffers)), contentWidth))
+			memDetailAt = len(lines)
+		}
 	} else {
 		lines = append(lines, errorStyle.Render("Mem: N/A"))
 	}
@@ -966,20 +2764,31 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 			swpUsed, swpTotal))
 	}
 
-	// Disk Usage - always compact (one line)
-	if d.systemMetrics.DiskUsage.Error == nil {
-		diskUsed := metrics.FormatBytes(d.systemMetrics.DiskUsage.Used)
-		diskTotal := metrics.FormatBytes(d.systemMetrics.DiskUsage.Total)
+	// Disk Usage - one compact line per monitored path
+	diskUsages := d.systemMetrics.DiskUsages
+	if len(diskUsages) == 0 {
+		diskUsages = []metrics.DiskUsageMetrics{d.systemMetrics.DiskUsage}
+	}
+	for _, du := range diskUsages {
+		// Only label lines with their mount path when more than one is shown
+		pathLabel := ""
+		if len(diskUsages) > 1 {
+			pathLabel = " " + du.Path
+		}
+		if du.Error != nil {
+			lines = append(lines, errorStyle.Render("Dsk: N/A"+pathLabel))
+			continue
+		}
+		diskUsed := metrics.FormatBytes(du.Used)
+		diskTotal := metrics.FormatBytes(du.Total)
 		// Use same calculation as Memory for consistency
-		barWidth := contentWidth - 5 - len(diskUsed) - 1 - len(diskTotal)
+		barWidth := contentWidth - 5 - len(diskUsed) - 1 - len(diskTotal) - len(pathLabel)
 		if barWidth < 10 {
 			barWidth = 10
 		}
-		lines = append(lines, fmt.Sprintf("Dsk %s %s/%s",
-			d.renderBar(d.systemMetrics.DiskUsage.Percentage, barWidth),
-			diskUsed, diskTotal))
-	} else {
-		lines = append(lines, errorStyle.Render("Dsk: N/A"))
+		lines = append(lines, fmt.Sprintf("Dsk %s %s/%s%s",
+			d.renderBar(du.Percentage, barWidth),
+			diskUsed, diskTotal, dimStyle.Render(pathLabel)))
 	}
 
 	// Disk I/O - verbose format with pipe separators
@@ -991,21 +2800,98 @@ func (d *Dashboard) renderSystemPanel(width, height int) string {
 		lines = append(lines, errorStyle.Render("Disk I/O | N/A"))
 	}
 
-	// Net I/O - verbose format with pipe separators
-	if d.systemMetrics.NetIO.Error == nil {
-		lines = append(lines, fmt.Sprintf("Net I/O  | Recv: %s | Sent: %s",
-			metrics.FormatRate(d.systemMetrics.NetIO.RecvBytesPerSec),
-			metrics.FormatRate(d.systemMetrics.NetIO.SentBytesPerSec)))
+	// Net I/O - verbose format with pipe separators, naming the busiest
+	// interface when there's room
+	if netIO := d.systemMetrics.NetIO; netIO.Error == nil {
+		rates := fmt.Sprintf("| Recv: %s | Sent: %s",
+			metrics.FormatRate(netIO.RecvBytesPerSec),
+			metrics.FormatRate(netIO.SentBytesPerSec))
+		line := "Net I/O  " + rates
+		if named := fmt.Sprintf("Net I/O (%s) %s", netIO.Primary, rates); netIO.Primary != "" && lipgloss.Width(named) <= contentWidth {
+			line = named
+		}
+		lines = append(lines, line)
 	} else {
 		lines = append(lines, errorStyle.Render("Net I/O  | N/A"))
 	}
 
+	lines = append(lines, d.remoteSystemLines()...)
+
+	// The memory breakdown is the first thing to go on a short panel (2 lines are borders)
+	if memDetail != "" && len(lines)+1 <= height-2 {
+		lines = append(lines[:memDetailAt], append([]string{memDetail}, lines[memDetailAt:]...)...)
+	}
+
 	content := strings.Join(lines, "\n")
 	return style.Width(width).Height(height).Render(content)
 }
 
+// remoteHostLabel pads a host name to a common width so remote rows line up
+func (d *Dashboard) remoteHostLabel(host string) string {
+	width := 0
+	for _, h := range d.remoteHosts {
+		width = max(width, lipgloss.Width(h.Host))
+	}
+	if width > 16 {
+		width = 16
+	}
+	host = truncateToWidth(host, width)
+	return host + strings.Repeat(" ", max(0, width-lipgloss.Width(host)))
+}
+
+// remoteSystemLines renders one CPU/memory/load row per remote host
+func (d *Dashboard) remoteSystemLines() []string {
+	if len(d.remoteHosts) == 0 {
+		return nil
+	}
+
+	lines := []string{"", boldStyle.Render("Hosts:")}
+	for _, h := range d.remoteHosts {
+		label := d.remoteHostLabel(h.Host)
+		switch {
+		case h.Updated.IsZero():
+			lines = append(lines, dimStyle.Render(label+" connecting…"))
+		case !h.Reachable():
+			lines = append(lines, errorStyle.Render(label+" unreachable"))
+		default:
+			sys := h.Snapshot.System
+			lines = append(lines, fmt.Sprintf("%s CPU %5.1f%% Mem %5.1f%% Ld %.2f",
+				label, sys.CPU.TotalPercent, sys.Memory.Percentage, sys.Load.Load1))
+		}
+	}
+	return lines
+}
+
+// remoteTokenLines renders one cost/token row per remote host
+func (d *Dashboard) remoteTokenLines() []string {
+	if len(d.remoteHosts) == 0 {
+		return nil
+	}
+
+	lines := []string{"", boldStyle.Render("Hosts:")}
+	for _, h := range d.remoteHosts {
+		label := d.remoteHostLabel(h.Host)
+		switch {
+		case h.Updated.IsZero():
+			lines = append(lines, dimStyle.Render(label+" connecting…"))
+		case !h.Reachable():
+			lines = append(lines, errorStyle.Render(label+" unreachable"))
+		case h.Snapshot.Tokens == nil || !h.Snapshot.Tokens.Available:
+			lines = append(lines, dimStyle.Render(label+" no token data"))
+		default:
+			t := h.Snapshot.Tokens
+			lines = append(lines, fmt.Sprintf("%s %s %s",
+				label,
+				costStyle.Render(metrics.FormatCost(t.TotalCost)),
+				dimStyle.Render("("+metrics.FormatTokensCompact(t.TotalTokens)+")")))
+		}
+	}
+	return lines
+}
+
 // renderTokenPanel renders the token usage panel with side-by-side layout
-// Left side: Total token stats, Right side: Per-model costs
+// Left side: Total token stats, Right side: Per-model costs.
+// tokenDisplayMode decides whether the total or the cost is emphasized.
 func (d *Dashboard) renderTokenPanel(width, height int) string {
 	style := panelStyle
 
@@ -1017,26 +2903,30 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 
 	// Title with lookback info aligned right
 	title := successStyle.Render("💰 Token Usage")
-	lookbackInfo := ""
-	if d.tokenMetrics != nil && !d.tokenMetrics.LookbackFrom.IsZero() {
-		// Format start time - use date if not this week
-		now := time.Now()
-		startTime := d.tokenMetrics.LookbackFrom
-		elapsed := now.Sub(startTime)
-
-		// Choose format based on how long ago
-		var timeStr string
-		if elapsed < 7*24*time.Hour {
-			timeStr = startTime.Format("Mon 3:04pm")
-		} else {
-			timeStr = startTime.Format("Jan 2 3:04pm")
+	if d.tokenMetrics.Source == metrics.TokenSourceCCUsage {
+		title += dimStyle.Render(" (ccusage)")
+	}
+	lookbackInfo := dimStyle.Render(d.lookbackLabel(time.Now()))
+	if d.clockSkewed(time.Now()) {
+		lookbackInfo = dimStyle.Render("⚠ clock skew? ") + lookbackInfo
+	}
+	// The scope goes after the title, with the project's whole path when
+	// there's room and its last element when not. When neither fits next to
+	// the lookback, a project scope gets a line of its own; "All Projects" is
+	// the default and is left out.
+	fitsTitle := func(scope string) bool {
+		return lipgloss.Width(title+" · "+scope)+1+lipgloss.Width(lookbackInfo) <= contentWidth
+	}
+	scopeLine := ""
+	if scopeInfo := d.scopeLabel(true); fitsTitle(scopeInfo) {
+		title += dimStyle.Render(" · ") + scopeInfo
+	} else if scopeInfo := d.scopeLabel(false); fitsTitle(scopeInfo) {
+		title += dimStyle.Render(" · ") + scopeInfo
+	} else if d.tokenMetrics.Project != "" {
+		scopeLine = d.scopeLabel(true)
+		if lipgloss.Width(scopeLine) > contentWidth {
+			scopeLine = d.scopeLabel(false)
 		}
-
-		// Add human-readable duration
-		durationStr := metrics.FormatDuration(elapsed)
-		lookbackInfo = dimStyle.Render(fmt.Sprintf("%s → Now (%s)", timeStr, durationStr))
-	} else if d.tokenMetrics != nil {
-		lookbackInfo = dimStyle.Render("All time")
 	}
 
 	titleLen := lipgloss.Width(title)
@@ -1046,6 +2936,9 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 		spacing = 1
 	}
 	headerLine := title + strings.Repeat(" ", spacing) + lookbackInfo
+	if scopeLine != "" {
+		headerLine += "\n" + scopeLine
+	}
 
 	if !d.tokenMetrics.Available {
 		var lines []string
@@ -1061,42 +2954,95 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 	// Helper to get model style by name
 	getModelStyle := func(modelName string) lipgloss.Style {
 		if strings.Contains(modelName, "opus") {
-			return lipgloss.NewStyle().Foreground(lipgloss.Color("#ff6b6b")) // Red for Opus
+			return lipgloss.NewStyle().Foreground(colorOpus) // Red for Opus
 		} else if strings.Contains(modelName, "sonnet") {
-			return lipgloss.NewStyle().Foreground(lipgloss.Color("#4ecdc4")) // Cyan for Sonnet
+			return lipgloss.NewStyle().Foreground(colorSonnet) // Cyan for Sonnet
 		} else if strings.Contains(modelName, "haiku") {
-			return lipgloss.NewStyle().Foreground(lipgloss.Color("#95e1d3")) // Light green for Haiku
+			return lipgloss.NewStyle().Foreground(colorHaiku) // Light green for Haiku
 		} else if strings.Contains(modelName, "glm") {
-			return lipgloss.NewStyle().Foreground(lipgloss.Color("#00bfff")) // Blue for GLM
+			return lipgloss.NewStyle().Foreground(colorGLM) // Blue for GLM
 		}
 		return dimStyle
 	}
 
 	// Build left column: Total stats
+	leftWidth := 22
 	var leftLines []string
 	hasCacheRead := d.tokenMetrics.CacheReadTokens > 0
 	hasCacheCreate := d.tokenMetrics.CacheCreationTokens > 0
 	hasRate := d.tokenMetrics.Rate > 0
 	hasAvg := d.tokenMetrics.SessionAvgRate > 0
+	// Below one token a minute the trend would only read 0/min
+	hasTrend := d.rateSmoothing > 0 && d.hasSmoothedRate && d.smoothedRate >= 1
 
-	// Compact format for left column
-	leftLines = append(leftLines, fmt.Sprintf("In:    %s", metrics.FormatTokensCompact(d.tokenMetrics.InputTokens)))
-	leftLines = append(leftLines, fmt.Sprintf("Out:   %s", metrics.FormatTokensCompact(d.tokenMetrics.OutputTokens)))
+	// Short counts for the left column unless exact ones are on
+	leftLines = append(leftLines, fmt.Sprintf("In:    %s", d.formatTokens(d.tokenMetrics.InputTokens)))
+	leftLines = append(leftLines, fmt.Sprintf("Out:   %s", d.formatTokens(d.tokenMetrics.OutputTokens)))
 	if hasCacheRead {
-		leftLines = append(leftLines, fmt.Sprintf("Cache: %s", metrics.FormatTokensCompact(d.tokenMetrics.CacheReadTokens)))
+		leftLines = append(leftLines, fmt.Sprintf("Cache: %s", d.formatTokens(d.tokenMetrics.CacheReadTokens)))
 	}
 	if hasCacheCreate {
-		leftLines = append(leftLines, fmt.Sprintf("Create:%s", metrics.FormatTokensCompact(d.tokenMetrics.CacheCreationTokens)))
+		leftLines = append(leftLines, fmt.Sprintf("Create:%s", d.formatTokens(d.tokenMetrics.CacheCreationTokens)))
+	}
+	if d.tokenMetrics.UserInputTokens > 0 {
+		leftLines = append(leftLines, fmt.Sprintf("User:  %s", d.formatTokens(d.tokenMetrics.UserInputTokens)))
+	}
+	costFirst := d.tokenDisplayMode == TokenDisplayCost
+	totalText := d.formatTokens(d.tokenMetrics.TotalTokens)
+	costText := costStyle.Render(metrics.FormatCost(d.tokenMetrics.TotalCost))
+	if costFirst {
+		// Cost leads and takes the emphasis
+		costText = costStyle.Bold(true).Render(metrics.FormatCost(d.tokenMetrics.TotalCost))
+	} else {
+		totalText = boldStyle.Render(totalText)
+	}
+	totalLine := fmt.Sprintf("Total: %s", totalText)
+	costLine := fmt.Sprintf("%s  %s", d.flashLabel("cost", "Cost:"), costText)
+	costLines := []string{costLine}
+	if sec := d.secondaryCost(d.tokenMetrics.TotalCost); sec != "" {
+		// Keep the left column within its fixed width; large amounts wrap below
+		if lipgloss.Width(costLine+sec) < leftWidth {
+			costLines[0] += sec
+		} else {
+			costLines = append(costLines, "      "+sec)
+		}
+	}
+	if d.tokenMetrics.CostPer1K > 0 {
+		costLines = append(costLines, dimStyle.Render(fmt.Sprintf("Cost/1K: %s", metrics.FormatCostPer1K(d.tokenMetrics.CostPer1K))))
+	}
+	// What building the cache costs against what reading from it costs
+	if d.tokenMetrics.CacheWriteCost > 0 {
+		costLines = append(costLines, dimStyle.Render(fmt.Sprintf("Cache build: %s", metrics.FormatCost(d.tokenMetrics.CacheWriteCost))))
+	}
+	if d.tokenMetrics.CacheReadCost > 0 {
+		costLines = append(costLines, dimStyle.Render(fmt.Sprintf("Cache read:  %s", metrics.FormatCost(d.tokenMetrics.CacheReadCost))))
+	}
+	reqLines := []string{fmt.Sprintf("Reqs:  %d", d.tokenMetrics.Prompts)}
+	if d.tokenMetrics.TokensPerPrompt > 0 {
+		// Few large requests or many small ones
+		reqLines = append(reqLines, dimStyle.Render(fmt.Sprintf("Per req: %s", d.formatTokens(int64(d.tokenMetrics.TokensPerPrompt)))))
+	}
+	if costFirst {
+		leftLines = append(leftLines, costLines...)
+		leftLines = append(leftLines, totalLine)
+		leftLines = append(leftLines, reqLines...)
+	} else {
+		leftLines = append(leftLines, totalLine)
+		leftLines = append(leftLines, reqLines...)
+		leftLines = append(leftLines, costLines...)
 	}
-	leftLines = append(leftLines, fmt.Sprintf("Total: %s", boldStyle.Render(metrics.FormatTokensCompact(d.tokenMetrics.TotalTokens))))
-	leftLines = append(leftLines, fmt.Sprintf("Reqs:  %d", d.tokenMetrics.Prompts))
-	leftLines = append(leftLines, fmt.Sprintf("Cost:  %s", costStyle.Render(metrics.FormatCost(d.tokenMetrics.TotalCost))))
 	if hasRate {
 		leftLines = append(leftLines, fmt.Sprintf("Rate:  %s", dimStyle.Render(metrics.FormatTokenRateCompact(d.tokenMetrics.Rate))))
 	}
+	if hasTrend {
+		leftLines = append(leftLines, fmt.Sprintf("Trend: %s", dimStyle.Render(metrics.FormatTokenRateCompact(d.smoothedRate))))
+	}
 	if hasAvg {
 		leftLines = append(leftLines, fmt.Sprintf("Avg:   %s", dimStyle.Render(metrics.FormatTokenRateCompact(d.tokenMetrics.SessionAvgRate))))
 	}
+	if burn := d.burnGauge(); burn != "" {
+		leftLines = append(leftLines, fmt.Sprintf("Burn:  %s", burn))
+	}
 
 	// Determine layout based on width
 	// For narrow panels, stack vertically; for wider panels, use side-by-side
@@ -1105,10 +3051,12 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 
 	// Calculate available width for model names based on layout
 	// In side-by-side: rightWidth = contentWidth - leftWidth(22) - separator(2)
-	// Model line format: "Name $XX.XX (XX,XXXtok)" - cost ~8 chars, tokens ~12 chars = ~20 chars for cost+tokens
-	leftWidth := 22
+	// Model line format: "Name $XX.XX (XX.XM)" - cost ~8 chars, tokens ~12 chars = ~20 chars for cost+tokens
 	rightWidth := contentWidth - leftWidth - 2
 	maxModelNameWidth := rightWidth - 22 // Reserve space for cost and token count
+	if d.exactTokens {
+		maxModelNameWidth -= 6 // 12,345,678 is six columns wider than 12.3M
+	}
 	if maxModelNameWidth < 10 {
 		maxModelNameWidth = 10 // Minimum display width
 	}
@@ -1118,17 +3066,46 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 	if modelCount > 0 {
 		rightLines = append(rightLines, boldStyle.Render("Models:"))
 		for _, usage := range d.tokenMetrics.ModelUsages {
-			displayName := shortenModelName(usage.Model)
-			// Dynamically truncate based on available space
-			if len(displayName) > maxModelNameWidth {
-				displayName = displayName[:maxModelNameWidth-1] + "…"
+			displayName := d.modelDisplayName(usage.Model)
+			// Dynamically truncate based on available space. A full ID keeps
+			// its end, where the snapshot date is.
+			if d.fullModelNames {
+				displayName = truncateStartToWidth(displayName, maxModelNameWidth)
+			} else {
+				displayName = truncateToWidth(displayName, maxModelNameWidth)
 			}
 			modelStyle := getModelStyle(usage.Model)
+			selected := usage.Model == d.selectedModel
+			if selected {
+				modelStyle = modelStyle.Reverse(true)
+			}
 			// All model info on one line: Name Cost (Tokens)
-			line := fmt.Sprintf("%s %s %s",
+			var line string
+			if usage.Excluded {
+				style := excludedStyle
+				if selected {
+					style = style.Reverse(true)
+				}
+				line = style.Render(fmt.Sprintf("%s %s (%s)", displayName,
+					metrics.FormatCost(usage.Cost), d.formatTokens(usage.TotalTokens)))
+				if lipgloss.Width(line)+5 <= rightWidth {
+					line += dimStyle.Render(" excl")
+				}
+				rightLines = append(rightLines, line)
+				continue
+			}
+			modelCostStyle := costStyle
+			if costFirst {
+				modelCostStyle = costStyle.Bold(true)
+			}
+			line = fmt.Sprintf("%s %s %s",
 				modelStyle.Render(displayName),
-				costStyle.Render(metrics.FormatCost(usage.Cost)),
-				dimStyle.Render("("+metrics.FormatTokensCompact(usage.TotalTokens)+")"))
+				modelCostStyle.Render(metrics.FormatCost(usage.Cost)),
+				dimStyle.Render("("+d.formatTokens(usage.TotalTokens)+")"))
+			// The secondary amount is dropped from model lines that would overflow
+			if sec := d.secondaryCost(usage.Cost); sec != "" && lipgloss.Width(line+sec) <= rightWidth {
+				line += sec
+			}
 			rightLines = append(rightLines, line)
 		}
 	}
@@ -1157,10 +3134,8 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 			right := rightLines[i]
 			// Pad left to fixed width
 			leftPadded := left + strings.Repeat(" ", max(0, leftWidth-lipgloss.Width(left)))
-			// Truncate right if needed (safety fallback)
-			if lipgloss.Width(right) > rightWidth {
-				right = right[:rightWidth-1] + "…"
-			}
+			// Truncate right if needed (safety fallback); it's styled, so cut around the escape codes
+			right = ansi.Truncate(right, rightWidth, "…")
 			lines = append(lines, leftPadded+"│ "+right)
 		}
 	} else {
@@ -1172,10 +3147,147 @@ func (d *Dashboard) renderTokenPanel(width, height int) string {
 		}
 	}
 
+	lines = append(lines, d.remoteTokenLines()...)
+
+	// The cost chart only goes below everything else, and only when it fits
+	if chart := d.hourlyCostChart(contentWidth); chart != nil && len(lines)+len(chart) <= height-2 {
+		lines = append(lines, chart...)
+	}
+
 	content := strings.Join(lines, "\n")
 	return style.Width(width).Height(height).Render(content)
 }
 
+// hourlyCostChart renders the cost of each of the last 24 hours as a
+// sparkline under a caption with the total and the peak hour, after a blank
+// line. It's nil outside the wide layouts and the focused token panel,
+// without any cost, or when width can't fit a column per hour.
+func (d *Dashboard) hourlyCostChart(width int) []string {
+	costs := d.tokenMetrics.HourlyCost
+	wide := d.layoutMode == LayoutWide || d.layoutMode == LayoutUltraWide || d.focusedPanel == 2
+	if !wide || len(costs) == 0 || width < len(costs) {
+		return nil
+	}
+
+	var total, peak float64
+	for _, cost := range costs {
+		total += cost
+		if cost > peak {
+			peak = cost
+		}
+	}
+	if total <= 0 {
+		return nil
+	}
+
+	caption := dimStyle.Render("Last 24h: ") + costStyle.Render(metrics.FormatCost(total)) +
+		dimStyle.Render(" · peak "+metrics.FormatCost(peak)+"/h")
+	return []string{"", caption, costStyle.Render(renderSparkline(costs, min(width/len(costs), 3)))}
+}
+
+// sparkBlocks are the bar heights of a sparkline, in eighths of a line
+var sparkBlocks = []string{" ", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+
+// renderSparkline draws values as a one-line bar chart scaled to the largest,
+// each bar barWidth columns wide. Non-zero values get at least the lowest bar.
+func renderSparkline(values []float64, barWidth int) string {
+	var peak float64
+	for _, v := range values {
+		if v > peak {
+			peak = v
+		}
+	}
+
+	var sb strings.Builder
+	for _, v := range values {
+		level := 0
+		if peak > 0 && v > 0 {
+			level = max(int(v/peak*8+0.5), 1)
+		}
+		sb.WriteString(strings.Repeat(sparkBlocks[level], barWidth))
+	}
+	return sb.String()
+}
+
+// burnHotRatio is the multiple of the session average rate at which the burn
+// gauge turns red
+const burnHotRatio = 2.0
+
+// burnGauge compares the 60s token rate to the session average: a down arrow
+// in green while below average, an up arrow in yellow above it and in red from
+// burnHotRatio times it. It's empty without a rate or an average to compare.
+func (d *Dashboard) burnGauge() string {
+	if d.tokenMetrics == nil || d.tokenMetrics.Rate <= 0 || d.tokenMetrics.SessionAvgRate <= 0 {
+		return ""
+	}
+	ratio := d.tokenMetrics.Rate / d.tokenMetrics.SessionAvgRate
+	switch {
+	case ratio < 1:
+		return successStyle.Render(fmt.Sprintf("▼ %.1f× avg", ratio))
+	case ratio < burnHotRatio:
+		return warningStyle.Render(fmt.Sprintf("▲ %.1f× avg", ratio))
+	default:
+		return errorStyle.Render(fmt.Sprintf("▲ %.1f× avg", ratio))
+	}
+}
+
+// scopeLabel names the projects the token totals cover: "Project: <path>"
+// after pressing a, with the home directory as ~, else "All Projects". Without
+// fullPath, only the last element of the path is given.
+func (d *Dashboard) scopeLabel(fullPath bool) string {
+	if d.tokenMetrics == nil || d.tokenMetrics.Project == "" {
+		return dimStyle.Render("All Projects")
+	}
+	name := filepath.Base(d.tokenMetrics.Project)
+	if fullPath {
+		name = shortenHome(d.tokenMetrics.Project)
+	}
+	return boldStyle.Render("Project: " + name)
+}
+
+// clockSkewed reports signs that the local clock is behind: logged events
+// from the future, or a lookback window that hasn't started yet. Spans are
+// shown as 0 meanwhile, rather than negative.
+func (d *Dashboard) clockSkewed(now time.Time) bool {
+	if d.tokenMetrics == nil {
+		return false
+	}
+	return d.tokenMetrics.ClockSkew > 0 || d.tokenMetrics.LookbackFrom.After(now)
+}
+
+// lookbackLabel describes the token lookback window, e.g. "Mon 9:00am → Now
+// (2d 3h)", or "All time" without a start
+func (d *Dashboard) lookbackLabel(now time.Time) string {
+	if d.tokenMetrics == nil || d.tokenMetrics.LookbackFrom.IsZero() {
+		return "All time"
+	}
+
+	// Format start time - use date if not this week
+	startTime := d.tokenMetrics.LookbackFrom
+	elapsed := now.Sub(startTime)
+	var timeStr string
+	if elapsed < 7*24*time.Hour {
+		timeStr = startTime.Format("Mon 3:04pm")
+	} else {
+		timeStr = startTime.Format("Jan 2 3:04pm")
+	}
+
+	// Add human-readable duration
+	if endTime := d.tokenMetrics.LookbackTo; !endTime.IsZero() {
+		return fmt.Sprintf("%s → %s (%s)", timeStr, endTime.Format("Mon 3:04pm"), metrics.FormatDuration(endTime.Sub(startTime)))
+	}
+	return fmt.Sprintf("%s → Now (%s)", timeStr, metrics.FormatDuration(elapsed))
+}
+
+// modelDisplayName returns the name the token panel shows for a model:
+// shortened unless full model names are on
+func (d *Dashboard) modelDisplayName(model string) string {
+	if d.fullModelNames {
+		return model
+	}
+	return shortenModelName(model)
+}
+
 // shortenModelName shortens common model names for display
 func shortenModelName(name string) string {
 	// Common patterns to shorten
@@ -1242,7 +3354,7 @@ func (d *Dashboard) calculateRequiredTokenWidth() int {
 
 	if d.tokenMetrics != nil && len(d.tokenMetrics.ModelUsages) > 0 {
 		for _, usage := range d.tokenMetrics.ModelUsages {
-			displayName := shortenModelName(usage.Model)
+			displayName := d.modelDisplayName(usage.Model)
 			if len(displayName) > maxNameLen {
 				maxNameLen = len(displayName)
 			}
@@ -1255,6 +3367,9 @@ func (d *Dashboard) calculateRequiredTokenWidth() int {
 // renderTmuxPanel renders the tmux sessions panel
 func (d *Dashboard) renderTmuxPanel(width, height int) string {
 	style := panelStyle
+	if d.errorFlash > 0 {
+		style = style.BorderForeground(colorRed)
+	}
 
 	if d.tmuxMetrics == nil {
 		return style.Width(width).Height(height).Render("Loading tmux metrics...")
@@ -1265,27 +3380,8 @@ func (d *Dashboard) renderTmuxPanel(width, height int) string {
 	// Calculate content width for right-alignment
 	contentWidth := width - 4 // Account for borders and padding
 
-	// Count sessions by status
-	statusCounts := make(map[metrics.SessionStatus]int)
-	for _, session := range d.tmuxMetrics.Sessions {
-		statusCounts[session.Status]++
-	}
-
 	// Build status summary (right-justified)
-	var statusParts []string
-	if count := statusCounts[metrics.StatusWorking]; count > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("🟢%d", count))
-	}
-	if count := statusCounts[metrics.StatusReady]; count > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("🔴%d", count))
-	}
-	if count := statusCounts[metrics.StatusActive]; count > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("🟡%d", count))
-	}
-	if count := statusCounts[metrics.StatusError]; count > 0 {
-		statusParts = append(statusParts, fmt.Sprintf("❌%d", count))
-	}
-	statusSummary := strings.Join(statusParts, " ")
+	statusSummary := d.sessionStatusSummary()
 
 	// Title with total count and status summary right-justified
 	// Show source indicator: 🔗 for hooks, 📺 for tmux
@@ -1325,6 +3421,9 @@ func (d *Dashboard) renderTmuxPanel(width, height int) string {
 
 	if len(d.tmuxMetrics.Sessions) == 0 {
 		lines = append(lines, "No active sessions")
+		if footer := d.sessionHistoryFooter(); footer != "" {
+			lines = append(lines, footer)
+		}
 		content := strings.Join(lines, "\n")
 		return style.Width(width).Height(height).Render(content)
 	}
@@ -1336,7 +3435,8 @@ func (d *Dashboard) renderTmuxPanel(width, height int) string {
 		availableLines = 1
 	}
 
-	sessionCount := len(d.tmuxMetrics.Sessions)
+	rows := d.sessionRows(d.pinnedFirst(d.tmuxMetrics.Sessions))
+	sessionCount := len(rows)
 	contentWidth = width - 4 // -4 for borders (2) and padding (2)
 
 	// Calculate columns needed to show ALL sessions (priority: show everything)
@@ -1391,8 +3491,7 @@ func (d *Dashboard) renderTmuxPanel(width, height int) string {
 		for col := 0; col < cols; col++ {
 			idx := col*rowCount + row
 			if idx < maxSessions {
-				session := d.tmuxMetrics.Sessions[idx]
-				cellContent := d.renderSessionCell(session, cellWidth)
+				cellContent := d.renderSessionRow(rows[idx], cellWidth)
 				// Apply explicit width constraint using lipgloss
 				cellStyle := lipgloss.NewStyle().Width(cellWidth)
 				cell := cellStyle.Render(cellContent)
@@ -1421,81 +3520,462 @@ func (d *Dashboard) renderTmuxPanel(width, height int) string {
 		lines = append(lines, dimStyle.Render(fmt.Sprintf("... +%d more", remaining)))
 	}
 
+	// Lifetime stats only take a line the sessions don't need
+	if footer := d.sessionHistoryFooter(); footer != "" && len(lines)-1 < availableLines {
+		lines = append(lines, footer)
+	}
+
 	content := strings.Join(lines, "\n")
 	return style.Width(width).Height(height).Render(content)
 }
 
-// renderSessionCell renders a single tmux session cell
+// pinnedFirst returns sessions with the --pin sessions moved to the front. The
+// partition is stable, so both groups keep the collector's status order.
+func (d *Dashboard) pinnedFirst(sessions []metrics.TmuxSession) []metrics.TmuxSession {
+	if len(d.pinnedSessions) == 0 {
+		return sessions
+	}
+	ordered := make([]metrics.TmuxSession, 0, len(sessions))
+	for _, session := range sessions {
+		if d.pinnedSessions[session.Name] {
+			ordered = append(ordered, session)
+		}
+	}
+	for _, session := range sessions {
+		if !d.pinnedSessions[session.Name] {
+			ordered = append(ordered, session)
+		}
+	}
+	return ordered
+}
+
+// minSessionGroup is the fewest sessions sharing a prefix that are grouped
+const minSessionGroup = 3
+
+// sessionGroup is the sessions whose names share a prefix, e.g. "agent-" for
+// agent-1 to agent-20, in the order they're listed
+type sessionGroup struct {
+	prefix   string
+	sessions []metrics.TmuxSession
+}
+
+// sessionRow is one row of the sessions panel: a session, a group's header,
+// or a member listed under its expanded group
+type sessionRow struct {
+	session metrics.TmuxSession
+	group   *sessionGroup
+	member  bool
+}
+
+// sessionPrefix returns name up to and including its last delimiter, e.g.
+// "agent-" for "agent-12", or "" when nothing precedes or follows it
+func sessionPrefix(name, delimiter string) string {
+	i := strings.LastIndex(name, delimiter)
+	if i <= 0 || i+len(delimiter) == len(name) {
+		return ""
+	}
+	return name[:i+len(delimiter)]
+}
+
+// sessionGroups finds the prefixes shared by at least minSessionGroup
+// sessions, in order of their first session. Pinned sessions stay on their own.
+func (d *Dashboard) sessionGroups(sessions []metrics.TmuxSession) []*sessionGroup {
+	var groups []*sessionGroup
+	byPrefix := make(map[string]*sessionGroup)
+	for _, session := range sessions {
+		prefix := sessionPrefix(session.Name, d.groupDelimiter)
+		if prefix == "" || d.pinnedSessions[session.Name] {
+			continue
+		}
+		group := byPrefix[prefix]
+		if group == nil {
+			group = &sessionGroup{prefix: prefix}
+			byPrefix[prefix] = group
+			groups = append(groups, group)
+		}
+		group.sessions = append(group.sessions, session)
+	}
+	return slices.DeleteFunc(groups, func(g *sessionGroup) bool { return len(g.sessions) < minSessionGroup })
+}
+
+// sessionRows lays out the sessions panel. With grouping on, each group takes
+// the place of its first session, followed by its members when expanded.
+func (d *Dashboard) sessionRows(sessions []metrics.TmuxSession) []sessionRow {
+	var groups []*sessionGroup
+	if d.groupByPrefix {
+		groups = d.sessionGroups(sessions)
+	}
+	grouped := make(map[string]*sessionGroup)
+	for _, group := range groups {
+		for _, session := range group.sessions {
+			grouped[session.Name] = group
+		}
+	}
+
+	rows := make([]sessionRow, 0, len(sessions))
+	listed := make(map[*sessionGroup]bool)
+	for _, session := range sessions {
+		group := grouped[session.Name]
+		if group == nil {
+			rows = append(rows, sessionRow{session: session})
+			continue
+		}
+		if listed[group] {
+			continue
+		}
+		listed[group] = true
+		rows = append(rows, sessionRow{group: group})
+		if group.prefix == d.expandedGroup {
+			for _, member := range group.sessions {
+				rows = append(rows, sessionRow{session: member, member: true})
+			}
+		}
+	}
+	return rows
+}
+
+// expandNextGroup expands the group after the expanded one, or collapses them
+// all after the last, and says which in the status bar
+func (d *Dashboard) expandNextGroup() {
+	if !d.groupByPrefix || d.tmuxMetrics == nil {
+		d.setStatusMessage("Press g to group sessions by name prefix", 3*time.Second)
+		return
+	}
+	groups := d.sessionGroups(d.pinnedFirst(d.tmuxMetrics.Sessions))
+	if len(groups) == 0 {
+		d.expandedGroup = ""
+		d.setStatusMessage(fmt.Sprintf("No name prefix is shared by %d or more sessions", minSessionGroup), 3*time.Second)
+		return
+	}
+	next := slices.IndexFunc(groups, func(g *sessionGroup) bool { return g.prefix == d.expandedGroup }) + 1
+	if next == len(groups) {
+		d.expandedGroup = ""
+		d.setStatusMessage("Session groups collapsed", 3*time.Second)
+		return
+	}
+	d.expandedGroup = groups[next].prefix
+	d.setStatusMessage(fmt.Sprintf("Expanded %s* (%d sessions)", groups[next].prefix, len(groups[next].sessions)), 3*time.Second)
+}
+
+// renderSessionRow renders one row from sessionRows; members are indented
+// under their group's header
+func (d *Dashboard) renderSessionRow(row sessionRow, width int) string {
+	switch {
+	case row.group != nil:
+		return d.renderSessionGroupCell(row.group, width)
+	case row.member:
+		return "  " + d.renderSessionCell(row.session, width-2)
+	default:
+		return d.renderSessionCell(row.session, width)
+	}
+}
+
+// renderSessionGroupCell renders a group's header with a status rollup, e.g.
+// "▸ agent-* (20): 🟢12 🔴8", ▾ when its members are listed below
+func (d *Dashboard) renderSessionGroupCell(group *sessionGroup, width int) string {
+	marker := "▸"
+	if group.prefix == d.expandedGroup {
+		marker = "▾"
+	}
+	counts := make(map[metrics.SessionStatus]int)
+	for _, session := range group.sessions {
+		counts[session.Status]++
+	}
+	line := fmt.Sprintf("%s %s* (%d): %s", marker, group.prefix, len(group.sessions), statusRollup(counts))
+	return truncateToWidth(line, width)
+}
+
+// sessionHistoryFooter summarizes completed sessions, e.g. "Lifetime: 142
+// sessions, avg 1h12m · 3 cleaned", the last part counting session files the
+// periodic cleanup removed. Empty until there's something to report.
+func (d *Dashboard) sessionHistoryFooter() string {
+	var parts []string
+	if d.tmuxMetrics != nil && d.tmuxMetrics.LifetimeSessions > 0 {
+		lifetime := fmt.Sprintf("Lifetime: %d sessions", d.tmuxMetrics.LifetimeSessions)
+		if d.tmuxMetrics.AvgSessionDuration > 0 {
+			lifetime += ", avg " + metrics.FormatDuration(d.tmuxMetrics.AvgSessionDuration)
+		}
+		parts = append(parts, lifetime)
+	}
+	if d.cleanedSessions > 0 {
+		parts = append(parts, fmt.Sprintf("%d cleaned", d.cleanedSessions))
+	}
+	if len(parts) == 0 {
+		return ""
+	}
+	return dimStyle.Render(strings.Join(parts, " · "))
+}
+
+// sessionStatusSummary returns per-status session counts, e.g. "🟢2 🔴1"
+// ("[WRK]2 [RDY]1" with --no-emoji)
+func (d *Dashboard) sessionStatusSummary() string {
+	if d.tmuxMetrics == nil {
+		return ""
+	}
+	return statusRollup(d.tmuxMetrics.StatusCounts)
+}
+
+// statusRollup formats per-status session counts, working first, e.g. "🟢2 🔴1"
+func statusRollup(statusCounts map[metrics.SessionStatus]int) string {
+	var statusParts []string
+	if count := statusCounts[metrics.StatusWorking]; count > 0 {
+		statusParts = append(statusParts, fmt.Sprintf("%s%d", metrics.StatusWorking.GetEmoji(), count))
+	}
+	if count := statusCounts[metrics.StatusReady]; count > 0 {
+		statusParts = append(statusParts, fmt.Sprintf("%s%d", metrics.StatusReady.GetEmoji(), count))
+	}
+	if count := statusCounts[metrics.StatusActive]; count > 0 {
+		statusParts = append(statusParts, fmt.Sprintf("%s%d", metrics.StatusActive.GetEmoji(), count))
+	}
+	if count := statusCounts[metrics.StatusError]; count > 0 {
+		statusParts = append(statusParts, fmt.Sprintf("%s%d", metrics.StatusError.GetEmoji(), count))
+	}
+	return strings.Join(statusParts, " ")
+}
+
+// attentionBadge returns the number of sessions waiting on the user, e.g.
+// "⚑ 2 need you", or "" when none are
+func (d *Dashboard) attentionBadge() string {
+	if d.tmuxMetrics == nil || d.tmuxMetrics.NeedsAttention == 0 {
+		return ""
+	}
+	flag := "⚑ "
+	if d.noEmoji {
+		flag = "! "
+	}
+	badge := fmt.Sprintf("%s%d need you", flag, d.tmuxMetrics.NeedsAttention)
+	if d.flashThresholds && d.flashing["sessions"] {
+		return flashStyle.Inherit(warningStyle).Render(badge)
+	}
+	return warningStyle.Render(badge)
+}
+
+// renderSessionCell renders a single tmux session cell. Every column is sized by
+// display width (not bytes), so cells line up whatever mix of emoji, text
+// labels and wide characters in names they contain.
 func (d *Dashboard) renderSessionCell(session metrics.TmuxSession, width int) string {
 	emoji := session.Status.GetEmoji()
 
-	// Convert ANSI color codes to hex colors for lipgloss
-	colorMap := map[string]string{
-		"\033[32m": "#00ff00", // Green - WORKING (Claude processing)
-		"\033[31m": "#ff0000", // Red - READY (Waiting for input)
-		"\033[33m": "#ffff00", // Yellow - ACTIVE (User in session)
-		"\033[91m": "#ff5555", // Bright Red - ERROR (Error state)
-		"\033[0m":  "#ffffff", // White/Reset
+	// Convert ANSI color codes to palette colors for lipgloss
+	colorMap := map[string]lipgloss.TerminalColor{
+		"\033[32m": colorWorking, // Green - WORKING (Claude processing)
+		"\033[31m": colorReady,   // Red - READY (Waiting for input)
+		"\033[33m": colorActive,  // Yellow - ACTIVE (User in session)
+		"\033[91m": colorError,   // Bright Red - ERROR (Error state)
+		"\033[0m":  colorWhite,   // White/Reset
 	}
 
 	ansiColor := session.Status.GetColor()
 	color, ok := colorMap[ansiColor]
 	if !ok {
-		color = "#ffffff"
+		color = colorWhite
 	}
 
-	statusStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
+	statusStyle := lipgloss.NewStyle().Foreground(color)
 
 	attached := ""
-	attachedWidth := 0
-	if session.Attached {
+	if session.Attached && d.noEmoji {
+		attached = "@"
+	} else if session.Attached {
 		attached = "📎"
-		attachedWidth = 3 // emoji + space
 	}
 
-	// Format: emoji name status windows idle attached
-	statusText := string(session.Status)
-	if len(statusText) > 7 {
-		statusText = statusText[:7]
+	// Format: indicator name status windows idle [cost] [model] attached
+	statusText := string(session.Status)
+	if len(statusText) > 7 {
+		statusText = statusText[:7]
+	}
+
+	// Format idle duration
+	idleStr := formatShortDuration(session.IdleDuration)
+
+	// Column widths. The indicator column is as wide as the widest indicator in
+	// the current mode (2 for emoji, 5 for text labels), and the attached column
+	// is always reserved, so names line up between attached and detached cells.
+	indicatorWidth := 0
+	for _, status := range []metrics.SessionStatus{metrics.StatusWorking, metrics.StatusReady, metrics.StatusActive, metrics.StatusError, session.Status} {
+		indicatorWidth = max(indicatorWidth, lipgloss.Width(status.GetEmoji()))
+	}
+	const statusWidth, windowsWidth, idleWidth, attachedWidth = 7, 3, 3, 2
+	fixedWidth := indicatorWidth + statusWidth + windowsWidth + idleWidth + attachedWidth + 5 // 5 separating spaces
+
+	// Optional columns are only added while names keep this comfortable width
+	const minNameWidth = 12
+
+	// Session age (A) gets its own column after idle when names keep a
+	// comfortable width, and otherwise takes the idle column's place
+	const ageWidth = 4
+	showAge, ageOnly := false, false
+	if d.showSessionAge {
+		if width-fixedWidth-ageWidth-1 >= minNameWidth {
+			showAge = true
+			fixedWidth += ageWidth + 1
+		} else {
+			ageOnly = true
+			fixedWidth += ageWidth - idleWidth
+		}
+	}
+
+	// The cost column is added for every cell or none, and only when any session
+	// has a cost and names keep a comfortable width
+	const costWidth = 7
+	showCost := d.anySessionCost() && width-fixedWidth-costWidth-1 >= minNameWidth
+	if showCost {
+		fixedWidth += costWidth + 1
+	}
+	// Likewise the model column, which gives way to cost on narrow cells
+	const modelWidth = 10
+	showModel := d.anySessionModel() && width-fixedWidth-modelWidth-1 >= minNameWidth
+	if showModel {
+		fixedWidth += modelWidth + 1
+	}
+
+	nameWidth := width - fixedWidth
+	if nameWidth < 6 {
+		nameWidth = 6 // Minimum readable name length
+	}
+
+	name := session.Name
+	if d.pinnedSessions[session.Name] && d.noEmoji {
+		name = "^" + name
+	} else if d.pinnedSessions[session.Name] {
+		name = "📌" + name
+	}
+
+	columns := []string{
+		padToWidth(emoji, indicatorWidth),
+		padToWidth(truncateToWidth(name, nameWidth), nameWidth),
+		statusStyle.Render(padToWidth(statusText, statusWidth)),
+		padToWidth(fmt.Sprintf("%dw", session.Windows), windowsWidth),
+	}
+	if ageOnly {
+		columns = append(columns, padToWidth(sessionAge(session), ageWidth))
+	} else {
+		columns = append(columns, padToWidth(idleStr, idleWidth))
+	}
+	if showAge {
+		columns = append(columns, dimStyle.Render(padToWidth(sessionAge(session), ageWidth)))
+	}
+	if showCost {
+		costStr := ""
+		if session.Cost > 0 {
+			costStr = formatSessionCost(session.Cost)
+		}
+		columns = append(columns, dimStyle.Render(padToWidth(costStr, costWidth)))
+	}
+	if showModel {
+		modelStr := ""
+		if session.Model != "" {
+			modelStr = truncateToWidth(shortenModelName(session.Model), modelWidth)
+		}
+		columns = append(columns, dimStyle.Render(padToWidth(modelStr, modelWidth)))
+	}
+	columns = append(columns, padToWidth(attached, attachedWidth))
+	line := strings.Join(columns, " ")
+
+	// Very narrow cells can't fit the minimum name; cut rather than wrap
+	if lipgloss.Width(line) > width {
+		line = lipgloss.NewStyle().MaxWidth(width).Render(line)
+	}
+	return line
+}
+
+// sessionAge returns how long ago the session was created, e.g. "12d", or ""
+// when its creation time isn't known
+func sessionAge(session metrics.TmuxSession) string {
+	if session.Created.IsZero() {
+		return ""
+	}
+	return formatShortDuration(time.Since(session.Created))
+}
+
+// formatShortDuration formats a duration in at most 3 columns for durations
+// under 1000 days, e.g. "45s", "12m", "99h", "5d"; "" for zero
+func formatShortDuration(d time.Duration) string {
+	switch {
+	case d <= 0:
+		return ""
+	case d < time.Minute:
+		return fmt.Sprintf("%ds", int(d.Seconds()))
+	case d < time.Hour:
+		return fmt.Sprintf("%dm", int(d.Minutes()))
+	case d < 100*time.Hour:
+		return fmt.Sprintf("%dh", int(d.Hours()))
+	}
+	return fmt.Sprintf("%dd", int(d.Hours()/24))
+}
+
+// anySessionCost reports whether any session has attributed spend, so the
+// session cells need a cost column
+func (d *Dashboard) anySessionCost() bool {
+	if d.tmuxMetrics == nil {
+		return false
+	}
+	for _, session := range d.tmuxMetrics.Sessions {
+		if session.Cost > 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// anySessionModel reports whether any session's model is known, so the session
+// cells need a model column
+func (d *Dashboard) anySessionModel() bool {
+	if d.tmuxMetrics == nil {
+		return false
+	}
+	for _, session := range d.tmuxMetrics.Sessions {
+		if session.Model != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// formatSessionCost formats an approximate per-session cost in at most 7
+// columns, e.g. "~$1.20", "~$42.5", "~$310", "~$12k"
+func formatSessionCost(cost float64) string {
+	switch {
+	case cost < 10:
+		return fmt.Sprintf("~$%.2f", cost)
+	case cost < 100:
+		return fmt.Sprintf("~$%.1f", cost)
+	case cost < 10000:
+		return fmt.Sprintf("~$%.0f", cost)
+	default:
+		return fmt.Sprintf("~$%.0fk", cost/1000)
 	}
+}
 
-	// Format idle duration
-	idleStr := ""
-	if session.IdleDuration > 0 {
-		if session.IdleDuration < time.Minute {
-			idleStr = fmt.Sprintf("%ds", int(session.IdleDuration.Seconds()))
-		} else if session.IdleDuration < time.Hour {
-			idleStr = fmt.Sprintf("%dm", int(session.IdleDuration.Minutes()))
+// renderCustomDateFields renders the date and time lines of the custom picker
+// for t, highlighting the selected field if it is one of the five starting at firstField
+func (d *Dashboard) renderCustomDateFields(t time.Time, firstField int) []string {
+	fieldStyle := dimStyle
+	selectedStyle := lipgloss.NewStyle().
+		Background(colorBlue).
+		Foreground(colorBlack).
+		Bold(true)
+
+	fields := []string{
+		fmt.Sprintf(" %04d ", t.Year()),
+		fmt.Sprintf(" %02d ", int(t.Month())),
+		fmt.Sprintf(" %02d ", t.Day()),
+		fmt.Sprintf(" %02d ", t.Hour()),
+		fmt.Sprintf(" %02d ", t.Minute()),
+	}
+	for i, f := range fields {
+		if d.lookbackEditField == firstField+i {
+			fields[i] = selectedStyle.Render(f)
 		} else {
-			idleStr = fmt.Sprintf("%dh", int(session.IdleDuration.Hours()))
+			fields[i] = fieldStyle.Render(f)
 		}
 	}
 
-	// Calculate available width for session name
-	// Fixed parts: emoji(2) + space(1) + space(1) + status(7) + space(1) + windows(~3) + space(1) + idle(3) + space(1) + attached
-	// Fixed overhead = ~20 chars + attachedWidth
-	fixedOverhead := 20 + attachedWidth
-	maxNameLen := width - fixedOverhead
-	if maxNameLen < 6 {
-		maxNameLen = 6 // Minimum readable name length
-	}
-
-	name := session.Name
-	if len(name) > maxNameLen {
-		name = name[:maxNameLen-1] + "…"
+	return []string{
+		fmt.Sprintf("  Date: %s-%s-%s", fields[0], fields[1], fields[2]),
+		fmt.Sprintf("  Time: %s:%s", fields[3], fields[4]),
 	}
-
-	// Build the line with dynamic name width
-	nameFormat := fmt.Sprintf("%%-%ds", maxNameLen)
-	line := fmt.Sprintf("%s "+nameFormat+" %s %dw %-3s %s",
-		emoji,
-		name,
-		statusStyle.Render(fmt.Sprintf("%-7s", statusText)),
-		session.Windows,
-		idleStr,
-		attached)
-
-	return line
 }
 
 // renderLookbackPicker renders the lookback time picker overlay
@@ -1516,65 +3996,28 @@ func (d *Dashboard) renderLookbackPicker() string {
 		// Custom date/time picker
 		lines = append(lines, "Set custom start date/time:")
 		lines = append(lines, "")
-
-		// Date/time fields
-		year := d.lookbackCustomDate.Year()
-		month := int(d.lookbackCustomDate.Month())
-		day := d.lookbackCustomDate.Day()
-		hour := d.lookbackCustomDate.Hour()
-		minute := d.lookbackCustomDate.Minute()
-
-		// Highlight selected field
-		fieldStyle := dimStyle
-		selectedStyle := lipgloss.NewStyle().
-			Background(lipgloss.Color("#00aaff")).
-			Foreground(lipgloss.Color("#000000")).
-			Bold(true)
-
-		yearStr := fmt.Sprintf(" %04d ", year)
-		monthStr := fmt.Sprintf(" %02d ", month)
-		dayStr := fmt.Sprintf(" %02d ", day)
-		hourStr := fmt.Sprintf(" %02d ", hour)
-		minStr := fmt.Sprintf(" %02d ", minute)
-
-		if d.lookbackEditField == 0 {
-			yearStr = selectedStyle.Render(yearStr)
-		} else {
-			yearStr = fieldStyle.Render(yearStr)
-		}
-		if d.lookbackEditField == 1 {
-			monthStr = selectedStyle.Render(monthStr)
-		} else {
-			monthStr = fieldStyle.Render(monthStr)
-		}
-		if d.lookbackEditField == 2 {
-			dayStr = selectedStyle.Render(dayStr)
-		} else {
-			dayStr = fieldStyle.Render(dayStr)
-		}
-		if d.lookbackEditField == 3 {
-			hourStr = selectedStyle.Render(hourStr)
-		} else {
-			hourStr = fieldStyle.Render(hourStr)
-		}
-		if d.lookbackEditField == 4 {
-			minStr = selectedStyle.Render(minStr)
+		lines = append(lines, d.renderCustomDateFields(d.lookbackCustomDate, 0)...)
+		lines = append(lines, "")
+		if d.lookbackCustomHasEnd {
+			lines = append(lines, "End date/time:")
+			lines = append(lines, "")
+			lines = append(lines, d.renderCustomDateFields(d.lookbackCustomEnd, 5)...)
+			if !d.lookbackCustomEnd.After(d.lookbackCustomDate) {
+				lines = append(lines, errorStyle.Render("  End must be after the start"))
+			}
 		} else {
-			minStr = fieldStyle.Render(minStr)
+			lines = append(lines, dimStyle.Render("Runs to now"))
 		}
-
-		lines = append(lines, fmt.Sprintf("  Date: %s-%s-%s", yearStr, monthStr, dayStr))
-		lines = append(lines, fmt.Sprintf("  Time: %s:%s", hourStr, minStr))
 		lines = append(lines, "")
 		lines = append(lines, dimStyle.Render("  ↑/↓: adjust value  ←/→/Tab: change field"))
-		lines = append(lines, dimStyle.Render("  Enter: apply  Esc: back to presets"))
+		lines = append(lines, dimStyle.Render("  e: toggle end  Enter: apply  Esc: back to presets"))
 	} else {
 		// Preset selection
 		// Calculate available content lines: panelHeight - 4 (borders + padding)
 		availableLines := panelHeight - 4
-		// Compact mode needed if: title(1) + instruction(1) + presets(7*2=14) + nav(1) = 17 > available
+		// Compact mode needed if: title(1) + instruction(1) + presets(10*2=20) + nav(1) = 23 > available
 		// Use compact single-line format when height is constrained
-		compactMode := availableLines < 17
+		compactMode := availableLines < 23
 
 		lines = append(lines, "Select a lookback period:")
 		if !compactMode {
@@ -1621,25 +4064,554 @@ func (d *Dashboard) renderLookbackPicker() string {
 		lines = append(lines, dimStyle.Render("  ↑/↓/j/k: navigate  Enter/Space: select  Esc/l: close"))
 	}
 
-	// Build the picker panel
-	content := strings.Join(lines, "\n")
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
 
-	pickerStyle := lipgloss.NewStyle().
+// renderCenteredPanel renders content in a bordered overlay panel centered horizontally
+func (d *Dashboard) renderCenteredPanel(content string, panelWidth, panelHeight int) string {
+	panelStyle := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#ffaa00")).
+		BorderForeground(colorOrange).
 		Padding(1, 2).
 		Width(panelWidth).
 		Height(panelHeight)
 
-	picker := pickerStyle.Render(content)
+	panel := panelStyle.Render(content)
 
-	// Center the picker on screen
 	leftPad := (d.width - panelWidth) / 2
 	if leftPad < 0 {
 		leftPad = 0
 	}
 
-	return lipgloss.NewStyle().PaddingLeft(leftPad).Render(picker)
+	return lipgloss.NewStyle().PaddingLeft(leftPad).Render(panel)
+}
+
+// renderSessionInspector renders the session inspector overlay with per-session
+// details, including the estimated context-window utilization
+func (d *Dashboard) renderSessionInspector() string {
+	panelHeight := d.height - 3
+	panelWidth := 90
+	if panelWidth > d.width-4 {
+		panelWidth = d.width - 4
+	}
+	contentWidth := panelWidth - 6 // borders (2) + padding (4)
+
+	var lines []string
+	lines = append(lines, boldStyle.Render("🔍 Session Inspector"))
+	lines = append(lines, "")
+
+	if d.tmuxMetrics == nil || len(d.tmuxMetrics.Sessions) == 0 {
+		lines = append(lines, dimStyle.Render("No active sessions"))
+	} else {
+		// Fixed columns: indicator + space + status(7) + space + source(6) + space + context(~34)
+		nameWidth := contentWidth - 51 - lipgloss.Width(metrics.StatusWorking.GetEmoji())
+		if nameWidth < 8 {
+			nameWidth = 8
+		}
+
+		availableLines := panelHeight - 8 // borders, padding, title, footer
+		for i, session := range d.tmuxMetrics.Sessions {
+			if i >= availableLines {
+				lines = append(lines, dimStyle.Render(fmt.Sprintf("... +%d more", len(d.tmuxMetrics.Sessions)-i)))
+				break
+			}
+
+			name := padToWidth(truncateToWidth(session.Name, nameWidth), nameWidth)
+
+			var ctx string
+			if session.ContextLimit > 0 {
+				ctx = fmt.Sprintf("ctx %s %s/%s",
+					d.renderMiniBar(session.ContextPercent(), 15),
+					metrics.FormatTokensCompact(session.ContextTokens),
+					metrics.FormatTokensCompact(session.ContextLimit))
+			} else if session.SessionID == "" {
+				ctx = dimStyle.Render("ctx n/a (needs hooks)")
+			} else {
+				ctx = dimStyle.Render("ctx n/a (no usage yet)")
+			}
+
+			lines = append(lines, fmt.Sprintf("%s %s %-7s %-6s %s",
+				session.Status.GetEmoji(),
+				name,
+				string(session.Status),
+				session.Source,
+				ctx))
+		}
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("  Context is the size of each session's latest request vs. its model's window"))
+	lines = append(lines, dimStyle.Render("  Esc/i: close"))
+
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
+
+// renderHourOfDay renders the hour-of-day overlay: a 24-bar histogram of tokens
+// used in each local hour across the lookback window
+func (d *Dashboard) renderHourOfDay() string {
+	panelHeight := d.height - 3
+	panelWidth := 84
+	if panelWidth > d.width-4 {
+		panelWidth = d.width - 4
+	}
+
+	var lines []string
+	lines = append(lines, boldStyle.Render("🕐 Usage by Hour of Day"))
+	zone, _ := time.Now().Zone()
+	if lookback := d.tokenCollector.GetLookback(); lookback.IsZero() {
+		lines = append(lines, dimStyle.Render("All time, local time ("+zone+")"))
+	} else if end := d.tokenCollector.GetLookbackEnd(); !end.IsZero() {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%s to %s, local time (%s)", lookback.Format("Jan 2 3:04pm"), end.Format("Jan 2 3:04pm"), zone)))
+	} else {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("Since %s, local time (%s)", lookback.Format("Jan 2 3:04pm"), zone)))
+	}
+	lines = append(lines, "")
+
+	var peak metrics.HourOfDayUsage
+	var totalTokens int64
+	var totalCost float64
+	for _, h := range d.hourOfDay {
+		totalTokens += h.Tokens
+		totalCost += h.Cost
+		if h.Tokens > peak.Tokens {
+			peak = h
+		}
+	}
+
+	switch {
+	case d.hourOfDayErr != nil:
+		lines = append(lines, errorStyle.Render(fmt.Sprintf("Query failed: %v", d.hourOfDayErr)))
+	case d.hourOfDay == nil:
+		lines = append(lines, dimStyle.Render("Loading…"))
+	case totalTokens == 0:
+		lines = append(lines, dimStyle.Render("No token usage in this window"))
+	default:
+		// Leave room for borders, padding, title, axis, summary and footer
+		chartHeight := panelHeight - 14
+		if chartHeight > 12 {
+			chartHeight = 12
+		}
+		if chartHeight < 3 {
+			chartHeight = 3
+		}
+
+		// Bars are 2 columns wide with a gap, eighth-block characters for the top cell
+		blocks := []string{" ", "▁", "▂", "▃", "▄", "▅", "▆", "▇", "█"}
+		for row := chartHeight - 1; row >= 0; row-- {
+			var sb strings.Builder
+			for _, h := range d.hourOfDay {
+				eighths := int(float64(h.Tokens)/float64(peak.Tokens)*float64(chartHeight*8) + 0.5)
+				if h.Tokens > 0 && eighths == 0 {
+					eighths = 1 // Keep light hours visible
+				}
+				fill := eighths - row*8
+				if fill > 8 {
+					fill = 8
+				}
+				if fill < 0 {
+					fill = 0
+				}
+				cell := strings.Repeat(blocks[fill], 2)
+				if h.Hour == peak.Hour {
+					cell = warningStyle.Render(cell)
+				} else {
+					cell = successStyle.Render(cell)
+				}
+				sb.WriteString(cell + " ")
+			}
+			lines = append(lines, sb.String())
+		}
+
+		var axis strings.Builder
+		for _, h := range d.hourOfDay {
+			fmt.Fprintf(&axis, "%02d ", h.Hour)
+		}
+		lines = append(lines, dimStyle.Render(axis.String()))
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("Peak:  %02d:00–%02d:00  %s tokens  %s",
+			peak.Hour, (peak.Hour+1)%24,
+			metrics.FormatTokensCompact(peak.Tokens),
+			costStyle.Render(metrics.FormatCost(peak.Cost))))
+		lines = append(lines, fmt.Sprintf("Total: %s tokens  %s",
+			metrics.FormatTokensCompact(totalTokens),
+			costStyle.Render(metrics.FormatCost(totalCost))))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("  Change the window with the lookback picker (l)  Esc/t: close"))
+
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
+
+// renderProjectUsage renders the per-project overlay: token totals and
+// estimated cost for each project across the lookback window, costliest first
+func (d *Dashboard) renderProjectUsage() string {
+	panelHeight := d.height - 3
+	panelWidth := 84
+	if panelWidth > d.width-4 {
+		panelWidth = d.width - 4
+	}
+
+	var lines []string
+	lines = append(lines, boldStyle.Render("📁 Cost by Project"))
+	if lookback := d.tokenCollector.GetLookback(); lookback.IsZero() {
+		lines = append(lines, dimStyle.Render("All time"))
+	} else if end := d.tokenCollector.GetLookbackEnd(); !end.IsZero() {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%s to %s", lookback.Format("Jan 2 3:04pm"), end.Format("Jan 2 3:04pm"))))
+	} else {
+		lines = append(lines, dimStyle.Render("Since "+lookback.Format("Jan 2 3:04pm")))
+	}
+	lines = append(lines, "")
+
+	switch {
+	case d.projectUsageErr != nil:
+		lines = append(lines, errorStyle.Render(fmt.Sprintf("Query failed: %v", d.projectUsageErr)))
+	case d.projectUsage == nil:
+		lines = append(lines, dimStyle.Render("Loading…"))
+	case len(d.projectUsage) == 0:
+		lines = append(lines, dimStyle.Render("No token usage in this window"))
+	default:
+		var totalTokens int64
+		var totalCost float64
+		for _, p := range d.projectUsage {
+			totalTokens += p.Tokens
+			totalCost += p.Cost
+		}
+
+		// Leave room for borders, padding, title, header, total and footer
+		maxRows := panelHeight - 11
+		if maxRows < 1 {
+			maxRows = 1
+		}
+		nameWidth := panelWidth - 4 - 30
+		if nameWidth < 12 {
+			nameWidth = 12
+		}
+
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%-*s %8s %10s %6s", nameWidth, "Project", "Tokens", "Cost", "Share")))
+		for i, p := range d.projectUsage {
+			if i == maxRows && len(d.projectUsage) > maxRows {
+				lines = append(lines, dimStyle.Render(fmt.Sprintf("… %d more", len(d.projectUsage)-maxRows)))
+				break
+			}
+			share := 0.0
+			if totalCost > 0 {
+				share = p.Cost / totalCost * 100
+			}
+			name := truncateToWidth(shortenHome(p.Path), nameWidth)
+			lines = append(lines, fmt.Sprintf("%-*s %8s %s %5.1f%%",
+				nameWidth, name,
+				metrics.FormatTokensCompact(p.Tokens),
+				costStyle.Render(fmt.Sprintf("%10s", metrics.FormatCost(p.Cost))),
+				share))
+		}
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("%-*s %8s %s",
+			nameWidth, "Total",
+			metrics.FormatTokensCompact(totalTokens),
+			costStyle.Render(fmt.Sprintf("%10s", metrics.FormatCost(totalCost)))+d.secondaryCost(totalCost)))
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("  Change the window with the lookback picker (l)  Esc/p: close"))
+
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
+
+// moveModelSelection moves the token panel's model highlight one row down
+// or up, starting from the first or last model when none is highlighted
+func (d *Dashboard) moveModelSelection(down bool) {
+	if d.tokenMetrics == nil || len(d.tokenMetrics.ModelUsages) == 0 {
+		d.selectedModel = ""
+		return
+	}
+	usages := d.tokenMetrics.ModelUsages
+	i := -1
+	for j, usage := range usages {
+		if usage.Model == d.selectedModel {
+			i = j
+			break
+		}
+	}
+	switch {
+	case i < 0 && down:
+		i = 0
+	case i < 0:
+		i = len(usages) - 1
+	case down && i < len(usages)-1:
+		i++
+	case !down && i > 0:
+		i--
+	}
+	d.selectedModel = usages[i].Model
+}
+
+// selectedModelUsage returns the highlighted model's usage, or nil when none
+// is highlighted or it has no usage in the current window
+func (d *Dashboard) selectedModelUsage() *metrics.ModelUsage {
+	if d.selectedModel == "" || d.tokenMetrics == nil {
+		return nil
+	}
+	for i := range d.tokenMetrics.ModelUsages {
+		if d.tokenMetrics.ModelUsages[i].Model == d.selectedModel {
+			return &d.tokenMetrics.ModelUsages[i]
+		}
+	}
+	return nil
+}
+
+// renderModelDetail renders the highlighted model's tokens by type, the rate
+// each type is billed at and what it adds to the model's cost, followed by
+// when the model was first and last used and its average token rate between
+func (d *Dashboard) renderModelDetail() string {
+	panelHeight := d.height - 3
+	panelWidth := 84
+	if panelWidth > d.width-4 {
+		panelWidth = d.width - 4
+	}
+
+	var lines []string
+	lines = append(lines, boldStyle.Render("🤖 "+d.selectedModel))
+	if lookback := d.tokenCollector.GetLookback(); lookback.IsZero() {
+		lines = append(lines, dimStyle.Render("All time"))
+	} else if end := d.tokenCollector.GetLookbackEnd(); !end.IsZero() {
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%s to %s", lookback.Format("Jan 2 3:04pm"), end.Format("Jan 2 3:04pm"))))
+	} else {
+		lines = append(lines, dimStyle.Render("Since "+lookback.Format("Jan 2 3:04pm")))
+	}
+	lines = append(lines, "")
+
+	usage := d.selectedModelUsage()
+	if usage == nil {
+		lines = append(lines, dimStyle.Render("No usage for this model in this window"))
+	} else {
+		pricing := metrics.PricingForModel(usage.Model)
+		rows := []struct {
+			label  string
+			tokens int64
+			rate   float64
+		}{
+			{"Input", usage.InputTokens, pricing.InputPerMillion},
+			{"Output", usage.OutputTokens, pricing.OutputPerMillion},
+			{"Cache read", usage.CacheReadTokens, pricing.CacheReadPerMillion},
+			{"Cache write", usage.CacheCreationTokens, pricing.CacheCreatePerMillion},
+		}
+		var totalCost float64
+		costs := make([]float64, len(rows))
+		for i, row := range rows {
+			costs[i] = float64(row.tokens) * row.rate / 1_000_000
+			totalCost += costs[i]
+		}
+
+		lines = append(lines, dimStyle.Render(fmt.Sprintf("%-12s %14s %10s %10s %6s", "Type", "Tokens", "Rate/M", "Cost", "Share")))
+		for i, row := range rows {
+			share := 0.0
+			if totalCost > 0 {
+				share = costs[i] / totalCost * 100
+			}
+			lines = append(lines, fmt.Sprintf("%-12s %14s %10s %s %5.1f%%",
+				row.label,
+				d.formatTokens(row.tokens),
+				metrics.FormatCost(row.rate),
+				costStyle.Render(fmt.Sprintf("%10s", metrics.FormatCost(costs[i]))),
+				share))
+		}
+		lines = append(lines, "")
+		lines = append(lines, fmt.Sprintf("%-12s %14s %10s %s",
+			"Total",
+			d.formatTokens(usage.TotalTokens),
+			"",
+			costStyle.Render(fmt.Sprintf("%10s", metrics.FormatCost(totalCost)))+d.secondaryCost(totalCost)))
+
+		lines = append(lines, "")
+		activity := d.modelActivity
+		switch {
+		case activity == nil:
+			lines = append(lines, dimStyle.Render("Loading…"))
+		case activity.err != nil:
+			lines = append(lines, errorStyle.Render(fmt.Sprintf("Query failed: %v", activity.err)))
+		case activity.first.IsZero():
+			lines = append(lines, dimStyle.Render("No activity in the token cache"))
+		default:
+			lines = append(lines, fmt.Sprintf("%-12s %s", "First used", activity.first.Format("Jan 2 3:04pm")))
+			lines = append(lines, fmt.Sprintf("%-12s %s", "Last used", activity.last.Format("Jan 2 3:04pm")))
+			// Averaged over the time between, so idle gaps lower it
+			if span := activity.last.Sub(activity.first); span >= time.Minute {
+				lines = append(lines, fmt.Sprintf("%-12s %s", "Rate",
+					metrics.FormatTokenRateCompact(float64(usage.TotalTokens)/span.Minutes())+dimStyle.Render(" on average between them")))
+			}
+		}
+
+		if usage.Excluded {
+			lines = append(lines, "")
+			lines = append(lines, dimStyle.Render("Excluded from the totals by --exclude-model"))
+		} else if d.tokenMetrics.TotalCost > 0 {
+			lines = append(lines, "")
+			lines = append(lines, dimStyle.Render(fmt.Sprintf("%.1f%% of the cost of all models", usage.Cost/d.tokenMetrics.TotalCost*100)))
+		}
+	}
+
+	lines = append(lines, "")
+	lines = append(lines, dimStyle.Render("  Rates are per million tokens  Esc/Enter: close"))
+
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
+
+// shortenHome replaces the home directory at the start of path with ~
+func shortenHome(path string) string {
+	home, err := os.UserHomeDir()
+	if err != nil || home == "" {
+		return path
+	}
+	if path == home {
+		return "~"
+	}
+	if strings.HasPrefix(path, home+"/") {
+		return "~" + path[len(home):]
+	}
+	return path
+}
+
+// keyBinding is one entry in the keybinding cheat sheet
+type keyBinding struct {
+	keys        string
+	description string
+}
+
+// keyHelpColumnWidth fits the longest cheat sheet line without wrapping
+const keyHelpColumnWidth = 70
+
+// renderKeyHelp renders the keybinding cheat sheet overlay. When it is taller
+// than the terminal it splits into two columns if the width allows, and
+// otherwise cuts off the rest behind a "… N more" line
+func (d *Dashboard) renderKeyHelp() string {
+	panelHeight := d.height - 2 // -2 borders
+	if !d.hideStatusBar {
+		panelHeight -= lipgloss.Height(d.renderStatusBar())
+	}
+	columnWidth := keyHelpColumnWidth
+	if columnWidth > d.width-10 {
+		columnWidth = d.width - 10 // borders (2) + padding (4) + margin (4)
+	}
+	if columnWidth < 20 {
+		columnWidth = 20
+	}
+
+	updateDesc := "Install update (only when one is available)"
+	if d.updateInfo != nil && d.updateInfo.UpdateAvailable {
+		updateDesc = fmt.Sprintf("Install update %s", d.updateInfo.LatestVersion)
+	}
+
+	sections := []struct {
+		title    string
+		bindings []keyBinding
+	}{
+		{"Dashboard", []keyBinding{
+			{"q, Ctrl+C", "Quit"},
+			{"r", "Refresh metrics now"},
+			{"h", "Cycle help panels (system, tokens, sessions)"},
+			{"1 2 3", "Show the system, token or sessions panel full screen"},
+			{"0, Esc", "Back to all panels"},
+			{"l", "Open lookback picker"},
+			{"i", "Open session inspector"},
+			{"t", "Show usage by hour of day"},
+			{"p", "Compare cost per project"},
+			{"$", "Toggle token panel emphasis between total and cost"},
+			{"k", "Toggle token counts between 1.2M and 1,234,567"},
+			{"↑/↓", "Highlight a model in the token panel"},
+			{"Enter", "Show the highlighted model's token and cost breakdown"},
+			{"A", "Show or hide session age in the sessions panel"},
+			{"f", "Flash metrics that cross a threshold"},
+			{"g", "Group sessions sharing a name prefix"},
+			{"G", "Expand the next session group"},
+			{"M", "Toggle full model IDs in the token panel"},
+			{"a", "Toggle token totals between this project and all projects"},
+			{"o", "Sort models by cost, tokens or name"},
+			{"m", "Copy stats as markdown"},
+			{"X X", "Clear token cache and re-ingest"},
+			{"R", "Re-read all logs from the start, keeping the cache"},
+			{"u", updateDesc},
+			{"?", "Show this cheat sheet"},
+		}},
+		{"Lookback picker", []keyBinding{
+			{"↑/↓, j/k", "Choose preset"},
+			{"Enter, Space", "Apply preset"},
+			{"←/→, Tab", "Change field (custom date)"},
+			{"e", "Add or remove an end (custom date)"},
+			{"Esc, l", "Close"},
+		}},
+		{"Session inspector", []keyBinding{
+			{"Esc, i, q", "Close"},
+		}},
+		{"Hour-of-day histogram", []keyBinding{
+			{"Esc, t, q", "Close"},
+		}},
+		{"Project costs", []keyBinding{
+			{"Esc, p, q", "Close"},
+		}},
+		{"Model detail", []keyBinding{
+			{"Esc, Enter, q", "Close"},
+		}},
+	}
+
+	// Each section is a block of lines starting with a blank spacer
+	var blocks [][]string
+	bodyLines := 0
+	for _, section := range sections {
+		block := []string{"", boldStyle.Render(section.title)}
+		for _, b := range section.bindings {
+			desc := truncateToWidth(b.description, columnWidth-17)
+			if b.keys == "u" && (d.updateInfo == nil || !d.updateInfo.UpdateAvailable) {
+				desc = dimStyle.Render(desc)
+			}
+			block = append(block, fmt.Sprintf("  %-14s %s", b.keys, desc))
+		}
+		blocks = append(blocks, block)
+		bodyLines += len(block)
+	}
+
+	// Padding (2), title and footer (3) leave the rest of the panel for sections
+	maxBody := panelHeight - 5
+	if maxBody < 1 {
+		maxBody = 1
+	}
+
+	panelWidth := columnWidth + 6
+	var body string
+	if bodyLines > maxBody && d.width-4 >= 2*columnWidth+8 {
+		// Split between sections where the taller column is shortest
+		split, tallest := 1, bodyLines
+		left := 0
+		for i := 1; i < len(blocks); i++ {
+			left += len(blocks[i-1])
+			if h := max(left, bodyLines-left); h < tallest {
+				split, tallest = i, h
+			}
+		}
+		column := lipgloss.NewStyle().Width(columnWidth)
+		body = lipgloss.JoinHorizontal(lipgloss.Top,
+			column.Render(strings.Join(fitLines(slices.Concat(blocks[:split]...), maxBody), "\n")),
+			"  ",
+			column.Render(strings.Join(fitLines(slices.Concat(blocks[split:]...), maxBody), "\n")))
+		panelWidth = 2*columnWidth + 8
+	} else {
+		body = strings.Join(fitLines(slices.Concat(blocks...), maxBody), "\n")
+	}
+
+	lines := []string{boldStyle.Render("⌨ Keyboard Shortcuts"), body, ""}
+	lines = append(lines, dimStyle.Render("  Press any key to close"))
+
+	return d.renderCenteredPanel(strings.Join(lines, "\n"), panelWidth, panelHeight)
+}
+
+// fitLines keeps the first maxLines of lines, replacing the last kept line
+// with a "… N more" note when some are cut off
+func fitLines(lines []string, maxLines int) []string {
+	if len(lines) <= maxLines {
+		return lines
+	}
+	kept := max(maxLines-1, 0)
+	return append(lines[:kept:kept], dimStyle.Render(fmt.Sprintf("  … %d more (enlarge the terminal to see them)", len(lines)-kept)))
 }
 
 func (d *Dashboard) renderHelpView() string {
@@ -1664,15 +4636,18 @@ CPU: Overall + per-core usage as N:[||| XX%]
 Memory/Swap: Used/Total with percentage bars
   Formatted in GB/MB for readability
 
-Disk: Root filesystem (/) usage with percentage bar
+Disk: Filesystem usage with percentage bar
   Shows used/total space in GB/TB
+  Root (/) by default; --disk-path=/,/home adds more
 
 Disk I/O: Read/write speeds in bytes/s or KB/s
 
-Net I/O: Network recv/sent speeds
+Net I/O: Network recv/sent speeds, all interfaces
+  Named after the busiest interface, e.g. (eth0)
 
 Load: 1min, 5min, 15min averages
-  Indicates overall system activity level`
+  Green below 0.7 per core, yellow below 1.0,
+  red when more work waits than there are cores`
 
 	case 2: // Token Usage
 		title = "Token Usage Panel"
@@ -1687,6 +4662,8 @@ Tokens:
 
 Rates:
   Rate: Current tok/min (60s window)
+  Trend: Rate averaged over refreshes
+    (--rate-smoothing)
   Avg: Session average tok/min
 
 Time:
@@ -1700,6 +4677,7 @@ Lookback: Press 'l' to open time picker
 Models: Per-model cost breakdown
   Color-coded: Opus(red) Sonnet(cyan) Haiku(green) GLM(blue)
   Sorted by cost (highest first)
+  Press '$' to put Cost above Total and bold the costs
 
 Data Sources:
   - Claude: ~/.claude/projects/*.jsonl (sessions)
@@ -1724,16 +4702,22 @@ Status (analyzes pane content):
   🟡 ACTIVE - User in session
   ❌ ERROR - Error or undefined state
 
-Detection: Analyzes last 15 lines for:
+Detection: Analyzes the pane plus 15 lines
+of scrollback (--capture-lines) for:
   Working indicators, prompts, errors
 
 Session Info:
   Name, status, windows (Xw), idle, 📎=attached
+  --no-emoji: [WRK] [RDY] [ACT] [ERR], @=attached
 
 Idle: Time since content changed (s/m/h)
 
 Layout: Auto-columns based on count/width
 
+Groups: g collapses 3+ sessions sharing a
+  name prefix (--group-by-prefix), e.g.
+  "▸ agent-* (20): 🟢12 🔴8"; G expands each
+
 Self-Update: Press 'u' when update available
   Status bar shows "⬆ vX.X.X available!"`
 	}
@@ -1818,7 +4802,7 @@ Self-Update: Press 'u' when update available
 
 	helpPanel := lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#ffaa00")). // Orange for help
+		BorderForeground(colorOrange). // Orange for help
 		Padding(0, 1).
 		Width(helpWidth).
 		Height(panelHeight).
@@ -1828,6 +4812,16 @@ Self-Update: Press 'u' when update available
 	return lipgloss.JoinHorizontal(lipgloss.Top, panel, " ", helpPanel)
 }
 
+// isStale reports whether metrics have not been updated for several refresh
+// intervals. Never true before the first update, while paused or with
+// --manual, where old data is expected.
+func (d *Dashboard) isStale() bool {
+	if d.lastUpdate.IsZero() || d.paused || d.manual {
+		return false
+	}
+	return time.Since(d.lastUpdate) > staleAfterIntervals*d.refreshInterval
+}
+
 // renderStatusBar renders the status bar at the bottom of the view.
 //
 // Wide/ultrawide (≥120 cols): single line —
@@ -1838,37 +4832,63 @@ Self-Update: Press 'u' when update available
 //   Line 2: time+version on the left, dimensions+shortcuts on the right
 func (d *Dashboard) renderStatusBar() string {
 	left := fmt.Sprintf("%s %s", d.lastUpdate.Format("15:04:05"), d.version)
+	if d.manual {
+		left += warningStyle.Render(" MANUAL")
+	} else if d.paused {
+		left += " ⏸ paused"
+	} else if d.isStale() {
+		left = errorStyle.Render(d.lastUpdate.Format("15:04:05")+" ⚠ stale") + " " + d.version
+	}
+	// Today's spend stays visible whatever window the token panel shows
+	todaySuffix := d.todayCost()
+	left += todaySuffix
+	// Instances share the cache, whose single writer makes them wait on each other
+	if d.instanceCount > 1 {
+		left += dimStyle.Render(fmt.Sprintf(" %d instances", d.instanceCount))
+	}
+	// The attention count is the one number worth keeping when panels are cut off
+	badgeSuffix := ""
+	if badge := d.attentionBadge(); badge != "" {
+		badgeSuffix = " " + badge
+	}
+	left += badgeSuffix
 
-	shortcuts := "l:lookback h:help q:quit r:refresh"
+	shortcuts := "?:keys l:lookback i:inspect h:help q:quit r:refresh"
 	if d.updateInfo != nil && d.updateInfo.UpdateAvailable && !d.updating {
-		shortcuts = "u:update l:lookback h:help q:quit r:refresh"
+		shortcuts = "u:update ?:keys l:lookback i:inspect h:help q:quit r:refresh"
 	}
 	right := fmt.Sprintf("%dx%d %s", d.width, d.height, shortcuts)
 
-	// Build the repo/update middle string
-	var middle string
+	// Update progress, transient messages and update notices; the repo link otherwise
+	var notice string
 	if d.updating {
-		middle = warningStyle.Render(d.updateStatus)
+		notice = warningStyle.Render(d.updateStatus)
+	} else if d.statusMessage != "" && time.Now().Before(d.statusMessageUntil) {
+		notice = warningStyle.Render(d.statusMessage)
 	} else if d.updateStatus != "" {
-		middle = errorStyle.Render(d.updateStatus)
+		notice = errorStyle.Render(d.updateStatus)
 	} else if d.updateInfo != nil && d.updateInfo.UpdateAvailable {
-		middle = successStyle.Render(fmt.Sprintf("⬆ %s available! Press u to update", d.updateInfo.LatestVersion))
-	} else {
+		notice = successStyle.Render(fmt.Sprintf("⬆ %s available! Press u to update", d.updateInfo.LatestVersion))
+	}
+	middle := notice
+	if middle == "" {
 		middle = dimStyle.Render("https://github.com/jedarden/ccdash")
 	}
 
+	if d.statusFormat != "" {
+		if line, ok := d.formatStatusBar(shortcuts, notice); ok {
+			return statusBarStyle.Render(line)
+		}
+		return statusBarStyle.Render(d.shortStatusLine("h q r", todaySuffix+badgeSuffix))
+	}
+
 	if d.layoutMode != LayoutCompact {
 		// Wide / ultrawide: single line with repo link centred between left and right
 		totalContent := lipgloss.Width(left) + lipgloss.Width(middle) + lipgloss.Width(right)
 		availableSpace := d.width - totalContent - 2 // -2 for statusBarStyle padding
 		if availableSpace < 4 {
 			// Not enough room — drop the middle
-			compactShortcuts := "l h q r"
-			if d.updateInfo != nil && d.updateInfo.UpdateAvailable {
-				compactShortcuts = "u l h q r"
-			}
-			return statusBarStyle.Render(fmt.Sprintf("%s %s %dx%d %s",
-				d.lastUpdate.Format("15:04"), d.version, d.width, d.height, compactShortcuts))
+			return statusBarStyle.Render(d.shortStatusLine("l h q r", todaySuffix+badgeSuffix))
 		}
 		leftSpacer := strings.Repeat(" ", availableSpace/2)
 		rightSpacer := strings.Repeat(" ", availableSpace-availableSpace/2)
@@ -1884,12 +4904,7 @@ func (d *Dashboard) renderStatusBar() string {
 	availableSpace := d.width - totalContent - 2
 	var statusLine string
 	if availableSpace < 2 {
-		compactShortcuts := "h q r"
-		if d.updateInfo != nil && d.updateInfo.UpdateAvailable {
-			compactShortcuts = "u h q r"
-		}
-		statusLine = fmt.Sprintf("%s %s %dx%d %s",
-			d.lastUpdate.Format("15:04"), d.version, d.width, d.height, compactShortcuts)
+		statusLine = d.shortStatusLine("h q r", todaySuffix+badgeSuffix)
 	} else {
 		statusLine = left + strings.Repeat(" ", availableSpace) + right
 	}
@@ -1900,23 +4915,104 @@ func (d *Dashboard) renderStatusBar() string {
 	)
 }
 
+// todayCost returns " today $X.XX" for the status bar, or "" before token
+// metrics are available
+func (d *Dashboard) todayCost() string {
+	if d.tokenMetrics == nil || !d.tokenMetrics.Available {
+		return ""
+	}
+	return dimStyle.Render(" today ") + costStyle.Render(metrics.FormatCost(d.tokenMetrics.TodayCost))
+}
+
+// shortStatusLine is the status bar squeezed to the time, version, size and
+// single-letter shortcuts, for terminals too narrow for the full bar
+func (d *Dashboard) shortStatusLine(shortcuts, suffix string) string {
+	if d.updateInfo != nil && d.updateInfo.UpdateAvailable {
+		shortcuts = "u " + shortcuts
+	}
+	timeStr := d.lastUpdate.Format("15:04")
+	if d.manual {
+		timeStr += warningStyle.Render(" MANUAL")
+	}
+	return fmt.Sprintf("%s %s %dx%d %s",
+		timeStr, d.version, d.width, d.height, shortcuts) + suffix
+}
+
+// formatStatusBar fills in the --status-format template, aligning its
+// sections left, center and right. It reports false when the result doesn't
+// fit the terminal width.
+func (d *Dashboard) formatStatusBar(shortcuts, notice string) (string, bool) {
+	timeStr := d.lastUpdate.Format("15:04:05")
+	if d.manual {
+		timeStr += warningStyle.Render(" MANUAL")
+	} else if d.paused {
+		timeStr += " ⏸ paused"
+	} else if d.isStale() {
+		timeStr = errorStyle.Render(timeStr + " ⚠ stale")
+	}
+	cost, tokens, today := "-", "-", "-"
+	if d.tokenMetrics != nil && d.tokenMetrics.Available {
+		cost = metrics.FormatCost(d.tokenMetrics.TotalCost) + d.secondaryCost(d.tokenMetrics.TotalCost)
+		tokens = metrics.FormatTokensCompact(d.tokenMetrics.TotalTokens)
+		today = metrics.FormatCost(d.tokenMetrics.TodayCost)
+	}
+	project := "all projects"
+	if d.tokenMetrics != nil && d.tokenMetrics.Project != "" {
+		project = shortenHome(d.tokenMetrics.Project)
+	}
+	sessions := 0
+	if d.tmuxMetrics != nil {
+		sessions = len(d.tmuxMetrics.Sessions)
+	}
+	values := map[string]string{
+		"time":      timeStr,
+		"version":   d.version,
+		"cost":      cost,
+		"tokens":    tokens,
+		"today":     today,
+		"project":   project,
+		"sessions":  fmt.Sprintf("%d", sessions),
+		"attention": d.attentionBadge(),
+		"update":    notice,
+		"size":      fmt.Sprintf("%dx%d", d.width, d.height),
+		"keys":      shortcuts,
+	}
+
+	var sections []string
+	total := 0
+	for _, section := range strings.Split(d.statusFormat, "|") {
+		section = statusTokenPattern.ReplaceAllStringFunc(section, func(token string) string {
+			return values[token[1:len(token)-1]]
+		})
+		section = strings.TrimSpace(section)
+		sections = append(sections, section)
+		total += lipgloss.Width(section)
+	}
+
+	availableSpace := d.width - total - 2 // -2 for statusBarStyle padding
+	if availableSpace < 2*(len(sections)-1) {
+		return "", false
+	}
+	switch len(sections) {
+	case 1:
+		return sections[0], true
+	case 2:
+		return sections[0] + strings.Repeat(" ", availableSpace) + sections[1], true
+	default:
+		leftSpacer := strings.Repeat(" ", availableSpace/2)
+		rightSpacer := strings.Repeat(" ", availableSpace-availableSpace/2)
+		return sections[0] + leftSpacer + sections[1] + rightSpacer + sections[2], true
+	}
+}
+
 // renderBar renders a progress bar with percentage inside (unified-dashboard style)
 func (d *Dashboard) renderBar(percent float64, width int) string {
 	if width < 10 {
 		return ""
 	}
 
-	// Determine color based on threshold (unified-dashboard style)
-	var color string
-	if percent >= 95 {
-		color = "#ff0000" // Red
-	} else if percent >= 80 {
-		color = "#ffaa00" // Orange
-	} else if percent >= 60 {
-		color = "#ffff00" // Yellow
-	} else {
-		color = "#00ff00" // Green
-	}
+	// Determine color based on the configured thresholds (unified-dashboard style)
+	color := metrics.GetStatusColor(percent, d.warnThreshold, d.critThreshold)
 
 	// Format percentage
 	percentText := fmt.Sprintf("%.1f%%", percent)
@@ -1937,8 +5033,8 @@ func (d *Dashboard) renderBar(percent float64, width int) string {
 	empty := strings.Repeat(" ", availableWidth-fillWidth)
 
 	// Apply styling
-	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
-	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	barStyle := lipgloss.NewStyle().Foreground(barColor(color))
+	dimStyle := lipgloss.NewStyle().Foreground(colorGray)
 
 	var bar strings.Builder
 	bar.WriteString("[")
@@ -1951,21 +5047,42 @@ func (d *Dashboard) renderBar(percent float64, width int) string {
 	return bar.String()
 }
 
+// Load per core at which a load average turns yellow and then red
+const (
+	loadWarnPerCore = 0.7
+	loadCritPerCore = 1.0
+)
+
+// renderLoad formats a load average colored by loadStyle
+func (d *Dashboard) renderLoad(load float64) string {
+	return d.loadStyle(load).Render(fmt.Sprintf("%.2f", load))
+}
+
+// loadStyle colors a load average by the load per core: green below
+// loadWarnPerCore, yellow below loadCritPerCore and red from there, when more
+// work is waiting than there are cores to run it
+func (d *Dashboard) loadStyle(load float64) lipgloss.Style {
+	cores := len(d.systemMetrics.CPU.PerCore)
+	if cores == 0 {
+		cores = runtime.NumCPU()
+	}
+
+	switch perCore := load / float64(cores); {
+	case perCore >= loadCritPerCore:
+		return errorStyle
+	case perCore >= loadWarnPerCore:
+		return warningStyle
+	default:
+		return successStyle
+	}
+}
+
 // renderMiniBar creates a compact progress bar with percentage inside
 // Format: "||| 42%" for use in CPU core display
 // Returns a fixed-width string to ensure bracket alignment
 func (d *Dashboard) renderMiniBar(percent float64, barWidth int) string {
-	// Determine color based on threshold
-	var color string
-	if percent >= 95 {
-		color = "#ff0000" // Red
-	} else if percent >= 80 {
-		color = "#ffaa00" // Orange
-	} else if percent >= 60 {
-		color = "#ffff00" // Yellow
-	} else {
-		color = "#00ff00" // Green
-	}
+	// Determine color based on the configured thresholds
+	color := metrics.GetStatusColor(percent, d.warnThreshold, d.critThreshold)
 
 	// Format percentage with fixed width (4 chars: "XXX%" or " XX%")
 	percentText := fmt.Sprintf("%3.0f%%", percent)
@@ -1991,8 +5108,8 @@ func (d *Dashboard) renderMiniBar(percent float64, barWidth int) string {
 	empty := strings.Repeat(" ", barAvailableWidth-fillWidth)
 
 	// Apply styling
-	barStyle := lipgloss.NewStyle().Foreground(lipgloss.Color(color))
-	dimStyle := lipgloss.NewStyle().Foreground(lipgloss.Color("#888888"))
+	barStyle := lipgloss.NewStyle().Foreground(barColor(color))
+	dimStyle := lipgloss.NewStyle().Foreground(colorGray)
 
 	return barStyle.Render(filled) + dimStyle.Render(empty) + " " + percentText
 }
@@ -2001,37 +5118,45 @@ func (d *Dashboard) renderMiniBar(percent float64, barWidth int) string {
 var (
 	panelStyle = lipgloss.NewStyle().
 		BorderStyle(lipgloss.RoundedBorder()).
-		BorderForeground(lipgloss.Color("#00aaff")).
+		BorderForeground(colorBlue).
 		Padding(0, 1) // Minimal padding for compact display
 
 	titleStyle = lipgloss.NewStyle().
 		Bold(true).
-		Foreground(lipgloss.Color("#00ffff")).
+		Foreground(colorCyan).
 		MarginBottom(1)
 
 	boldStyle = lipgloss.NewStyle().
 		Bold(true)
 
 	successStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00ff00"))
+		Foreground(colorGreen)
 
 	costStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffaa00")).
+		Foreground(colorOrange).
 		Bold(true)
 
 	warningStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ffaa00"))
+		Foreground(colorOrange)
 
 	errorStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#ff0000")).
+		Foreground(colorRed).
 		Bold(true)
 
 	dimStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#888888"))
+		Foreground(colorGray)
+
+	// Models left out of the totals by --exclude-model
+	excludedStyle = dimStyle.Strikethrough(true)
+
+	// A metric that just crossed a threshold, with --flash-thresholds
+	flashStyle = lipgloss.NewStyle().
+			Reverse(true).
+			Bold(true)
 
 	statusBarStyle = lipgloss.NewStyle().
-		Foreground(lipgloss.Color("#00ffff")).
-		Background(lipgloss.Color("#1a1a1a")).
+		Foreground(colorCyan).
+		Background(colorStatusBar).
 		Padding(0, 1)
 )
 
@@ -2044,6 +5169,52 @@ func max(a, b int) int {
 	return b
 }
 
+// padToWidth pads s with spaces to the given display width
+func padToWidth(s string, width int) string {
+	if w := lipgloss.Width(s); w < width {
+		return s + strings.Repeat(" ", width-w)
+	}
+	return s
+}
+
+// truncateToWidth shortens s to at most width display columns, ending in "…"
+// when cut. Measures whole runes so wide characters are never split.
+func truncateToWidth(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	var sb strings.Builder
+	used := 0
+	for _, r := range s {
+		rw := lipgloss.Width(string(r))
+		if used+rw > width-1 {
+			break
+		}
+		sb.WriteRune(r)
+		used += rw
+	}
+	return sb.String() + "…"
+}
+
+// truncateStartToWidth is truncateToWidth keeping the end of s, starting with
+// "…" when cut
+func truncateStartToWidth(s string, width int) string {
+	if lipgloss.Width(s) <= width {
+		return s
+	}
+	runes := []rune(s)
+	used, start := 0, len(runes)
+	for start > 0 {
+		rw := lipgloss.Width(string(runes[start-1]))
+		if used+rw > width-1 {
+			break
+		}
+		start--
+		used += rw
+	}
+	return "…" + string(runes[start:])
+}
+
 func wrapText(text string, width int) string {
 	if len(text) <= width {
 		return text
@@ -0,0 +1,1522 @@
+package ui
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/jedarden/ccdash/internal/export"
+	"github.com/jedarden/ccdash/internal/metrics"
+	"github.com/jedarden/ccdash/internal/remote"
+	"github.com/jedarden/ccdash/internal/updater"
+	"github.com/muesli/termenv"
+)
+
+func TestRenderSessionCellWidthIsConsistent(t *testing.T) {
+	sessions := []metrics.TmuxSession{
+		{Name: "api", Status: metrics.StatusWorking, Windows: 1},
+		{Name: "frontend-refactor-long-name", Status: metrics.StatusReady, Windows: 1, Attached: true, IdleDuration: 5 * time.Minute},
+		{Name: "docs", Status: metrics.StatusActive, Windows: 1, IdleDuration: 150 * time.Hour},
+		{Name: "日本語のセッション名", Status: metrics.StatusError, Windows: 1, Attached: true},
+		{Name: "mystery", Status: metrics.SessionStatus("UNKNOWN"), Windows: 1, IdleDuration: 42 * time.Second},
+	}
+
+	tests := []struct {
+		name    string
+		noEmoji bool
+		width   int
+	}{
+		{"emoji wide", false, 55},
+		{"emoji narrow", false, 28},
+		{"text labels", true, 40},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dashboard{}
+			d.SetNoEmoji(tt.noEmoji)
+			defer d.SetNoEmoji(false)
+
+			windowsColumn := -1
+			for _, session := range sessions {
+				cell := d.renderSessionCell(session, tt.width)
+				if w := lipgloss.Width(cell); w != tt.width {
+					t.Errorf("%s: expected cell width %d, got %d: %q", session.Name, tt.width, w, cell)
+				}
+
+				// Columns after the name must start at the same display offset in every cell
+				idx := strings.Index(cell, " 1w ")
+				if idx < 0 {
+					t.Fatalf("%s: windows column missing: %q", session.Name, cell)
+				}
+				offset := lipgloss.Width(cell[:idx])
+				if windowsColumn < 0 {
+					windowsColumn = offset
+				} else if offset != windowsColumn {
+					t.Errorf("%s: windows column at %d, expected %d: %q", session.Name, offset, windowsColumn, cell)
+				}
+			}
+		})
+	}
+}
+
+func TestTruncateToWidth(t *testing.T) {
+	tests := []struct {
+		name  string
+		input string
+		width int
+		want  string
+	}{
+		{"fits", "api", 6, "api"},
+		{"ascii cut", "frontend", 6, "front…"},
+		{"wide runes are not split", "日本語です", 6, "日本…"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := truncateToWidth(tt.input, tt.width)
+			if got != tt.want {
+				t.Errorf("truncateToWidth(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+			}
+			if lipgloss.Width(got) > tt.width {
+				t.Errorf("truncateToWidth(%q, %d) is %d columns wide", tt.input, tt.width, lipgloss.Width(got))
+			}
+		})
+	}
+}
+
+func TestTruncateStartToWidth(t *testing.T) {
+	tests := []struct {
+		input string
+		width int
+		want  string
+	}{
+		{"claude-opus-4-5", 20, "claude-opus-4-5"},
+		{"claude-opus-4-5-20251101", 10, "…-20251101"},
+		{"モデル-20251101", 12, "…ル-20251101"},
+	}
+
+	for _, tt := range tests {
+		got := truncateStartToWidth(tt.input, tt.width)
+		if got != tt.want {
+			t.Errorf("truncateStartToWidth(%q, %d) = %q, want %q", tt.input, tt.width, got, tt.want)
+		}
+		if lipgloss.Width(got) > tt.width {
+			t.Errorf("truncateStartToWidth(%q, %d) is %d columns wide", tt.input, tt.width, lipgloss.Width(got))
+		}
+	}
+}
+
+func TestRemoteHostLabel(t *testing.T) {
+	d := &Dashboard{remoteHosts: []remote.HostSnapshot{{Host: "gpu-box"}, {Host: "ビルドサーバー-東京-01"}}}
+
+	// Labels share one width, capped at 16 columns, with wide runes kept whole
+	for _, host := range []string{"gpu-box", "ビルドサーバー-東京-01"} {
+		if w := lipgloss.Width(d.remoteHostLabel(host)); w != 16 {
+			t.Errorf("Expected %q labelled 16 columns wide, got %d: %q", host, w, d.remoteHostLabel(host))
+		}
+	}
+	if got := d.remoteHostLabel("ビルドサーバー-東京-01"); got != "ビルドサーバー-…" {
+		t.Errorf("Expected the wide host cut between runes, got %q", got)
+	}
+}
+
+func TestTokenDisplayModeOrdering(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_500_000,
+			TotalCost:   12.5,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-opus-4-5-20251101", TotalTokens: 1_500_000, Cost: 12.5},
+			},
+		},
+	}
+
+	tests := []struct {
+		name      string
+		mode      TokenDisplayMode
+		first     string
+		second    string
+		modelLine string
+	}{
+		{"total emphasis", TokenDisplayTokens, "Total:", "Cost:", "Opus 4.5 $12.50 (1.5M)"},
+		{"cost emphasis", TokenDisplayCost, "Cost:", "Total:", "Opus 4.5 $12.50 (1.5M)"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d.tokenDisplayMode = tt.mode
+			panel := d.renderTokenPanel(80, 20)
+
+			first, second := strings.Index(panel, tt.first), strings.Index(panel, tt.second)
+			if first < 0 || second < 0 || first > second {
+				t.Errorf("Expected %q before %q:\n%s", tt.first, tt.second, panel)
+			}
+			if !strings.Contains(panel, tt.modelLine) {
+				t.Errorf("Expected model line %q:\n%s", tt.modelLine, panel)
+			}
+		})
+	}
+}
+
+func TestRenderSessionCellCostColumn(t *testing.T) {
+	sessions := []metrics.TmuxSession{
+		{Name: "api", Status: metrics.StatusWorking, Windows: 1, Cost: 1.2},
+		{Name: "docs", Status: metrics.StatusReady, Windows: 1, Attached: true},
+	}
+	d := &Dashboard{tmuxMetrics: &metrics.TmuxMetrics{Sessions: sessions}}
+
+	windowsColumn := -1
+	for _, session := range sessions {
+		cell := d.renderSessionCell(session, 55)
+		if w := lipgloss.Width(cell); w != 55 {
+			t.Errorf("%s: expected cell width 55, got %d: %q", session.Name, w, cell)
+		}
+		idx := strings.Index(cell, " 1w ")
+		if idx < 0 {
+			t.Fatalf("%s: windows column missing: %q", session.Name, cell)
+		}
+		if offset := lipgloss.Width(cell[:idx]); windowsColumn < 0 {
+			windowsColumn = offset
+		} else if offset != windowsColumn {
+			t.Errorf("%s: windows column at %d, expected %d: %q", session.Name, offset, windowsColumn, cell)
+		}
+	}
+
+	if cell := d.renderSessionCell(sessions[0], 55); !strings.Contains(cell, "~$1.20") {
+		t.Errorf("Expected cost in a wide cell: %q", cell)
+	}
+	if cell := d.renderSessionCell(sessions[0], 28); strings.Contains(cell, "~$") {
+		t.Errorf("Expected no cost column in a narrow cell: %q", cell)
+	}
+}
+
+func TestRenderSessionCellModelColumn(t *testing.T) {
+	sessions := []metrics.TmuxSession{
+		{Name: "api", Status: metrics.StatusWorking, Windows: 1, Model: "claude-opus-4-5-20251101"},
+		{Name: "docs", Status: metrics.StatusReady, Windows: 1},
+	}
+	d := &Dashboard{tmuxMetrics: &metrics.TmuxMetrics{Sessions: sessions}}
+
+	for _, session := range sessions {
+		if w := lipgloss.Width(d.renderSessionCell(session, 55)); w != 55 {
+			t.Errorf("%s: expected cell width 55, got %d", session.Name, w)
+		}
+	}
+	if cell := d.renderSessionCell(sessions[0], 55); !strings.Contains(cell, "Opus 4.5") {
+		t.Errorf("Expected shortened model in a wide cell: %q", cell)
+	}
+	if cell := d.renderSessionCell(sessions[0], 28); strings.Contains(cell, "Opus") {
+		t.Errorf("Expected no model column in a narrow cell: %q", cell)
+	}
+}
+
+func TestPinnedSessionsRenderFirst(t *testing.T) {
+	var sessions []metrics.TmuxSession
+	for i := 0; i < 30; i++ {
+		sessions = append(sessions, metrics.TmuxSession{Name: fmt.Sprintf("s%02d", i), Status: metrics.StatusActive, Windows: 1})
+	}
+	tmux := &metrics.TmuxMetrics{Available: true, Sessions: sessions, Total: len(sessions)}
+	d := &Dashboard{tmuxMetrics: tmux}
+	d.SetPinnedSessions([]string{"s29", "s25"})
+
+	// Stable partition: pinned sessions lead in their original order
+	var names []string
+	for _, session := range d.pinnedFirst(sessions) {
+		names = append(names, session.Name)
+	}
+	if got := strings.Join(names[:4], ","); got != "s25,s29,s00,s01" {
+		t.Errorf("Expected pinned sessions first in collector order, got %s", got)
+	}
+
+	// A panel with room for only a few sessions still shows both pinned ones
+	panel := d.renderTmuxPanel(40, 8)
+	if !strings.Contains(panel, "+") || !strings.Contains(panel, "📌s25") || !strings.Contains(panel, "📌s29") {
+		t.Errorf("Expected pinned sessions with a 📌 in a truncated panel:\n%s", panel)
+	}
+	if strings.Contains(panel, "s28") {
+		t.Errorf("Expected unpinned s28 to be cut off:\n%s", panel)
+	}
+}
+
+func TestSessionGroupsByPrefix(t *testing.T) {
+	var sessions []metrics.TmuxSession
+	for i := 1; i <= 20; i++ {
+		status := metrics.StatusWorking
+		if i > 12 {
+			status = metrics.StatusReady
+		}
+		sessions = append(sessions, metrics.TmuxSession{Name: fmt.Sprintf("agent-%d", i), Status: status, Windows: 1})
+	}
+	sessions = append(sessions,
+		metrics.TmuxSession{Name: "api", Status: metrics.StatusActive, Windows: 1},
+		metrics.TmuxSession{Name: "web-1", Status: metrics.StatusActive, Windows: 1},
+		metrics.TmuxSession{Name: "web-2", Status: metrics.StatusActive, Windows: 1},
+	)
+	tmux := &metrics.TmuxMetrics{Available: true, Sessions: sessions, Total: len(sessions)}
+	d := &Dashboard{tmuxMetrics: tmux}
+	if err := d.SetSessionGrouping(true, "-"); err != nil {
+		t.Fatalf("SetSessionGrouping: %v", err)
+	}
+
+	// Two web- sessions are too few to group
+	panel := d.renderTmuxPanel(60, 12)
+	if !strings.Contains(panel, "▸ agent-* (20): 🟢12 🔴8") {
+		t.Errorf("Expected one collapsed agent-* row with a status rollup:\n%s", panel)
+	}
+	if strings.Contains(panel, "agent-3 ") || !strings.Contains(panel, "web-2") || !strings.Contains(panel, "api") {
+		t.Errorf("Expected agent sessions hidden and the others listed:\n%s", panel)
+	}
+
+	// G lists the group's members under it, and collapses it again after the last group
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	panel = d.renderTmuxPanel(60, 30)
+	if !strings.Contains(panel, "▾ agent-* (20)") || !strings.Contains(panel, "  🟢 agent-3 ") {
+		t.Errorf("Expected the expanded group's members indented under it:\n%s", panel)
+	}
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("G")})
+	if d.expandedGroup != "" {
+		t.Errorf("Expected G past the last group to collapse it, got %q expanded", d.expandedGroup)
+	}
+
+	// Pinned sessions stay on their own
+	d.SetPinnedSessions([]string{"agent-1"})
+	if groups := d.sessionGroups(d.pinnedFirst(sessions)); len(groups) != 1 || len(groups[0].sessions) != 19 {
+		t.Errorf("Expected agent-1 left out of its group once pinned, got %+v", groups)
+	}
+
+	// g turns grouping off
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("g")})
+	if rows := d.sessionRows(sessions); len(rows) != len(sessions) {
+		t.Errorf("Expected a row per session with grouping off, got %d rows", len(rows))
+	}
+}
+
+func TestSessionPrefix(t *testing.T) {
+	tests := []struct {
+		name, delimiter, want string
+	}{
+		{"agent-12", "-", "agent-"},
+		{"team-a-3", "-", "team-a-"},
+		{"api", "-", ""},
+		{"-1", "-", ""},
+		{"agent-", "-", ""},
+		{"worker_7", "_", "worker_"},
+		{"host:agent-1", "-", "host:agent-"},
+	}
+	for _, tt := range tests {
+		if got := sessionPrefix(tt.name, tt.delimiter); got != tt.want {
+			t.Errorf("sessionPrefix(%q, %q) = %q, want %q", tt.name, tt.delimiter, got, tt.want)
+		}
+	}
+}
+
+func TestStatusBarShowsAttentionCount(t *testing.T) {
+	tmux := &metrics.TmuxMetrics{Sessions: []metrics.TmuxSession{
+		{Name: "api", Status: metrics.StatusReady},
+		{Name: "docs", Status: metrics.StatusWorking},
+		{Name: "web", Status: metrics.StatusError},
+	}}
+	tmux.CountStatuses()
+
+	for _, width := range []int{200, 90, 40} {
+		d := &Dashboard{tmuxMetrics: tmux, width: width, height: 30, layoutMode: LayoutCompact}
+		if width >= 120 {
+			d.layoutMode = LayoutWide
+		}
+		if bar := d.renderStatusBar(); !strings.Contains(bar, "2 need you") {
+			t.Errorf("width %d: expected attention count in status bar: %q", width, bar)
+		}
+	}
+
+	d := &Dashboard{tmuxMetrics: &metrics.TmuxMetrics{}, width: 200, height: 30, layoutMode: LayoutWide}
+	if bar := d.renderStatusBar(); strings.Contains(bar, "need you") {
+		t.Errorf("Expected no attention count without waiting sessions: %q", bar)
+	}
+}
+
+func TestStatusBarShowsInstanceCount(t *testing.T) {
+	d := &Dashboard{tmuxMetrics: &metrics.TmuxMetrics{}, width: 200, height: 30, layoutMode: LayoutWide}
+
+	d.Update(metricsMsg{instances: 1})
+	if bar := d.renderStatusBar(); strings.Contains(bar, "instances") {
+		t.Errorf("Expected no instance count for a single instance: %q", bar)
+	}
+	d.Update(metricsMsg{instances: 2})
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "2 instances") {
+		t.Errorf("Expected the instance count in the status bar: %q", bar)
+	}
+}
+
+func TestCPUCoreLayout(t *testing.T) {
+	tests := []struct {
+		name         string
+		lines        int
+		coresPerLine int
+		wantMore     string // "+N more cores" line, empty when every core is shown
+	}{
+		{"default", defaultCPUCoreLines, 0, "+40 more cores"},
+		{"more lines", 16, 0, ""},
+		{"fixed per line", 6, 2, "+52 more cores"},
+		{"one per line", 64, 1, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := &Dashboard{systemMetrics: metrics.SystemMetrics{CPU: metrics.CPUMetrics{PerCore: make([]float64, 64)}}}
+			if err := d.SetCPUCoreLayout(tt.lines, tt.coresPerLine); err != nil {
+				t.Fatalf("SetCPUCoreLayout failed: %v", err)
+			}
+			panel := d.renderSystemPanel(80, 90)
+			more := ""
+			for _, line := range strings.Split(panel, "\n") {
+				if i := strings.Index(line, "+"); i >= 0 && strings.Contains(line, "more cores") {
+					more = strings.TrimSpace(strings.TrimRight(line[i:], "│ "))
+				}
+			}
+			if more != tt.wantMore {
+				t.Errorf("Expected %q, got %q", tt.wantMore, more)
+			}
+			if !strings.Contains(panel, "63:[") && tt.wantMore == "" {
+				t.Errorf("Expected the last core to be shown:\n%s", panel)
+			}
+		})
+	}
+
+	d := &Dashboard{}
+	if err := d.SetCPUCoreLayout(0, 0); err == nil {
+		t.Error("Expected an error for zero core lines")
+	}
+}
+
+func TestMemoryBreakdownLine(t *testing.T) {
+	const gb = 1 << 30
+	d := &Dashboard{systemMetrics: metrics.SystemMetrics{Memory: metrics.MemoryMetrics{
+		Used: 28 * gb, Total: 32 * gb, Percentage: 87.5, Available: 20 * gb, Cached: 16 * gb, Buffers: gb,
+	}}}
+
+	if panel := d.renderSystemPanel(80, 30); !strings.Contains(panel, "Avail 20.00 GB") {
+		t.Errorf("Expected the memory breakdown on a tall panel:\n%s", panel)
+	}
+	if panel := d.renderSystemPanel(80, 9); strings.Contains(panel, "Avail") {
+		t.Errorf("Expected no memory breakdown on a short panel:\n%s", panel)
+	}
+
+	used := d.renderSystemPanel(80, 30)
+	d.SetMemoryByAvailable(true)
+	if byAvailable := d.renderSystemPanel(80, 30); !strings.Contains(byAvailable, "37.5%") || !strings.Contains(used, "87.5%") {
+		t.Errorf("Expected the bar at 87.5%% by used and 37.5%% by available:\n%s\n%s", used, byAvailable)
+	}
+}
+
+func TestNetIOLineNamesInterface(t *testing.T) {
+	d := &Dashboard{systemMetrics: metrics.SystemMetrics{NetIO: metrics.NetIOMetrics{
+		RecvBytesPerSec: 2048, SentBytesPerSec: 1024, Primary: "enp0s31f6",
+	}}}
+
+	if panel := d.renderSystemPanel(80, 30); !strings.Contains(panel, "Net I/O (enp0s31f6) | Recv:") {
+		t.Errorf("Expected the busiest interface on the Net I/O line:\n%s", panel)
+	}
+	if panel := d.renderSystemPanel(44, 30); strings.Contains(panel, "enp0s31f6") || !strings.Contains(panel, "Net I/O  | Recv:") {
+		t.Errorf("Expected the interface left out when it doesn't fit:\n%s", panel)
+	}
+}
+
+func TestFailedUpdateErrorExpires(t *testing.T) {
+	d := &Dashboard{
+		width:      120,
+		height:     40,
+		updating:   true,
+		updateInfo: &updater.UpdateInfo{UpdateAvailable: true, LatestVersion: "v9.9.9"},
+	}
+
+	_, cmd := d.Update(updateCompleteMsg{err: fmt.Errorf("checksum mismatch")})
+	if cmd == nil || d.updating {
+		t.Fatal("Expected a failed update to schedule clearing its error")
+	}
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "Update failed: checksum mismatch") || !strings.Contains(bar, "u:update") {
+		t.Errorf("Expected the error and the u shortcut to retry:\n%s", bar)
+	}
+
+	// A timer left over from an earlier failure doesn't cut this one short
+	d.Update(updateErrorExpiredMsg{})
+	if d.updateStatus == "" {
+		t.Fatal("Expected the error to stay until its time is up")
+	}
+
+	d.updateStatusUntil = time.Now().Add(-time.Second)
+	d.Update(updateErrorExpiredMsg{})
+	if bar := d.renderStatusBar(); strings.Contains(bar, "Update failed") || !strings.Contains(bar, "v9.9.9 available") {
+		t.Errorf("Expected the update notice back once the error expired:\n%s", bar)
+	}
+}
+
+func TestBellOnErrorFiresOnTransitionOnly(t *testing.T) {
+	d := &Dashboard{sessionStatuses: make(map[string]metrics.SessionStatus), lastNotified: make(map[string]time.Time), lastBell: make(map[string]time.Time)}
+	d.SetBellOnError(true)
+	refresh := func(status metrics.SessionStatus) tea.Cmd {
+		return d.handleSessionTransitions(&metrics.TmuxMetrics{Sessions: []metrics.TmuxSession{{Name: "api", Status: status}}})
+	}
+
+	// A session already in ERROR at startup doesn't ring
+	if refresh(metrics.StatusError); d.bellPending || d.errorFlash != 0 {
+		t.Errorf("Expected no bell for a session first seen in ERROR")
+	}
+	refresh(metrics.StatusWorking)
+
+	if refresh(metrics.StatusError); !d.bellPending || d.errorFlash != errorFlashRefreshes {
+		t.Fatalf("Expected a bell and a flash when the session enters ERROR, flash=%d", d.errorFlash)
+	}
+	// The next frame carries the bell, once
+	d.width, d.height = 80, 24
+	if view := d.View(); !strings.HasPrefix(view, "\a") || strings.Contains(d.View(), "\a") {
+		t.Error("Expected the bell in the next frame only")
+	}
+	// Staying in ERROR doesn't ring again, and the flash wears off
+	for i := 0; i < errorFlashRefreshes; i++ {
+		if refresh(metrics.StatusError); d.bellPending {
+			t.Errorf("Refresh %d: expected no bell while the session stays in ERROR", i+1)
+		}
+	}
+	if d.errorFlash != 0 {
+		t.Errorf("Expected the flash to wear off, got %d", d.errorFlash)
+	}
+}
+
+func TestAlertCooldown(t *testing.T) {
+	d := &Dashboard{sessionStatuses: make(map[string]metrics.SessionStatus), lastNotified: make(map[string]time.Time), lastBell: make(map[string]time.Time)}
+	d.SetBellOnError(true)
+	d.SetOnReadyCommand("true")
+	if err := d.SetAlertCooldown(-time.Second); err == nil {
+		t.Error("Expected an error for a negative cooldown")
+	}
+	if err := d.SetAlertCooldown(time.Minute); err != nil {
+		t.Fatal(err)
+	}
+	refresh := func(statuses ...metrics.SessionStatus) tea.Cmd {
+		tmux := &metrics.TmuxMetrics{}
+		for i, status := range statuses {
+			tmux.Sessions = append(tmux.Sessions, metrics.TmuxSession{Name: fmt.Sprintf("s%d", i), Status: status})
+		}
+		return d.handleSessionTransitions(tmux)
+	}
+	refresh(metrics.StatusWorking, metrics.StatusWorking)
+
+	// The first READY alerts, and flapping back within the cooldown doesn't
+	if cmd := refresh(metrics.StatusReady, metrics.StatusWorking); cmd == nil {
+		t.Fatal("Expected an alert when the session becomes READY")
+	}
+	refresh(metrics.StatusWorking, metrics.StatusWorking)
+	if cmd := refresh(metrics.StatusReady, metrics.StatusWorking); cmd != nil {
+		t.Error("Expected no alert for a session flapping back to READY within the cooldown")
+	}
+
+	// Other sessions and other kinds of alert have their own cooldowns
+	if cmd := refresh(metrics.StatusError, metrics.StatusReady); cmd == nil || !d.bellPending || d.errorFlash != errorFlashRefreshes {
+		t.Errorf("Expected a bell and a READY alert for alerts not yet given, flash=%d", d.errorFlash)
+	}
+	d.errorFlash, d.bellPending = 0, false
+	refresh(metrics.StatusWorking, metrics.StatusWorking)
+	if cmd := refresh(metrics.StatusError, metrics.StatusWorking); cmd != nil || d.bellPending || d.errorFlash != 0 {
+		t.Errorf("Expected no bell or flash for a session re-entering ERROR within the cooldown, flash=%d", d.errorFlash)
+	}
+
+	// Once the cooldown has passed, the session alerts again
+	d.lastNotified["s0"] = time.Now().Add(-2 * time.Minute)
+	refresh(metrics.StatusWorking, metrics.StatusWorking)
+	if cmd := refresh(metrics.StatusReady, metrics.StatusWorking); cmd == nil {
+		t.Error("Expected an alert once the cooldown has passed")
+	}
+}
+
+func TestResizeIsCoalesced(t *testing.T) {
+	d := &Dashboard{}
+
+	// The first size applies immediately
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+	if d.width != 100 || d.height != 30 || d.layoutMode != LayoutCompact {
+		t.Fatalf("Expected initial size 100x30, got %dx%d", d.width, d.height)
+	}
+
+	// A burst schedules one debounce tick and leaves the layout alone
+	var scheduled int
+	for _, w := range []int{110, 130, 150, 170} {
+		if _, cmd := d.Update(tea.WindowSizeMsg{Width: w, Height: 40}); cmd != nil {
+			scheduled++
+		}
+	}
+	if scheduled != 1 {
+		t.Errorf("Expected one debounce tick for a resize burst, got %d", scheduled)
+	}
+	if d.width != 100 {
+		t.Errorf("Expected layout to wait for the debounce, width is %d", d.width)
+	}
+
+	// The tick applies the last size
+	d.Update(resizeMsg{})
+	if d.width != 170 || d.height != 40 || d.layoutMode == LayoutCompact {
+		t.Errorf("Expected 170x40 three-column layout after debounce, got %dx%d mode %d", d.width, d.height, d.layoutMode)
+	}
+	if _, cmd := d.Update(tea.WindowSizeMsg{Width: 90, Height: 40}); cmd == nil {
+		t.Error("Expected a new debounce tick after the previous one fired")
+	}
+}
+
+func TestFixedPanelWidths(t *testing.T) {
+	d := &Dashboard{}
+	if err := d.SetPanelWidths(20, 0); err == nil {
+		t.Error("Expected a system panel width below the minimum to be rejected")
+	}
+	if err := d.SetPanelWidths(50, 80); err != nil {
+		t.Fatalf("SetPanelWidths failed: %v", err)
+	}
+
+	if system, token, ok := d.fixedPanelWidths(200); !ok || system != 50 || token != 80 {
+		t.Errorf("Expected 50 and 80 in 200 columns, got %d, %d, %v", system, token, ok)
+	}
+	// 50 + 80 leaves the sessions panel under 30 columns
+	if system, token, ok := d.fixedPanelWidths(150); ok || system != 0 || token != 0 {
+		t.Errorf("Expected automatic widths in 150 columns, got %d, %d, %v", system, token, ok)
+	}
+
+	// Only the token panel fixed: the system panel keeps its automatic width
+	d.SetPanelWidths(0, 100)
+	if _, token, ok := d.fixedPanelWidths(200); !ok || token != 100 {
+		t.Errorf("Expected a 100-column token panel in 200 columns, got %d, %v", token, ok)
+	}
+	d.Update(tea.WindowSizeMsg{Width: 150, Height: 30})
+	if !strings.Contains(d.statusMessage, "using automatic widths") {
+		t.Errorf("Expected a note that the widths don't fit, got %q", d.statusMessage)
+	}
+}
+
+func TestMaxWidthCentersLayout(t *testing.T) {
+	d := &Dashboard{minimalMode: true, hideStatusBar: true}
+	if err := d.SetMaxWidth(60); err == nil {
+		t.Error("Expected a max width below the minimum to be rejected")
+	}
+	if err := d.SetMaxWidth(160); err != nil {
+		t.Fatalf("SetMaxWidth failed: %v", err)
+	}
+
+	// Panels are laid out at the cap, and the frame is centered
+	d.Update(tea.WindowSizeMsg{Width: 400, Height: 20})
+	if d.width != 160 || d.layoutMode != LayoutUltraWide {
+		t.Fatalf("Expected a 160-column three-panel layout, got width %d mode %d", d.width, d.layoutMode)
+	}
+	for _, line := range strings.Split(strings.TrimRight(d.View(), "\n"), "\n") {
+		if !strings.HasPrefix(line, strings.Repeat(" ", 120)) {
+			t.Errorf("Expected a 120-column left margin: %q", line)
+		}
+		if w := lipgloss.Width(line); w > 400 {
+			t.Errorf("Line wider than the terminal (%d): %q", w, line)
+		}
+	}
+
+	// Terminals narrower than the cap use their full width
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 20})
+	d.Update(resizeMsg{})
+	if d.width != 100 || d.layoutMode != LayoutCompact {
+		t.Errorf("Expected the full 100 columns below the cap, got width %d mode %d", d.width, d.layoutMode)
+	}
+	for _, line := range strings.Split(d.View(), "\n") {
+		if w := lipgloss.Width(line); w > 100 {
+			t.Errorf("Line wider than the terminal (%d): %q", w, line)
+		}
+	}
+}
+
+func TestSecondaryCurrency(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_000_000,
+			TotalCost:   12.3,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-opus-4-5-20251101", TotalTokens: 1_000_000, Cost: 12.3},
+			},
+		},
+	}
+
+	if err := d.SetSecondaryCurrency("GBP", 0); err == nil {
+		t.Error("Expected an error for a currency without a rate")
+	}
+	if panel := d.renderTokenPanel(80, 20); strings.Contains(panel, "£") {
+		t.Errorf("Expected USD only by default:\n%s", panel)
+	}
+
+	if err := d.SetSecondaryCurrency("GBP", 0.8); err != nil {
+		t.Fatalf("SetSecondaryCurrency failed: %v", err)
+	}
+	panel := d.renderTokenPanel(80, 20)
+	if !strings.Contains(panel, "Cost:  $12.30 (£9.84)") {
+		t.Errorf("Expected converted total on the Cost line:\n%s", panel)
+	}
+	if !strings.Contains(panel, "Opus 4.5 $12.30 (1.0M) (£9.84)") {
+		t.Errorf("Expected converted cost on the model line:\n%s", panel)
+	}
+}
+
+func TestTokenPanelShowsLookbackEnd(t *testing.T) {
+	midnight := time.Date(2025, 3, 11, 0, 0, 0, 0, time.Local)
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:    true,
+			LookbackFrom: midnight.AddDate(0, 0, -1),
+			LookbackTo:   midnight,
+		},
+	}
+
+	panel := d.renderTokenPanel(100, 20)
+	if !strings.Contains(panel, "→ Tue 12:00am (1d)") {
+		t.Errorf("Expected the range end and its length in the header:\n%s", panel)
+	}
+	if strings.Contains(panel, "→ Now") {
+		t.Errorf("Expected a bounded range not to run to now:\n%s", panel)
+	}
+}
+
+func TestCustomLookbackEnd(t *testing.T) {
+	start := time.Now().AddDate(0, 0, -7)
+	d := &Dashboard{width: 100, height: 40, lookbackMode: true, lookbackCustomMode: true, lookbackCustomDate: start}
+	toggleEnd := tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("e")}
+
+	d.handleLookbackKey(toggleEnd)
+	if !d.lookbackCustomHasEnd || !d.lookbackCustomEnd.Equal(start.Add(8*time.Hour)) {
+		t.Fatalf("Expected e to add an end 8h after the start, got %v", d.lookbackCustomEnd)
+	}
+	for i := 0; i < 7; i++ {
+		d.handleLookbackKey(tea.KeyMsg{Type: tea.KeyTab})
+	}
+	if d.lookbackEditField != 7 {
+		t.Fatalf("Expected tab to reach the end's day field, got field %d", d.lookbackEditField)
+	}
+	d.adjustCustomDate(-1)
+	if !d.lookbackCustomEnd.Equal(start.Add(8*time.Hour).AddDate(0, 0, -1)) || !d.lookbackCustomDate.Equal(start) {
+		t.Fatalf("Expected only the end to move back a day, got %v to %v", d.lookbackCustomDate, d.lookbackCustomEnd)
+	}
+
+	// An end before the start is refused and explained
+	if picker := d.renderLookbackPicker(); !strings.Contains(picker, "End must be after the start") {
+		t.Errorf("Expected the picker to explain the invalid range:\n%s", picker)
+	}
+	d.handleLookbackKey(tea.KeyMsg{Type: tea.KeyEnter})
+	if !d.lookbackMode {
+		t.Error("Expected enter to keep the picker open for an invalid range")
+	}
+
+	// Turning the end off moves the cursor back to the start's fields
+	d.handleLookbackKey(toggleEnd)
+	if d.lookbackCustomHasEnd || d.lookbackEditField != 2 {
+		t.Errorf("Expected no end and the start's day field selected, got end=%v field %d", d.lookbackCustomHasEnd, d.lookbackEditField)
+	}
+}
+
+func TestSetLookbackCustomStart(t *testing.T) {
+	d := &Dashboard{
+		tokenCollector: &metrics.TokenCollector{},
+		lookbackPresets: []LookbackPreset{
+			{Key: "today", GetTime: metrics.StartOfToday},
+			{Key: "custom"},
+		},
+	}
+
+	if err := d.SetLookback("today"); err != nil || d.lookbackSelectedIndex != 0 {
+		t.Fatalf("Expected the today preset, got index %d, %v", d.lookbackSelectedIndex, err)
+	}
+
+	// Other values start a custom range, parsed like --since
+	if err := d.SetLookback("2025-11-01T09:00"); err != nil {
+		t.Fatalf("SetLookback failed: %v", err)
+	}
+	want := time.Date(2025, 11, 1, 9, 0, 0, 0, time.Local)
+	if !d.tokenCollector.GetLookback().Equal(want) || !d.lookbackCustomDate.Equal(want) || d.lookbackSelectedIndex != 1 {
+		t.Errorf("Expected a custom start at %v, got %v (index %d)", want, d.tokenCollector.GetLookback(), d.lookbackSelectedIndex)
+	}
+	if err := d.SetLookback("3d"); err != nil {
+		t.Fatalf("SetLookback failed: %v", err)
+	}
+	if ago := time.Since(d.tokenCollector.GetLookback()); ago < 71*time.Hour || ago > 73*time.Hour {
+		t.Errorf("Expected 3d to start about 72h ago, got %v", ago)
+	}
+
+	err := d.SetLookback("fortnight")
+	if err == nil || !strings.Contains(err.Error(), "today") {
+		t.Errorf("Expected an error listing the presets, got %v", err)
+	}
+}
+
+func TestRenderProjectUsage(t *testing.T) {
+	d := &Dashboard{width: 100, height: 30, tokenCollector: &metrics.TokenCollector{}}
+	if view := d.renderProjectUsage(); !strings.Contains(view, "Loading…") {
+		t.Errorf("Expected a loading state before the query returns:\n%s", view)
+	}
+
+	d.Update(projectUsageMsg{projects: []metrics.ProjectUsage{
+		{Project: "-srv-api", Path: "/srv/api", Tokens: 3_000_000, Cost: 30},
+		{Project: "-srv-web", Path: "/srv/web", Tokens: 1_000_000, Cost: 10},
+	}})
+	view := d.renderProjectUsage()
+	for _, want := range []string{"/srv/api", "75.0%", "/srv/web", "25.0%", "$40.00"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the project view:\n%s", want, view)
+		}
+	}
+	if strings.Index(view, "/srv/api") > strings.Index(view, "/srv/web") {
+		t.Errorf("Expected the costliest project first:\n%s", view)
+	}
+}
+
+func TestRenderSessionCellAge(t *testing.T) {
+	session := metrics.TmuxSession{
+		Name: "old", Status: metrics.StatusReady, Windows: 1,
+		IdleDuration: 5 * time.Minute, Created: time.Now().Add(-12 * 24 * time.Hour),
+	}
+	d := &Dashboard{tmuxMetrics: &metrics.TmuxMetrics{Sessions: []metrics.TmuxSession{session}}}
+
+	if cell := d.renderSessionCell(session, 55); strings.Contains(cell, "12d") {
+		t.Errorf("Expected no age before toggling: %q", cell)
+	}
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'A'}})
+	for _, tt := range []struct {
+		width    int
+		wantIdle bool
+	}{{55, true}, {30, false}} {
+		cell := d.renderSessionCell(session, tt.width)
+		if w := lipgloss.Width(cell); w != tt.width {
+			t.Errorf("width %d: expected cell width %d, got %d: %q", tt.width, tt.width, w, cell)
+		}
+		if !strings.Contains(cell, "12d") {
+			t.Errorf("width %d: expected the session age: %q", tt.width, cell)
+		}
+		if got := strings.Contains(cell, " 5m "); got != tt.wantIdle {
+			t.Errorf("width %d: expected idle shown=%v: %q", tt.width, tt.wantIdle, cell)
+		}
+	}
+}
+
+func TestQuitAbandonsCollection(t *testing.T) {
+	d := &Dashboard{}
+	if err := d.SetCollectTimeout(10 * time.Millisecond); err == nil {
+		t.Error("Expected a collect timeout under 100ms to be rejected")
+	}
+	if err := d.SetCollectTimeout(5 * time.Second); err != nil {
+		t.Fatalf("SetCollectTimeout failed: %v", err)
+	}
+
+	d.quitCtx, d.quit = context.WithCancel(context.Background())
+	_, cmd := d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'q'}})
+	if cmd == nil {
+		t.Fatal("Expected q to quit")
+	}
+	if d.quitCtx.Err() == nil {
+		t.Error("Expected quitting to cancel in-flight collection")
+	}
+
+	// Ctrl+C from an overlay cancels collection too
+	overlays := map[string]func(*Dashboard){
+		"inspector":   func(d *Dashboard) { d.inspectMode = true },
+		"hourly":      func(d *Dashboard) { d.hourlyMode = true },
+		"projects":    func(d *Dashboard) { d.projectsMode = true },
+		"model":       func(d *Dashboard) { d.modelMode = true },
+		"keybindings": func(d *Dashboard) { d.keyHelpMode = true },
+	}
+	for name, open := range overlays {
+		d := &Dashboard{}
+		d.quitCtx, d.quit = context.WithCancel(context.Background())
+		open(d)
+		if _, cmd := d.Update(tea.KeyMsg{Type: tea.KeyCtrlC}); cmd == nil {
+			t.Errorf("%s: expected Ctrl+C to quit", name)
+		}
+		if d.quitCtx.Err() == nil {
+			t.Errorf("%s: expected Ctrl+C to cancel in-flight collection", name)
+		}
+	}
+}
+
+func TestFullModelNames(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_000_000,
+			TotalCost:   12.3,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-opus-4-5-20251101", TotalTokens: 1_000_000, Cost: 12.3},
+			},
+		},
+	}
+
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "Opus 4.5 $12.30") {
+		t.Errorf("Expected the short name by default:\n%s", panel)
+	}
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("M")})
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "claude-opus-4-5-20251101 $12.30") {
+		t.Errorf("Expected the raw model ID after pressing M:\n%s", panel)
+	}
+
+	// A narrow panel keeps the end of the ID, where the date is
+	if panel := d.renderTokenPanel(60, 20); !strings.Contains(panel, "…-20251101 $12.30") {
+		t.Errorf("Expected the ID to be cut from the front:\n%s", panel)
+	}
+}
+
+func TestFocusedPanelFillsTerminal(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1_000_000},
+	}
+	d.Update(tea.WindowSizeMsg{Width: 100, Height: 30})
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("2")})
+	view := d.View()
+	if !strings.Contains(view, "Token Usage") || strings.Contains(view, "System Resources") {
+		t.Errorf("Expected only the token panel after pressing 2:\n%s", view)
+	}
+	// Panel plus status bar fill the terminal exactly
+	if lines := strings.Split(view, "\n"); len(lines) != 30 || lipgloss.Width(lines[0]) != 100 {
+		t.Errorf("Expected a 100x30 frame, got %d lines of width %d", len(lines), lipgloss.Width(lines[0]))
+	}
+
+	d.Update(tea.KeyMsg{Type: tea.KeyEsc})
+	if d.focusedPanel != 0 || !strings.Contains(d.View(), "System Resources") {
+		t.Error("Expected Esc to return to all panels")
+	}
+}
+
+func TestStatusFormat(t *testing.T) {
+	d := &Dashboard{
+		version: "v1.2.3",
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_200_000,
+			TotalCost:   12.3,
+			TodayCost:   4.5,
+		},
+		tmuxMetrics: &metrics.TmuxMetrics{
+			Sessions: []metrics.TmuxSession{{Name: "api"}, {Name: "web"}},
+		},
+		width:      120,
+		height:     40,
+		layoutMode: LayoutUltraWide,
+	}
+
+	if err := d.SetStatusFormat("{time} {costs}"); err == nil || !strings.Contains(err.Error(), "{costs}") {
+		t.Errorf("Expected an unknown token to be rejected, got %v", err)
+	}
+	if err := d.SetStatusFormat("{cost} | {tokens} | {sessions} sessions"); err != nil {
+		t.Fatalf("SetStatusFormat failed: %v", err)
+	}
+
+	bar := d.renderStatusBar()
+	if lipgloss.Height(bar) != 1 || lipgloss.Width(bar) != 120 {
+		t.Errorf("Expected one line filling the width, got %dx%d: %q", lipgloss.Width(bar), lipgloss.Height(bar), bar)
+	}
+	fields := strings.Fields(bar)
+	if len(fields) != 4 || fields[0] != "$12.30" || fields[1] != "1.2M" || fields[2] != "2" {
+		t.Errorf("Expected cost, tokens and session count in order, got %q", bar)
+	}
+	if !strings.HasPrefix(strings.TrimSpace(bar), "$12.30") || !strings.HasSuffix(strings.TrimSpace(bar), "2 sessions") {
+		t.Errorf("Expected the sections aligned left and right, got %q", bar)
+	}
+
+	// Too wide for the terminal: the short status line instead
+	d.width = 20
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "v1.2.3 20x40 h q r") {
+		t.Errorf("Expected the short status line when the template doesn't fit, got %q", bar)
+	}
+
+	if err := d.SetStatusFormat("{today} today"); err != nil {
+		t.Fatalf("SetStatusFormat failed: %v", err)
+	}
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "$4.50 today") {
+		t.Errorf("Expected today's cost from {today}, got %q", bar)
+	}
+}
+
+func TestStatusBarShowsTodayCost(t *testing.T) {
+	d := &Dashboard{
+		version: "v1.2.3",
+		tokenMetrics: &metrics.TokenMetrics{
+			Available: true,
+			TotalCost: 80,
+			TodayCost: 4.5,
+		},
+		width:      160,
+		height:     40,
+		layoutMode: LayoutUltraWide,
+	}
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "v1.2.3 today $4.50") {
+		t.Errorf("Expected today's cost next to the version, got %q", bar)
+	}
+
+	// Kept when the status bar is squeezed
+	d.width = 60
+	if bar := d.renderStatusBar(); !strings.Contains(bar, "today $4.50") {
+		t.Errorf("Expected today's cost on the short status line, got %q", bar)
+	}
+}
+
+func TestExcludedModelRendersDim(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_000_000,
+			TotalCost:   3,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-sonnet-4-5-20250929", TotalTokens: 1_000_000, Cost: 3},
+				{Model: "claude-haiku-4-5-20250929", TotalTokens: 4_000_000, Cost: 4, Excluded: true},
+			},
+		},
+	}
+
+	panel := d.renderTokenPanel(100, 20)
+	if !strings.Contains(panel, "Haiku 4.5 $4.00 (4.0M) excl") {
+		t.Errorf("Expected the excluded model marked in the breakdown:\n%s", panel)
+	}
+	if !strings.Contains(panel, "Sonnet 4.5 $3.00 (1.0M)") || strings.Contains(panel, "(1.0M) excl") {
+		t.Errorf("Expected counted models unmarked:\n%s", panel)
+	}
+}
+
+func TestBurnGauge(t *testing.T) {
+	tests := []struct {
+		rate, avg float64
+		want      string
+	}{
+		{500, 1000, "▼ 0.5× avg"},
+		{1500, 1000, "▲ 1.5× avg"},
+		{3000, 1000, "▲ 3.0× avg"},
+		{1500, 0, ""}, // No average yet
+		{0, 1000, ""}, // Idle
+	}
+
+	for _, tt := range tests {
+		d := &Dashboard{
+			tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1_000_000, Rate: tt.rate, SessionAvgRate: tt.avg},
+		}
+		panel := d.renderTokenPanel(100, 20)
+		if tt.want == "" {
+			if strings.Contains(panel, "Burn:") {
+				t.Errorf("Expected no burn gauge for rate %v avg %v:\n%s", tt.rate, tt.avg, panel)
+			}
+			continue
+		}
+		if !strings.Contains(panel, "Burn:  "+tt.want) {
+			t.Errorf("Expected %q for rate %v avg %v:\n%s", tt.want, tt.rate, tt.avg, panel)
+		}
+	}
+}
+
+func TestRateSmoothing(t *testing.T) {
+	d := &Dashboard{}
+	if err := d.SetRateSmoothing(1); err == nil {
+		t.Error("Expected a smoothing factor of 1 to be rejected")
+	}
+	if err := d.SetRateSmoothing(0.5); err != nil {
+		t.Fatalf("SetRateSmoothing: %v", err)
+	}
+
+	// The first rate is taken as is, then each refresh moves halfway to the new one
+	for _, rate := range []float64{8000, 0, 4000} {
+		d.Update(metricsMsg{tokens: &metrics.TokenMetrics{Available: true, TotalTokens: 1_000_000, Rate: rate}})
+	}
+	if d.smoothedRate != 4000 {
+		t.Errorf("Expected a trend of 4000 after 8000, 0 and 4000, got %v", d.smoothedRate)
+	}
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "Trend: 4.0K/min") {
+		t.Errorf("Expected the smoothed rate on a Trend line:\n%s", panel)
+	}
+
+	// Refreshes without token data don't count as an idle minute
+	d.Update(metricsMsg{})
+	d.Update(metricsMsg{tokens: &metrics.TokenMetrics{Available: false}})
+	d.Update(metricsMsg{tokens: &metrics.TokenMetrics{Available: true, Rate: math.NaN()}})
+	if d.smoothedRate != 4000 {
+		t.Errorf("Expected missing rates to leave the trend at 4000, got %v", d.smoothedRate)
+	}
+
+	// Off by default
+	d = &Dashboard{tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1_000_000, Rate: 4000}}
+	d.sampleTokenRate(d.tokenMetrics)
+	if panel := d.renderTokenPanel(100, 20); strings.Contains(panel, "Trend:") {
+		t.Errorf("Expected no Trend line without --rate-smoothing:\n%s", panel)
+	}
+}
+
+func TestThresholdFlash(t *testing.T) {
+	d := &Dashboard{warnThreshold: 80, critThreshold: 95}
+	d.SetFlashThresholds(true)
+	refresh := func(cpu, cost float64, from time.Time) {
+		d.Update(metricsMsg{
+			system: metrics.SystemMetrics{CPU: metrics.CPUMetrics{TotalPercent: cpu}},
+			tokens: &metrics.TokenMetrics{Available: true, TotalCost: cost, LookbackFrom: from},
+		})
+	}
+	monday := time.Date(2025, 11, 3, 9, 0, 0, 0, time.UTC)
+
+	// Nothing flashes on the first refresh, with nothing to compare against
+	refresh(50, 4, monday)
+	if len(d.flashing) != 0 {
+		t.Errorf("Expected no flashes on the first refresh, got %v", d.flashing)
+	}
+
+	// CPU into the critical band and cost past $5 flash for one refresh
+	refresh(97, 5.5, monday)
+	if !d.flashing["cpu"] || !d.flashing["cost"] {
+		t.Errorf("Expected CPU and cost to flash, got %v", d.flashing)
+	}
+	refresh(98, 6, monday)
+	if len(d.flashing) != 0 {
+		t.Errorf("Expected flashes to last one refresh, got %v", d.flashing)
+	}
+
+	// Falling back doesn't flash, and neither does a higher cost from a longer lookback
+	refresh(20, 60, monday.AddDate(0, 0, -30))
+	if len(d.flashing) != 0 {
+		t.Errorf("Expected no flashes for a drop or a new lookback window, got %v", d.flashing)
+	}
+}
+
+func TestCostMilestone(t *testing.T) {
+	tests := []struct {
+		cost, want float64
+	}{
+		{0.5, 0},
+		{1, 1},
+		{1.99, 1},
+		{2, 2},
+		{4.99, 2},
+		{7, 5},
+		{10, 10},
+		{49, 20},
+		{1234, 1000},
+	}
+	for _, tt := range tests {
+		if got := costMilestone(tt.cost); got != tt.want {
+			t.Errorf("costMilestone(%v) = %v, want %v", tt.cost, got, tt.want)
+		}
+	}
+}
+
+func TestSnapshotFile(t *testing.T) {
+	tmpDir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(tmpDir)
+	path := filepath.Join(tmpDir, "snapshot.json")
+
+	d := &Dashboard{}
+	if err := d.SetSnapshotFile(filepath.Join(tmpDir, "missing", "snapshot.json"), false); err == nil {
+		t.Error("Expected an error for a snapshot file in a missing directory")
+	}
+	if err := d.SetSnapshotFile(path, true); err != nil {
+		t.Fatal(err)
+	}
+	_, cmd := d.Update(metricsMsg{
+		tokens: &metrics.TokenMetrics{Available: true, TotalCost: 4.5},
+		tmux: &metrics.TmuxMetrics{Available: true, Sessions: []metrics.TmuxSession{
+			{Name: "api", LastLines: []string{"secret"}},
+		}},
+	})
+	// Run the write, which is batched with the session transition commands
+	var run func(tea.Cmd)
+	run = func(cmd tea.Cmd) {
+		if cmd == nil {
+			return
+		}
+		switch msg := cmd().(type) {
+		case tea.BatchMsg:
+			for _, c := range msg {
+				run(c)
+			}
+		case snapshotWrittenMsg:
+			if msg.err != nil {
+				t.Fatalf("Snapshot write failed: %v", msg.err)
+			}
+			d.Update(msg)
+		}
+	}
+	run(cmd)
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var snap export.Snapshot
+	if err := json.Unmarshal(data, &snap); err != nil {
+		t.Fatalf("Snapshot file isn't JSON: %v", err)
+	}
+	if snap.Tokens == nil || snap.Tokens.TotalCost != 4.5 {
+		t.Errorf("Expected the token metrics in the snapshot, got %+v", snap.Tokens)
+	}
+	if snap.Tmux == nil || len(snap.Tmux.Sessions) != 1 || snap.Tmux.Sessions[0].Name == "api" || snap.Tmux.Sessions[0].LastLines != nil {
+		t.Errorf("Expected a redacted session in the snapshot, got %+v", snap.Tmux)
+	}
+	// Redacting works on a copy, so the dashboard keeps the real session
+	if s := d.tmuxMetrics.Sessions[0]; s.Name != "api" || len(s.LastLines) != 1 {
+		t.Errorf("Expected the dashboard's session to be unchanged, got %+v", s)
+	}
+	if d.snapshotWriting {
+		t.Error("Expected the next refresh to be free to write again")
+	}
+}
+
+func TestLoadStylePerCore(t *testing.T) {
+	d := &Dashboard{
+		systemMetrics: metrics.SystemMetrics{CPU: metrics.CPUMetrics{PerCore: make([]float64, 4)}},
+	}
+
+	tests := []struct {
+		load float64
+		want lipgloss.Style
+	}{
+		{2.0, successStyle}, // 0.5 per core
+		{2.8, warningStyle}, // 0.7 per core
+		{3.9, warningStyle}, // Just under one per core
+		{4.0, errorStyle},   // Every core busy
+		{12.5, errorStyle},
+	}
+	for _, tt := range tests {
+		if got := d.loadStyle(tt.load); got.GetForeground() != tt.want.GetForeground() {
+			t.Errorf("Load %.2f on 4 cores: expected color %v, got %v", tt.load, tt.want.GetForeground(), got.GetForeground())
+		}
+	}
+
+	// The number itself is unchanged by the color
+	if got := d.renderLoad(3.14159); !strings.Contains(got, "3.14") {
+		t.Errorf("Expected the load with two decimals, got %q", got)
+	}
+}
+
+func TestMarkdownSummary(t *testing.T) {
+	now := time.Date(2026, 10, 16, 12, 0, 0, 0, time.UTC)
+	d := &Dashboard{
+		version: "v1.2.3",
+		systemMetrics: metrics.SystemMetrics{
+			CPU:        metrics.CPUMetrics{TotalPercent: 42.5, PerCore: []float64{40, 45}},
+			Memory:     metrics.MemoryMetrics{Used: 4 << 30, Total: 16 << 30, Percentage: 25},
+			LastUpdate: now,
+		},
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:    true,
+			TotalTokens:  1_234_567,
+			TotalCost:    12.5,
+			LookbackFrom: now.Add(-2 * time.Hour),
+			ModelUsages:  []metrics.ModelUsage{{Model: "claude-sonnet-4-5-20250929", TotalTokens: 1_234_567, Cost: 12.5}},
+		},
+		tmuxMetrics: &metrics.TmuxMetrics{
+			Sessions: []metrics.TmuxSession{{Name: "web|api", Status: metrics.StatusReady, IdleDuration: 5 * time.Minute}},
+		},
+	}
+
+	md := d.markdownSummary(now)
+	for _, want := range []string{
+		"## ccdash v1.2.3 — 2026-10-16 12:00 UTC",
+		"| CPU | 42.5% (2 cores) |",
+		"| Window | " + d.lookbackLabel(now) + " |",
+		"| Total | 1,234,567 |",
+		"| Cost | $12.50 |",
+		`| web\|api | [RDY] | 5.0m |`,
+	} {
+		if !strings.Contains(md, want) {
+			t.Errorf("Expected %q in summary:\n%s", want, md)
+		}
+	}
+	if strings.Contains(md, "Swap") {
+		t.Errorf("Expected no swap row without swap:\n%s", md)
+	}
+}
+
+func TestTokenPanelShowsScope(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1000},
+	}
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "All Projects") {
+		t.Errorf("Expected All Projects in the header:\n%s", panel)
+	}
+
+	// A project scope is shown even when it doesn't fit beside the lookback,
+	// by its whole path when there's room
+	t.Setenv("HOME", "/home/me")
+	d.tokenMetrics.Project = "/home/me/src/app"
+	for width, want := range map[int]string{100: "Project: ~/src/app", 40: "Project: ~/src/app", 20: "Project: app"} {
+		if panel := d.renderTokenPanel(width, 20); !strings.Contains(panel, want) {
+			t.Errorf("Expected %s in the header at width %d:\n%s", want, width, panel)
+		}
+	}
+
+	if err := d.SetStatusFormat("{project} | {time}"); err != nil {
+		t.Fatalf("SetStatusFormat failed: %v", err)
+	}
+	d.width = 80
+	if bar, ok := d.formatStatusBar("", ""); !ok || !strings.HasPrefix(strings.TrimSpace(bar), "~/src/app") {
+		t.Errorf("Expected the project path in the status bar, got %q", bar)
+	}
+}
+
+func TestHourlyCostChart(t *testing.T) {
+	costs := make([]float64, 24)
+	costs[20], costs[23] = 1, 4
+	d := &Dashboard{
+		layoutMode:   LayoutWide,
+		tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1000, HourlyCost: costs},
+	}
+
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "Last 24h: $5.00 · peak $4.00/h") ||
+		!strings.Contains(panel, "▂▂▂      ███") {
+		t.Errorf("Expected the cost chart in a tall wide panel:\n%s", panel)
+	}
+	if panel := d.renderTokenPanel(100, 8); strings.Contains(panel, "Last 24h") {
+		t.Errorf("Expected no cost chart without room for it:\n%s", panel)
+	}
+	d.layoutMode = LayoutNarrow
+	if panel := d.renderTokenPanel(100, 20); strings.Contains(panel, "Last 24h") {
+		t.Errorf("Expected no cost chart in the narrow layout:\n%s", panel)
+	}
+}
+
+func TestRenderSparkline(t *testing.T) {
+	if got := renderSparkline([]float64{0, 0.01, 4, 8}, 1); got != " ▁▄█" {
+		t.Errorf("Expected \" ▁▄█\", got %q", got)
+	}
+	if got := renderSparkline([]float64{0, 0}, 2); got != "    " {
+		t.Errorf("Expected blanks without values, got %q", got)
+	}
+}
+
+func TestManualRefresh(t *testing.T) {
+	d := &Dashboard{
+		version:         "v1.2.3",
+		manual:          true,
+		lastUpdate:      time.Now().Add(-time.Hour),
+		refreshInterval: 2 * time.Second,
+		width:           160,
+		height:          40,
+		layoutMode:      LayoutUltraWide,
+	}
+	if d.isStale() {
+		t.Error("Expected old data not to be stale in manual mode")
+	}
+	for _, width := range []int{160, 60} {
+		d.width = width
+		if bar := d.renderStatusBar(); !strings.Contains(bar, "MANUAL") || strings.Contains(bar, "stale") {
+			t.Errorf("Expected MANUAL and no stale marker at width %d, got %q", width, bar)
+		}
+	}
+
+	// Losing focus doesn't pause what isn't running
+	d.Update(tea.BlurMsg{})
+	if d.paused {
+		t.Error("Expected no pause on blur in manual mode")
+	}
+}
+
+func TestSessionCleanup(t *testing.T) {
+	d := &Dashboard{
+		sessionCleanupInterval: time.Minute,
+		lastCleanup:            time.Now(),
+		tmuxMetrics:            &metrics.TmuxMetrics{LifetimeSessions: 5},
+	}
+	if cmd := d.cleanupSessions(); cmd != nil || d.cleanupInFlight {
+		t.Error("Expected no cleanup within the interval")
+	}
+	d.lastCleanup = time.Time{}
+	d.sessionCleanupInterval = 0
+	if cmd := d.cleanupSessions(); cmd != nil {
+		t.Error("Expected no cleanup when turned off")
+	}
+
+	d.cleanupInFlight = true
+	d.Update(sessionCleanupMsg{cleaned: 2})
+	d.Update(sessionCleanupMsg{cleaned: 1})
+	if d.cleanupInFlight || d.cleanedSessions != 3 {
+		t.Errorf("Expected 3 cleaned and nothing in flight, got %d (in flight %v)", d.cleanedSessions, d.cleanupInFlight)
+	}
+	if footer := d.sessionHistoryFooter(); !strings.Contains(footer, "Lifetime: 5 sessions · 3 cleaned") {
+		t.Errorf("Expected the cleaned count in the footer, got %q", footer)
+	}
+
+	d.tmuxMetrics = nil
+	if footer := d.sessionHistoryFooter(); !strings.Contains(footer, "3 cleaned") || strings.Contains(footer, "Lifetime") {
+		t.Errorf("Expected only the cleaned count, got %q", footer)
+	}
+}
+
+func TestExactTokens(t *testing.T) {
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{
+			Available:   true,
+			TotalTokens: 1_234_567,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-opus-4-5-20251101", TotalTokens: 1_234_567, Cost: 12.3},
+			},
+		},
+	}
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "Total: 1.2M") || strings.Contains(panel, "1,234,567") {
+		t.Errorf("Expected short counts by default:\n%s", panel)
+	}
+
+	d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("k")})
+	panel := d.renderTokenPanel(100, 20)
+	if !strings.Contains(panel, "Total: 1,234,567") || !strings.Contains(panel, "Opus 4.5 $12.30 (1,234,567)") {
+		t.Errorf("Expected exact counts after k, including the model line:\n%s", panel)
+	}
+}
+
+func TestClockSkewNote(t *testing.T) {
+	now := time.Now()
+	d := &Dashboard{
+		tokenMetrics: &metrics.TokenMetrics{Available: true, TotalTokens: 1000, LookbackFrom: now.Add(-time.Hour)},
+	}
+	if panel := d.renderTokenPanel(100, 20); strings.Contains(panel, "clock skew") {
+		t.Errorf("Expected no clock note with a sane clock:\n%s", panel)
+	}
+
+	d.tokenMetrics.ClockSkew = 3 * time.Hour
+	if panel := d.renderTokenPanel(100, 20); !strings.Contains(panel, "⚠ clock skew?") {
+		t.Errorf("Expected a clock note with events from the future:\n%s", panel)
+	}
+
+	// A window that starts in the future shows an elapsed time of 0, not a negative one
+	d.tokenMetrics.ClockSkew = 0
+	d.tokenMetrics.LookbackFrom = now.Add(5 * time.Hour)
+	if !d.clockSkewed(now) {
+		t.Error("Expected a lookback start in the future to count as skew")
+	}
+	if label := d.lookbackLabel(now); !strings.HasSuffix(label, "(0s)") {
+		t.Errorf("Expected a clamped duration, got %q", label)
+	}
+}
+
+func TestModelDetail(t *testing.T) {
+	d := &Dashboard{
+		width:          100,
+		height:         30,
+		tokenCollector: &metrics.TokenCollector{},
+		tokenMetrics: &metrics.TokenMetrics{
+			Available: true,
+			TotalCost: 20,
+			ModelUsages: []metrics.ModelUsage{
+				{Model: "claude-opus-4-5-20251101", InputTokens: 1_000_000, OutputTokens: 200_000, TotalTokens: 1_200_000, Cost: 10},
+				{Model: "claude-sonnet-4-5-20250929", InputTokens: 500_000, CacheReadTokens: 2_000_000, TotalTokens: 2_500_000, Cost: 10},
+			},
+		},
+	}
+	key := func(k tea.KeyType) { d.Update(tea.KeyMsg{Type: k}) }
+
+	// Enter does nothing until a model is highlighted
+	key(tea.KeyEnter)
+	if d.modelMode {
+		t.Fatal("Expected Enter without a highlighted model to do nothing")
+	}
+
+	// ↓ starts at the first model and stops at the last
+	key(tea.KeyDown)
+	if d.selectedModel != "claude-opus-4-5-20251101" {
+		t.Fatalf("Expected the first model highlighted, got %q", d.selectedModel)
+	}
+	key(tea.KeyDown)
+	key(tea.KeyDown)
+	if d.selectedModel != "claude-sonnet-4-5-20250929" {
+		t.Fatalf("Expected the highlight to stop at the last model, got %q", d.selectedModel)
+	}
+
+	key(tea.KeyEnter)
+	if !d.modelMode {
+		t.Fatal("Expected Enter to open the model detail")
+	}
+	view := d.renderModelDetail()
+	// Sonnet 4.5: $3/M input, $0.30/M cache read
+	for _, want := range []string{"claude-sonnet-4-5-20250929", "Input", "$3.00", "$1.50", "Cache read", "$0.30", "$0.60", "$2.10", "50.0% of the cost of all models"} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the model detail:\n%s", want, view)
+		}
+	}
+
+	// Activity loads after the detail opens; results for another model are dropped
+	if !strings.Contains(view, "Loading…") {
+		t.Errorf("Expected activity to be loading:\n%s", view)
+	}
+	first := time.Date(2026, 10, 16, 9, 0, 0, 0, time.Local)
+	d.Update(modelActivityMsg{model: "claude-opus-4-5-20251101", first: first, last: first.Add(time.Hour)})
+	if d.modelActivity != nil {
+		t.Error("Expected activity for another model to be ignored")
+	}
+	d.Update(modelActivityMsg{model: d.selectedModel, first: first, last: first.Add(100 * time.Minute)})
+	view = d.renderModelDetail()
+	for _, want := range []string{"First used   Oct 16 9:00am", "Last used    Oct 16 10:40am", "Rate         " + metrics.FormatTokenRateCompact(25_000)} {
+		if !strings.Contains(view, want) {
+			t.Errorf("Expected %q in the model detail:\n%s", want, view)
+		}
+	}
+
+	key(tea.KeyEsc)
+	if d.modelMode || d.selectedModel == "" {
+		t.Errorf("Expected Esc to close the detail and keep the highlight, got modelMode=%v selected=%q", d.modelMode, d.selectedModel)
+	}
+	key(tea.KeyEsc)
+	if d.selectedModel != "" {
+		t.Errorf("Expected a second Esc to clear the highlight, got %q", d.selectedModel)
+	}
+}
+
+func TestBarColorsIn16Colors(t *testing.T) {
+	profile := lipgloss.ColorProfile()
+	defer lipgloss.SetColorProfile(profile)
+	lipgloss.SetColorProfile(termenv.ANSI)
+
+	d := &Dashboard{warnThreshold: 80, critThreshold: 95}
+	// Yellow (60% up to warn) and orange (warn up to crit) must stay apart
+	yellow := d.renderBar(70, 30)
+	orange := d.renderBar(85, 30)
+	if !strings.Contains(yellow, "\x1b[93m") || !strings.Contains(orange, "\x1b[33m") {
+		t.Errorf("Expected bright yellow and yellow bars, got %q and %q", yellow, orange)
+	}
+	// The empty part of a bar is gray, not white
+	if !strings.Contains(d.renderBar(10, 30), "\x1b[90m") {
+		t.Errorf("Expected a gray empty bar, got %q", d.renderBar(10, 30))
+	}
+
+	lipgloss.SetColorProfile(termenv.ANSI256)
+	if bar := d.renderBar(85, 30); !strings.Contains(bar, "\x1b[38;5;214m") {
+		t.Errorf("Expected orange from the 256-color palette, got %q", bar)
+	}
+}
+
+func TestKeyHelpFitsTerminal(t *testing.T) {
+	for _, size := range []struct{ width, height int }{{80, 20}, {100, 56}, {200, 36}} {
+		d := &Dashboard{}
+		d.Update(tea.WindowSizeMsg{Width: size.width, Height: size.height})
+		d.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune("?")})
+
+		view := d.View()
+		if h := lipgloss.Height(view); h > size.height {
+			t.Errorf("%dx%d: key help is %d lines tall", size.width, size.height, h)
+		}
+		for _, line := range strings.Split(view, "\n") {
+			if w := lipgloss.Width(line); w > size.width {
+				t.Errorf("%dx%d: line is %d columns wide: %q", size.width, size.height, w, line)
+				break
+			}
+		}
+
+		cut := strings.Contains(view, "more (enlarge the terminal")
+		if wantCut := size.height == 20; cut != wantCut {
+			t.Errorf("%dx%d: expected cut off = %v:\n%s", size.width, size.height, wantCut, view)
+		}
+		if !cut && !strings.Contains(view, "Model detail") {
+			t.Errorf("%dx%d: expected every section:\n%s", size.width, size.height, view)
+		}
+	}
+}
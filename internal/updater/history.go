@@ -0,0 +1,97 @@
+package updater
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	// HistoryFileName is the log of self-updates in the history directory
+	HistoryFileName = "update-history.log"
+
+	// maxHistorySize bounds the update log. Past it, the oldest entries are
+	// dropped until the log is half this size.
+	maxHistorySize = 64 * 1024
+)
+
+// SetHistoryDir sets the directory of the update log. Updates aren't logged
+// until it's set.
+func (u *Updater) SetHistoryDir(dir string) {
+	u.historyDir = dir
+}
+
+// recordUpdate appends one line to the update log, e.g.
+//
+//	2026-10-16T09:12:44Z v1.0.3 -> v1.0.4 ok updated=/usr/local/bin/ccdash,/home/me/bin/ccdash
+//	2026-10-16T09:20:02Z v1.0.3 -> v1.0.4 failed error="download failed with status 503"
+//
+// Failing to write the log never fails the update.
+func (u *Updater) recordUpdate(info *UpdateInfo, updated, failed []string, err error) {
+	if u.historyDir == "" {
+		return
+	}
+
+	result := "ok"
+	if err != nil {
+		result = "failed"
+	}
+	fields := []string{
+		time.Now().UTC().Format(time.RFC3339),
+		u.currentVersion, "->", info.LatestVersion,
+		result,
+	}
+	if len(updated) > 0 {
+		fields = append(fields, "updated="+strings.Join(updated, ","))
+	}
+	if len(failed) > 0 {
+		fields = append(fields, fmt.Sprintf("failed=%q", strings.Join(failed, "; ")))
+	}
+	if err != nil {
+		fields = append(fields, fmt.Sprintf("error=%q", err.Error()))
+	}
+
+	appendHistory(filepath.Join(u.historyDir, HistoryFileName), strings.Join(fields, " ")+"\n")
+}
+
+// appendHistory appends line to the log at path, first dropping the oldest
+// lines if the log has outgrown maxHistorySize
+func appendHistory(path, line string) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	if info, err := os.Stat(path); err == nil && info.Size()+int64(len(line)) > maxHistorySize {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		for len(data) > maxHistorySize/2 {
+			i := bytes.IndexByte(data, '\n')
+			if i < 0 {
+				data = nil
+				break
+			}
+			data = data[i+1:]
+		}
+		tmpPath := path + ".tmp"
+		if err := os.WriteFile(tmpPath, data, 0644); err != nil {
+			return err
+		}
+		if err := os.Rename(tmpPath, path); err != nil {
+			os.Remove(tmpPath)
+			return err
+		}
+	}
+
+	f, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(line)
+	return err
+}
@@ -0,0 +1,52 @@
+package updater
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestAppendHistoryTrimsOldestLines(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	path := filepath.Join(dir, "logs", HistoryFileName)
+
+	// 100-byte lines, numbered so the survivors show which were dropped
+	line := func(i int) string { return fmt.Sprintf("%04d %s\n", i, strings.Repeat("x", 94)) }
+	count := maxHistorySize / 100
+	for i := 0; i < count; i++ {
+		if err := appendHistory(path, line(i)); err != nil {
+			t.Fatalf("appendHistory failed: %v", err)
+		}
+	}
+	if info, err := os.Stat(path); err != nil || info.Size() != int64(count*100) {
+		t.Fatalf("Expected %d bytes before the limit, got %v (%v)", count*100, info, err)
+	}
+
+	// The next line passes the limit, so the log is cut to half before appending
+	if err := appendHistory(path, line(count)); err != nil {
+		t.Fatalf("appendHistory failed: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("Failed to read history: %v", err)
+	}
+	if len(data) > maxHistorySize/2+100 {
+		t.Errorf("Expected the log trimmed to about %d bytes, got %d", maxHistorySize/2, len(data))
+	}
+	lines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+	if last := lines[len(lines)-1]; last != strings.TrimSuffix(line(count), "\n") {
+		t.Errorf("Expected the new line last, got %q", last)
+	}
+	if first := lines[0]; first != strings.TrimSuffix(line(count-len(lines)+1), "\n") {
+		t.Errorf("Expected only whole lines, oldest dropped first, got %q first", first)
+	}
+	if _, err := os.Stat(path + ".tmp"); !os.IsNotExist(err) {
+		t.Errorf("Expected no leftover temp file, got %v", err)
+	}
+}
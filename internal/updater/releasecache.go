@@ -0,0 +1,87 @@
+package updater
+
+import (
+	"encoding/json"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// ReleaseCacheFileName is the last release fetched from the GitHub API,
+	// with the validators that let the next check ask whether it changed
+	ReleaseCacheFileName = "release-cache.json"
+
+	// maxReleaseSize bounds the release JSON read from the GitHub API
+	maxReleaseSize = 4 << 20
+)
+
+// releaseCache is the last release response and its validators. It's kept in
+// memory and, once SetCacheDir is called, in a file, so a restarted instance
+// can make a conditional request too.
+type releaseCache struct {
+	URL          string          `json:"url"`
+	ETag         string          `json:"etag,omitempty"`
+	LastModified string          `json:"last_modified,omitempty"`
+	Release      json.RawMessage `json:"release"`
+}
+
+// SetCacheDir sets the directory of the release cache. Instances sharing it
+// share their validators, so only the first to see a new release downloads
+// its details. Without it, the cache only lasts as long as the process.
+func (u *Updater) SetCacheDir(dir string) {
+	u.cacheDir = dir
+}
+
+// loadReleaseCache returns the last release response for the API URL, from
+// memory or the cache file, or nil if there's none
+func (u *Updater) loadReleaseCache() *releaseCache {
+	if u.release != nil {
+		return u.release
+	}
+	if u.cacheDir == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(filepath.Join(u.cacheDir, ReleaseCacheFileName))
+	if err != nil {
+		return nil
+	}
+	var c releaseCache
+	if err := json.Unmarshal(data, &c); err != nil || c.URL != u.apiURL || len(c.Release) == 0 {
+		return nil
+	}
+	u.release = &c
+	return u.release
+}
+
+// saveReleaseCache keeps c for the next check. Failing to write the file
+// only means the next instance to start makes a full request.
+func (u *Updater) saveReleaseCache(c *releaseCache) {
+	u.release = c
+	if u.cacheDir == "" {
+		return
+	}
+
+	data, err := json.Marshal(c)
+	if err != nil {
+		return
+	}
+	if err := os.MkdirAll(u.cacheDir, 0755); err != nil {
+		return
+	}
+	path := filepath.Join(u.cacheDir, ReleaseCacheFileName)
+	tmp, err := os.CreateTemp(u.cacheDir, ReleaseCacheFileName+".*.tmp")
+	if err != nil {
+		return
+	}
+	_, err = tmp.Write(data)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+	}
+}
@@ -0,0 +1,127 @@
+package updater
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// releaseServer serves a release with an ETag, answering a matching
+// If-None-Match with 304, until limited is set
+type releaseServer struct {
+	*httptest.Server
+	limited     int // Status for rate-limited responses; 0 to serve normally
+	full        int // 200 responses sent
+	conditional int // Requests that carried If-None-Match
+}
+
+func newReleaseServer(t *testing.T) *releaseServer {
+	s := &releaseServer{}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("If-None-Match") != "" {
+			s.conditional++
+		}
+		switch {
+		case s.limited != 0:
+			w.Header().Set("X-RateLimit-Remaining", "0")
+			w.Header().Set("X-RateLimit-Reset", "1700000000")
+			w.WriteHeader(s.limited)
+		case r.Header.Get("If-None-Match") == `"v1"`:
+			w.WriteHeader(http.StatusNotModified)
+		default:
+			s.full++
+			w.Header().Set("ETag", `"v1"`)
+			w.Write([]byte(`{"tag_name":"v1.2.3","name":"ccdash v1.2.3"}`))
+		}
+	}))
+	t.Cleanup(s.Close)
+	t.Setenv("GITHUB_TOKEN", "")
+	return s
+}
+
+func newTestUpdater(apiURL, cacheDir string) *Updater {
+	u := NewUpdater("v1.0.0")
+	u.apiURL = apiURL
+	u.SetCacheDir(cacheDir)
+	return u
+}
+
+func TestFetchLatestReleaseNotModified(t *testing.T) {
+	s := newReleaseServer(t)
+	u := newTestUpdater(s.URL, "")
+
+	for i := 0; i < 3; i++ {
+		release, err := u.fetchLatestRelease()
+		if err != nil {
+			t.Fatalf("Fetch %d failed: %v", i+1, err)
+		}
+		if release.TagName != "v1.2.3" {
+			t.Errorf("Fetch %d: expected v1.2.3, got %q", i+1, release.TagName)
+		}
+	}
+	// Only the first fetch downloads the release; the rest are answered with 304
+	if s.full != 1 || s.conditional != 2 {
+		t.Errorf("Expected 1 full and 2 conditional requests, got %d and %d", s.full, s.conditional)
+	}
+}
+
+func TestFetchLatestReleaseRateLimited(t *testing.T) {
+	for _, status := range []int{http.StatusForbidden, http.StatusTooManyRequests} {
+		s := newReleaseServer(t)
+		s.limited = status
+
+		// Without a cached release there's nothing to fall back on
+		u := newTestUpdater(s.URL, "")
+		if _, err := u.fetchLatestRelease(); err == nil || !strings.Contains(err.Error(), "rate limit") {
+			t.Errorf("Status %d: expected a rate limit error, got %v", status, err)
+		}
+
+		// With one, it stands in
+		s.limited = 0
+		if _, err := u.fetchLatestRelease(); err != nil {
+			t.Fatalf("Status %d: fetch failed: %v", status, err)
+		}
+		s.limited = status
+		release, err := u.fetchLatestRelease()
+		if err != nil || release.TagName != "v1.2.3" {
+			t.Errorf("Status %d: expected the cached release, got %+v, %v", status, release, err)
+		}
+	}
+}
+
+func TestReleaseCacheFile(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	s := newReleaseServer(t)
+
+	if _, err := newTestUpdater(s.URL, dir).fetchLatestRelease(); err != nil {
+		t.Fatalf("Fetch failed: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, ReleaseCacheFileName)); err != nil {
+		t.Fatalf("Expected the release cache file: %v", err)
+	}
+
+	// Another instance picks up the validators and gets a 304
+	u := newTestUpdater(s.URL, dir)
+	if c := u.loadReleaseCache(); c == nil || c.ETag != `"v1"` {
+		t.Fatalf("Expected the cached release with its ETag, got %+v", c)
+	}
+	release, err := u.fetchLatestRelease()
+	if err != nil || release.TagName != "v1.2.3" {
+		t.Errorf("Expected the cached release, got %+v, %v", release, err)
+	}
+	if s.full != 1 || s.conditional != 1 {
+		t.Errorf("Expected 1 full and 1 conditional request, got %d and %d", s.full, s.conditional)
+	}
+
+	// A cache for another URL isn't used
+	if c := newTestUpdater(s.URL+"/other", dir).loadReleaseCache(); c != nil {
+		t.Errorf("Expected no cached release for another URL, got %+v", c)
+	}
+}
@@ -2,6 +2,7 @@ package updater
 
 import (
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
@@ -9,9 +10,13 @@ import (
 	"os/exec"
 	"path/filepath"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 	"syscall"
 	"time"
+
+	"golang.org/x/sys/unix"
 )
 
 const (
@@ -19,8 +24,13 @@ const (
 	GitHubRepo = "jedarden/ccdash"
 	// GitHubAPIURL is the GitHub API endpoint for releases
 	GitHubAPIURL = "https://api.github.com/repos/" + GitHubRepo + "/releases/latest"
+	// LockFileName is the file locked while an update replaces binaries
+	LockFileName = "update.lock"
 )
 
+// ErrUpdateInProgress is returned when another ccdash instance holds the update lock
+var ErrUpdateInProgress = errors.New("another ccdash instance is already updating")
+
 // Release represents a GitHub release
 type Release struct {
 	TagName string  `json:"tag_name"`
@@ -53,6 +63,24 @@ type Updater struct {
 	lastCheck      time.Time
 	cachedInfo     *UpdateInfo
 	checkInterval  time.Duration
+
+	// Build metadata reported in the User-Agent, empty when not set at build time
+	commit    string
+	buildDate string
+
+	// lockDir holds the lock file that serializes updates across instances
+	lockDir string
+
+	// historyDir holds the update log; empty to not log updates
+	historyDir string
+
+	// apiURL is the latest release endpoint, GitHubAPIURL outside tests
+	apiURL string
+
+	// release is the last release response, for conditional requests, and
+	// cacheDir keeps it across restarts; empty to keep it in memory only
+	release  *releaseCache
+	cacheDir string
 }
 
 // NewUpdater creates a new Updater instance
@@ -63,7 +91,32 @@ func NewUpdater(currentVersion string) *Updater {
 			Timeout: 10 * time.Second,
 		},
 		checkInterval: 5 * time.Minute, // Check every 5 minutes
+		lockDir:       os.TempDir(),
+		apiURL:        GitHubAPIURL,
+	}
+}
+
+// SetBuildInfo records the commit and build date of this binary, so requests
+// identify the exact build rather than just its version
+func (u *Updater) SetBuildInfo(commit, buildDate string) {
+	u.commit = commit
+	u.buildDate = buildDate
+}
+
+// userAgent returns the User-Agent for GitHub requests, e.g.
+// "ccdash/v0.8.0 (commit 1a2b3c4; built 2025-01-02T03:04:05Z)"
+func (u *Updater) userAgent() string {
+	var details []string
+	if u.commit != "" {
+		details = append(details, "commit "+u.commit)
+	}
+	if u.buildDate != "" {
+		details = append(details, "built "+u.buildDate)
+	}
+	if len(details) == 0 {
+		return "ccdash/" + u.currentVersion
 	}
+	return fmt.Sprintf("ccdash/%s (%s)", u.currentVersion, strings.Join(details, "; "))
 }
 
 // CheckForUpdate checks GitHub for a newer version
@@ -79,30 +132,9 @@ func (u *Updater) CheckForUpdate() *UpdateInfo {
 	}
 
 	// Fetch latest release from GitHub
-	req, err := http.NewRequest("GET", GitHubAPIURL, nil)
+	release, err := u.fetchLatestRelease()
 	if err != nil {
-		info.Error = fmt.Sprintf("Failed to create request: %v", err)
-		return info
-	}
-
-	req.Header.Set("Accept", "application/vnd.github.v3+json")
-	req.Header.Set("User-Agent", "ccdash/"+u.currentVersion)
-
-	resp, err := u.httpClient.Do(req)
-	if err != nil {
-		info.Error = fmt.Sprintf("Failed to check for updates: %v", err)
-		return info
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		info.Error = fmt.Sprintf("GitHub API returned status %d", resp.StatusCode)
-		return info
-	}
-
-	var release Release
-	if err := json.NewDecoder(resp.Body).Decode(&release); err != nil {
-		info.Error = fmt.Sprintf("Failed to parse release info: %v", err)
+		info.Error = err.Error()
 		return info
 	}
 
@@ -129,6 +161,92 @@ func (u *Updater) CheckForUpdate() *UpdateInfo {
 	return info
 }
 
+// fetchLatestRelease gets the latest release from the GitHub API. The request
+// is conditional on the last response, which GitHub answers with 304 Not
+// Modified without counting it against the rate limit, and is authenticated
+// with $GITHUB_TOKEN when set, for a higher limit. If the limit is reached
+// anyway, the last release fetched stands in.
+func (u *Updater) fetchLatestRelease() (*Release, error) {
+	cached := u.loadReleaseCache()
+
+	req, err := http.NewRequest("GET", u.apiURL, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/vnd.github.v3+json")
+	req.Header.Set("User-Agent", u.userAgent())
+	if token := os.Getenv("GITHUB_TOKEN"); token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	if cached != nil {
+		if cached.ETag != "" {
+			req.Header.Set("If-None-Match", cached.ETag)
+		}
+		if cached.LastModified != "" {
+			req.Header.Set("If-Modified-Since", cached.LastModified)
+		}
+	}
+
+	resp, err := u.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to check for updates: %w", err)
+	}
+	defer resp.Body.Close()
+
+	var body []byte
+	switch {
+	case resp.StatusCode == http.StatusOK:
+		body, err = io.ReadAll(io.LimitReader(resp.Body, maxReleaseSize))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read release info: %w", err)
+		}
+	case resp.StatusCode == http.StatusNotModified && cached != nil:
+		body = cached.Release
+	case isRateLimited(resp) && cached != nil:
+		body = cached.Release
+	case isRateLimited(resp):
+		return nil, rateLimitError(resp)
+	default:
+		return nil, fmt.Errorf("GitHub API returned status %d", resp.StatusCode)
+	}
+
+	var release Release
+	if err := json.Unmarshal(body, &release); err != nil {
+		return nil, fmt.Errorf("failed to parse release info: %w", err)
+	}
+	if resp.StatusCode == http.StatusOK {
+		u.saveReleaseCache(&releaseCache{
+			URL:          u.apiURL,
+			ETag:         resp.Header.Get("ETag"),
+			LastModified: resp.Header.Get("Last-Modified"),
+			Release:      body,
+		})
+	}
+	return &release, nil
+}
+
+// isRateLimited reports whether a GitHub API response was refused for
+// exceeding the rate limit
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode != http.StatusForbidden && resp.StatusCode != http.StatusTooManyRequests {
+		return false
+	}
+	return resp.Header.Get("X-RateLimit-Remaining") == "0" || resp.Header.Get("Retry-After") != ""
+}
+
+// rateLimitError explains a rate-limited response, with when the limit resets
+// if GitHub said
+func rateLimitError(resp *http.Response) error {
+	msg := "GitHub API rate limit reached"
+	if reset, err := strconv.ParseInt(resp.Header.Get("X-RateLimit-Reset"), 10, 64); err == nil {
+		msg += " until " + time.Unix(reset, 0).Format("15:04")
+	}
+	if os.Getenv("GITHUB_TOKEN") == "" {
+		msg += "; set GITHUB_TOKEN for a higher limit"
+	}
+	return errors.New(msg)
+}
+
 // findDownloadURL finds the appropriate binary for the current platform
 func (u *Updater) findDownloadURL(assets []Asset) string {
 	// Build expected asset name based on OS and arch
@@ -223,14 +341,70 @@ func (u *Updater) PerformUpdate(info *UpdateInfo) error {
 	return syscall.Exec(execPath, os.Args, os.Environ())
 }
 
-// PerformUpdateWithRestart downloads the update and restarts using multiple methods
-func (u *Updater) PerformUpdateWithRestart(info *UpdateInfo) error {
+// SetLockDir sets the directory of the lock file that keeps instances from
+// updating at the same time. It defaults to the system temp directory.
+func (u *Updater) SetLockDir(dir string) {
+	u.lockDir = dir
+}
+
+// acquireLock takes the update lock without waiting, returning
+// ErrUpdateInProgress when another instance holds it. The lock is released
+// when the returned function is called or the process exits.
+func (u *Updater) acquireLock() (release func(), err error) {
+	if err := os.MkdirAll(u.lockDir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create lock directory: %w", err)
+	}
+	f, err := os.OpenFile(filepath.Join(u.lockDir, LockFileName), os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open update lock: %w", err)
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if errors.Is(err, syscall.EWOULDBLOCK) {
+			return nil, ErrUpdateInProgress
+		}
+		return nil, fmt.Errorf("failed to lock %s: %w", f.Name(), err)
+	}
+	return func() {
+		syscall.Flock(int(f.Fd()), syscall.LOCK_UN)
+		f.Close()
+	}, nil
+}
+
+// PerformUpdateWithRestart downloads the update and restarts using multiple methods.
+// Only one instance updates at a time: if another holds the update lock, it
+// returns ErrUpdateInProgress without touching any binary. Every attempt past
+// the lock is recorded in the update log (see SetHistoryDir).
+func (u *Updater) PerformUpdateWithRestart(info *UpdateInfo) (err error) {
 	if !info.UpdateAvailable || info.DownloadURL == "" {
 		return fmt.Errorf("no update available or download URL not found")
 	}
 
+	// Two instances updating at once would clobber each other's download and
+	// .old backups in every location
+	release, err := u.acquireLock()
+	if err != nil {
+		return err
+	}
+	locked := true
+	defer func() {
+		if locked {
+			release()
+		}
+	}()
+
+	// Failures are logged here; a successful update is logged before the
+	// restart, which doesn't return
+	var updated, updateErrors []string
+	recorded := false
+	defer func() {
+		if err != nil && !recorded {
+			u.recordUpdate(info, updated, updateErrors, err)
+		}
+	}()
+
 	// Find all locations where ccdash is installed
-	allLocations := findAllBinaryLocations()
+	allLocations := FindAllBinaryLocations()
 	if len(allLocations) == 0 {
 		return fmt.Errorf("failed to find any ccdash binary locations")
 	}
@@ -277,14 +451,11 @@ func (u *Updater) PerformUpdateWithRestart(info *UpdateInfo) error {
 	}
 
 	// Update all found locations
-	var updateErrors []string
-	var updatedCount int
-
 	for _, targetPath := range allLocations {
 		if err := updateBinaryAt(tmpPath, targetPath); err != nil {
 			updateErrors = append(updateErrors, fmt.Sprintf("%s: %v", targetPath, err))
 		} else {
-			updatedCount++
+			updated = append(updated, targetPath)
 		}
 	}
 
@@ -292,14 +463,54 @@ func (u *Updater) PerformUpdateWithRestart(info *UpdateInfo) error {
 	os.Remove(tmpPath)
 
 	// If no locations were updated successfully, return error
-	if updatedCount == 0 {
+	if len(updated) == 0 {
 		return fmt.Errorf("failed to update any binary location: %v", updateErrors)
 	}
+	u.recordUpdate(info, updated, updateErrors, nil)
+	recorded = true
+
+	// Release the lock first so the restarted process, or another instance,
+	// can update later
+	release()
+	locked = false
 
 	// Try multiple restart methods using the current executable path
 	return u.restartApplication(realExecPath)
 }
 
+// UpdateTarget is a binary PerformUpdateWithRestart would replace
+type UpdateTarget struct {
+	Path      string
+	NeedsSudo bool // Its directory isn't writable, so sudo or pkexec would be used
+	Running   bool // The binary of this process, which is restarted afterwards
+}
+
+// PlanUpdate lists the binaries PerformUpdateWithRestart would replace, in
+// path order, without downloading or changing anything
+func (u *Updater) PlanUpdate() []UpdateTarget {
+	running := ""
+	if execPath, err := os.Executable(); err == nil {
+		running = execPath
+		if realPath, err := filepath.EvalSymlinks(execPath); err == nil {
+			running = realPath
+		}
+	}
+
+	locations := FindAllBinaryLocations()
+	sort.Strings(locations)
+	targets := make([]UpdateTarget, 0, len(locations))
+	for _, path := range locations {
+		targets = append(targets, UpdateTarget{
+			Path: path,
+			// updateBinaryAt renames the old binary aside, which needs write
+			// access to the directory rather than the file
+			NeedsSudo: unix.Access(filepath.Dir(path), unix.W_OK) != nil,
+			Running:   path == running,
+		})
+	}
+	return targets
+}
+
 // updateBinaryAt updates the binary at the specified path
 // On Linux/macOS, a running binary can be renamed but not overwritten (ETXTBSY).
 // The correct approach is: rename old -> copy new to original path -> delete old
@@ -441,9 +652,9 @@ func (u *Updater) restartApplication(execPath string) error {
 	return fmt.Errorf("all restart methods failed, syscall.Exec error: %v", execErr)
 }
 
-// findAllBinaryLocations finds all locations where ccdash binary exists
+// FindAllBinaryLocations finds all locations where ccdash binary exists
 // This includes the current executable and common installation paths
-func findAllBinaryLocations() []string {
+func FindAllBinaryLocations() []string {
 	locations := make(map[string]bool)
 
 	// 1. Get the currently running executable (most important)
@@ -526,9 +737,9 @@ func copyFile(src, dst string) error {
 // compareVersions compares two semantic version strings
 // Returns -1 if v1 < v2, 0 if v1 == v2, 1 if v1 > v2
 func compareVersions(v1, v2 string) int {
-	// Remove 'v' prefix if present
-	v1 = strings.TrimPrefix(v1, "v")
-	v2 = strings.TrimPrefix(v2, "v")
+	// Remove 'v' prefix if present, and any build metadata ("+commit")
+	v1, _, _ = strings.Cut(strings.TrimPrefix(v1, "v"), "+")
+	v2, _, _ = strings.Cut(strings.TrimPrefix(v2, "v"), "+")
 
 	parts1 := strings.Split(v1, ".")
 	parts2 := strings.Split(v2, ".")
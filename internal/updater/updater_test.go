@@ -0,0 +1,111 @@
+package updater
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+)
+
+func TestCompareVersions(t *testing.T) {
+	tests := []struct {
+		v1, v2 string
+		want   int
+	}{
+		{"v1.0.3", "v1.0.3", 0},
+		{"v1.0.3", "v1.0.4", -1},
+		{"v1.1.0", "v1.0.9", 1},
+		{"1.2", "v1.2.0", 0},
+		{"v1.10.0", "v1.9.0", 1},
+		// Build metadata doesn't make a version newer or older
+		{"v1.0.3+1a2b3c4", "v1.0.3", 0},
+		{"v1.0.3", "v1.0.3+1a2b3c4", 0},
+		{"v1.0.3+1a2b3c4", "v1.0.4+5d6e7f8", -1},
+		{"v1.0.4+5d6e7f8", "v1.0.3", 1},
+	}
+
+	for _, tt := range tests {
+		if got := compareVersions(tt.v1, tt.v2); got != tt.want {
+			t.Errorf("compareVersions(%q, %q) = %d, want %d", tt.v1, tt.v2, got, tt.want)
+		}
+	}
+}
+
+func TestUserAgent(t *testing.T) {
+	tests := []struct {
+		commit, buildDate string
+		want              string
+	}{
+		{"", "", "ccdash/v0.8.0"},
+		{"1a2b3c4", "", "ccdash/v0.8.0 (commit 1a2b3c4)"},
+		{"", "2025-01-02T03:04:05Z", "ccdash/v0.8.0 (built 2025-01-02T03:04:05Z)"},
+		{"1a2b3c4", "2025-01-02T03:04:05Z", "ccdash/v0.8.0 (commit 1a2b3c4; built 2025-01-02T03:04:05Z)"},
+	}
+
+	for _, tt := range tests {
+		u := NewUpdater("v0.8.0")
+		u.SetBuildInfo(tt.commit, tt.buildDate)
+		if got := u.userAgent(); got != tt.want {
+			t.Errorf("userAgent() with commit %q, build date %q = %q, want %q", tt.commit, tt.buildDate, got, tt.want)
+		}
+	}
+}
+
+func TestPlanUpdate(t *testing.T) {
+	dir, err := os.MkdirTemp("", "ccdash-test-*")
+	if err != nil {
+		t.Fatalf("Failed to create temp dir: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(dir) })
+	dir, _ = filepath.EvalSymlinks(dir)
+
+	writable, readOnly := filepath.Join(dir, "bin"), filepath.Join(dir, "ro")
+	for _, d := range []string{writable, readOnly} {
+		if err := os.MkdirAll(d, 0755); err != nil {
+			t.Fatalf("Failed to create %s: %v", d, err)
+		}
+		if err := os.WriteFile(filepath.Join(d, "ccdash"), []byte("#!/bin/sh\n"), 0755); err != nil {
+			t.Fatalf("Failed to write binary: %v", err)
+		}
+	}
+	if err := os.Chmod(readOnly, 0555); err != nil {
+		t.Fatalf("Failed to make %s read-only: %v", readOnly, err)
+	}
+	t.Cleanup(func() { os.Chmod(readOnly, 0755) })
+	t.Setenv("HOME", dir)
+	t.Setenv("PATH", readOnly+string(os.PathListSeparator)+writable)
+
+	targets := NewUpdater("v1.0.3").PlanUpdate()
+	paths := make([]string, len(targets))
+	for i, target := range targets {
+		paths[i] = target.Path
+	}
+	if !slices.IsSorted(paths) {
+		t.Errorf("Expected targets in path order, got %v", paths)
+	}
+
+	find := func(path string) UpdateTarget {
+		i := slices.Index(paths, path)
+		if i < 0 {
+			t.Fatalf("Expected %s in the plan, got %v", path, paths)
+		}
+		return targets[i]
+	}
+	if target := find(filepath.Join(writable, "ccdash")); target.NeedsSudo || target.Running {
+		t.Errorf("Expected the writable binary to need no sudo and not be running, got %+v", target)
+	}
+	// Root can write anywhere, so only other users see the directory as read-only
+	if target := find(filepath.Join(readOnly, "ccdash")); target.NeedsSudo != (os.Geteuid() != 0) {
+		t.Errorf("Expected NeedsSudo %v for the read-only directory, got %+v", os.Geteuid() != 0, target)
+	}
+
+	running := 0
+	for _, target := range targets {
+		if target.Running {
+			running++
+		}
+	}
+	if running != 1 {
+		t.Errorf("Expected the test binary marked running once, got %d in %+v", running, targets)
+	}
+}